@@ -390,6 +390,7 @@ func BenchmarkFullRPCCycle(b *testing.B) {
 			serialize.BitsToBytes(
 				rpc.BitSizePrefix() +
 					rpc.BitSizeContext(ctx) +
+					rpc.BitSizeTimeout(0) +
 					serialize.BitSizeUInt64(requestID) +
 					serialize.BitSizeUInt64(serviceID) +
 					serialize.BitSizeUInt64(methodID) +
@@ -401,6 +402,7 @@ func BenchmarkFullRPCCycle(b *testing.B) {
 			writer.Reset()
 			rpc.SerializePrefix(writer, rpc.RequestPrefix)
 			rpc.SerializeContext(writer, ctx)
+			rpc.SerializeTimeout(writer, 0)
 			serialize.SerializeUInt64(writer, requestID)
 			serialize.SerializeUInt64(writer, serviceID)
 			serialize.SerializeUInt64(writer, methodID)
@@ -434,12 +436,14 @@ func BenchmarkFullRPCCycle(b *testing.B) {
 			serialize.BitsToBytes(
 				rpc.BitSizePrefix() +
 					rpc.BitSizeContext(ctx) +
+					rpc.BitSizeTimeout(0) +
 					serialize.BitSizeUInt64(requestID) +
 					serialize.BitSizeUInt64(serviceID) +
 					serialize.BitSizeUInt64(methodID) +
 					req.BitSize()))
 		rpc.SerializePrefix(writer, rpc.RequestPrefix)
 		rpc.SerializeContext(writer, ctx)
+		rpc.SerializeTimeout(writer, 0)
 		serialize.SerializeUInt64(writer, requestID)
 		serialize.SerializeUInt64(writer, serviceID)
 		serialize.SerializeUInt64(writer, methodID)
@@ -459,6 +463,9 @@ func BenchmarkFullRPCCycle(b *testing.B) {
 			var reqCtx context.Context = context.Background()
 			rpc.DeserializeContext(&reqCtx, reader)
 
+			var timeout time.Duration
+			rpc.DeserializeTimeout(&timeout, reader)
+
 			var reqID, svcID, methID uint64
 			serialize.DeserializeUInt64(&reqID, reader)
 			serialize.DeserializeUInt64(&svcID, reader)
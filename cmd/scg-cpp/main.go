@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/kbirk/scg/internal/gen/cpp_gen"
@@ -15,15 +16,30 @@ const (
 )
 
 var (
-	input   string
-	output  string
-	baseDir string
+	input    string
+	output   string
+	baseDir  string
+	includes includeDirs
 )
 
+// includeDirs collects the repeatable `--include` / `-I` flag.
+type includeDirs []string
+
+func (i *includeDirs) String() string {
+	return strings.Join(*i, ",")
+}
+
+func (i *includeDirs) Set(value string) error {
+	*i = append(*i, value)
+	return nil
+}
+
 func main() {
 
 	flag.StringVar(&input, "input", "", "Input dir")
 	flag.StringVar(&output, "output", "", "Output dir")
+	flag.Var(&includes, "include", "Include dir searched for imported files, may be repeated")
+	flag.Var(&includes, "I", "Include dir searched for imported files, may be repeated")
 	flag.StringVar(&baseDir, "base-dir", "", "Golang base package")
 
 	flag.Parse()
@@ -40,24 +56,36 @@ func main() {
 
 	red := color.New(color.FgRed, color.Bold).SprintFunc()
 	green := color.New(color.FgGreen, color.Bold).SprintFunc()
+	yellow := color.New(color.FgYellow, color.Bold).SprintFunc()
 
-	p, err := parse.NewParse(input)
+	p, err := parse.NewParse(input, includes...)
 	if err != nil {
 		os.Stderr.WriteString(red("ERROR: ") + fmt.Sprintf("Failed to parse input: %v\n", err.Error()))
 		os.Exit(1)
 	}
 
+	for _, warning := range p.Warnings {
+		os.Stderr.WriteString(yellow("WARNING: ") + fmt.Sprintf("%v\n", warning.Error().Error()))
+	}
+
 	err = cpp_gen.GenerateCppCode(baseDir, output, p)
 	if err != nil {
 		os.Stderr.WriteString(red("ERROR: ") + fmt.Sprintf("Failed to generate cpp output: %v\n", err.Error()))
 		os.Exit(1)
 	}
 
-	if len(p.Files) == 0 {
+	generated := 0
+	for _, file := range p.Files {
+		if !file.External {
+			generated++
+		}
+	}
+
+	if generated == 0 {
 		os.Stderr.WriteString(red("ERROR: ") + "No files to generate\n")
 		os.Exit(1)
 	}
 
-	os.Stdout.WriteString(green("SUCCESS: ") + fmt.Sprintf("Generated code for %d files\n", len(p.Files)))
+	os.Stdout.WriteString(green("SUCCESS: ") + fmt.Sprintf("Generated code for %d files\n", generated))
 	os.Stdout.WriteString(p.ToStringPretty())
 }
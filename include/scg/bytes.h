@@ -0,0 +1,162 @@
+#pragma once
+
+#include <cstdint>
+#include <string>
+#include <vector>
+#include <stdexcept>
+#include <utility>
+
+#include "scg/serialize.h"
+
+#include "nlohmann/json.hpp"
+
+namespace scg {
+namespace type {
+
+// standard base64 with padding, as used by Go's encoding/json for []byte
+inline std::string base64_encode(const std::vector<uint8_t>& data)
+{
+	static constexpr char alphabet[] = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+	std::string out;
+	out.reserve(((data.size() + 2) / 3) * 4);
+
+	size_t i = 0;
+	for (; i + 2 < data.size(); i += 3) {
+		uint32_t n = (uint32_t(data[i]) << 16) | (uint32_t(data[i+1]) << 8) | uint32_t(data[i+2]);
+		out.push_back(alphabet[(n >> 18) & 0x3f]);
+		out.push_back(alphabet[(n >> 12) & 0x3f]);
+		out.push_back(alphabet[(n >> 6) & 0x3f]);
+		out.push_back(alphabet[n & 0x3f]);
+	}
+
+	size_t rem = data.size() - i;
+	if (rem == 1) {
+		uint32_t n = uint32_t(data[i]) << 16;
+		out.push_back(alphabet[(n >> 18) & 0x3f]);
+		out.push_back(alphabet[(n >> 12) & 0x3f]);
+		out.append("==");
+	} else if (rem == 2) {
+		uint32_t n = (uint32_t(data[i]) << 16) | (uint32_t(data[i+1]) << 8);
+		out.push_back(alphabet[(n >> 18) & 0x3f]);
+		out.push_back(alphabet[(n >> 12) & 0x3f]);
+		out.push_back(alphabet[(n >> 6) & 0x3f]);
+		out.push_back('=');
+	}
+
+	return out;
+}
+
+inline std::pair<std::vector<uint8_t>, bool> base64_decode(const std::string& str)
+{
+	auto decode = [](char c) -> int {
+		if (c >= 'A' && c <= 'Z') return c - 'A';
+		if (c >= 'a' && c <= 'z') return c - 'a' + 26;
+		if (c >= '0' && c <= '9') return c - '0' + 52;
+		if (c == '+') return 62;
+		if (c == '/') return 63;
+		return -1;
+	};
+
+	std::vector<uint8_t> out;
+	if (str.size() % 4 != 0) {
+		return std::make_pair(out, false);
+	}
+	out.reserve((str.size() / 4) * 3);
+
+	for (size_t i = 0; i < str.size(); i += 4) {
+		bool last = i + 4 == str.size();
+		int pad = 0;
+		if (last && str[i+3] == '=') {
+			pad = str[i+2] == '=' ? 2 : 1;
+		}
+
+		uint32_t n = 0;
+		for (size_t j = 0; j < 4; j++) {
+			int v = 0;
+			if (j < 4 - size_t(pad)) {
+				v = decode(str[i+j]);
+				if (v < 0) {
+					return std::make_pair(std::vector<uint8_t>(), false);
+				}
+			}
+			n = (n << 6) | uint32_t(v);
+		}
+
+		out.push_back(uint8_t(n >> 16));
+		if (pad < 2) {
+			out.push_back(uint8_t(n >> 8));
+		}
+		if (pad < 1) {
+			out.push_back(uint8_t(n));
+		}
+	}
+
+	return std::make_pair(out, true);
+}
+
+// bytes holds the value of a `bytes` field. It is a byte vector in every respect
+// but its JSON encoding, which is a base64 string rather than an array of
+// numbers, matching the encoding of []byte in Go. Other byte vectors, such as
+// a list<byte>, are left to encode as arrays.
+class bytes : public std::vector<uint8_t> {
+
+public:
+
+	using std::vector<uint8_t>::vector;
+
+	inline bytes() = default;
+
+	inline bytes(const std::vector<uint8_t>& data)
+		: std::vector<uint8_t>(data)
+	{
+	}
+
+	inline bytes(std::vector<uint8_t>&& data)
+		: std::vector<uint8_t>(std::move(data))
+	{
+	}
+
+	// bytes share the encoding of any other vector, but are copied in bulk
+	// rather than one element at a time
+	friend inline uint32_t bit_size(const bytes& value)
+	{
+		return scg::serialize::bit_size(static_cast<const std::vector<uint8_t>&>(value));
+	}
+
+	template <typename WriterType>
+	friend inline void serialize(WriterType& writer, const bytes& value)
+	{
+		scg::serialize::serialize(writer, static_cast<const std::vector<uint8_t>&>(value));
+	}
+
+	template <typename ReaderType>
+	friend inline error::Error deserialize(bytes& value, ReaderType& reader)
+	{
+		return scg::serialize::deserialize(static_cast<std::vector<uint8_t>&>(value), reader);
+	}
+
+};
+
+// nlohmann json serialization
+
+inline void to_json(nlohmann::json& j, const bytes& data)
+{
+	j = base64_encode(data);
+}
+
+inline void from_json(const nlohmann::json& j, bytes& data)
+{
+	if (j.is_null()) {
+		data.clear();
+		return;
+	}
+	auto [res, ok] = base64_decode(j.get<std::string>());
+	if (!ok) {
+		throw std::runtime_error("invalid base64 string");
+	}
+	data = std::move(res);
+}
+
+}
+}
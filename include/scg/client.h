@@ -24,10 +24,26 @@
 #include "scg/middleware.h"
 #include "scg/transport.h"
 #include "scg/stream.h"
+#include "scg/status.h"
 
 namespace scg {
 namespace rpc {
 
+// serializeCancel serializes a CANCEL frame for an in-flight unary request.
+inline std::vector<uint8_t> serializeCancel(uint64_t requestID)
+{
+	using scg::serialize::bit_size;
+
+	serialize::Writer writer(
+		scg::serialize::bits_to_bytes(
+			bit_size(CANCEL_PREFIX) +
+			bit_size(requestID)));
+
+	writer.write(CANCEL_PREFIX);
+	writer.write(requestID);
+	return writer.bytes();
+}
+
 enum class ConnectionStatus {
 	NOT_CONNECTED,
 	CONNECTED,
@@ -94,7 +110,12 @@ public:
 		std::lock_guard<std::mutex> lock(mu_);
 
 		failPendingRequestsUnsafe("Connection closed");
-		failStreamsUnsafe(error::Error("connection closed"));
+		failStreamsUnsafe(unavailable("connection closed"));
+
+		for (auto& pair : draining_) {
+			pair.second->close();
+		}
+		draining_.clear();
 
 		return disconnectUnsafe();
 	}
@@ -108,7 +129,7 @@ public:
 
 		auto err = connectUnsafe();
 		if (err) {
-			return std::make_pair(nullptr, err);
+			return std::make_pair(nullptr, unavailable(err.message()));
 		}
 
 		uint64_t streamID = requestID_++;
@@ -128,7 +149,7 @@ public:
 			},
 			config_.streamRecvBufferSize);
 
-		streams_[streamID] = stream;
+		streams_[streamID] = LiveStream{stream, connectionGeneration_};
 
 		err = sendBytesUnsafe(serializeStreamOpen(ctx, streamID, serviceID, methodID));
 		if (err) {
@@ -147,15 +168,16 @@ public:
 			return std::make_pair(serialize::Reader({}), err);
 		}
 
-		if (ctx.hasDeadline()) {
-			auto status = future.wait_until(ctx.getDeadline());
-			if (status == std::future_status::timeout) {
-				// Remove request from map
-				std::lock_guard<std::mutex> lock(mu_);
-				requests_.erase(requestID);
-				return std::make_pair(serialize::Reader({}), error::Error("Request timed out"));
-			}
+		// Cancelling the context, or reaching its deadline, fails the request in
+		// place of its response, unless the response has already arrived.
+		auto cancelID = ctx.onCancel([this, requestID]() {
+			cancelRequest(requestID, Status(Code::CANCELED, "Request cancelled"));
+		});
+		if (ctx.hasDeadline() && future.wait_until(ctx.getDeadline()) != std::future_status::ready) {
+			cancelRequest(requestID, Status(Code::DEADLINE_EXCEEDED, "Request timed out"));
 		}
+		future.wait();
+		ctx.removeOnCancel(cancelID);
 
 		return receiveMessage(future);
 	}
@@ -172,31 +194,129 @@ public:
 
 protected:
 
+	// PendingRequest is a unary request awaiting its response on the connection
+	// of generation gen.
+	struct PendingRequest {
+		std::shared_ptr<std::promise<serialize::Reader>> promise;
+		uint64_t gen;
+	};
+
+	// LiveStream is a stream opened on the connection of generation gen.
+	struct LiveStream {
+		std::shared_ptr<ClientStream> stream;
+		uint64_t gen;
+	};
+
+	// cancelRequest fails a pending request with the status and, if its
+	// response is still outstanding, tells the server to cancel its handler. The
+	// CANCEL is best-effort: a failed send means the connection is gone, which
+	// cancels the handler anyway.
+	void cancelRequest(uint64_t requestID, const Status& status)
+	{
+		std::lock_guard<std::mutex> lock(mu_);
+		auto it = requests_.find(requestID);
+		if (it == requests_.end()) {
+			return;
+		}
+		auto conn = connectionUnsafe(it->second.gen);
+		it->second.promise->set_value(createErrorReader(status));
+		requests_.erase(it);
+		if (conn) {
+			conn->send(serializeCancel(requestID));
+		}
+	}
+
+	// connectionUnsafe returns the connection of generation gen, whether it is
+	// the current connection or one draining after a GOAWAY, or nullptr if it
+	// is gone (caller holds mu_).
+	std::shared_ptr<Connection> connectionUnsafe(uint64_t gen)
+	{
+		if (gen == connectionGeneration_) {
+			return status_ == ConnectionStatus::CONNECTED ? connection_ : nullptr;
+		}
+		auto it = draining_.find(gen);
+		if (it != draining_.end()) {
+			return it->second;
+		}
+		return nullptr;
+	}
+
 	void failPendingRequestsUnsafe(const std::string& error)
 	{
 		for (auto& pair : requests_) {
-			pair.second->set_value(createErrorReader(error));
+			pair.second.promise->set_value(createErrorReader(error));
 		}
 		requests_.clear();
 	}
 
+	// failPendingRequestsUnsafe fails the pending requests sent on the
+	// connection of generation gen.
+	void failPendingRequestsUnsafe(const std::string& error, uint64_t gen)
+	{
+		for (auto it = requests_.begin(); it != requests_.end();) {
+			if (it->second.gen == gen) {
+				it->second.promise->set_value(createErrorReader(error));
+				it = requests_.erase(it);
+			} else {
+				++it;
+			}
+		}
+	}
+
+	// unavailable returns a local connection failure as an UNAVAILABLE status,
+	// so that callers can distinguish it from a failure reported by the server.
+	static error::Error unavailable(const std::string& message)
+	{
+		return Status(Code::UNAVAILABLE, message).toError();
+	}
+
 	void failStreamsUnsafe(const error::Error& err)
 	{
 		for (auto& pair : streams_) {
-			pair.second->die(err);
+			pair.second.stream->die(err);
 		}
 		streams_.clear();
 	}
 
+	// failStreamsUnsafe fails the streams opened on the connection of
+	// generation gen.
+	void failStreamsUnsafe(const error::Error& err, uint64_t gen)
+	{
+		for (auto it = streams_.begin(); it != streams_.end();) {
+			if (it->second.gen == gen) {
+				it->second.stream->die(err);
+				it = streams_.erase(it);
+			} else {
+				++it;
+			}
+		}
+	}
+
+	// handleGoAway stops issuing new calls on the connection of generation gen,
+	// after the server announced that it is shutting down. The calls in flight
+	// on it run to completion while the next call connects anew; the server
+	// closes the connection once they are done.
+	void handleGoAway(uint64_t gen)
+	{
+		std::lock_guard<std::mutex> lock(mu_);
+		if (gen != connectionGeneration_ || !connection_) {
+			return;
+		}
+		draining_[gen] = connection_;
+		connection_.reset();
+		status_ = ConnectionStatus::NOT_CONNECTED;
+	}
+
 	void removeStream(uint64_t streamID)
 	{
 		std::lock_guard<std::mutex> lock(mu_);
 		streams_.erase(streamID);
 	}
 
-	// handleStreamFrame routes one inbound stream frame to its ClientStream.
-	// Runs on the transport I/O thread (via onMessage), preserving per-stream order.
-	void handleStreamFrame(serialize::Reader& reader)
+	// handleStreamFrame routes one inbound stream frame on the connection of
+	// generation gen to its ClientStream. Runs on the transport I/O thread (via
+	// onMessage), preserving per-stream order.
+	void handleStreamFrame(uint64_t gen, serialize::Reader& reader)
 	{
 		uint64_t streamID = 0;
 		if (serialize::deserialize(streamID, reader)) {
@@ -212,7 +332,7 @@ protected:
 			std::shared_ptr<Connection> conn;
 			{
 				std::lock_guard<std::mutex> lock(mu_);
-				conn = connection_;
+				conn = connectionUnsafe(gen);
 			}
 			if (conn) {
 				conn->send(serializeStreamControl(STREAM_FRAME_PONG));
@@ -222,6 +342,10 @@ protected:
 		if (frameKind == STREAM_FRAME_PONG) {
 			return; // liveness already recorded via lastActivity
 		}
+		if (frameKind == STREAM_FRAME_GOAWAY) {
+			handleGoAway(gen);
+			return;
+		}
 
 		std::shared_ptr<ClientStream> stream;
 		{
@@ -230,7 +354,7 @@ protected:
 			if (it == streams_.end()) {
 				return; // unknown / already-closed stream
 			}
-			stream = it->second;
+			stream = it->second.stream;
 		}
 
 		switch (frameKind) {
@@ -239,8 +363,9 @@ protected:
 					// Bounded buffer overflowed: notify the server and drop the stream.
 					{
 						std::lock_guard<std::mutex> lock(mu_);
-						if (connection_) {
-							connection_->send(serializeStreamClose(streamID, STREAM_STATUS_ERROR, "stream receive buffer overflow"));
+						auto conn = connectionUnsafe(gen);
+						if (conn) {
+							conn->send(serializeStreamClose(streamID, Status(Code::RESOURCE_EXHAUSTED, "stream receive buffer overflow")));
 						}
 					}
 					removeStream(streamID);
@@ -251,17 +376,17 @@ protected:
 				stream->closeRecv(nullptr);
 				break;
 			case STREAM_FRAME_CLOSE: {
-				uint8_t status = 0;
-				serialize::deserialize(status, reader);
-				std::string message;
-				serialize::deserialize(message, reader);
-				if (status == STREAM_STATUS_OK) {
+				Status status;
+				auto err = deserialize(status, reader);
+				if (err) {
+					stream->die(err);
+				} else if (status.ok()) {
 					stream->die(nullptr);
 				} else {
-					if (message.empty()) {
-						message = "stream closed with error";
+					if (status.message().empty()) {
+						status = Status(status.code(), "stream closed with error");
 					}
-					stream->die(error::Error(message));
+					stream->die(status.toError());
 				}
 				removeStream(streamID);
 				break;
@@ -298,28 +423,39 @@ protected:
 		// Set up handlers
 		connection_->setFailHandler([this, gen](const error::Error& err) {
 			std::lock_guard<std::mutex> lock(mu_);
+			if (draining_.erase(gen) != 0) {
+				// The server closing a connection it told to GOAWAY is expected.
+				failPendingRequestsUnsafe("Connection closed", gen);
+				failStreamsUnsafe(unavailable("connection closed"), gen);
+				return;
+			}
 			if (gen != connectionGeneration_) {
 				return; // stale handler from a replaced connection
 			}
 			status_ = ConnectionStatus::FAILED;
-			// Fail all pending requests and live streams
-			failPendingRequestsUnsafe("Connection failed: " + err.message());
-			failStreamsUnsafe(error::Error("connection failed: " + err.message()));
+			// Fail the pending requests and live streams of the connection
+			failPendingRequestsUnsafe("Connection failed: " + err.message(), gen);
+			failStreamsUnsafe(unavailable("connection failed: " + err.message()), gen);
 		});
 
 		connection_->setCloseHandler([this, gen]() {
 			std::lock_guard<std::mutex> lock(mu_);
+			if (draining_.erase(gen) != 0) {
+				failPendingRequestsUnsafe("Connection closed", gen);
+				failStreamsUnsafe(unavailable("connection closed"), gen);
+				return;
+			}
 			if (gen != connectionGeneration_) {
 				return; // stale handler from a replaced connection
 			}
 			status_ = ConnectionStatus::NOT_CONNECTED;
-			// Fail all pending requests and live streams
-			failPendingRequestsUnsafe("Connection closed");
-			failStreamsUnsafe(error::Error("connection closed"));
+			// Fail the pending requests and live streams of the connection
+			failPendingRequestsUnsafe("Connection closed", gen);
+			failStreamsUnsafe(unavailable("connection closed"), gen);
 		});
 
-		connection_->setMessageHandler([this](const std::vector<uint8_t>& data) {
-			onMessage(data);
+		connection_->setMessageHandler([this, gen](const std::vector<uint8_t>& data) {
+			onMessage(gen, data);
 		});
 
 		startKeepaliveUnsafe();
@@ -341,24 +477,40 @@ protected:
 	{
 		auto err = connectUnsafe();
 		if (err) {
-			return err;
+			return unavailable(err.message());
 		}
 
 		if (status_ == ConnectionStatus::CONNECTED && connection_) {
-			return connection_->send(msg);
+			err = connection_->send(msg);
+			if (err) {
+				return unavailable(err.message());
+			}
+			return nullptr;
 		}
 
-		return error::Error("Connection not available");
+		return unavailable("Connection not available");
 	}
 
+	// createErrorReader returns a response that fails a pending request locally
+	// with an UNAVAILABLE status.
 	serialize::Reader createErrorReader(std::string err)
+	{
+		return createErrorReader(Status(Code::UNAVAILABLE, err));
+	}
+
+	// createErrorReader returns a response that fails a pending request locally
+	// with the status.
+	serialize::Reader createErrorReader(const Status& status)
 	{
 		using scg::serialize::bit_size; // adl trickery
 
 		serialize::Writer writer(
 			scg::serialize::bits_to_bytes(
 				bit_size(ERROR_RESPONSE) +
-				bit_size(err)));
+				bit_size(status)));
+
+		writer.write(ERROR_RESPONSE);
+		writer.write(status);
 
 		return serialize::Reader(writer.bytes());
 	}
@@ -441,8 +593,8 @@ protected:
 		if (connection_ != timedOut) {
 			return;
 		}
-		failPendingRequestsUnsafe("keepalive timeout");
-		failStreamsUnsafe(error::Error("keepalive timeout"));
+		failPendingRequestsUnsafe("keepalive timeout", connectionGeneration_);
+		failStreamsUnsafe(unavailable("keepalive timeout"), connectionGeneration_);
 		status_ = ConnectionStatus::FAILED;
 		connection_->close();
 		connection_.reset();
@@ -460,7 +612,7 @@ protected:
 		}
 	}
 
-	void onMessage(const std::vector<uint8_t>& data)
+	void onMessage(uint64_t gen, const std::vector<uint8_t>& data)
 	{
 		lastActivityNs_.store(steadyNowNs());
 
@@ -476,7 +628,7 @@ protected:
 		}
 
 		if (prefix == STREAM_PREFIX) {
-			handleStreamFrame(reader);
+			handleStreamFrame(gen, reader);
 			return;
 		}
 
@@ -500,7 +652,7 @@ protected:
 
 		auto iter = requests_.find(requestID);
 		if (iter != requests_.end()) {
-			iter->second->set_value(reader);
+			iter->second.promise->set_value(reader);
 		} else {
 			// Response for an unknown request ID — this can happen when a context
 			// timeout cleaned up the request before the server's response arrived.
@@ -524,10 +676,12 @@ protected:
 
 		using scg::serialize::bit_size; // adl trickery
 
+		uint64_t timeout = ctx.timeout();
 		serialize::Writer writer(
 			scg::serialize::bits_to_bytes(
 				bit_size(REQUEST_PREFIX) +
 				bit_size(ctx) +
+				bit_size(timeout) +
 				bit_size(requestID) +
 				bit_size(serviceID) +
 				bit_size(methodID) +
@@ -535,6 +689,7 @@ protected:
 
 		writer.write(REQUEST_PREFIX);
 		writer.write(ctx);
+		writer.write(timeout);
 		writer.write(requestID);
 		writer.write(serviceID);
 		writer.write(methodID);
@@ -544,14 +699,15 @@ protected:
 
 		std::lock_guard<std::mutex> lock(mu_);
 
-		requests_[requestID] = promise;
-
 		auto err = sendBytesUnsafe(writer.bytes());
 		if (err) {
-			requests_.erase(requestID);
 			return std::make_tuple(std::future<serialize::Reader>(), 0, err);
 		}
 
+		// Registered under the same lock as the send, so the response cannot be
+		// read before the request is, and bound to the connection it was sent on
+		requests_[requestID] = PendingRequest{promise, connectionGeneration_};
+
 		return std::make_tuple(promise->get_future(), requestID, nullptr);
 	}
 
@@ -566,13 +722,15 @@ protected:
 			return std::make_pair(reader, nullptr);
 		}
 
-		std::string errMsg;
-		serialize::deserialize(errMsg, reader);
-
-		if (errMsg == "") {
-			errMsg = "Unknown error";
+		Status status;
+		auto err = deserialize(status, reader);
+		if (err) {
+			return std::make_pair(serialize::Reader({}), err);
+		}
+		if (status.ok()) {
+			status = Status(Code::UNKNOWN, "Unknown error");
 		}
-		return std::make_pair(serialize::Reader({}), error::Error(errMsg));
+		return std::make_pair(serialize::Reader({}), status.toError());
 	}
 
 private:
@@ -585,12 +743,16 @@ private:
 	std::vector<scg::middleware::Middleware> middleware_;
 
 	uint64_t requestID_;
-	std::map<uint64_t, std::shared_ptr<std::promise<serialize::Reader>>> requests_;
-	std::map<uint64_t, std::shared_ptr<ClientStream>> streams_;
+	std::map<uint64_t, PendingRequest> requests_;
+	std::map<uint64_t, LiveStream> streams_;
 
 	// Bumped on each (re)connect so stale connection handlers can be ignored.
 	uint64_t connectionGeneration_ = 0;
 
+	// Connections told to GOAWAY by the server, by generation, kept until their
+	// calls complete and the server closes them.
+	std::map<uint64_t, std::shared_ptr<Connection>> draining_;
+
 	// Keepalive state.
 	std::atomic<int64_t> lastActivityNs_{0};
 	std::atomic<bool> keepaliveRunning_{false};
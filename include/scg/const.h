@@ -22,6 +22,14 @@ namespace rpc {
 		0x00, 0x00, 0x73, 0x63,
 		0x67, 0x2D, 0x73, 0x74,
 		0x72, 0x65, 0x61, 0x6D};
+	// CANCEL_PREFIX tags a frame cancelling an in-flight unary request, sent by
+	// the client when a call is cancelled or times out before its response
+	// arrives. It carries only the request id. "scg-cancel"
+	constexpr std::array<uint8_t, 16> CANCEL_PREFIX = {
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x73, 0x63,
+		0x67, 0x2D, 0x63, 0x61,
+		0x6E, 0x63, 0x65, 0x6C};
 
 	constexpr uint8_t ERROR_RESPONSE = 0x01;
 	constexpr uint8_t MESSAGE_RESPONSE = 0x02;
@@ -34,15 +42,12 @@ namespace rpc {
 	constexpr uint32_t DEFAULT_MAX_RECV_MESSAGE_SIZE = 32u << 20; // 32 MiB
 
 	// Streaming frame kinds, carried as a uint8 immediately after the stream id.
-	constexpr uint8_t STREAM_FRAME_OPEN = 0x01;        // client -> server: open (ctx, serviceID, methodID)
+	constexpr uint8_t STREAM_FRAME_OPEN = 0x01;        // client -> server: open (ctx, timeout, serviceID, methodID)
 	constexpr uint8_t STREAM_FRAME_MESSAGE = 0x02;     // bidirectional: a single serialized message
 	constexpr uint8_t STREAM_FRAME_HALF_CLOSE = 0x03;  // sender done sending, still receiving
-	constexpr uint8_t STREAM_FRAME_CLOSE = 0x04;       // terminal: status + message
+	constexpr uint8_t STREAM_FRAME_CLOSE = 0x04;       // terminal: status
 	constexpr uint8_t STREAM_FRAME_PING = 0x05;        // connection-level keepalive probe (stream id ignored)
 	constexpr uint8_t STREAM_FRAME_PONG = 0x06;        // connection-level keepalive reply (stream id ignored)
-
-	// Stream close statuses, carried in a CLOSE frame.
-	constexpr uint8_t STREAM_STATUS_OK = 0x00;
-	constexpr uint8_t STREAM_STATUS_ERROR = 0x01;
+	constexpr uint8_t STREAM_FRAME_GOAWAY = 0x07;      // server -> client: shutting down, issue new calls elsewhere (stream id ignored)
 }
 }
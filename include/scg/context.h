@@ -3,6 +3,12 @@
 #include <string>
 #include <map>
 #include <chrono>
+#include <algorithm>
+#include <atomic>
+#include <functional>
+#include <limits>
+#include <memory>
+#include <mutex>
 
 #include "scg/serialize.h"
 #include "scg/error.h"
@@ -16,6 +22,7 @@ public:
 
 	Context()
 		: hasDeadline_(false)
+		, cancel_(std::make_shared<CancelState>())
 	{
 	}
 
@@ -37,6 +44,91 @@ public:
 		return deadline_;
 	}
 
+	// expired returns true if the deadline of the context has passed.
+	bool expired() const {
+		return hasDeadline_ && std::chrono::system_clock::now() >= deadline_;
+	}
+
+	// cancel cancels the context and every copy of it. A client call made with
+	// the context returns CANCELED, and the server cancels the context of its
+	// handler, which observes it with cancelled().
+	void cancel() {
+		std::lock_guard<std::mutex> lock(cancel_->mu);
+		if (cancel_->cancelled.exchange(true)) {
+			return;
+		}
+		for (auto& pair : cancel_->callbacks) {
+			pair.second();
+		}
+		cancel_->callbacks.clear();
+	}
+
+	bool cancelled() const {
+		return cancel_->cancelled.load();
+	}
+
+	// onCancel registers a callback to run when the context, or any copy of it,
+	// is cancelled, and returns an id that removes it with removeOnCancel. The
+	// callback runs on the cancelling thread, or immediately if the context is
+	// already cancelled, and must not itself cancel the context.
+	uint64_t onCancel(std::function<void()> callback) const {
+		{
+			std::lock_guard<std::mutex> lock(cancel_->mu);
+			if (!cancel_->cancelled.load()) {
+				uint64_t id = cancel_->nextID++;
+				cancel_->callbacks[id] = std::move(callback);
+				return id;
+			}
+		}
+		callback();
+		return 0;
+	}
+
+	// removeOnCancel removes a callback registered with onCancel. Once it
+	// returns, the callback is not running and will not run.
+	void removeOnCancel(uint64_t id) const {
+		std::lock_guard<std::mutex> lock(cancel_->mu);
+		cancel_->callbacks.erase(id);
+	}
+
+	// timeout returns the nanoseconds remaining until the deadline, or zero if
+	// the context has none. Requests and stream OPEN frames carry the deadline as
+	// this duration rather than as a point in time, so that the client and server
+	// need not agree on the clock. A deadline that has already passed is one
+	// nanosecond, so that the server still observes it.
+	uint64_t timeout() const {
+		if (!hasDeadline_) {
+			return 0;
+		}
+		auto remaining = deadline_ - std::chrono::system_clock::now();
+		if (remaining <= remaining.zero()) {
+			return 1;
+		}
+		// a deadline too far off for a count of nanoseconds is sent as the largest
+		auto maxNanos = std::chrono::nanoseconds::max();
+		if (remaining >= std::chrono::duration_cast<std::chrono::system_clock::duration>(maxNanos)) {
+			return uint64_t(maxNanos.count());
+		}
+		return uint64_t(std::chrono::duration_cast<std::chrono::nanoseconds>(remaining).count());
+	}
+
+	// setTimeout sets the deadline to the timeout in nanoseconds from now. A zero
+	// timeout leaves the deadline unchanged, while one past the range of the
+	// clock, which a peer may send, sets the latest deadline the clock holds.
+	void setTimeout(uint64_t nanos) {
+		if (nanos == 0) {
+			return;
+		}
+		auto timeout = std::chrono::duration_cast<std::chrono::system_clock::duration>(
+			std::chrono::nanoseconds(int64_t(std::min<uint64_t>(nanos, uint64_t(std::numeric_limits<int64_t>::max())))));
+		auto now = std::chrono::system_clock::now();
+		if (timeout > std::chrono::system_clock::time_point::max() - now) {
+			setDeadline(std::chrono::system_clock::time_point::max());
+			return;
+		}
+		setDeadline(now + timeout);
+	}
+
 	inline void put(const std::string& key, const std::vector<uint8_t>& val)
 	{
 		values_[key] = val;
@@ -127,9 +219,19 @@ public:
 
 private:
 
+	// CancelState is shared by a context and its copies. Callbacks run while mu
+	// is held, so that removing one waits for it to complete.
+	struct CancelState {
+		std::mutex mu;
+		std::atomic<bool> cancelled{false};
+		uint64_t nextID = 1;
+		std::map<uint64_t, std::function<void()>> callbacks;
+	};
+
 	std::map<std::string, std::vector<uint8_t>> values_;
 	std::chrono::system_clock::time_point deadline_;
 	bool hasDeadline_;
+	std::shared_ptr<CancelState> cancel_;
 };
 
 }
@@ -0,0 +1,609 @@
+#pragma once
+
+#include <algorithm>
+#include <array>
+#include <map>
+#include <memory>
+#include <optional>
+#include <unordered_set>
+#include <utility>
+#include <variant>
+#include <vector>
+#include <type_traits>
+
+#include "scg/error.h"
+#include "scg/serialize.h"
+
+namespace scg {
+namespace serialize {
+
+// A delta encodes a value against a baseline of the same type. Messages,
+// containers, optionals and variants are diffed recursively, all other values
+// are written in full. The encoding matches the Go implementation:
+//
+//   - message: for each field, a bit flagging whether it differs from the
+//     baseline, followed by its delta when it does
+//   - optional: a presence bit, then the delta against the baseline value if
+//     the baseline is also present, otherwise the full value
+//   - list: the length, then for each element within the length of the
+//     baseline a changed bit and its delta, and the remaining elements in full
+//   - array: a changed bit for each element, followed by its delta
+//   - map: the removed keys, then the added or changed entries, each a key
+//     followed by the delta against the baseline value or the full value
+//   - set: the removed elements, then the added elements
+//   - variant: the discriminator, then the delta against the baseline
+//     alternative if it is the same alternative, otherwise the full value
+//
+// A delta is decoded in place, into a value holding the baseline. A writer in
+// the deterministic mode writes the elements of sets in ascending order, maps
+// already being ordered.
+
+template <typename T>
+inline bool equal(const T& a, const T& b)
+{
+	return a == b;
+}
+
+template <typename T>
+inline bool equal(const std::unique_ptr<T>& a, const std::unique_ptr<T>& b)
+{
+	if (!a || !b) {
+		return !a && !b;
+	}
+	return equal(*a, *b);
+}
+
+// values without an overload are written in full
+template <typename T>
+inline uint32_t bit_size_delta(const T& value, const T&)
+{
+	return bit_size(value);
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta(WriterType& writer, const T& value, const T&)
+{
+	serialize(writer, value);
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta(T& value, ReaderType& reader)
+{
+	return deserialize(value, reader);
+}
+
+// As in serialize.h, every container overload is declared before any of them
+// are defined.
+template <typename T> inline uint32_t bit_size_delta(const std::vector<T>& value, const std::vector<T>& base);
+template <typename WriterType, typename T> inline void serialize_delta(WriterType& writer, const std::vector<T>& value, const std::vector<T>& base);
+template <typename ReaderType, typename T> inline error::Error deserialize_delta(std::vector<T>& value, ReaderType& reader);
+inline uint32_t bit_size_delta(const std::vector<uint8_t>& value, const std::vector<uint8_t>& base);
+template <typename WriterType> inline void serialize_delta(WriterType& writer, const std::vector<uint8_t>& value, const std::vector<uint8_t>& base);
+template <typename ReaderType> inline error::Error deserialize_delta(std::vector<uint8_t>& value, ReaderType& reader);
+template <typename K, typename V> inline uint32_t bit_size_delta(const std::map<K,V>& value, const std::map<K,V>& base);
+template <typename K, typename V, typename WriterType> inline void serialize_delta(WriterType& writer, const std::map<K,V>& value, const std::map<K,V>& base);
+template <typename K, typename V, typename ReaderType> inline error::Error deserialize_delta(std::map<K,V>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size_delta(const std::unordered_set<T>& value, const std::unordered_set<T>& base);
+template <typename WriterType, typename T> inline void serialize_delta(WriterType& writer, const std::unordered_set<T>& value, const std::unordered_set<T>& base);
+template <typename ReaderType, typename T> inline error::Error deserialize_delta(std::unordered_set<T>& value, ReaderType& reader);
+template <typename T, size_t N> inline uint32_t bit_size_delta(const std::array<T, N>& value, const std::array<T, N>& base);
+template <typename WriterType, typename T, size_t N> inline void serialize_delta(WriterType& writer, const std::array<T, N>& value, const std::array<T, N>& base);
+template <typename ReaderType, typename T, size_t N> inline error::Error deserialize_delta(std::array<T, N>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size_delta(const std::optional<T>& value, const std::optional<T>& base);
+template <typename WriterType, typename T> inline void serialize_delta(WriterType& writer, const std::optional<T>& value, const std::optional<T>& base);
+template <typename ReaderType, typename T> inline error::Error deserialize_delta(std::optional<T>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size_delta(const std::unique_ptr<T>& value, const std::unique_ptr<T>& base);
+template <typename WriterType, typename T> inline void serialize_delta(WriterType& writer, const std::unique_ptr<T>& value, const std::unique_ptr<T>& base);
+template <typename ReaderType, typename T> inline error::Error deserialize_delta(std::unique_ptr<T>& value, ReaderType& reader);
+template <typename... Ts> inline uint32_t bit_size_delta(const std::variant<std::monostate, Ts...>& value, const std::variant<std::monostate, Ts...>& base);
+template <typename WriterType, typename... Ts> inline void serialize_delta(WriterType& writer, const std::variant<std::monostate, Ts...>& value, const std::variant<std::monostate, Ts...>& base);
+template <typename ReaderType, typename... Ts> inline error::Error deserialize_delta(std::variant<std::monostate, Ts...>& value, ReaderType& reader);
+
+// The field helpers write the changed bit, followed by the delta when the value
+// differs from the baseline.
+template <typename T>
+inline uint32_t bit_size_delta_field(const T& value, const T& base)
+{
+	if (equal(value, base)) {
+		return bit_size(false);
+	}
+	return bit_size(true) + bit_size_delta(value, base);
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta_field(WriterType& writer, const T& value, const T& base)
+{
+	bool changed = !equal(value, base);
+	serialize(writer, changed);
+	if (changed) {
+		serialize_delta(writer, value, base);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta_field(T& value, ReaderType& reader)
+{
+	bool changed = false;
+	auto err = deserialize(changed, reader);
+	if (err) {
+		return err;
+	}
+	if (!changed) {
+		return nullptr;
+	}
+	return deserialize_delta(value, reader);
+}
+
+template <typename T>
+inline uint32_t bit_size_delta(const std::vector<T>& value, const std::vector<T>& base)
+{
+	uint32_t size = bit_size(uint32_t(value.size()));
+	for (size_t i = 0; i < value.size(); i++) {
+		if (i < base.size()) {
+			size += bit_size_delta_field<T>(value[i], base[i]);
+		} else {
+			size += bit_size(value[i]);
+		}
+	}
+	return size;
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta(WriterType& writer, const std::vector<T>& value, const std::vector<T>& base)
+{
+	serialize(writer, uint32_t(value.size()));
+	for (size_t i = 0; i < value.size(); i++) {
+		if (i < base.size()) {
+			serialize_delta_field<WriterType, T>(writer, value[i], base[i]);
+		} else {
+			serialize(writer, value[i]);
+		}
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta(std::vector<T>& value, ReaderType& reader)
+{
+	uint32_t size = 0;
+	auto err = deserialize(size, reader);
+	if (err) {
+		return err;
+	}
+	if (size < value.size()) {
+		value.erase(value.begin() + size, value.end());
+	}
+	size_t num_base = value.size();
+	for (size_t i = 0; i < num_base; i++) {
+		if constexpr (std::is_same_v<T, bool>) {
+			// std::vector<bool> does not hand out references to its elements
+			bool item = value[i];
+			err = deserialize_delta_field(item, reader);
+			value[i] = item;
+		} else {
+			err = deserialize_delta_field(value[i], reader);
+		}
+		if (err) {
+			return err;
+		}
+	}
+	value.reserve(num_base + bounded_reserve_count(size - uint32_t(num_base), reader));
+	for (size_t i = num_base; i < size; i++) {
+		T item{};
+		err = deserialize(item, reader);
+		if (err) {
+			return err;
+		}
+		value.push_back(std::move(item));
+	}
+	return nullptr;
+}
+
+// byte vectors are written in full
+inline uint32_t bit_size_delta(const std::vector<uint8_t>& value, const std::vector<uint8_t>&)
+{
+	return bit_size(value);
+}
+
+template <typename WriterType>
+inline void serialize_delta(WriterType& writer, const std::vector<uint8_t>& value, const std::vector<uint8_t>&)
+{
+	serialize(writer, value);
+}
+
+template <typename ReaderType>
+inline error::Error deserialize_delta(std::vector<uint8_t>& value, ReaderType& reader)
+{
+	return deserialize(value, reader);
+}
+
+template <typename K, typename V>
+inline uint32_t bit_size_delta(const std::map<K,V>& value, const std::map<K,V>& base)
+{
+	uint32_t num_removed = 0;
+	uint32_t num_upserted = 0;
+	uint32_t size = 0;
+	for (const auto& [key, val] : base) {
+		if (value.find(key) == value.end()) {
+			num_removed++;
+			size += bit_size(key);
+		}
+	}
+	for (const auto& [key, val] : value) {
+		auto it = base.find(key);
+		if (it == base.end()) {
+			num_upserted++;
+			size += bit_size(key) + bit_size(val);
+		} else if (!equal(val, it->second)) {
+			num_upserted++;
+			size += bit_size(key) + bit_size_delta(val, it->second);
+		}
+	}
+	return bit_size(num_removed) + bit_size(num_upserted) + size;
+}
+
+template <typename K, typename V, typename WriterType>
+inline void serialize_delta(WriterType& writer, const std::map<K,V>& value, const std::map<K,V>& base)
+{
+	std::vector<const K*> removed;
+	for (const auto& [key, val] : base) {
+		if (value.find(key) == value.end()) {
+			removed.push_back(&key);
+		}
+	}
+	serialize(writer, uint32_t(removed.size()));
+	for (const auto* key : removed) {
+		serialize(writer, *key);
+	}
+
+	std::vector<std::pair<typename std::map<K,V>::const_iterator, typename std::map<K,V>::const_iterator>> upserted;
+	for (auto it = value.begin(); it != value.end(); it++) {
+		auto bit = base.find(it->first);
+		if (bit == base.end() || !equal(it->second, bit->second)) {
+			upserted.emplace_back(it, bit);
+		}
+	}
+	serialize(writer, uint32_t(upserted.size()));
+	for (const auto& [it, bit] : upserted) {
+		serialize(writer, it->first);
+		if (bit == base.end()) {
+			serialize(writer, it->second);
+		} else {
+			serialize_delta(writer, it->second, bit->second);
+		}
+	}
+}
+
+template <typename K, typename V, typename ReaderType>
+inline error::Error deserialize_delta(std::map<K,V>& value, ReaderType& reader)
+{
+	uint32_t num_removed = 0;
+	auto err = deserialize(num_removed, reader);
+	if (err) {
+		return err;
+	}
+	for (uint32_t i = 0; i < num_removed; i++) {
+		K key{};
+		err = deserialize(key, reader);
+		if (err) {
+			return err;
+		}
+		value.erase(key);
+	}
+
+	uint32_t num_upserted = 0;
+	err = deserialize(num_upserted, reader);
+	if (err) {
+		return err;
+	}
+	for (uint32_t i = 0; i < num_upserted; i++) {
+		K key{};
+		err = deserialize(key, reader);
+		if (err) {
+			return err;
+		}
+		auto it = value.find(key);
+		if (it != value.end()) {
+			err = deserialize_delta(it->second, reader);
+			if (err) {
+				return err;
+			}
+			continue;
+		}
+		V val{};
+		err = deserialize(val, reader);
+		if (err) {
+			return err;
+		}
+		value.emplace(std::move(key), std::move(val));
+	}
+	return nullptr;
+}
+
+template <typename T>
+inline uint32_t bit_size_delta(const std::unordered_set<T>& value, const std::unordered_set<T>& base)
+{
+	uint32_t num_removed = 0;
+	uint32_t num_added = 0;
+	uint32_t size = 0;
+	for (const auto& item : base) {
+		if (value.find(item) == value.end()) {
+			num_removed++;
+			size += bit_size(item);
+		}
+	}
+	for (const auto& item : value) {
+		if (base.find(item) == base.end()) {
+			num_added++;
+			size += bit_size(item);
+		}
+	}
+	return bit_size(num_removed) + bit_size(num_added) + size;
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta(WriterType& writer, const std::unordered_set<T>& value, const std::unordered_set<T>& base)
+{
+	std::vector<const T*> removed;
+	for (const auto& item : base) {
+		if (value.find(item) == value.end()) {
+			removed.push_back(&item);
+		}
+	}
+	if (writer.deterministic()) {
+		std::sort(removed.begin(), removed.end(), [](const T* a, const T* b) {
+			return *a < *b;
+		});
+	}
+	serialize(writer, uint32_t(removed.size()));
+	for (const auto* item : removed) {
+		serialize(writer, *item);
+	}
+
+	std::vector<const T*> added;
+	for (const auto& item : value) {
+		if (base.find(item) == base.end()) {
+			added.push_back(&item);
+		}
+	}
+	if (writer.deterministic()) {
+		std::sort(added.begin(), added.end(), [](const T* a, const T* b) {
+			return *a < *b;
+		});
+	}
+	serialize(writer, uint32_t(added.size()));
+	for (const auto* item : added) {
+		serialize(writer, *item);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta(std::unordered_set<T>& value, ReaderType& reader)
+{
+	uint32_t num_removed = 0;
+	auto err = deserialize(num_removed, reader);
+	if (err) {
+		return err;
+	}
+	for (uint32_t i = 0; i < num_removed; i++) {
+		T item{};
+		err = deserialize(item, reader);
+		if (err) {
+			return err;
+		}
+		value.erase(item);
+	}
+
+	uint32_t num_added = 0;
+	err = deserialize(num_added, reader);
+	if (err) {
+		return err;
+	}
+	for (uint32_t i = 0; i < num_added; i++) {
+		T item{};
+		err = deserialize(item, reader);
+		if (err) {
+			return err;
+		}
+		value.insert(std::move(item));
+	}
+	return nullptr;
+}
+
+template <typename T, size_t N>
+inline uint32_t bit_size_delta(const std::array<T, N>& value, const std::array<T, N>& base)
+{
+	uint32_t size = 0;
+	for (size_t i = 0; i < N; i++) {
+		size += bit_size_delta_field(value[i], base[i]);
+	}
+	return size;
+}
+
+template <typename WriterType, typename T, size_t N>
+inline void serialize_delta(WriterType& writer, const std::array<T, N>& value, const std::array<T, N>& base)
+{
+	for (size_t i = 0; i < N; i++) {
+		serialize_delta_field(writer, value[i], base[i]);
+	}
+}
+
+template <typename ReaderType, typename T, size_t N>
+inline error::Error deserialize_delta(std::array<T, N>& value, ReaderType& reader)
+{
+	for (size_t i = 0; i < N; i++) {
+		auto err = deserialize_delta_field(value[i], reader);
+		if (err) {
+			return err;
+		}
+	}
+	return nullptr;
+}
+
+template <typename T>
+inline uint32_t bit_size_delta(const std::optional<T>& value, const std::optional<T>& base)
+{
+	if (!value) {
+		return bit_size(false);
+	}
+	if (base) {
+		return bit_size(true) + bit_size_delta(*value, *base);
+	}
+	return bit_size(true) + bit_size(*value);
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta(WriterType& writer, const std::optional<T>& value, const std::optional<T>& base)
+{
+	serialize(writer, value.has_value());
+	if (!value) {
+		return;
+	}
+	if (base) {
+		serialize_delta(writer, *value, *base);
+	} else {
+		serialize(writer, *value);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta(std::optional<T>& value, ReaderType& reader)
+{
+	bool present = false;
+	auto err = deserialize(present, reader);
+	if (err) {
+		return err;
+	}
+	if (!present) {
+		value.reset();
+		return nullptr;
+	}
+	if (value) {
+		return deserialize_delta(*value, reader);
+	}
+	value.emplace();
+	return deserialize(*value, reader);
+}
+
+template <typename T>
+inline uint32_t bit_size_delta(const std::unique_ptr<T>& value, const std::unique_ptr<T>& base)
+{
+	if (!value) {
+		return bit_size(false);
+	}
+	if (base) {
+		return bit_size(true) + bit_size_delta(*value, *base);
+	}
+	return bit_size(true) + bit_size(*value);
+}
+
+template <typename WriterType, typename T>
+inline void serialize_delta(WriterType& writer, const std::unique_ptr<T>& value, const std::unique_ptr<T>& base)
+{
+	serialize(writer, value != nullptr);
+	if (!value) {
+		return;
+	}
+	if (base) {
+		serialize_delta(writer, *value, *base);
+	} else {
+		serialize(writer, *value);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize_delta(std::unique_ptr<T>& value, ReaderType& reader)
+{
+	bool present = false;
+	auto err = deserialize(present, reader);
+	if (err) {
+		return err;
+	}
+	if (!present) {
+		value.reset();
+		return nullptr;
+	}
+	if (value) {
+		return deserialize_delta(*value, reader);
+	}
+	value = std::make_unique<T>();
+	return deserialize(*value, reader);
+}
+
+template <std::size_t I, typename... Ts>
+inline uint32_t bit_size_delta_variant_alternative(const std::variant<Ts...>& value, const std::variant<Ts...>& base)
+{
+	if constexpr (I < sizeof...(Ts)) {
+		if (value.index() == I) {
+			if (base.index() == I) {
+				return bit_size_delta(std::get<I>(value), std::get<I>(base));
+			}
+			return bit_size(std::get<I>(value));
+		}
+		return bit_size_delta_variant_alternative<I + 1>(value, base);
+	} else {
+		return 0;
+	}
+}
+
+template <std::size_t I, typename WriterType, typename... Ts>
+inline void serialize_delta_variant_alternative(WriterType& writer, const std::variant<Ts...>& value, const std::variant<Ts...>& base)
+{
+	if constexpr (I < sizeof...(Ts)) {
+		if (value.index() == I) {
+			if (base.index() == I) {
+				serialize_delta(writer, std::get<I>(value), std::get<I>(base));
+			} else {
+				serialize(writer, std::get<I>(value));
+			}
+			return;
+		}
+		serialize_delta_variant_alternative<I + 1>(writer, value, base);
+	}
+}
+
+template <std::size_t I, typename ReaderType, typename... Ts>
+inline error::Error deserialize_delta_variant_alternative(std::variant<Ts...>& value, uint32_t index, ReaderType& reader)
+{
+	if constexpr (I < sizeof...(Ts)) {
+		if (index == I) {
+			if (value.index() == I) {
+				return deserialize_delta(std::get<I>(value), reader);
+			}
+			return deserialize(value.template emplace<I>(), reader);
+		}
+		return deserialize_delta_variant_alternative<I + 1>(value, index, reader);
+	} else {
+		return error::Error::Errorf("variant has unrecognized discriminator %u", index);
+	}
+}
+
+template <typename... Ts>
+inline uint32_t bit_size_delta(const std::variant<std::monostate, Ts...>& value, const std::variant<std::monostate, Ts...>& base)
+{
+	return bit_size(static_cast<uint32_t>(value.index())) + bit_size_delta_variant_alternative<1>(value, base);
+}
+
+template <typename WriterType, typename... Ts>
+inline void serialize_delta(WriterType& writer, const std::variant<std::monostate, Ts...>& value, const std::variant<std::monostate, Ts...>& base)
+{
+	serialize(writer, static_cast<uint32_t>(value.index()));
+	serialize_delta_variant_alternative<1>(writer, value, base);
+}
+
+template <typename ReaderType, typename... Ts>
+inline error::Error deserialize_delta(std::variant<std::monostate, Ts...>& value, ReaderType& reader)
+{
+	uint32_t index = 0;
+	auto err = deserialize(index, reader);
+	if (err) {
+		return err;
+	}
+	if (index == 0) {
+		value = std::monostate{};
+		return nullptr;
+	}
+	return deserialize_delta_variant_alternative<1>(value, index, reader);
+}
+
+}
+}
@@ -0,0 +1,206 @@
+#pragma once
+
+#include <chrono>
+#include <cstdint>
+#include <string>
+#include <stdexcept>
+#include <utility>
+
+#include "nlohmann/json.hpp"
+
+namespace scg {
+namespace type {
+
+namespace detail {
+
+inline std::string format_duration_fraction(uint64_t value, int precision)
+{
+	if (value == 0) {
+		return "";
+	}
+	std::string digits(precision, '0');
+	for (int i = precision - 1; i >= 0; i--) {
+		digits[i] = char('0' + value % 10);
+		value /= 10;
+	}
+	digits.erase(digits.find_last_not_of('0') + 1);
+	return "." + digits;
+}
+
+}
+
+// format_duration formats the duration as Go's time.Duration.String does, for
+// example "1.5s", "1h2m3s" or "250µs".
+inline std::string format_duration(std::chrono::nanoseconds duration)
+{
+	int64_t ns = int64_t(duration.count());
+	if (ns == 0) {
+		return "0s";
+	}
+
+	bool negative = ns < 0;
+	uint64_t u = negative ? uint64_t(0) - uint64_t(ns) : uint64_t(ns);
+
+	std::string out;
+	if (u < 1000000000ULL) {
+		// sub-second durations use a smaller unit
+		if (u < 1000ULL) {
+			out = std::to_string(u) + "ns";
+		} else if (u < 1000000ULL) {
+			out = std::to_string(u / 1000ULL) + detail::format_duration_fraction(u % 1000ULL, 3) + "µs";
+		} else {
+			out = std::to_string(u / 1000000ULL) + detail::format_duration_fraction(u % 1000000ULL, 6) + "ms";
+		}
+	} else {
+		uint64_t seconds = u / 1000000000ULL;
+		out = std::to_string(seconds % 60) + detail::format_duration_fraction(u % 1000000000ULL, 9) + "s";
+		uint64_t minutes = seconds / 60;
+		if (minutes > 0) {
+			out = std::to_string(minutes % 60) + "m" + out;
+			uint64_t hours = minutes / 60;
+			if (hours > 0) {
+				out = std::to_string(hours) + "h" + out;
+			}
+		}
+	}
+
+	return negative ? "-" + out : out;
+}
+
+// parse_duration parses a duration in the format accepted by Go's
+// time.ParseDuration: a signed sequence of decimal numbers, each with an
+// optional fraction and a unit suffix of "ns", "us", "µs", "ms", "s", "m" or
+// "h", such as "-1.5h" or "2h45m".
+inline std::pair<std::chrono::nanoseconds, bool> parse_duration(const std::string& str)
+{
+	const auto invalid = std::make_pair(std::chrono::nanoseconds(0), false);
+	constexpr uint64_t max_magnitude = uint64_t(1) << 63;
+
+	size_t i = 0;
+	bool negative = false;
+	if (i < str.size() && (str[i] == '-' || str[i] == '+')) {
+		negative = str[i] == '-';
+		i++;
+	}
+	if (str.substr(i) == "0") {
+		return std::make_pair(std::chrono::nanoseconds(0), true);
+	}
+	if (i == str.size()) {
+		return invalid;
+	}
+
+	uint64_t total = 0;
+	while (i < str.size()) {
+		// the integer part
+		uint64_t whole = 0;
+		size_t start = i;
+		while (i < str.size() && str[i] >= '0' && str[i] <= '9') {
+			if (whole > (max_magnitude - uint64_t(str[i] - '0')) / 10) {
+				return invalid;
+			}
+			whole = whole * 10 + uint64_t(str[i] - '0');
+			i++;
+		}
+		bool has_whole = i > start;
+
+		// the fractional part, digits beyond the precision of a uint64 are ignored
+		uint64_t fraction = 0;
+		uint64_t scale = 1;
+		bool has_fraction = false;
+		if (i < str.size() && str[i] == '.') {
+			i++;
+			start = i;
+			while (i < str.size() && str[i] >= '0' && str[i] <= '9') {
+				if (scale <= UINT64_MAX / 10 / 10) {
+					fraction = fraction * 10 + uint64_t(str[i] - '0');
+					scale *= 10;
+				}
+				i++;
+			}
+			has_fraction = i > start;
+		}
+		if (!has_whole && !has_fraction) {
+			return invalid;
+		}
+
+		// the unit
+		start = i;
+		while (i < str.size() && str[i] != '.' && (str[i] < '0' || str[i] > '9')) {
+			i++;
+		}
+		std::string unit = str.substr(start, i - start);
+		uint64_t multiplier = 0;
+		if (unit == "ns") {
+			multiplier = 1ULL;
+		} else if (unit == "us" || unit == "µs" || unit == "μs") {
+			multiplier = 1000ULL;
+		} else if (unit == "ms") {
+			multiplier = 1000000ULL;
+		} else if (unit == "s") {
+			multiplier = 1000000000ULL;
+		} else if (unit == "m") {
+			multiplier = 60ULL * 1000000000ULL;
+		} else if (unit == "h") {
+			multiplier = 60ULL * 60ULL * 1000000000ULL;
+		} else {
+			return invalid;
+		}
+
+		if (whole > max_magnitude / multiplier) {
+			return invalid;
+		}
+		uint64_t value = whole * multiplier;
+		if (fraction > 0) {
+			// rounded as Go does
+			value += uint64_t(double(fraction) * (double(multiplier) / double(scale)));
+		}
+		if (value > max_magnitude || total > max_magnitude - value) {
+			return invalid;
+		}
+		total += value;
+	}
+
+	if (!negative && total > max_magnitude - 1) {
+		return invalid;
+	}
+	int64_t ns = negative ? int64_t(uint64_t(0) - total) : int64_t(total);
+	return std::make_pair(std::chrono::nanoseconds(ns), true);
+}
+
+}
+}
+
+// nlohmann json serialization
+//
+// Durations are encoded in the string form of Go's time.Duration, such as
+// "1.5s". An integer is read as a number of nanoseconds.
+
+NLOHMANN_JSON_NAMESPACE_BEGIN
+
+template <>
+struct adl_serializer<std::chrono::nanoseconds> {
+
+	static void to_json(json& j, const std::chrono::nanoseconds& duration)
+	{
+		j = scg::type::format_duration(duration);
+	}
+
+	static void from_json(const json& j, std::chrono::nanoseconds& duration)
+	{
+		if (j.is_null()) {
+			duration = std::chrono::nanoseconds(0);
+			return;
+		}
+		if (j.is_number_integer()) {
+			duration = std::chrono::nanoseconds(j.get<int64_t>());
+			return;
+		}
+		auto [res, ok] = scg::type::parse_duration(j.get<std::string>());
+		if (!ok) {
+			throw std::runtime_error("invalid duration string");
+		}
+		duration = res;
+	}
+};
+
+NLOHMANN_JSON_NAMESPACE_END
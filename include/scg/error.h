@@ -6,8 +6,14 @@
 #include <cstddef>
 #include <cstdio>
 #include <cstdarg>
+#include <memory>
 
 namespace scg {
+
+namespace rpc {
+class Status;
+}
+
 namespace error {
 
 class Error {
@@ -59,7 +65,19 @@ public:
 		return err;
 	}
 
+	// withStatus returns an error with the message and the status it carries,
+	// see scg::rpc::Status::toError.
+	static inline Error withStatus(const std::string& msg, std::shared_ptr<const rpc::Status> status)
+	{
+		Error err(msg);
+		if (err) {
+			err.status_ = std::move(status);
+		}
+		return err;
+	}
+
 	inline Error(const Error& other)
+		: status_(other.status_)
 	{
 		if (other.msg_) {
 			size_t len = std::strlen(other.msg_);
@@ -68,7 +86,9 @@ public:
 		}
 	}
 
-	inline Error(Error&& other) noexcept : msg_(other.msg_)
+	inline Error(Error&& other) noexcept
+		: msg_(other.msg_)
+		, status_(std::move(other.status_))
 	{
 		other.msg_ = nullptr;
 	}
@@ -83,6 +103,7 @@ public:
 				msg_ = new char[len + 1];
 				std::memcpy(msg_, other.msg_, len + 1);
 			}
+			status_ = other.status_;
 		}
 		return *this;
 	}
@@ -93,6 +114,7 @@ public:
 			delete[] msg_;
 			msg_ = other.msg_;
 			other.msg_ = nullptr;
+			status_ = std::move(other.status_);
 		}
 		return *this;
 	}
@@ -112,9 +134,18 @@ public:
 		return msg_ ? std::string(msg_) : "";
 	}
 
+	// status returns the status the error carries, or nullptr for an error that
+	// did not originate from one. Use scg::rpc::Status::fromError to classify
+	// any error.
+	inline const rpc::Status* status() const
+	{
+		return status_.get();
+	}
+
 private:
 
 	char* msg_ = nullptr;
+	std::shared_ptr<const rpc::Status> status_;
 };
 
 inline bool operator== (const Error& a, const Error& b)
@@ -0,0 +1,251 @@
+#pragma once
+
+#include <algorithm>
+#include <array>
+#include <cstdint>
+#include <cstring>
+
+namespace scg {
+namespace hash {
+
+// Sha256 is a streaming SHA-256, as specified by FIPS 180-4, used to hash
+// messages without depending on a crypto library.
+class Sha256 {
+public:
+
+	inline Sha256()
+	{
+		reset();
+	}
+
+	void reset()
+	{
+		state_ = {
+			0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+			0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+		};
+		numBytes_ = 0;
+		bufferSize_ = 0;
+	}
+
+	void update(const uint8_t* data, size_t size)
+	{
+		numBytes_ += size;
+		if (bufferSize_ > 0) {
+			size_t n = std::min(size, buffer_.size() - bufferSize_);
+			std::memcpy(buffer_.data() + bufferSize_, data, n);
+			bufferSize_ += n;
+			data += n;
+			size -= n;
+			if (bufferSize_ < buffer_.size()) {
+				return;
+			}
+			compress(buffer_.data());
+			bufferSize_ = 0;
+		}
+		for (; size >= buffer_.size(); data += buffer_.size(), size -= buffer_.size()) {
+			compress(data);
+		}
+		std::memcpy(buffer_.data(), data, size);
+		bufferSize_ = size;
+	}
+
+	// sum returns the digest of the bytes written so far, without changing the
+	// state of the hash.
+	std::array<uint8_t, 32> sum() const
+	{
+		Sha256 h = *this;
+
+		uint64_t numBits = h.numBytes_ * 8;
+		uint8_t pad[72] = { 0x80 };
+		size_t padSize = (h.bufferSize_ < 56 ? 56 : 120) - h.bufferSize_;
+		for (uint32_t i = 0; i < 8; i++) {
+			pad[padSize + i] = uint8_t(numBits >> (56 - i * 8));
+		}
+		h.update(pad, padSize + 8);
+
+		std::array<uint8_t, 32> digest;
+		for (uint32_t i = 0; i < 8; i++) {
+			digest[i * 4] = uint8_t(h.state_[i] >> 24);
+			digest[i * 4 + 1] = uint8_t(h.state_[i] >> 16);
+			digest[i * 4 + 2] = uint8_t(h.state_[i] >> 8);
+			digest[i * 4 + 3] = uint8_t(h.state_[i]);
+		}
+		return digest;
+	}
+
+private:
+
+	static inline uint32_t rotr(uint32_t x, uint32_t n)
+	{
+		return (x >> n) | (x << (32 - n));
+	}
+
+	void compress(const uint8_t* block)
+	{
+		static constexpr uint32_t k[64] = {
+			0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+			0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+			0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+			0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+			0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+			0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+			0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+			0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+		};
+
+		uint32_t w[64];
+		for (uint32_t i = 0; i < 16; i++) {
+			w[i] = (uint32_t(block[i * 4]) << 24) | (uint32_t(block[i * 4 + 1]) << 16) | (uint32_t(block[i * 4 + 2]) << 8) | uint32_t(block[i * 4 + 3]);
+		}
+		for (uint32_t i = 16; i < 64; i++) {
+			uint32_t s0 = rotr(w[i - 15], 7) ^ rotr(w[i - 15], 18) ^ (w[i - 15] >> 3);
+			uint32_t s1 = rotr(w[i - 2], 17) ^ rotr(w[i - 2], 19) ^ (w[i - 2] >> 10);
+			w[i] = w[i - 16] + s0 + w[i - 7] + s1;
+		}
+
+		uint32_t a = state_[0], b = state_[1], c = state_[2], d = state_[3];
+		uint32_t e = state_[4], f = state_[5], g = state_[6], h = state_[7];
+		for (uint32_t i = 0; i < 64; i++) {
+			uint32_t s1 = rotr(e, 6) ^ rotr(e, 11) ^ rotr(e, 25);
+			uint32_t ch = (e & f) ^ (~e & g);
+			uint32_t t1 = h + s1 + ch + k[i] + w[i];
+			uint32_t s0 = rotr(a, 2) ^ rotr(a, 13) ^ rotr(a, 22);
+			uint32_t maj = (a & b) ^ (a & c) ^ (b & c);
+			uint32_t t2 = s0 + maj;
+			h = g;
+			g = f;
+			f = e;
+			e = d + t1;
+			d = c;
+			c = b;
+			b = a;
+			a = t1 + t2;
+		}
+
+		state_[0] += a;
+		state_[1] += b;
+		state_[2] += c;
+		state_[3] += d;
+		state_[4] += e;
+		state_[5] += f;
+		state_[6] += g;
+		state_[7] += h;
+	}
+
+	std::array<uint32_t, 8> state_;
+	std::array<uint8_t, 64> buffer_;
+	uint64_t numBytes_ = 0;
+	size_t bufferSize_ = 0;
+};
+
+}
+
+namespace serialize {
+
+// HashWriter streams the deterministic encoding of a value into a hasher, one
+// buffered chunk at a time, rather than retaining it. The hasher must provide
+// update(const uint8_t* data, size_t size). The bytes written are exactly those
+// of the deterministic encoding, including the zero padding of the final byte,
+// once flush is called.
+template <typename HasherType>
+class HashWriter {
+public:
+
+	inline explicit HashWriter(HasherType& hasher)
+		: hasher_(hasher)
+	{
+	}
+
+	template <typename T>
+	inline void write(const T& data)
+	{
+		serialize(*this, data);
+	}
+
+	bool deterministic() const
+	{
+		return true;
+	}
+
+	void writeBits(uint8_t val, uint32_t num_bits_to_write)
+	{
+		if (num_bits_to_write == 0) {
+			return;
+		}
+
+		// Mask val to ensure we only write numBitsToWrite bits
+		val &= (1 << num_bits_to_write) - 1;
+
+		pending_ |= uint32_t(val) << numPendingBits_;
+		numPendingBits_ += num_bits_to_write;
+		if (numPendingBits_ >= 8) {
+			push(uint8_t(pending_));
+			pending_ >>= 8;
+			numPendingBits_ -= 8;
+		}
+	}
+
+	void writeByte(uint8_t val)
+	{
+		writeBits(val, 8);
+	}
+
+	void writeBytes(const uint8_t* data, uint32_t size)
+	{
+		if (numPendingBits_ != 0) {
+			for (uint32_t i = 0; i < size; i++) {
+				writeBits(data[i], 8);
+			}
+			return;
+		}
+		if (size >= buffer_.size()) {
+			// large aligned writes bypass the buffer
+			drain();
+			hasher_.update(data, size);
+			return;
+		}
+		for (uint32_t i = 0; i < size; i++) {
+			push(data[i]);
+		}
+	}
+
+	// flush writes the buffered bytes, and the final partial byte, if any, to
+	// the hasher.
+	void flush()
+	{
+		if (numPendingBits_ != 0) {
+			push(uint8_t(pending_));
+			pending_ = 0;
+			numPendingBits_ = 0;
+		}
+		drain();
+	}
+
+private:
+
+	inline void push(uint8_t b)
+	{
+		buffer_[bufferSize_++] = b;
+		if (bufferSize_ == buffer_.size()) {
+			drain();
+		}
+	}
+
+	inline void drain()
+	{
+		if (bufferSize_ > 0) {
+			hasher_.update(buffer_.data(), bufferSize_);
+			bufferSize_ = 0;
+		}
+	}
+
+	HasherType& hasher_;
+	std::array<uint8_t, 256> buffer_;
+	size_t bufferSize_ = 0;
+	uint32_t pending_ = 0;
+	uint32_t numPendingBits_ = 0;
+};
+
+}
+}
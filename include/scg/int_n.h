@@ -0,0 +1,209 @@
+#pragma once
+
+#include <cstdint>
+#include <string>
+#include <stdexcept>
+#include <type_traits>
+
+#include "scg/error.h"
+
+#include "nlohmann/json.hpp"
+
+namespace scg {
+namespace type {
+
+namespace detail {
+
+// the smallest native integer holding N bits
+template <uint32_t N, bool Signed>
+using int_n_value_type = std::conditional_t<(N <= 8), std::conditional_t<Signed, int8_t, uint8_t>,
+	std::conditional_t<(N <= 16), std::conditional_t<Signed, int16_t, uint16_t>,
+	std::conditional_t<(N <= 32), std::conditional_t<Signed, int32_t, uint32_t>,
+	std::conditional_t<Signed, int64_t, uint64_t>>>>;
+
+template <typename WriterType>
+inline void write_bits_n(WriterType& writer, uint64_t value, uint32_t num_bits)
+{
+	while (num_bits > 0) {
+		uint32_t n = num_bits < 8 ? num_bits : 8;
+		writer.writeBits(uint8_t(value), n);
+		value >>= 8;
+		num_bits -= n;
+	}
+}
+
+template <typename ReaderType>
+inline error::Error read_bits_n(uint64_t& value, ReaderType& reader, uint32_t num_bits)
+{
+	value = 0;
+	for (uint32_t shift = 0; shift < num_bits; shift += 8) {
+		uint32_t n = num_bits - shift < 8 ? num_bits - shift : 8;
+		uint8_t b = 0;
+		auto err = reader.readBits(b, n);
+		if (err) {
+			return err;
+		}
+		value |= uint64_t(b) << shift;
+	}
+	return nullptr;
+}
+
+}
+
+// uint_n and int_n hold a uint<N> or int<N> field. They convert implicitly to
+// and from the smallest native integer holding N bits, and are written with
+// exactly N bits, least significant bits first, signed values in two's
+// complement. Encoding a value that does not fit in N bits throws
+// std::out_of_range, as serialization cannot fail.
+
+template <uint32_t N>
+class uint_n {
+
+	static_assert(N >= 1 && N <= 64, "uint_n width must be between 1 and 64 bits");
+
+public:
+
+	using value_type = detail::int_n_value_type<N, false>;
+
+	static constexpr value_type max()
+	{
+		return N == 64 ? value_type(~uint64_t(0)) : value_type((uint64_t(1) << (N % 64)) - 1);
+	}
+
+	constexpr uint_n() = default;
+
+	constexpr uint_n(value_type value)
+		: value_(value)
+	{
+	}
+
+	constexpr operator value_type() const
+	{
+		return value_;
+	}
+
+	friend inline constexpr uint32_t bit_size(const uint_n&)
+	{
+		return N;
+	}
+
+	template <typename WriterType>
+	friend inline void serialize(WriterType& writer, const uint_n& value)
+	{
+		if (value.value_ > max()) {
+			throw std::out_of_range("value " + std::to_string(value.value_) + " is out of range for uint<" + std::to_string(N) + ">");
+		}
+		detail::write_bits_n(writer, uint64_t(value.value_), N);
+	}
+
+	template <typename ReaderType>
+	friend inline error::Error deserialize(uint_n& value, ReaderType& reader)
+	{
+		uint64_t bits = 0;
+		auto err = detail::read_bits_n(bits, reader, N);
+		if (err) {
+			return err;
+		}
+		value.value_ = value_type(bits);
+		return nullptr;
+	}
+
+private:
+
+	value_type value_ = 0;
+
+};
+
+template <uint32_t N>
+class int_n {
+
+	static_assert(N >= 1 && N <= 64, "int_n width must be between 1 and 64 bits");
+
+public:
+
+	using value_type = detail::int_n_value_type<N, true>;
+
+	static constexpr value_type min()
+	{
+		return N == 64 ? value_type(INT64_MIN) : value_type(-(int64_t(1) << ((N - 1) % 64)));
+	}
+
+	static constexpr value_type max()
+	{
+		return N == 64 ? value_type(INT64_MAX) : value_type((int64_t(1) << ((N - 1) % 64)) - 1);
+	}
+
+	constexpr int_n() = default;
+
+	constexpr int_n(value_type value)
+		: value_(value)
+	{
+	}
+
+	constexpr operator value_type() const
+	{
+		return value_;
+	}
+
+	friend inline constexpr uint32_t bit_size(const int_n&)
+	{
+		return N;
+	}
+
+	template <typename WriterType>
+	friend inline void serialize(WriterType& writer, const int_n& value)
+	{
+		if (value.value_ < min() || value.value_ > max()) {
+			throw std::out_of_range("value " + std::to_string(value.value_) + " is out of range for int<" + std::to_string(N) + ">");
+		}
+		detail::write_bits_n(writer, uint64_t(int64_t(value.value_)), N);
+	}
+
+	template <typename ReaderType>
+	friend inline error::Error deserialize(int_n& value, ReaderType& reader)
+	{
+		uint64_t bits = 0;
+		auto err = detail::read_bits_n(bits, reader, N);
+		if (err) {
+			return err;
+		}
+		// sign extend from bit N-1
+		uint32_t shift = 64 - N;
+		value.value_ = value_type(int64_t(bits << shift) >> shift);
+		return nullptr;
+	}
+
+private:
+
+	value_type value_ = 0;
+
+};
+
+// nlohmann json serialization
+
+template <uint32_t N>
+inline void to_json(nlohmann::json& j, const uint_n<N>& value)
+{
+	j = uint64_t(value);
+}
+
+template <uint32_t N>
+inline void from_json(const nlohmann::json& j, uint_n<N>& value)
+{
+	value = j.get<typename uint_n<N>::value_type>();
+}
+
+template <uint32_t N>
+inline void to_json(nlohmann::json& j, const int_n<N>& value)
+{
+	j = int64_t(value);
+}
+
+template <uint32_t N>
+inline void from_json(const nlohmann::json& j, int_n<N>& value)
+{
+	value = j.get<typename int_n<N>::value_type>();
+}
+
+}
+}
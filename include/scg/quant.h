@@ -0,0 +1,118 @@
+#pragma once
+
+#include <cmath>
+#include <cstdint>
+
+#include "scg/error.h"
+#include "scg/int_n.h"
+
+#include "nlohmann/json.hpp"
+
+namespace scg {
+namespace type {
+
+// quant holds a quant<T, min, max, N> field. The bounds are given as ratios of
+// integers, as C++17 does not allow floating point template parameters, and the
+// value converts implicitly to and from T.
+//
+// The value is written as an N bit unsigned integer, scaled over [min, max] and
+// rounded to the nearest of the 2^N - 1 steps. Values outside of the range are
+// clamped, and NaN is written as min. The arithmetic is done in double with
+// each step rounded separately, matching the Go implementation bit for bit.
+template <typename T, int64_t MinNum, int64_t MinDen, int64_t MaxNum, int64_t MaxDen, uint32_t N>
+class quant {
+
+	static_assert(N >= 1 && N <= 32, "quant width must be between 1 and 32 bits");
+	static_assert(MinDen > 0 && MaxDen > 0, "quant bounds must have positive denominators");
+
+public:
+
+	using value_type = T;
+
+	static constexpr double min()
+	{
+		return double(MinNum) / double(MinDen);
+	}
+
+	static constexpr double max()
+	{
+		return double(MaxNum) / double(MaxDen);
+	}
+
+	static constexpr uint64_t steps()
+	{
+		return (uint64_t(1) << N) - 1;
+	}
+
+	// precision returns the distance between two adjacent values.
+	static constexpr double precision()
+	{
+		return (max() - min()) / double(steps());
+	}
+
+	constexpr quant() = default;
+
+	constexpr quant(value_type value)
+		: value_(value)
+	{
+	}
+
+	constexpr operator value_type() const
+	{
+		return value_;
+	}
+
+	friend inline constexpr uint32_t bit_size(const quant&)
+	{
+		return N;
+	}
+
+	template <typename WriterType>
+	friend inline void serialize(WriterType& writer, const quant& value)
+	{
+		double val = double(value.value_);
+		if (!(val > min())) {
+			val = min();
+		} else if (val > max()) {
+			val = max();
+		}
+		double normalized = (val - min()) / (max() - min());
+		double scaled = normalized * double(steps());
+		detail::write_bits_n(writer, uint64_t(std::round(scaled)), N);
+	}
+
+	template <typename ReaderType>
+	friend inline error::Error deserialize(quant& value, ReaderType& reader)
+	{
+		uint64_t bits = 0;
+		auto err = detail::read_bits_n(bits, reader, N);
+		if (err) {
+			return err;
+		}
+		double scaled = double(bits) * (max() - min());
+		value.value_ = value_type(min() + scaled / double(steps()));
+		return nullptr;
+	}
+
+private:
+
+	value_type value_ = 0;
+
+};
+
+// nlohmann json serialization
+
+template <typename T, int64_t MinNum, int64_t MinDen, int64_t MaxNum, int64_t MaxDen, uint32_t N>
+inline void to_json(nlohmann::json& j, const quant<T, MinNum, MinDen, MaxNum, MaxDen, N>& value)
+{
+	j = T(value);
+}
+
+template <typename T, int64_t MinNum, int64_t MinDen, int64_t MaxNum, int64_t MaxDen, uint32_t N>
+inline void from_json(const nlohmann::json& j, quant<T, MinNum, MinDen, MaxNum, MaxDen, N>& value)
+{
+	value = j.get<T>();
+}
+
+}
+}
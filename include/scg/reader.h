@@ -17,6 +17,10 @@
 namespace scg {
 namespace serialize {
 
+// MAX_DEPTH bounds how deeply recursive messages may nest when deserializing.
+// Must match pkg/serialize/reader.go.
+constexpr uint32_t MAX_DEPTH = 64;
+
 class ReaderView {
 public:
 
@@ -47,6 +51,46 @@ public:
 		return consumed >= size_ ? 0 : size_ - consumed;
 	}
 
+	// numBitsRead returns the current bit position of the reader.
+	inline uint32_t numBitsRead() const
+	{
+		return numBitsRead_;
+	}
+
+	// remainingBits returns the number of unread bits still available.
+	inline uint64_t remainingBits() const
+	{
+		return uint64_t(size_) * 8 - numBitsRead_;
+	}
+
+	// descend records that deserialization entered a recursive message. It fails
+	// once the nesting exceeds MAX_DEPTH so a hostile payload cannot exhaust the
+	// stack. Every successful call must be paired with a call to ascend.
+	inline error::Error descend()
+	{
+		if (depth_ >= MAX_DEPTH) {
+			return error::Error::Errorf("Reader exceeded the maximum nesting depth of %u", MAX_DEPTH);
+		}
+		depth_++;
+		return nullptr;
+	}
+
+	// ascend records that deserialization left a recursive message.
+	inline void ascend()
+	{
+		depth_--;
+	}
+
+	// skipBits advances the reader without copying out the skipped bits.
+	error::Error skipBits(uint32_t num_bits)
+	{
+		if (uint64_t(num_bits) > remainingBits()) {
+			return error::Error("Reader does not contain enough data to fill the argument");
+		}
+		numBitsRead_ += num_bits;
+		return nullptr;
+	}
+
 	error::Error readBits(uint8_t& val, uint32_t num_bits_to_read)
 	{
 		assert(num_bits_to_read <= 8 && "ReaderView::readBits only supports reading up to 8 bits at a time");
@@ -164,6 +208,7 @@ private:
 	const uint8_t* bytes_;
 	uint32_t size_;
 	uint32_t numBitsRead_ = 0;
+	uint32_t depth_ = 0;
 };
 
 // Reader that owns the buffer
@@ -195,6 +240,46 @@ public:
 		return consumed >= bytes_.size() ? 0 : static_cast<uint32_t>(bytes_.size()) - consumed;
 	}
 
+	// numBitsRead returns the current bit position of the reader.
+	inline uint32_t numBitsRead() const
+	{
+		return numBitsRead_;
+	}
+
+	// remainingBits returns the number of unread bits still available.
+	inline uint64_t remainingBits() const
+	{
+		return uint64_t(bytes_.size()) * 8 - numBitsRead_;
+	}
+
+	// descend records that deserialization entered a recursive message. It fails
+	// once the nesting exceeds MAX_DEPTH so a hostile payload cannot exhaust the
+	// stack. Every successful call must be paired with a call to ascend.
+	inline error::Error descend()
+	{
+		if (depth_ >= MAX_DEPTH) {
+			return error::Error::Errorf("Reader exceeded the maximum nesting depth of %u", MAX_DEPTH);
+		}
+		depth_++;
+		return nullptr;
+	}
+
+	// ascend records that deserialization left a recursive message.
+	inline void ascend()
+	{
+		depth_--;
+	}
+
+	// skipBits advances the reader without copying out the skipped bits.
+	error::Error skipBits(uint32_t num_bits)
+	{
+		if (uint64_t(num_bits) > remainingBits()) {
+			return error::Error("Reader does not contain enough data to fill the argument");
+		}
+		numBitsRead_ += num_bits;
+		return nullptr;
+	}
+
 	error::Error readBits(uint8_t& val, uint32_t num_bits_to_read)
 	{
 		assert(num_bits_to_read <= 8 && "Reader::readBits only supports reading up to 8 bits at a time");
@@ -311,6 +396,31 @@ private:
 
 	std::vector<uint8_t> bytes_;
 	uint32_t numBitsRead_ = 0;
+	uint32_t depth_ = 0;
+};
+
+// DepthGuard ascends out of a recursive message when deserialization of it
+// returns, regardless of which path it returns through.
+template <typename ReaderType>
+class DepthGuard {
+public:
+
+	inline explicit DepthGuard(ReaderType& reader)
+		: reader_(reader)
+	{
+	}
+
+	inline ~DepthGuard()
+	{
+		reader_.ascend();
+	}
+
+	DepthGuard(const DepthGuard&) = delete;
+	DepthGuard& operator=(const DepthGuard&) = delete;
+
+private:
+
+	ReaderType& reader_;
 };
 
 }
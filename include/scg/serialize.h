@@ -1,11 +1,17 @@
 #pragma once
 
+#include <algorithm>
 #include <array>
+#include <chrono>
 #include <map>
+#include <memory>
+#include <optional>
 #include <unordered_map>
 #include <set>
 #include <unordered_set>
+#include <utility>
 #include <string>
+#include <variant>
 #include <vector>
 #include <cassert>
 #include <type_traits>
@@ -260,6 +266,47 @@ inline error::Error deserialize(float64_t& value, ReaderType& reader)
 	return nullptr;
 }
 
+// durations are written as a zigzag encoded number of nanoseconds
+inline constexpr uint32_t bit_size(std::chrono::nanoseconds value)
+{
+	return bit_size(int64_t(value.count()));
+}
+
+template <typename WriterType>
+inline void serialize(WriterType& writer, std::chrono::nanoseconds value)
+{
+	serialize(writer, int64_t(value.count()));
+}
+
+template <typename ReaderType>
+inline error::Error deserialize(std::chrono::nanoseconds& value, ReaderType& reader)
+{
+	int64_t nanoseconds = 0;
+	auto err = deserialize(nanoseconds, reader);
+	if (err) {
+		return err;
+	}
+	value = std::chrono::nanoseconds(nanoseconds);
+	return nullptr;
+}
+
+// sorted_elements returns pointers to the elements of an unordered container in
+// ascending order, which a writer in the deterministic mode writes them in to
+// match the Go encoding.
+template <typename Container, typename Less>
+inline std::vector<const typename Container::value_type*> sorted_elements(const Container& value, Less less)
+{
+	std::vector<const typename Container::value_type*> sorted;
+	sorted.reserve(value.size());
+	for (const auto& item : value) {
+		sorted.push_back(&item);
+	}
+	std::sort(sorted.begin(), sorted.end(), [&less](const auto* a, const auto* b) {
+		return less(*a, *b);
+	});
+	return sorted;
+}
+
 // bounded_reserve_count caps a wire-declared element count to the bytes actually
 // remaining in the reader, so a hostile count cannot drive a huge
 // reserve()/resize() before the elements are read. The container still grows to
@@ -379,6 +426,34 @@ inline error::Error deserialize(T& value, ReaderType& reader)
 	return nullptr;
 }
 
+// Containers may hold one another, and a std container is not found through
+// argument dependent lookup within this namespace, so every container overload
+// is declared before any of them are defined.
+template <typename T> inline uint32_t bit_size(const std::vector<T>& value);
+template <typename WriterType, typename T> inline void serialize(WriterType& writer, const std::vector<T>& value);
+template <typename ReaderType, typename T> inline error::Error deserialize(std::vector<T>& value, ReaderType& reader);
+inline uint32_t bit_size(const std::vector<uint8_t>& value);
+template <typename WriterType> inline void serialize(WriterType& writer, const std::vector<uint8_t>& value);
+template <typename ReaderType> inline error::Error deserialize(std::vector<uint8_t>& value, ReaderType& reader);
+template <typename K, typename V> inline uint32_t bit_size(const std::map<K,V>& value);
+template <typename K, typename V, typename WriterType> inline void serialize(WriterType& writer, const std::map<K,V>& value);
+template <typename K, typename V, typename ReaderType> inline error::Error deserialize(std::map<K,V>& value, ReaderType& reader);
+template <typename K, typename V> inline uint32_t bit_size(const std::unordered_map<K,V>& value);
+template <typename K, typename V, typename WriterType> inline void serialize(WriterType& writer, const std::unordered_map<K,V>& value);
+template <typename K, typename V, typename ReaderType> inline error::Error deserialize(std::unordered_map<K,V>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size(const std::set<T>& value);
+template <typename WriterType, typename T> inline void serialize(WriterType& writer, const std::set<T>& value);
+template <typename ReaderType, typename T> inline error::Error deserialize(std::set<T>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size(const std::unordered_set<T>& value);
+template <typename WriterType, typename T> inline void serialize(WriterType& writer, const std::unordered_set<T>& value);
+template <typename ReaderType, typename T> inline error::Error deserialize(std::unordered_set<T>& value, ReaderType& reader);
+template <typename T, size_t N> inline uint32_t bit_size(const std::array<T, N>& value);
+template <typename WriterType, typename T, size_t N> inline void serialize(WriterType& writer, const std::array<T, N>& value);
+template <typename ReaderType, typename T, size_t N> inline error::Error deserialize(std::array<T, N>& value, ReaderType& reader);
+template <typename T> inline uint32_t bit_size(const std::optional<T>& value);
+template <typename WriterType, typename T> inline void serialize(WriterType& writer, const std::optional<T>& value);
+template <typename ReaderType, typename T> inline error::Error deserialize(std::optional<T>& value, ReaderType& reader);
+
 template <typename T>
 inline uint32_t bit_size(const std::vector<T>& value)
 {
@@ -422,6 +497,40 @@ inline error::Error deserialize(std::vector<T>& value, ReaderType& reader)
 	return nullptr;
 }
 
+// Byte vectors share the encoding of any other vector, but are copied in bulk
+// rather than one element at a time.
+inline uint32_t bit_size(const std::vector<uint8_t>& value)
+{
+	return bit_size(uint32_t(value.size())) + (value.size() * 8);
+}
+
+template <typename WriterType>
+inline void serialize(WriterType& writer, const std::vector<uint8_t>& value)
+{
+	serialize(writer, uint32_t(value.size()));
+	writer.writeBytes(value.data(), uint32_t(value.size()));
+}
+
+template <typename ReaderType>
+inline error::Error deserialize(std::vector<uint8_t>& value, ReaderType& reader)
+{
+	uint32_t len = 0;
+	auto err = deserialize(len, reader);
+	if (err) {
+		return err;
+	}
+
+	if (len > reader.remainingBytes()) {
+		return error::Error("declared bytes length exceeds remaining bytes");
+	}
+
+	value.resize(len);
+	if (len > 0) {
+		return reader.readBytes(value.data(), len);
+	}
+	return nullptr;
+}
+
 template <typename K, typename V>
 inline uint32_t bit_size(const std::map<K,V>& value)
 {
@@ -462,7 +571,7 @@ inline error::Error deserialize(std::map<K,V>& value, ReaderType& reader)
 		if (err) {
 			return err;
 		}
-		value[key] = val;
+		value[key] = std::move(val);
 	}
 	return nullptr;
 }
@@ -481,6 +590,16 @@ template <typename K, typename V, typename WriterType>
 inline void serialize(WriterType& writer, const std::unordered_map<K,V>& value)
 {
 	serialize(writer, uint32_t(value.size()));
+	if (writer.deterministic()) {
+		auto sorted = sorted_elements(value, [](const auto& a, const auto& b) {
+			return a.first < b.first;
+		});
+		for (const auto* entry : sorted) {
+			serialize(writer, entry->first);
+			serialize(writer, entry->second);
+		}
+		return;
+	}
 	for (const auto& [key, val] : value) {
 		serialize(writer, key);
 		serialize(writer, val);
@@ -510,7 +629,7 @@ inline error::Error deserialize(std::unordered_map<K,V>& value, ReaderType& read
 		if (err) {
 			return err;
 		}
-		value[key] = val;
+		value[key] = std::move(val);
 	}
 	return nullptr;
 }
@@ -542,13 +661,17 @@ inline error::Error deserialize(std::set<T>& value, ReaderType& reader)
 	if (err) {
 		return err;
 	}
+	value.clear();
 	for (auto i = uint32_t(0); i < size; i++) {
 		T t{};
 		err = deserialize(t, reader);
 		if (err) {
 			return err;
 		}
-		value.insert(t);
+		// a set holds each element once, so a duplicate is malformed
+		if (!value.insert(std::move(t)).second) {
+			return error::Error("set contains duplicate element");
+		}
 	}
 	return nullptr;
 }
@@ -567,6 +690,15 @@ template <typename WriterType, typename T>
 inline void serialize(WriterType& writer, const std::unordered_set<T>& value)
 {
 	serialize(writer, uint32_t(value.size()));
+	if (writer.deterministic()) {
+		auto sorted = sorted_elements(value, [](const T& a, const T& b) {
+			return a < b;
+		});
+		for (const auto* item : sorted) {
+			serialize(writer, *item);
+		}
+		return;
+	}
 	for (const auto& item : value) {
 		serialize(writer, item);
 	}
@@ -581,6 +713,7 @@ inline error::Error deserialize(std::unordered_set<T>& value, ReaderType& reader
 		return err;
 	}
 	// Bound the reserve against the bytes present (see vector/unordered_map).
+	value.clear();
 	value.reserve(bounded_reserve_count(size, reader));
 	for (auto i = uint32_t(0); i < size; i++) {
 		T t{};
@@ -588,7 +721,10 @@ inline error::Error deserialize(std::unordered_set<T>& value, ReaderType& reader
 		if (err) {
 			return err;
 		}
-		value.insert(t);
+		// a set holds each element once, so a duplicate is malformed
+		if (!value.insert(std::move(t)).second) {
+			return error::Error("set contains duplicate element");
+		}
 	}
 	return nullptr;
 }
@@ -621,10 +757,142 @@ inline error::Error deserialize(std::array<T, N>& value, ReaderType& reader)
 		if (err) {
 			return err;
 		}
-		value[i] = t;
+		value[i] = std::move(t);
 	}
 	return nullptr;
 }
 
+// std::optional is encoded as a presence bit followed by the value when set.
+template <typename T>
+inline uint32_t bit_size(const std::optional<T>& value)
+{
+	if (!value) {
+		return 1;
+	}
+	return 1 + bit_size(*value);
+}
+
+template <typename WriterType, typename T>
+inline void serialize(WriterType& writer, const std::optional<T>& value)
+{
+	serialize(writer, value.has_value());
+	if (value) {
+		serialize(writer, *value);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize(std::optional<T>& value, ReaderType& reader)
+{
+	bool present = false;
+	auto err = deserialize(present, reader);
+	if (err) {
+		return err;
+	}
+	if (!present) {
+		value.reset();
+		return nullptr;
+	}
+	T t{};
+	err = deserialize(t, reader);
+	if (err) {
+		return err;
+	}
+	value = std::move(t);
+	return nullptr;
+}
+
+// std::unique_ptr holds recursive optional fields and shares the encoding of
+// std::optional.
+template <typename T>
+inline uint32_t bit_size(const std::unique_ptr<T>& value)
+{
+	if (!value) {
+		return 1;
+	}
+	return 1 + bit_size(*value);
+}
+
+template <typename WriterType, typename T>
+inline void serialize(WriterType& writer, const std::unique_ptr<T>& value)
+{
+	serialize(writer, value != nullptr);
+	if (value) {
+		serialize(writer, *value);
+	}
+}
+
+template <typename ReaderType, typename T>
+inline error::Error deserialize(std::unique_ptr<T>& value, ReaderType& reader)
+{
+	bool present = false;
+	auto err = deserialize(present, reader);
+	if (err) {
+		return err;
+	}
+	if (!present) {
+		value.reset();
+		return nullptr;
+	}
+	value = std::make_unique<T>();
+	return deserialize(*value, reader);
+}
+
+// std::variant is encoded as a discriminator holding the index of the active
+// alternative, followed by its value. The first alternative must be
+// std::monostate, which carries no value and represents an unset variant.
+template <typename... Ts>
+inline uint32_t bit_size(const std::variant<std::monostate, Ts...>& value)
+{
+	uint32_t size = bit_size(static_cast<uint32_t>(value.index()));
+	std::visit([&size](const auto& alternative) {
+		using AlternativeType = std::decay_t<decltype(alternative)>;
+		if constexpr (!std::is_same_v<AlternativeType, std::monostate>) {
+			size += bit_size(alternative);
+		}
+	}, value);
+	return size;
+}
+
+template <typename WriterType, typename... Ts>
+inline void serialize(WriterType& writer, const std::variant<std::monostate, Ts...>& value)
+{
+	serialize(writer, static_cast<uint32_t>(value.index()));
+	std::visit([&writer](const auto& alternative) {
+		using AlternativeType = std::decay_t<decltype(alternative)>;
+		if constexpr (!std::is_same_v<AlternativeType, std::monostate>) {
+			serialize(writer, alternative);
+		}
+	}, value);
+}
+
+template <std::size_t I, typename ReaderType, typename... Ts>
+inline error::Error deserialize_variant_alternative(std::variant<Ts...>& value, uint32_t index, ReaderType& reader)
+{
+	if constexpr (I < sizeof...(Ts)) {
+		if (index == I) {
+			return deserialize(value.template emplace<I>(), reader);
+		}
+		return deserialize_variant_alternative<I + 1>(value, index, reader);
+	} else {
+		return error::Error::Errorf("variant has unrecognized discriminator %u", index);
+	}
+}
+
+template <typename ReaderType, typename... Ts>
+inline error::Error deserialize(std::variant<std::monostate, Ts...>& value, ReaderType& reader)
+{
+	uint32_t index = 0;
+	auto err = deserialize(index, reader);
+	if (err) {
+		return err;
+	}
+	if (index == 0) {
+		value = std::monostate{};
+		return nullptr;
+	}
+	return deserialize_variant_alternative<1>(value, index, reader);
+}
+
 }
 }
@@ -25,6 +25,7 @@
 #include "scg/middleware.h"
 #include "scg/transport.h"
 #include "scg/stream.h"
+#include "scg/status.h"
 
 namespace scg {
 namespace rpc {
@@ -170,61 +171,58 @@ public:
 		return nullptr;
 	}
 
-	// Stop the server and wait for thread to finish
+	// Stop the server immediately and wait for its threads to finish. The calls
+	// in flight are abandoned, and their clients see the connection close.
 	error::Error shutdown()
 	{
-		// Check if already stopped
+		stop();
+		return nullptr;
+	}
+
+	// Gracefully stop the server: stop accepting connections and send a GOAWAY
+	// on each open connection, so that clients issue their next calls on a new
+	// connection, then wait up to timeout for the in-flight unary requests and
+	// streams to complete before closing the connections. Calls that arrive on a
+	// connection before its client has seen the GOAWAY are still served. Unlike
+	// shutdown(), the returned error reports the calls that were abandoned when
+	// the timeout elapsed, if any.
+	error::Error shutdown(std::chrono::milliseconds timeout)
+	{
 		if (!running_) {
-			// Join threads if they're still running
-			keepaliveCv_.notify_all();
-			if (keepaliveThread_.joinable()) {
-				keepaliveThread_.join();
-			}
-			if (transportThread_.joinable()) {
-				transportThread_.join();
-			}
+			stop();
 			return nullptr;
 		}
 
-		// Signal shutdown
-		running_ = false;
-
-		// Stop the keepalive scanner.
-		keepaliveCv_.notify_all();
-		if (keepaliveThread_.joinable()) {
-			keepaliveThread_.join();
-		}
-
-		// Stop the transport
-		if (transport_) {
-			transport_->stop();
+		// Stop accepting connections, and tell the clients of the open ones to
+		// go away.
+		std::vector<std::shared_ptr<Connection>> conns;
+		{
+			std::lock_guard<std::mutex> lock(mu_);
+			draining_ = true;
+			for (auto& pair : connections_) {
+				conns.push_back(pair.second);
+			}
 		}
-
-		// Wait for threads to finish
-		if (transportThread_.joinable()) {
-			transportThread_.join();
+		for (auto& conn : conns) {
+			conn->send(serializeStreamControl(STREAM_FRAME_GOAWAY));
 		}
 
-		// Fail all live streams so their handler threads unblock and exit, then
-		// wait for every (detached) handler to finish before tearing down state.
+		// Wait for the calls in flight to complete.
+		error::Error abandoned;
 		{
 			std::unique_lock<std::mutex> lock(mu_);
-			for (auto& cp : connStreams_) {
-				for (auto& sp : cp.second) {
-					sp.second->die(error::Error("server shutting down"));
-				}
+			bool drained = handlersDone_.wait_for(lock, timeout, [this]() {
+				return activeRequests_ == 0 && activeStreamHandlers_ == 0;
+			});
+			if (!drained) {
+				abandoned = error::Error(
+					"shutdown abandoned " + std::to_string(activeRequests_) + " requests and " +
+					std::to_string(activeStreamHandlers_) + " streams");
 			}
-			connStreams_.clear();
-			streamHandlersDone_.wait(lock, [this]() { return activeStreamHandlers_ == 0; });
 		}
 
-		// Now clean up (thread is stopped, no more concurrent access)
-		std::lock_guard<std::mutex> lock(mu_);
-
-		// Just clear connections - their destructors will call close()
-		connections_.clear();
-
-		return nullptr;
+		stop();
+		return abandoned;
 	}
 
 	// Check if server is running
@@ -288,6 +286,63 @@ public:
 	}
 
 private:
+	// stop stops the transport and keepalive threads, fails the live streams
+	// and closes the connections. Once stopped, it only joins any threads still
+	// running.
+	void stop()
+	{
+		// Check if already stopped
+		if (!running_) {
+			// Join threads if they're still running
+			keepaliveCv_.notify_all();
+			if (keepaliveThread_.joinable()) {
+				keepaliveThread_.join();
+			}
+			if (transportThread_.joinable()) {
+				transportThread_.join();
+			}
+			return;
+		}
+
+		// Signal shutdown
+		running_ = false;
+
+		// Stop the keepalive scanner.
+		keepaliveCv_.notify_all();
+		if (keepaliveThread_.joinable()) {
+			keepaliveThread_.join();
+		}
+
+		// Stop the transport
+		if (transport_) {
+			transport_->stop();
+		}
+
+		// Wait for threads to finish
+		if (transportThread_.joinable()) {
+			transportThread_.join();
+		}
+
+		// Fail all live streams so their handler threads unblock and exit, then
+		// wait for every (detached) handler to finish before tearing down state.
+		{
+			std::unique_lock<std::mutex> lock(mu_);
+			for (auto& cp : connStreams_) {
+				for (auto& sp : cp.second) {
+					sp.second->die(error::Error("server shutting down"));
+				}
+			}
+			connStreams_.clear();
+			handlersDone_.wait(lock, [this]() { return activeStreamHandlers_ == 0; });
+		}
+
+		// Now clean up (thread is stopped, no more concurrent access)
+		std::lock_guard<std::mutex> lock(mu_);
+
+		// Just clear connections - their destructors will call close()
+		connections_.clear();
+	}
+
 	// Start the server (internal helper)
 	error::Error initialize()
 	{
@@ -323,9 +378,13 @@ private:
 
 		uint64_t connID = nextConnectionID_++;
 
-		// Store the connection first
+		// Store the connection first, unless the server is shutting down
 		{
 			std::lock_guard<std::mutex> lock(mu_);
+			if (draining_) {
+				conn->close();
+				return;
+			}
 			connections_[connID] = conn;
 		}
 		{
@@ -359,13 +418,18 @@ private:
 				return;
 			}
 
-			// Dispatch the unary request to the worker pool so the single io thread
-			// stays free to read more frames, and handlers run concurrently across
-			// cores (and may block without stalling the event loop). Stream frames
-			// are routed inline above to preserve per-stream order.
-			asio::post(threadPool_, [this, connID, data]() {
-				handleMessage(connID, data);
-			});
+			if (prefix == CANCEL_PREFIX) {
+				handleCancel(connID, reader);
+				return;
+			}
+
+			if (prefix != REQUEST_PREFIX) {
+				handleError(error::Error("Unexpected prefix"));
+				return;
+			}
+
+			// Unary handlers run on the worker pool; see handleRequest.
+			handleRequest(connID, reader);
 		});
 
 		conn->setCloseHandler([this, connID]() {
@@ -381,6 +445,7 @@ private:
 	void onConnectionClose(uint64_t connID)
 	{
 		failConnStreams(connID, error::Error("connection closed"));
+		cancelConnRequests(connID);
 
 		eraseActivity(connID);
 
@@ -396,6 +461,7 @@ private:
 	void onConnectionFail(uint64_t connID, const error::Error& err)
 	{
 		failConnStreams(connID, error::Error("connection failed: " + err.message()));
+		cancelConnRequests(connID);
 
 		eraseActivity(connID);
 
@@ -542,6 +608,12 @@ private:
 			if (deserialize(ctx, reader)) {
 				return;
 			}
+			uint64_t timeout = 0;
+			if (serialize::deserialize(timeout, reader)) {
+				return;
+			}
+			// bound the stream by the deadline of the caller
+			ctx.setTimeout(timeout);
 			uint64_t serviceID = 0;
 			if (serialize::deserialize(serviceID, reader)) {
 				return;
@@ -562,7 +634,7 @@ private:
 			}
 
 			auto stream = std::make_shared<ServerStream>(conn, ctx, streamID, config_.streamRecvBufferSize);
-			std::string rejectReason;
+			Status reject;
 			bool spawn = false;
 			{
 				std::lock_guard<std::mutex> lock(mu_);
@@ -571,17 +643,17 @@ private:
 				}
 				auto& connMap = connStreams_[connID];
 				if (connMap.count(streamID) != 0) {
-					rejectReason = "duplicate stream id";
+					reject = Status(Code::ALREADY_EXISTS, "duplicate stream id");
 				} else if (config_.maxConcurrentStreams > 0 && connMap.size() >= config_.maxConcurrentStreams) {
-					rejectReason = "max concurrent streams exceeded";
+					reject = Status(Code::RESOURCE_EXHAUSTED, "max concurrent streams exceeded");
 				} else {
 					connMap[streamID] = stream;
 					activeStreamHandlers_++;
 					spawn = true;
 				}
 			}
-			if (!rejectReason.empty()) {
-				conn->send(serializeStreamClose(streamID, STREAM_STATUS_ERROR, rejectReason));
+			if (!reject.ok()) {
+				conn->send(serializeStreamClose(streamID, reject));
 				return;
 			}
 			if (spawn) {
@@ -620,7 +692,7 @@ private:
 						}
 					}
 					if (conn) {
-						conn->send(serializeStreamClose(streamID, STREAM_STATUS_ERROR, "stream receive buffer overflow"));
+						conn->send(serializeStreamClose(streamID, Status(Code::RESOURCE_EXHAUSTED, "stream receive buffer overflow")));
 					}
 					removeStream(connID, streamID);
 				}
@@ -629,7 +701,7 @@ private:
 				stream->halfClose();
 				break;
 			case STREAM_FRAME_CLOSE:
-				stream->die(error::Error("stream cancelled by client"));
+				stream->die(Status(Code::CANCELED, "stream cancelled by client").toError());
 				removeStream(connID, streamID);
 				break;
 			default:
@@ -645,7 +717,7 @@ private:
 
 		std::lock_guard<std::mutex> lock(mu_);
 		if (--activeStreamHandlers_ == 0) {
-			streamHandlersDone_.notify_all();
+			handlersDone_.notify_all();
 		}
 	}
 
@@ -671,15 +743,15 @@ private:
 			middlewareStack = getMiddlewareStack(serviceID);
 		}
 
-		auto finish = [&](uint8_t status, const std::string& msg) {
+		auto finish = [&](const Status& status) {
 			if (conn) {
-				conn->send(serializeStreamClose(streamID, status, msg));
+				conn->send(serializeStreamClose(streamID, status));
 			}
 			removeStream(connID, streamID);
 		};
 
 		if (!handler) {
-			finish(STREAM_STATUS_ERROR, "service with id " + std::to_string(serviceID) + " does not support streaming");
+			finish(Status(Code::UNIMPLEMENTED, "service with id " + std::to_string(serviceID) + " does not support streaming"));
 			return;
 		}
 
@@ -695,16 +767,12 @@ private:
 				return std::make_pair(sentinel, nullptr);
 			});
 		if (mwResult.second) {
-			finish(STREAM_STATUS_ERROR, mwResult.second.message());
+			finish(Status::fromError(mwResult.second));
 			return;
 		}
 
 		auto err = handler(stream->context(), stream, methodID);
-		if (err) {
-			finish(STREAM_STATUS_ERROR, err.message());
-		} else {
-			finish(STREAM_STATUS_OK, "");
-		}
+		finish(Status::fromError(err));
 	}
 
 	StreamHandler getStreamService(uint64_t serviceID) const
@@ -716,33 +784,68 @@ private:
 		return nullptr;
 	}
 
-	// Handle a single message
-	void handleMessage(uint64_t connID, const std::vector<uint8_t>& data)
+	// handleRequest reads the header of a unary request frame (prefix already
+	// consumed) on the I/O thread, so that the request is registered before a
+	// CANCEL for it can be read, and dispatches the handler to the worker pool.
+	void handleRequest(uint64_t connID, serialize::Reader& reader)
 	{
-		serialize::Reader reader(data);
+		// Read context using ADL
+		context::Context ctx;
+		if (deserialize(ctx, reader)) {
+			return;
+		}
 
-		try {
-			// Read prefix
-			std::array<uint8_t, 16> prefix;
-			serialize::deserialize(prefix, reader);
+		// Bound the handler by the deadline of the caller
+		uint64_t timeout = 0;
+		if (serialize::deserialize(timeout, reader)) {
+			return;
+		}
+		ctx.setTimeout(timeout);
 
-			if (prefix != REQUEST_PREFIX) {
-				handleError(error::Error("Unexpected prefix"));
-				return;
-			}
+		// Read request ID
+		uint64_t requestID = 0;
+		if (serialize::deserialize(requestID, reader)) {
+			return;
+		}
 
-			// Read context using ADL
-			context::Context ctx;
-			deserialize(ctx, reader);
+		// Read service ID
+		uint64_t serviceID = 0;
+		if (serialize::deserialize(serviceID, reader)) {
+			return;
+		}
 
-			// Read request ID
-			uint64_t requestID = 0;
-			serialize::deserialize(requestID, reader);
+		// Register the request so that a CANCEL from the client cancels the
+		// context of its handler; copies of a context share its cancellation.
+		bool tracked = false;
+		{
+			std::lock_guard<std::mutex> lock(mu_);
+			auto& requests = connRequests_[connID];
+			if (requests.count(requestID) == 0) {
+				requests.emplace(requestID, ctx);
+				tracked = true;
+			}
+			activeRequests_++;
+		}
 
-			// Read service ID
-			uint64_t serviceID = 0;
-			serialize::deserialize(serviceID, reader);
+		// Dispatch the handler to the worker pool so the single io thread stays
+		// free to read more frames, and handlers run concurrently across cores
+		// (and may block without stalling the event loop).
+		asio::post(threadPool_, [this, connID, ctx, requestID, serviceID, reader, tracked]() mutable {
+			runUnaryHandler(connID, ctx, requestID, serviceID, reader);
+			if (tracked) {
+				removeRequest(connID, requestID);
+			}
+			std::lock_guard<std::mutex> lock(mu_);
+			if (--activeRequests_ == 0) {
+				handlersDone_.notify_all();
+			}
+		});
+	}
 
+	// runUnaryHandler runs the handler of a unary request and sends the response.
+	void runUnaryHandler(uint64_t connID, context::Context& ctx, uint64_t requestID, uint64_t serviceID, serialize::Reader& reader)
+	{
+		try {
 			// Get service handler and middleware and connection
 			// Hold shared_ptr to keep connection alive even if removed from map
 			ServiceHandler handler;
@@ -762,7 +865,7 @@ private:
 			}
 
 			if (!handler) {
-				auto response = respondWithError(requestID, error::Error("Service not found"));
+				auto response = respondWithError(requestID, Status(Code::UNIMPLEMENTED, "Service not found").toError());
 				conn->send(response);
 				return;
 			}
@@ -770,6 +873,11 @@ private:
 			// Call handler
 			auto response = handler(ctx, middlewareStack, requestID, reader);
 
+			// The client no longer waits for the response of a cancelled request
+			if (ctx.cancelled()) {
+				return;
+			}
+
 			// Send response
 			conn->send(response);
 
@@ -778,6 +886,53 @@ private:
 		}
 	}
 
+	// handleCancel cancels the context of the handler of the unary request named
+	// by a CANCEL frame (prefix already consumed). A request that has already
+	// completed is ignored.
+	void handleCancel(uint64_t connID, serialize::Reader& reader)
+	{
+		uint64_t requestID = 0;
+		if (serialize::deserialize(requestID, reader)) {
+			return;
+		}
+		std::lock_guard<std::mutex> lock(mu_);
+		auto it = connRequests_.find(connID);
+		if (it == connRequests_.end()) {
+			return;
+		}
+		auto rit = it->second.find(requestID);
+		if (rit != it->second.end()) {
+			rit->second.cancel();
+		}
+	}
+
+	void removeRequest(uint64_t connID, uint64_t requestID)
+	{
+		std::lock_guard<std::mutex> lock(mu_);
+		auto it = connRequests_.find(connID);
+		if (it != connRequests_.end()) {
+			it->second.erase(requestID);
+			if (it->second.empty()) {
+				connRequests_.erase(it);
+			}
+		}
+	}
+
+	// cancelConnRequests cancels the handlers of every in-flight unary request of
+	// a connection, as no response can be delivered once it is gone.
+	void cancelConnRequests(uint64_t connID)
+	{
+		std::lock_guard<std::mutex> lock(mu_);
+		auto it = connRequests_.find(connID);
+		if (it == connRequests_.end()) {
+			return;
+		}
+		for (auto& pair : it->second) {
+			pair.second.cancel();
+		}
+		connRequests_.erase(it);
+	}
+
 	// Get service handler by ID
 	ServiceHandler getService(uint64_t serviceID) const
 	{
@@ -819,19 +974,19 @@ private:
 	{
 		using scg::serialize::bit_size; // ADL trickery
 
-		std::string errMsg = err ? err.message() : "Unknown error";
+		Status status = err ? Status::fromError(err) : Status(Code::UNKNOWN, "Unknown error");
 
 		size_t bitSize =
 			bit_size(RESPONSE_PREFIX) +
 			bit_size(requestID) +
 			bit_size(ERROR_RESPONSE) +
-			bit_size(errMsg);
+			bit_size(status);
 
 		serialize::Writer writer(serialize::bits_to_bytes(bitSize));
 		writer.write(RESPONSE_PREFIX);
 		writer.write(requestID);
 		writer.write(ERROR_RESPONSE);
-		writer.write(errMsg);
+		writer.write(status);
 
 		return writer.bytes();
 	}
@@ -867,7 +1022,19 @@ private:
 	std::map<uint64_t, StreamHandler> streamServices_;
 	std::map<uint64_t, std::map<uint64_t, std::shared_ptr<ServerStream>>> connStreams_;
 	int activeStreamHandlers_ = 0;
-	std::condition_variable streamHandlersDone_;
+
+	// Unary handlers running on the worker pool, counted so that shutdown can
+	// drain them along with the stream handlers.
+	int activeRequests_ = 0;
+	std::condition_variable handlersDone_;
+
+	// Set once shutdown begins; new connections are refused while the open
+	// ones drain.
+	bool draining_ = false;
+
+	// In-flight unary requests by connection and request id. The registered
+	// context shares its cancellation with the one passed to the handler.
+	std::map<uint64_t, std::map<uint64_t, context::Context>> connRequests_;
 
 	asio::thread_pool threadPool_;
 	std::thread transportThread_;
@@ -883,24 +1050,25 @@ private:
 	std::condition_variable keepaliveCv_;
 };
 
-// Helper function to create an error response
+// Helper function to create an error response carrying the status of err, see
+// Status::fromError
 inline std::vector<uint8_t> respondWithError(uint64_t requestID, const error::Error& err)
 {
 	using scg::serialize::bit_size; // ADL trickery
 
-	std::string errMsg = err ? err.message() : "Unknown error";
+	Status status = err ? Status::fromError(err) : Status(Code::UNKNOWN, "Unknown error");
 
 	size_t bitSize =
 		bit_size(RESPONSE_PREFIX) +
 		bit_size(requestID) +
 		bit_size(ERROR_RESPONSE) +
-		bit_size(errMsg);
+		bit_size(status);
 
 	serialize::Writer writer(serialize::bits_to_bytes(bitSize));
 	writer.write(RESPONSE_PREFIX);
 	writer.write(requestID);
 	writer.write(ERROR_RESPONSE);
-	writer.write(errMsg);
+	writer.write(status);
 
 	return writer.bytes();
 }
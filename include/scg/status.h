@@ -0,0 +1,220 @@
+#pragma once
+
+#include <cstdint>
+#include <memory>
+#include <string>
+#include <vector>
+
+#include "scg/error.h"
+#include "scg/serialize.h"
+
+namespace scg {
+namespace rpc {
+
+// Code classifies the outcome of a call, so that callers can branch on the kind
+// of failure rather than on its message. The values match the Go rpc.Code and
+// must not be renumbered.
+enum class Code : uint32_t {
+	OK = 0,
+	CANCELED = 1,
+	UNKNOWN = 2,
+	INVALID_ARGUMENT = 3,
+	DEADLINE_EXCEEDED = 4,
+	NOT_FOUND = 5,
+	ALREADY_EXISTS = 6,
+	PERMISSION_DENIED = 7,
+	RESOURCE_EXHAUSTED = 8,
+	FAILED_PRECONDITION = 9,
+	ABORTED = 10,
+	OUT_OF_RANGE = 11,
+	UNIMPLEMENTED = 12,
+	INTERNAL = 13,
+	UNAVAILABLE = 14,
+	DATA_LOSS = 15,
+	UNAUTHENTICATED = 16,
+};
+
+inline std::string codeName(Code code)
+{
+	switch (code) {
+		case Code::OK: return "OK";
+		case Code::CANCELED: return "Canceled";
+		case Code::UNKNOWN: return "Unknown";
+		case Code::INVALID_ARGUMENT: return "InvalidArgument";
+		case Code::DEADLINE_EXCEEDED: return "DeadlineExceeded";
+		case Code::NOT_FOUND: return "NotFound";
+		case Code::ALREADY_EXISTS: return "AlreadyExists";
+		case Code::PERMISSION_DENIED: return "PermissionDenied";
+		case Code::RESOURCE_EXHAUSTED: return "ResourceExhausted";
+		case Code::FAILED_PRECONDITION: return "FailedPrecondition";
+		case Code::ABORTED: return "Aborted";
+		case Code::OUT_OF_RANGE: return "OutOfRange";
+		case Code::UNIMPLEMENTED: return "Unimplemented";
+		case Code::INTERNAL: return "Internal";
+		case Code::UNAVAILABLE: return "Unavailable";
+		case Code::DATA_LOSS: return "DataLoss";
+		case Code::UNAUTHENTICATED: return "Unauthenticated";
+	}
+	return "Code(" + std::to_string(uint32_t(code)) + ")";
+}
+
+// StatusDetail is an encoded detail message and the name of its type.
+struct StatusDetail {
+	std::string type;
+	std::vector<uint8_t> data;
+};
+
+// Status is the outcome of a call as sent by the server in an error response or
+// a stream CLOSE frame. A handler returns one as an error with toError, and a
+// client recovers it from an error with fromError.
+class Status {
+public:
+
+	Status() = default;
+
+	inline Status(Code code, const std::string& message)
+		: code_(code)
+		, message_(message)
+	{
+	}
+
+	inline Code code() const
+	{
+		return code_;
+	}
+
+	inline const std::string& message() const
+	{
+		return message_;
+	}
+
+	inline const std::vector<StatusDetail>& details() const
+	{
+		return details_;
+	}
+
+	inline bool ok() const
+	{
+		return code_ == Code::OK;
+	}
+
+	// addDetail appends an encoded detail message to the status. Generated
+	// messages identify their type with messageName.
+	template <typename T>
+	inline Status& addDetail(const T& msg)
+	{
+		details_.push_back(StatusDetail{T::messageName(), msg.toBytes()});
+		return *this;
+	}
+
+	// detail decodes the first detail of type T into msg. It returns false if the
+	// status has no such detail or it cannot be decoded.
+	template <typename T>
+	inline bool detail(T& msg) const
+	{
+		for (const auto& d : details_) {
+			if (d.type == T::messageName()) {
+				return !msg.fromBytes(d.data);
+			}
+		}
+		return false;
+	}
+
+	// toError returns an error carrying the status, or nullptr for an OK status.
+	// Its message is the message of the status, or the name of its code if the
+	// message is empty.
+	inline error::Error toError() const
+	{
+		if (ok()) {
+			return nullptr;
+		}
+		return error::Error::withStatus(message_.empty() ? codeName(code_) : message_, std::make_shared<const Status>(*this));
+	}
+
+	// fromError returns the status carried by err. An error that does not carry
+	// one is UNKNOWN with the error as its message, and nullptr is OK.
+	static inline Status fromError(const error::Error& err)
+	{
+		if (!err) {
+			return Status();
+		}
+		if (err.status()) {
+			return *err.status();
+		}
+		return Status(Code::UNKNOWN, err.message());
+	}
+
+	friend inline uint32_t bit_size(const Status& status)
+	{
+		using scg::serialize::bit_size; // adl trickery
+
+		uint32_t size =
+			bit_size(uint32_t(status.code_)) +
+			bit_size(status.message_) +
+			bit_size(uint32_t(status.details_.size()));
+		for (const auto& d : status.details_) {
+			size += bit_size(d.type);
+			size += bit_size(d.data);
+		}
+		return size;
+	}
+
+	template <typename WriterType>
+	friend inline void serialize(WriterType& writer, const Status& status)
+	{
+		using scg::serialize::serialize;
+
+		serialize(writer, uint32_t(status.code_));
+		serialize(writer, status.message_);
+		serialize(writer, uint32_t(status.details_.size()));
+		for (const auto& d : status.details_) {
+			serialize(writer, d.type);
+			serialize(writer, d.data);
+		}
+	}
+
+	template <typename ReaderType>
+	friend inline error::Error deserialize(Status& status, ReaderType& reader)
+	{
+		using scg::serialize::deserialize;
+
+		uint32_t code = 0;
+		auto err = deserialize(code, reader);
+		if (err) {
+			return err;
+		}
+		status.code_ = Code(code);
+		err = deserialize(status.message_, reader);
+		if (err) {
+			return err;
+		}
+		uint32_t count = 0;
+		err = deserialize(count, reader);
+		if (err) {
+			return err;
+		}
+		status.details_.clear();
+		for (uint32_t i = 0; i < count; i++) {
+			StatusDetail d;
+			err = deserialize(d.type, reader);
+			if (err) {
+				return err;
+			}
+			err = deserialize(d.data, reader);
+			if (err) {
+				return err;
+			}
+			status.details_.push_back(std::move(d));
+		}
+		return nullptr;
+	}
+
+private:
+
+	Code code_ = Code::OK;
+	std::string message_;
+	std::vector<StatusDetail> details_;
+};
+
+}
+}
@@ -17,6 +17,7 @@
 #include "scg/const.h"
 #include "scg/context.h"
 #include "scg/transport.h"
+#include "scg/status.h"
 
 namespace scg {
 namespace rpc {
@@ -58,12 +59,14 @@ inline std::vector<uint8_t> serializeStreamOpen(const context::Context& ctx, uin
 {
 	using scg::serialize::bit_size;
 
+	uint64_t timeout = ctx.timeout();
 	serialize::Writer writer(
 		scg::serialize::bits_to_bytes(
 			bit_size(STREAM_PREFIX) +
 			bit_size(streamID) +
 			bit_size(STREAM_FRAME_OPEN) +
 			bit_size(ctx) +
+			bit_size(timeout) +
 			bit_size(serviceID) +
 			bit_size(methodID)));
 
@@ -71,6 +74,7 @@ inline std::vector<uint8_t> serializeStreamOpen(const context::Context& ctx, uin
 	writer.write(streamID);
 	writer.write(STREAM_FRAME_OPEN);
 	writer.write(ctx);
+	writer.write(timeout);
 	writer.write(serviceID);
 	writer.write(methodID);
 	return writer.bytes();
@@ -95,8 +99,8 @@ inline std::vector<uint8_t> serializeStreamMessage(uint64_t streamID, const T& m
 	return writer.bytes();
 }
 
-// serializeStreamControl builds a connection-level keepalive frame (PING/PONG).
-// The stream id is unused (0).
+// serializeStreamControl builds a connection-level control frame (PING, PONG or
+// GOAWAY). The stream id is unused (0).
 inline std::vector<uint8_t> serializeStreamControl(uint8_t frameKind)
 {
 	using scg::serialize::bit_size;
@@ -130,7 +134,9 @@ inline std::vector<uint8_t> serializeStreamHalfClose(uint64_t streamID)
 	return writer.bytes();
 }
 
-inline std::vector<uint8_t> serializeStreamClose(uint64_t streamID, uint8_t status, const std::string& message)
+// serializeStreamClose builds the terminal CLOSE frame of a stream. An OK status
+// closes the stream cleanly.
+inline std::vector<uint8_t> serializeStreamClose(uint64_t streamID, const Status& status)
 {
 	using scg::serialize::bit_size;
 
@@ -139,14 +145,12 @@ inline std::vector<uint8_t> serializeStreamClose(uint64_t streamID, uint8_t stat
 			bit_size(STREAM_PREFIX) +
 			bit_size(streamID) +
 			bit_size(STREAM_FRAME_CLOSE) +
-			bit_size(status) +
-			bit_size(message)));
+			bit_size(status)));
 
 	writer.write(STREAM_PREFIX);
 	writer.write(streamID);
 	writer.write(STREAM_FRAME_CLOSE);
 	writer.write(status);
-	writer.write(message);
 	return writer.bytes();
 }
 
@@ -175,7 +179,7 @@ public:
 		if (queue_.size() >= bufferSize_) {
 			dead_ = true;
 			recvClosed_ = true;
-			recvErr_ = error::Error("stream receive buffer overflow");
+			recvErr_ = Status(Code::RESOURCE_EXHAUSTED, "stream receive buffer overflow").toError();
 			cv_.notify_all();
 			return true;
 		}
@@ -320,8 +324,9 @@ public:
 		}
 		// Notify the server first (while the connection is up), then fail the
 		// local stream so a blocked recv() returns.
-		auto err = sendFn_(serializeStreamClose(streamID_, STREAM_STATUS_ERROR, "stream cancelled by client"));
-		queue_.die(error::Error("stream cancelled by client"));
+		Status status(Code::CANCELED, "stream cancelled by client");
+		auto err = sendFn_(serializeStreamClose(streamID_, status));
+		queue_.die(status.toError());
 		return err;
 	}
 
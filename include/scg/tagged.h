@@ -0,0 +1,164 @@
+#pragma once
+
+#include <cstdint>
+
+#include "scg/pack.h"
+#include "scg/error.h"
+#include "scg/serialize.h"
+
+namespace scg {
+namespace serialize {
+
+// WireKind describes how the payload of a tagged field is laid out on the wire.
+// It carries just enough information for a decoder that does not recognize the
+// field index to skip over the payload, which is what makes tagged messages
+// forward and backward compatible. Must match pkg/serialize/tagged.go.
+enum class WireKind : uint8_t {
+	BIT = 0,         // a single bit (bool)
+	BYTE = 1,        // 8 bits (byte, uint8, int8)
+	VAR_UINT16 = 2,  // varint capped at 2 bytes
+	VAR_UINT32 = 3,  // varint capped at 4 bytes
+	VAR_UINT64 = 4,  // varint capped at 8 bytes
+	VAR_INT16 = 5,   // sign bit followed by a varint capped at 2 bytes
+	VAR_INT32 = 6,   // sign bit followed by a varint capped at 4 bytes
+	VAR_INT64 = 7,   // sign bit followed by a varint capped at 8 bytes
+	FIXED32 = 8,     // 32 bits (float32)
+	FIXED64 = 9,     // 64 bits (float64)
+	FIXED128 = 10,   // 128 bits (uuid)
+	DELIMITED = 11,  // bit length prefix followed by the payload
+};
+
+constexpr uint32_t WIRE_KIND_NUM_BITS = 4;
+
+inline constexpr uint32_t bit_size_field_tag(uint32_t index)
+{
+	return var_uint_bit_size(index, 4) + WIRE_KIND_NUM_BITS;
+}
+
+template <typename WriterType>
+inline void serialize_field_tag(WriterType& writer, uint32_t index, WireKind kind)
+{
+	var_encode_uint(writer, index, 4);
+	writer.writeBits(static_cast<uint8_t>(kind), WIRE_KIND_NUM_BITS);
+}
+
+template <typename ReaderType>
+inline error::Error deserialize_field_tag(uint32_t& index, WireKind& kind, ReaderType& reader)
+{
+	uint64_t val = 0;
+	auto err = var_decode_uint(val, reader, 4);
+	if (err) {
+		return err;
+	}
+	index = static_cast<uint32_t>(val);
+
+	uint8_t k = 0;
+	err = reader.readBits(k, WIRE_KIND_NUM_BITS);
+	if (err) {
+		return err;
+	}
+	if (k > static_cast<uint8_t>(WireKind::DELIMITED)) {
+		return error::Error::Errorf("field %u has unrecognized wire kind %u", index, k);
+	}
+	kind = static_cast<WireKind>(k);
+	return nullptr;
+}
+
+// check_wire_kind validates that a recognized field index arrived with the wire
+// kind the decoder expects. A mismatch means the field changed type between
+// schema versions, which is not a compatible change.
+inline error::Error check_wire_kind(uint32_t index, WireKind got, WireKind expected)
+{
+	if (got != expected) {
+		return error::Error::Errorf("field %u has wire kind %u, expected %u", index, static_cast<uint32_t>(got), static_cast<uint32_t>(expected));
+	}
+	return nullptr;
+}
+
+// bit_size_delimited returns the size of the length prefix for a delimited
+// payload of the provided number of bits. It does not include the payload.
+inline constexpr uint32_t bit_size_delimited(uint32_t num_bits)
+{
+	return var_uint_bit_size(num_bits, 8);
+}
+
+template <typename WriterType>
+inline void serialize_delimited(WriterType& writer, uint32_t num_bits)
+{
+	var_encode_uint(writer, num_bits, 8);
+}
+
+// deserialize_delimited reads the length prefix of a delimited payload and
+// returns the reader bit position at which the payload ends. The declared length
+// is bounded against the data actually present.
+template <typename ReaderType>
+inline error::Error deserialize_delimited(uint32_t& end, ReaderType& reader)
+{
+	uint64_t num_bits = 0;
+	auto err = var_decode_uint(num_bits, reader, 8);
+	if (err) {
+		return err;
+	}
+	if (num_bits > reader.remainingBits()) {
+		return error::Error::Errorf("declared length of %llu bits exceeds %llu remaining bits",
+			static_cast<unsigned long long>(num_bits), static_cast<unsigned long long>(reader.remainingBits()));
+	}
+	end = reader.numBitsRead() + static_cast<uint32_t>(num_bits);
+	return nullptr;
+}
+
+// finish_delimited advances the reader to the end of a delimited payload. A
+// decoder for an older schema may legitimately consume less than the payload
+// holds (a nested message gained fields), but never more.
+template <typename ReaderType>
+inline error::Error finish_delimited(ReaderType& reader, uint32_t end)
+{
+	if (reader.numBitsRead() > end) {
+		return error::Error::Errorf("delimited payload overrun by %u bits", reader.numBitsRead() - end);
+	}
+	return reader.skipBits(end - reader.numBitsRead());
+}
+
+// skip_field consumes the payload of a field the decoder does not recognize.
+template <typename ReaderType>
+inline error::Error skip_field(ReaderType& reader, WireKind kind)
+{
+	uint64_t u = 0;
+	int64_t i = 0;
+	switch (kind) {
+	case WireKind::BIT:
+		return reader.skipBits(1);
+	case WireKind::BYTE:
+		return reader.skipBits(8);
+	case WireKind::VAR_UINT16:
+		return var_decode_uint(u, reader, 2);
+	case WireKind::VAR_UINT32:
+		return var_decode_uint(u, reader, 4);
+	case WireKind::VAR_UINT64:
+		return var_decode_uint(u, reader, 8);
+	case WireKind::VAR_INT16:
+		return var_decode_int(i, reader, 2);
+	case WireKind::VAR_INT32:
+		return var_decode_int(i, reader, 4);
+	case WireKind::VAR_INT64:
+		return var_decode_int(i, reader, 8);
+	case WireKind::FIXED32:
+		return reader.skipBits(32);
+	case WireKind::FIXED64:
+		return reader.skipBits(64);
+	case WireKind::FIXED128:
+		return reader.skipBits(128);
+	case WireKind::DELIMITED: {
+		uint32_t end = 0;
+		auto err = deserialize_delimited(end, reader);
+		if (err) {
+			return err;
+		}
+		return finish_delimited(reader, end);
+	}
+	}
+	return error::Error::Errorf("unrecognized wire kind %u", static_cast<uint32_t>(kind));
+}
+
+}
+}
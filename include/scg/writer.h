@@ -35,6 +35,19 @@ public:
 		serialize(*this, data);
 	}
 
+	// setDeterministic enables the deterministic mode, in which the elements of
+	// unordered maps and sets are written in ascending order, so that equal
+	// messages always encode to the same bytes, matching the Go encoding.
+	void setDeterministic(bool deterministic)
+	{
+		deterministic_ = deterministic;
+	}
+
+	bool deterministic() const
+	{
+		return deterministic_;
+	}
+
 	void clear()
 	{
 		numBitsWritten_ = 0;
@@ -148,6 +161,7 @@ private:
 
 	std::vector<uint8_t> bytes_;
 	uint32_t numBitsWritten_ = 0;
+	bool deterministic_ = false;
 };
 
 class WriterView {
@@ -165,6 +179,19 @@ public:
 		serialize(*this, data);
 	}
 
+	// setDeterministic enables the deterministic mode, in which the elements of
+	// unordered maps and sets are written in ascending order, so that equal
+	// messages always encode to the same bytes, matching the Go encoding.
+	void setDeterministic(bool deterministic)
+	{
+		deterministic_ = deterministic;
+	}
+
+	bool deterministic() const
+	{
+		return deterministic_;
+	}
+
 	void writeBits(uint8_t val, uint32_t num_bits_to_write)
 	{
 		if (num_bits_to_write == 0) {
@@ -272,6 +299,7 @@ private:
 
 	std::vector<uint8_t>& bytes_;
 	uint32_t numBitsWritten_ = 0;
+	bool deterministic_ = false;
 };
 
 }
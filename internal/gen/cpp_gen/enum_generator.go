@@ -14,18 +14,22 @@ type EnumValueArgs struct {
 	ValueNameUpperCase string
 	ValueString        string
 	Index              int
+	Deprecated         bool
 }
 
 type EnumArgs struct {
 	EnumNamePascalCase string
 	EnumNameUpperCase  string
 	EnumValueArgs      []EnumValueArgs
+	Deprecated         bool
 }
 
-const enumTemplateStr = `
-enum class {{.EnumNamePascalCase}} {
-{{- range .EnumValueArgs}}
-	{{.ValueNameUpperCase}} = {{.Index}},{{end}}
+const enumTemplateStr = `{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
+enum class {{if .Deprecated}}[[deprecated]] {{end}}{{.EnumNamePascalCase}} {
+{{- range .EnumValueArgs}}{{if .Deprecated}}
+	// Deprecated: Marked as deprecated in the schema.{{end}}
+	{{.ValueNameUpperCase}}{{if .Deprecated}} [[deprecated]]{{end}} = {{.Index}},{{end}}
 };
 
 {{- range .EnumValueArgs}}
@@ -62,6 +66,7 @@ func generateEnumCppCode(enum *parse.EnumDefinition) (string, error) {
 			ValueNameUpperCase: strings.ToUpper(util.EnsureSnakeCase(v.Name)),
 			ValueString:        v.Value,
 			Index:              i,
+			Deprecated:         v.Options.Bool(parse.OptionDeprecated),
 		})
 	}
 
@@ -69,6 +74,7 @@ func generateEnumCppCode(enum *parse.EnumDefinition) (string, error) {
 		EnumNamePascalCase: util.EnsurePascalCase(enum.Name),
 		EnumNameUpperCase:  strings.ToUpper(util.EnsureSnakeCase(enum.Name)),
 		EnumValueArgs:      enumValueArgs,
+		Deprecated:         enum.Options.Bool(parse.OptionDeprecated),
 	}
 
 	buf := &bytes.Buffer{}
@@ -2,10 +2,12 @@ package cpp_gen
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"text/template"
 
 	"github.com/kbirk/scg/internal/parse"
+	"github.com/kbirk/scg/internal/util"
 )
 
 type FileArgs struct {
@@ -15,14 +17,26 @@ type FileArgs struct {
 	Enums      string
 	Typedefs   string
 	Consts     string
+	Structs    string
 	Messages   string
 	Servers    string
 	Clients    string
 }
 
+// the declarations marked as deprecated are used by the generated code itself,
+// so their warnings are silenced within the file and raised only where they are
+// used outside of it.
 const fileTemplateStr = `
 {{.Header}}
-{{.Imports}}{{ range .Namespaces }}
+{{.Imports}}
+#if defined(_MSC_VER)
+#pragma warning(push)
+#pragma warning(disable : 4996)
+#else
+#pragma GCC diagnostic push
+#pragma GCC diagnostic ignored "-Wdeprecated-declarations"
+#endif
+{{ range .Namespaces }}
 namespace {{.}} { {{end}}
 
 // Import float type aliases from scg::serialize namespace
@@ -32,10 +46,17 @@ using scg::serialize::float64_t;
 {{.Enums}}
 {{.Typedefs}}
 {{.Consts}}
+{{.Structs}}
 {{.Messages}}
 {{.Servers}}
 {{.Clients}}{{ range .Namespaces }}
 } {{end}}
+
+#if defined(_MSC_VER)
+#pragma warning(pop)
+#else
+#pragma GCC diagnostic pop
+#endif
 `
 
 var (
@@ -83,8 +104,18 @@ func generateFileCppCode(baseDir string, pkg *parse.Package, file *parse.File) (
 		constsCode = append(constsCode, consts)
 	}
 
+	var structForwardDeclarations []string
+	var structCode []string
 	var messageCode []string
 	for _, msg := range file.MessagesSortedByDependenciesAndKeys() {
+		if msg.IsRecursive() || msg.IsNested() {
+			structForwardDeclarations = append(structForwardDeclarations, fmt.Sprintf("struct %s;", util.EnsurePascalCase(msg.Name)))
+		}
+		declaration, err := generateMessageDeclarationCppCode(msg)
+		if err != nil {
+			return "", err
+		}
+		structCode = append(structCode, declaration)
 		message, err := generateMessageCppCode(msg)
 		if err != nil {
 			return "", err
@@ -117,6 +148,7 @@ func generateFileCppCode(baseDir string, pkg *parse.Package, file *parse.File) (
 		Enums:      strings.Join(enumCode, "\n"),
 		Typedefs:   strings.Join(typedefCode, "\n"),
 		Consts:     strings.Join(constsCode, "\n"),
+		Structs:    strings.Join(append(structForwardDeclarations, structCode...), "\n"),
 		Messages:   strings.Join(messageCode, "\n"),
 		Servers:    strings.Join(serverCode, "\n"),
 		Clients:    strings.Join(clientCode, "\n"),
@@ -27,6 +27,9 @@ var (
 		"scg/reader.h",
 		"scg/writer.h",
 		"scg/timestamp.h",
+		"scg/duration.h",
+		"scg/int_n.h",
+		"scg/quant.h",
 		"scg/uuid.h",
 		"nlohmann/json.hpp",
 	}
@@ -34,10 +37,17 @@ var (
 		"scg/typedef.h",
 		"scg/message.h",
 		"scg/serialize.h",
+		"scg/bytes.h",
+		"scg/tagged.h",
 		"scg/reader.h",
 		"scg/writer.h",
 		"scg/timestamp.h",
+		"scg/duration.h",
+		"scg/int_n.h",
+		"scg/quant.h",
 		"scg/uuid.h",
+		"scg/delta.h",
+		"scg/hash.h",
 		"nlohmann/json.hpp",
 	}
 	serviceIncludes = []string{
@@ -3,6 +3,8 @@ package cpp_gen
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -10,25 +12,95 @@ import (
 	"github.com/kbirk/scg/internal/util"
 )
 
+type OneofMemberArgs struct {
+	FieldNameCamelCase string
+	FieldJSONName      string
+	FieldType          string
+	Discriminator      int
+}
+
+// MessageFieldArgs describes a member of the generated struct, or for tagged
+// messages a field on the wire. Fields with a condition (optional fields and
+// oneof members) are only written when it holds, and the prelude prepares the
+// field to receive the value when it is read.
 type MessageFieldArgs struct {
 	FieldNameCamelCase string
+	FieldJSONName      string
+	FieldDeprecated    bool
 	FieldType          string
+	FieldElementType   string
 	FieldDefaultValue  string
+	FieldIndex         uint32
+	FieldWireKind      string
+	FieldOptional      bool
+	FieldPointer       bool
+	FieldOneofMembers  []OneofMemberArgs
+	FieldValue         string
+	FieldCondition     string
+	FieldPrelude       string
+}
+
+// NestedTypeArgs aliases a declaration nested within a message, which is
+// generated at namespace scope, as a member type of the enclosing struct.
+type NestedTypeArgs struct {
+	Alias string
+	Type  string
+}
+
+// MessageConstArgs describes a constant declared within the generated struct,
+// such as the precision of a quant field.
+type MessageConstArgs struct {
+	Name    string
+	Type    string
+	Value   string
+	Comment string
 }
 
 type MessageArgs struct {
 	MessageNamePascalCase       string
+	MessageFullName             string
+	Deprecated                  bool
+	NestedTypes                 []NestedTypeArgs
+	MessageConsts               []MessageConstArgs
 	MessageFields               []MessageFieldArgs
 	MessageFieldsCommaSeparated string
+	Tagged                      bool
+	TaggedFields                []MessageFieldArgs
+	NumRequiredFields           int
+	CountConditions             []string
+	HasCustomJSON               bool
+	Recursive                   bool
+	HasPointerFields            bool
 }
 
-const messageTemplateStr = `
-struct {{.MessageNamePascalCase}} : scg::type::Message { {{- range .MessageFields}}
+// message structs are declared ahead of their definitions so that recursive
+// messages can refer to one another, along with the serialization functions of
+// recursive messages, which may be called before they are defined. Nested
+// declarations are generated at namespace scope and aliased within the struct.
+// Messages holding a recursive field on the heap declare copy operations that
+// copy it deeply, so that they remain copyable values like any other message.
+const messageDeclarationTemplateStr = `{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
+struct {{if .Deprecated}}[[deprecated]] {{end}}{{.MessageNamePascalCase}} : scg::type::Message { {{- range .NestedTypes}}
+	using {{.Alias}} = {{.Type}};{{end}}{{if .NestedTypes}}
+{{end}}{{- range .MessageConsts}}
+	// {{.Comment}}
+	static constexpr {{.Type}} {{.Name}} = {{.Value}};{{end}}{{if .MessageConsts}}
+{{end}}{{- range .MessageFields}}{{if .FieldDeprecated}}
+	// Deprecated: Marked as deprecated in the schema.{{end}}
 {{- if ne .FieldDefaultValue ""}}
-	{{.FieldType}} {{.FieldNameCamelCase}} = {{.FieldDefaultValue}};
+	{{if .FieldDeprecated}}[[deprecated]] {{end}}{{.FieldType}} {{.FieldNameCamelCase}} = {{.FieldDefaultValue}};
 {{- else}}
-	{{.FieldType}} {{.FieldNameCamelCase}};
+	{{if .FieldDeprecated}}[[deprecated]] {{end}}{{.FieldType}} {{.FieldNameCamelCase}};
 {{- end}}{{- end}}
+{{- if .HasPointerFields}}
+
+	{{.MessageNamePascalCase}}() = default;
+	inline {{.MessageNamePascalCase}}(const {{.MessageNamePascalCase}}& other);
+	{{.MessageNamePascalCase}}({{.MessageNamePascalCase}}&& other) = default;
+	inline {{.MessageNamePascalCase}}& operator=(const {{.MessageNamePascalCase}}& other);
+	{{.MessageNamePascalCase}}& operator=({{.MessageNamePascalCase}}&& other) = default;
+{{- end}}
 
 	inline std::vector<uint8_t> toJSON() const;
 	inline void fromJSON(const std::vector<uint8_t>& data);
@@ -37,7 +109,82 @@ struct {{.MessageNamePascalCase}} : scg::type::Message { {{- range .MessageField
 	inline scg::error::Error fromBytes(const std::vector<uint8_t>& data);
 	inline scg::error::Error fromBytes(const uint8_t* data, uint32_t size);
 
-};{{if gt (len .MessageFields) 0}}
+	inline std::vector<uint8_t> toBytesDelta(const {{.MessageNamePascalCase}}& baseline) const;
+	inline scg::error::Error fromBytesDelta(const std::vector<uint8_t>& data);
+	inline scg::error::Error fromBytesDelta(const uint8_t* data, uint32_t size);
+
+	inline std::array<uint8_t, 32> sum256() const;
+	template <typename HasherType>
+	inline void hashTo(HasherType& hasher) const;
+
+	static inline const char* messageName();
+
+};{{if .Recursive}}
+
+inline void to_json(nlohmann::json& j, const {{.MessageNamePascalCase}}& m);
+inline void from_json(const nlohmann::json& j, {{.MessageNamePascalCase}}& m);
+template <typename WriterType>
+inline void serialize(WriterType& writer, const {{.MessageNamePascalCase}}& value);
+template <typename ReaderType>
+inline scg::error::Error deserialize({{.MessageNamePascalCase}}& value, ReaderType& reader);
+inline uint32_t bit_size(const {{.MessageNamePascalCase}}& value);
+inline bool operator==(const {{.MessageNamePascalCase}}& a, const {{.MessageNamePascalCase}}& b);
+template <typename WriterType>
+inline void serialize_delta(WriterType& writer, const {{.MessageNamePascalCase}}& value, const {{.MessageNamePascalCase}}& base);
+template <typename ReaderType>
+inline scg::error::Error deserialize_delta({{.MessageNamePascalCase}}& value, ReaderType& reader);
+inline uint32_t bit_size_delta(const {{.MessageNamePascalCase}}& value, const {{.MessageNamePascalCase}}& base);{{end}}
+`
+
+const messageTemplateStr = `
+{{- if .HasPointerFields}}
+inline {{.MessageNamePascalCase}}::{{.MessageNamePascalCase}}(const {{.MessageNamePascalCase}}& other)
+	: scg::type::Message(other){{range .MessageFields}}
+	, {{.FieldNameCamelCase}}({{if .FieldPointer}}other.{{.FieldNameCamelCase}} ? std::make_unique<{{.FieldElementType}}>(*other.{{.FieldNameCamelCase}}) : nullptr{{else}}other.{{.FieldNameCamelCase}}{{end}}){{end}}
+{
+}
+
+inline {{.MessageNamePascalCase}}& {{.MessageNamePascalCase}}::operator=(const {{.MessageNamePascalCase}}& other)
+{
+	if (this != &other) {
+		*this = {{.MessageNamePascalCase}}(other);
+	}
+	return *this;
+}
+
+{{end}}
+{{- if .HasCustomJSON}}
+inline void to_json(nlohmann::json& j, const {{.MessageNamePascalCase}}& m) {
+	j = nlohmann::json::object();{{range .MessageFields}}{{if .FieldOptional}}
+	if (m.{{.FieldNameCamelCase}}) {
+		j["{{.FieldJSONName}}"] = *m.{{.FieldNameCamelCase}};
+	}{{else if .FieldOneofMembers}}{{$field := .}}
+	switch (m.{{.FieldNameCamelCase}}.index()) { {{- range .FieldOneofMembers}}
+	case {{.Discriminator}}:
+		j["{{$field.FieldJSONName}}"]["{{.FieldJSONName}}"] = std::get<{{.Discriminator}}>(m.{{$field.FieldNameCamelCase}});
+		break;{{end}}
+	default:
+		break;
+	}{{else}}
+	j["{{.FieldJSONName}}"] = m.{{.FieldNameCamelCase}};{{end}}{{end}}
+}
+
+inline void from_json(const nlohmann::json& j, {{.MessageNamePascalCase}}& m) { {{- range .MessageFields}}{{if .FieldOptional}}
+	if (j.contains("{{.FieldJSONName}}") && !j.at("{{.FieldJSONName}}").is_null()) {
+		m.{{.FieldNameCamelCase}} = {{if .FieldPointer}}std::make_unique<{{.FieldElementType}}>(j.at("{{.FieldJSONName}}").get<{{.FieldElementType}}>()){{else}}j.at("{{.FieldJSONName}}").get<{{.FieldElementType}}>(){{end}};
+	} else {
+		m.{{.FieldNameCamelCase}}.reset();
+	}{{else if .FieldOneofMembers}}{{$field := .}}
+	m.{{.FieldNameCamelCase}} = std::monostate{};
+	if (j.contains("{{.FieldJSONName}}") && !j.at("{{.FieldJSONName}}").is_null()) {
+		const auto& o = j.at("{{.FieldJSONName}}"); {{- range $i, $member := .FieldOneofMembers}}
+		{{if $i}}} else {{end}}if (o.contains("{{.FieldJSONName}}")) {
+			m.{{$field.FieldNameCamelCase}}.emplace<{{.Discriminator}}>(o.at("{{.FieldJSONName}}").get<{{.FieldType}}>());{{end}}
+		}
+	}{{else}}
+	j.at("{{.FieldJSONName}}").get_to(m.{{.FieldNameCamelCase}});{{end}}{{end}}
+}
+{{else if gt (len .MessageFields) 0}}
 NLOHMANN_DEFINE_TYPE_NON_INTRUSIVE({{.MessageNamePascalCase}}, {{.MessageFieldsCommaSeparated}}){{else}}
 inline void to_json(nlohmann::json& j, const {{.MessageNamePascalCase}}& m) {
 	j = nlohmann::json::object();
@@ -47,7 +194,87 @@ inline void from_json(const nlohmann::json& j, {{.MessageNamePascalCase}}& m) {
 }
 {{end}}
 
-{{if gt (len .MessageFields) 0 }}
+{{if .Tagged }}
+
+template <typename WriterType>
+inline void serialize(WriterType& writer, const {{.MessageNamePascalCase}}& value)
+{
+	using scg::serialize::bit_size; // adl trickery
+	{{- template "fieldCount" .}}
+	scg::serialize::serialize(writer, numFields);{{range .TaggedFields}}{{if .FieldCondition}}
+	if ({{.FieldCondition}}) { {{- end}}
+	scg::serialize::serialize_field_tag(writer, {{.FieldIndex}}, scg::serialize::WireKind::{{.FieldWireKind}});{{if eq .FieldWireKind "DELIMITED"}}
+	scg::serialize::serialize_delimited(writer, bit_size({{.FieldValue}}));{{end}}
+	writer.write({{.FieldValue}});{{if .FieldCondition}}
+	}{{end}}{{end}}
+}
+
+template <typename ReaderType>
+inline scg::error::Error deserialize({{.MessageNamePascalCase}}& value, ReaderType& reader)
+{
+	{{- template "descend" .}}
+	// fields missing from the payload retain their default value
+	value = {{.MessageNamePascalCase}}{};
+
+	uint32_t numFields = 0;
+	auto err = scg::serialize::deserialize(numFields, reader);
+	if (err) {
+		return err;
+	}
+	for (uint32_t fieldNum = 0; fieldNum < numFields; fieldNum++) {
+		uint32_t fieldIndex = 0;
+		scg::serialize::WireKind wireKind;
+		err = scg::serialize::deserialize_field_tag(fieldIndex, wireKind, reader);
+		if (err) {
+			return err;
+		}
+		switch (fieldIndex) { {{- range .TaggedFields}}
+		case {{.FieldIndex}}: {
+			err = scg::serialize::check_wire_kind(fieldIndex, wireKind, scg::serialize::WireKind::{{.FieldWireKind}});
+			if (err) {
+				return err;
+			}{{if .FieldPrelude}}
+			{{.FieldPrelude}}{{end}}{{if eq .FieldWireKind "DELIMITED"}}
+			uint32_t end = 0;
+			err = scg::serialize::deserialize_delimited(end, reader);
+			if (err) {
+				return err;
+			}
+			err = reader.read({{.FieldValue}});
+			if (err) {
+				return err;
+			}
+			err = scg::serialize::finish_delimited(reader, end);{{else}}
+			err = reader.read({{.FieldValue}});{{end}}
+			break;
+		}{{end}}
+		default:
+			// unknown field written by a newer schema
+			err = scg::serialize::skip_field(reader, wireKind);
+			break;
+		}
+		if (err) {
+			return err;
+		}
+	}
+	return nullptr;
+}
+
+inline uint32_t bit_size(const {{.MessageNamePascalCase}}& value)
+{
+	using scg::serialize::bit_size; // adl trickery
+	{{- template "fieldCount" .}}
+	uint32_t size = scg::serialize::bit_size(numFields);{{- range .TaggedFields}}{{if .FieldCondition}}
+	if ({{.FieldCondition}}) { {{- end}}{{if eq .FieldWireKind "DELIMITED"}}
+	{
+		uint32_t numBits = bit_size({{.FieldValue}});
+		size += scg::serialize::bit_size_field_tag({{.FieldIndex}}) + scg::serialize::bit_size_delimited(numBits) + numBits;
+	}{{else}}
+	size += scg::serialize::bit_size_field_tag({{.FieldIndex}}) + bit_size({{.FieldValue}});{{end}}{{if .FieldCondition}}
+	}{{end}}{{end}}
+	return size;
+}
+{{else if gt (len .MessageFields) 0 }}
 
 template <typename WriterType>
 inline void serialize(WriterType& writer, const {{.MessageNamePascalCase}}& value)
@@ -59,6 +286,7 @@ inline void serialize(WriterType& writer, const {{.MessageNamePascalCase}}& valu
 template <typename ReaderType>
 inline scg::error::Error deserialize({{.MessageNamePascalCase}}& value, ReaderType& reader)
 {
+	{{- template "descend" .}}
 	scg::error::Error err;
 	{{range .MessageFields}}err = reader.read(value.{{.FieldNameCamelCase}});
 	if (err) {
@@ -74,11 +302,17 @@ inline uint32_t bit_size(const {{.MessageNamePascalCase}}& value)
 	size += bit_size(value.{{.FieldNameCamelCase}});{{end}}
 	return size;
 }
+{{end}}
+{{- template "delta" .}}
+{{if or (gt (len .MessageFields) 0) .Tagged }}
 
 std::vector<uint8_t> {{.MessageNamePascalCase}}::toJSON() const
-{
+{ {{- if .HasCustomJSON}}
+	nlohmann::json j;
+	to_json(j, *this);{{else if gt (len .MessageFields) 0}}
 	nlohmann::json j({ {{- range $index, $element := .MessageFields}}{{if $index}}, {{end}}
-		{"{{$element.FieldNameCamelCase}}", {{$element.FieldNameCamelCase}} }{{end}} });
+		{"{{$element.FieldNameCamelCase}}", {{$element.FieldNameCamelCase}} }{{end}} });{{else}}
+	nlohmann::json j = nlohmann::json::object();{{end}}
 	auto str = j.dump();
 	return std::vector<uint8_t>(str.begin(), str.end());
 }
@@ -86,8 +320,9 @@ std::vector<uint8_t> {{.MessageNamePascalCase}}::toJSON() const
 void {{.MessageNamePascalCase}}::fromJSON(const std::vector<uint8_t>& data)
 {
 	nlohmann::json j = nlohmann::json::parse(std::string(data.begin(), data.end()));
-	{{range .MessageFields}}j.at("{{.FieldNameCamelCase}}").get_to({{.FieldNameCamelCase}});
-	{{end}}
+	{{if .HasCustomJSON}}from_json(j, *this);
+	{{else}}{{range .MessageFields}}j.at("{{.FieldNameCamelCase}}").get_to(this->{{.FieldNameCamelCase}});
+	{{end}}{{end}}
 }
 
 std::vector<uint8_t> {{.MessageNamePascalCase}}::toBytes() const
@@ -156,10 +391,123 @@ scg::error::Error {{.MessageNamePascalCase}}::fromBytes(const uint8_t* data, uin
 	return nullptr;
 }
 {{end}}
+const char* {{.MessageNamePascalCase}}::messageName()
+{
+	return "{{.MessageFullName}}";
+}
+{{- template "hash" .}}
 `
 
+// absent optional fields and unset oneofs are omitted from a tagged payload, so
+// the number of fields written is only known at runtime when the message has any
+const messageTaggedFieldCountTemplateStr = `
+{{- define "fieldCount"}}
+	uint32_t numFields = {{.NumRequiredFields}};{{range .CountConditions}}
+	if ({{.}}) {
+		numFields++;
+	}{{end}}
+{{- end}}`
+
+// deltas are written field by field, in index order, for tagged and untagged
+// messages alike. A delta is read in place, into a message holding the
+// baseline.
+const messageDeltaTemplateStr = `
+{{- define "delta"}}
+
+inline bool operator==(const {{.MessageNamePascalCase}}& a, const {{.MessageNamePascalCase}}& b)
+{ {{- if .MessageFields}}
+	return {{range $index, $element := .MessageFields}}{{if $index}} &&
+		{{end}}scg::serialize::equal(a.{{.FieldNameCamelCase}}, b.{{.FieldNameCamelCase}}){{end}};{{else}}
+	return true;{{end}}
+}
+
+inline bool operator!=(const {{.MessageNamePascalCase}}& a, const {{.MessageNamePascalCase}}& b)
+{
+	return !(a == b);
+}
+
+template <typename WriterType>
+inline void serialize_delta(WriterType& writer, const {{.MessageNamePascalCase}}& value, const {{.MessageNamePascalCase}}& base)
+{ {{- range .MessageFields}}
+	scg::serialize::serialize_delta_field(writer, value.{{.FieldNameCamelCase}}, base.{{.FieldNameCamelCase}});{{end}}
+}
+
+template <typename ReaderType>
+inline scg::error::Error deserialize_delta({{.MessageNamePascalCase}}& value, ReaderType& reader)
+{ {{- if .MessageFields}}
+	{{- template "descend" .}}
+	scg::error::Error err;{{range .MessageFields}}
+	err = scg::serialize::deserialize_delta_field(value.{{.FieldNameCamelCase}}, reader);
+	if (err) {
+		return err;
+	}{{end}}{{end}}
+	return nullptr;
+}
+
+inline uint32_t bit_size_delta(const {{.MessageNamePascalCase}}& value, const {{.MessageNamePascalCase}}& base)
+{
+	uint32_t size = 0;{{- range .MessageFields}}
+	size += scg::serialize::bit_size_delta_field(value.{{.FieldNameCamelCase}}, base.{{.FieldNameCamelCase}});{{end}}
+	return size;
+}
+
+std::vector<uint8_t> {{.MessageNamePascalCase}}::toBytesDelta(const {{.MessageNamePascalCase}}& baseline) const
+{ {{- if not .MessageFields}}
+	return std::vector<uint8_t>();{{else}}
+	std::vector<uint8_t> data;
+	data.resize(scg::serialize::bits_to_bytes(bit_size_delta(*this, baseline)), 0);
+	scg::serialize::WriterView writer(data);
+	serialize_delta(writer, *this, baseline);
+	return data;{{end}}
+}
+
+scg::error::Error {{.MessageNamePascalCase}}::fromBytesDelta(const std::vector<uint8_t>& data)
+{
+	scg::serialize::ReaderView reader(data);
+	return deserialize_delta(*this, reader);
+}
+
+scg::error::Error {{.MessageNamePascalCase}}::fromBytesDelta(const uint8_t* data, uint32_t size)
+{
+	scg::serialize::ReaderView reader(data, size);
+	return deserialize_delta(*this, reader);
+}
+{{- end}}`
+
+// messages are hashed over their deterministic encoding, streamed into the
+// hasher rather than encoded into a buffer, so that the digest matches Go.
+const messageHashTemplateStr = `
+{{- define "hash"}}
+
+std::array<uint8_t, 32> {{.MessageNamePascalCase}}::sum256() const
+{
+	scg::hash::Sha256 hasher;
+	hashTo(hasher);
+	return hasher.sum();
+}
+
+template <typename HasherType>
+void {{.MessageNamePascalCase}}::hashTo(HasherType& hasher) const
+{
+	scg::serialize::HashWriter<HasherType> writer(hasher);
+	serialize(writer, *this);
+	writer.flush();
+}
+{{- end}}`
+
+// bounds the nesting of recursive messages
+const messageDescendTemplateStr = `
+{{- define "descend"}}{{if .Recursive}}
+	auto depthErr = reader.descend();
+	if (depthErr) {
+		return depthErr;
+	}
+	scg::serialize::DepthGuard<ReaderType> depthGuard(reader);
+{{end}}{{end}}`
+
 var (
-	messageTemplate = template.Must(template.New("messageTemplateCpp").Parse(messageTemplateStr))
+	messageDeclarationTemplate = template.Must(template.New("messageDeclarationTemplateCpp").Parse(messageDeclarationTemplateStr))
+	messageTemplate            = template.Must(template.New("messageTemplateCpp").Parse(messageTemplateStr + messageTaggedFieldCountTemplateStr + messageDeltaTemplateStr + messageHashTemplateStr + messageDescendTemplateStr))
 )
 
 func convertPackageNameToCppNamespaces(name string) []string {
@@ -236,8 +584,12 @@ func mapDataTypeToCppType(dataType parse.DataType) (string, error) {
 		return "int64_t", nil
 	case parse.DataTypeString:
 		return "std::string", nil
+	case parse.DataTypeBytes:
+		return "scg::type::bytes", nil
 	case parse.DataTypeTimestamp:
 		return "scg::type::timestamp", nil
+	case parse.DataTypeDuration:
+		return "std::chrono::nanoseconds", nil
 	case parse.DataTypeUUID:
 		return "scg::type::uuid", nil
 	case parse.DataTypeFloat32:
@@ -268,6 +620,8 @@ func mapDataTypeToCppDefaultValue(dataType parse.DataType) (string, error) {
 		return "0.0f", nil
 	case parse.DataTypeFloat64:
 		return "0.0", nil
+	case parse.DataTypeDuration:
+		return "std::chrono::nanoseconds(0)", nil
 	}
 
 	return "", fmt.Errorf("unrecognized type: %v", dataType)
@@ -293,6 +647,37 @@ func mapDataTypeDefinitionToCppType(dataType *parse.DataTypeDefinition) (string,
 			return "", err
 		}
 		return fmt.Sprintf("std::vector<%s>", subtype), nil
+	case parse.DataTypeArray:
+
+		subtype, err := mapDataTypeDefinitionToCppType(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("std::array<%s, %d>", subtype, dataType.Length), nil
+	case parse.DataTypeSet:
+		key, err := mapDataTypeComparableDefinitionToCppType(dataType.Key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("std::unordered_set<%s>", key), nil
+	case parse.DataTypeUIntN:
+		return fmt.Sprintf("scg::type::uint_n<%d>", dataType.Bits), nil
+	case parse.DataTypeIntN:
+		return fmt.Sprintf("scg::type::int_n<%d>", dataType.Bits), nil
+	case parse.DataTypeQuant:
+		subtype, err := mapDataTypeToCppType(dataType.SubType.Type)
+		if err != nil {
+			return "", err
+		}
+		minNum, minDen, err := mapQuantBoundToRatio(dataType.QuantMin)
+		if err != nil {
+			return "", err
+		}
+		maxNum, maxDen, err := mapQuantBoundToRatio(dataType.QuantMax)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("scg::type::quant<%s, %d, %d, %d, %d, %d>", subtype, minNum, minDen, maxNum, maxDen, dataType.Bits), nil
 	case parse.DataTypeCustom:
 		if dataType.ImportedFromOtherPackage {
 			return fmt.Sprintf("%s::%s", convertPackageNameToCppNamespacePrefix(dataType.CustomTypePackage), util.EnsurePascalCase(dataType.CustomType)), nil
@@ -303,52 +688,288 @@ func mapDataTypeDefinitionToCppType(dataType *parse.DataTypeDefinition) (string,
 	return mapDataTypeToCppType(dataType.Type)
 }
 
+// mapQuantBoundToRatio returns a quant bound as a ratio of integers, which are
+// used as template parameters as C++17 does not allow floating point ones. Both
+// must be exactly representable as doubles for the division to round to the
+// same value as the bound.
+func mapQuantBoundToRatio(bound float64) (int64, int64, error) {
+	literal := strconv.FormatFloat(bound, 'g', -1, 64)
+	ratio, ok := new(big.Rat).SetString(literal)
+	limit := big.NewInt(1 << 53)
+	if !ok || new(big.Int).Abs(ratio.Num()).Cmp(limit) > 0 || ratio.Denom().Cmp(limit) > 0 {
+		return 0, 0, fmt.Errorf("quant bound %s cannot be represented as a ratio of integers", literal)
+	}
+	return ratio.Num().Int64(), ratio.Denom().Int64(), nil
+}
+
 func mapDataTypeDefinitionToDefaultValue(dataType *parse.DataTypeDefinition) (string, error) {
 
 	switch dataType.Type {
 	case parse.DataTypeMap,
 		parse.DataTypeList,
+		parse.DataTypeSet,
 		parse.DataTypeCustom,
 		parse.DataTypeString,
+		parse.DataTypeBytes,
 		parse.DataTypeTimestamp,
+		parse.DataTypeUIntN,
+		parse.DataTypeIntN,
+		parse.DataTypeQuant,
 		parse.DataTypeUUID:
 		return "", nil
+	case parse.DataTypeArray:
+		// value-initialize the elements
+		return "{}", nil
 	}
 
 	return mapDataTypeToCppDefaultValue(dataType.Type)
 }
 
+func mapDataTypeDefinitionToWireKind(dataType *parse.DataTypeDefinition) (string, error) {
+	switch dataType.Type {
+	case parse.DataTypeBool:
+		return "BIT", nil
+	case parse.DataTypeByte,
+		parse.DataTypeUInt8,
+		parse.DataTypeInt8:
+		return "BYTE", nil
+	case parse.DataTypeUInt16:
+		return "VAR_UINT16", nil
+	case parse.DataTypeUInt32:
+		return "VAR_UINT32", nil
+	case parse.DataTypeUInt64:
+		return "VAR_UINT64", nil
+	case parse.DataTypeInt16:
+		return "VAR_INT16", nil
+	case parse.DataTypeInt32:
+		return "VAR_INT32", nil
+	case parse.DataTypeInt64,
+		parse.DataTypeDuration:
+		return "VAR_INT64", nil
+	case parse.DataTypeFloat32:
+		return "FIXED32", nil
+	case parse.DataTypeFloat64:
+		return "FIXED64", nil
+	case parse.DataTypeUUID:
+		return "FIXED128", nil
+	case parse.DataTypeString,
+		parse.DataTypeBytes,
+		parse.DataTypeTimestamp,
+		parse.DataTypeUIntN,
+		parse.DataTypeIntN,
+		parse.DataTypeQuant,
+		parse.DataTypeMap,
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet,
+		parse.DataTypeCustom:
+		return "DELIMITED", nil
+	}
+	return "", fmt.Errorf("unrecognized type: %v", dataType.Type)
+}
+
 func getMessageFieldArg(field *parse.MessageFieldDefinition) (MessageFieldArgs, error) {
 	cppType, err := mapDataTypeDefinitionToCppType(field.DataTypeDefinition)
 	if err != nil {
 		return MessageFieldArgs{}, err
 	}
 	defaultValue, err := mapDataTypeDefinitionToDefaultValue(field.DataTypeDefinition)
+	if err != nil {
+		return MessageFieldArgs{}, err
+	}
+	wireKind, err := mapDataTypeDefinitionToWireKind(field.DataTypeDefinition)
+	if err != nil {
+		return MessageFieldArgs{}, err
+	}
+	jsonName := getFieldJSONName(field)
+	deprecated := field.Options.Bool(parse.OptionDeprecated)
+	if field.Optional && field.Recursive {
+		// a message cannot hold an optional of itself, so recursion goes through the heap
+		return MessageFieldArgs{
+			FieldNameCamelCase: util.EnsureCamelCase(field.Name),
+			FieldJSONName:      jsonName,
+			FieldDeprecated:    deprecated,
+			FieldType:          fmt.Sprintf("std::unique_ptr<%s>", cppType),
+			FieldElementType:   cppType,
+			FieldIndex:         field.Index,
+			FieldWireKind:      wireKind,
+			FieldOptional:      true,
+			FieldPointer:       true,
+			FieldValue:         fmt.Sprintf("*value.%s", util.EnsureCamelCase(field.Name)),
+			FieldCondition:     fmt.Sprintf("value.%s", util.EnsureCamelCase(field.Name)),
+			FieldPrelude:       fmt.Sprintf("value.%s = std::make_unique<%s>();", util.EnsureCamelCase(field.Name), cppType),
+		}, nil
+	}
+	if field.Optional {
+		return MessageFieldArgs{
+			FieldNameCamelCase: util.EnsureCamelCase(field.Name),
+			FieldJSONName:      jsonName,
+			FieldDeprecated:    deprecated,
+			FieldType:          fmt.Sprintf("std::optional<%s>", cppType),
+			FieldElementType:   cppType,
+			FieldIndex:         field.Index,
+			FieldWireKind:      wireKind,
+			FieldOptional:      true,
+			FieldValue:         fmt.Sprintf("*value.%s", util.EnsureCamelCase(field.Name)),
+			FieldCondition:     fmt.Sprintf("value.%s", util.EnsureCamelCase(field.Name)),
+			FieldPrelude:       fmt.Sprintf("value.%s.emplace();", util.EnsureCamelCase(field.Name)),
+		}, nil
+	}
 	return MessageFieldArgs{
 		FieldNameCamelCase: util.EnsureCamelCase(field.Name),
+		FieldJSONName:      jsonName,
+		FieldDeprecated:    deprecated,
 		FieldType:          cppType,
+		FieldElementType:   cppType,
 		FieldDefaultValue:  defaultValue,
+		FieldIndex:         field.Index,
+		FieldWireKind:      wireKind,
+		FieldValue:         fmt.Sprintf("value.%s", util.EnsureCamelCase(field.Name)),
 	}, nil
 }
 
-func generateMessageCppCode(msg *parse.MessageDefinition) (string, error) {
+// getFieldJSONName returns the key of the field when serialized to JSON, which
+// defaults to the camel case member name unless overridden by `json_name`.
+func getFieldJSONName(field *parse.MessageFieldDefinition) string {
+	jsonName := field.Options.String(parse.OptionJSONName)
+	if jsonName != "" {
+		return jsonName
+	}
+	return util.EnsureCamelCase(field.Name)
+}
+
+// getOneofFieldArgs returns the struct member holding the oneof, along with
+// one tagged field per member. The oneof is held in a std::variant whose first
+// alternative, std::monostate, represents an unset oneof, so the alternative
+// index of each member is its 1-based position in index order.
+func getOneofFieldArgs(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) (MessageFieldArgs, []MessageFieldArgs, error) {
+	oneofName := util.EnsureCamelCase(oneof.Name)
+
+	oneofArg := MessageFieldArgs{
+		FieldNameCamelCase: oneofName,
+		FieldJSONName:      oneofName,
+	}
+	taggedArgs := []MessageFieldArgs{}
+
+	alternatives := []string{"std::monostate"}
+	for i, field := range msg.OneofFieldsByIndex(oneof.Name) {
+		fieldArg, err := getMessageFieldArg(field)
+		if err != nil {
+			return MessageFieldArgs{}, nil, err
+		}
+		discriminator := i + 1
+		alternatives = append(alternatives, fieldArg.FieldType)
+		oneofArg.FieldOneofMembers = append(oneofArg.FieldOneofMembers, OneofMemberArgs{
+			FieldNameCamelCase: fieldArg.FieldNameCamelCase,
+			FieldJSONName:      fieldArg.FieldJSONName,
+			FieldType:          fieldArg.FieldType,
+			Discriminator:      discriminator,
+		})
+
+		fieldArg.FieldValue = fmt.Sprintf("std::get<%d>(value.%s)", discriminator, oneofName)
+		fieldArg.FieldCondition = fmt.Sprintf("value.%s.index() == %d", oneofName, discriminator)
+		fieldArg.FieldPrelude = fmt.Sprintf("value.%s.emplace<%d>();", oneofName, discriminator)
+		taggedArgs = append(taggedArgs, fieldArg)
+	}
+	oneofArg.FieldType = fmt.Sprintf("std::variant<%s>", strings.Join(alternatives, ", "))
+
+	return oneofArg, taggedArgs, nil
+}
+
+func getMessageArgs(msg *parse.MessageDefinition) (MessageArgs, error) {
 	args := MessageArgs{
 		MessageNamePascalCase: util.EnsurePascalCase(msg.Name),
+		MessageFullName:       msg.FullName(),
 		MessageFields:         []MessageFieldArgs{},
+		Tagged:                msg.Tagged,
+		Recursive:             msg.IsRecursive(),
+		Deprecated:            msg.Options.Bool(parse.OptionDeprecated),
+	}
+	for _, nested := range msg.Nested {
+		args.NestedTypes = append(args.NestedTypes, NestedTypeArgs{
+			Alias: util.EnsurePascalCase(nested[strings.LastIndex(nested, ".")+1:]),
+			Type:  util.EnsurePascalCase(nested),
+		})
 	}
 	fields := []string{}
 	for _, field := range msg.FieldsByIndex() {
+		if field.Oneof != "" {
+			// the oneof as a whole is held at the position of its first member
+			if !msg.IsFirstOneofField(field) {
+				continue
+			}
+			oneofArg, taggedArgs, err := getOneofFieldArgs(msg, msg.Oneofs[field.Oneof])
+			if err != nil {
+				return MessageArgs{}, err
+			}
+			args.MessageFields = append(args.MessageFields, oneofArg)
+			args.TaggedFields = append(args.TaggedFields, taggedArgs...)
+			fields = append(fields, oneofArg.FieldNameCamelCase)
+			args.CountConditions = append(args.CountConditions, fmt.Sprintf("value.%s.index() != 0", oneofArg.FieldNameCamelCase))
+			args.HasCustomJSON = true
+			continue
+		}
 		fieldArg, err := getMessageFieldArg(field)
 		if err != nil {
-			return "", err
+			return MessageArgs{}, err
 		}
 		args.MessageFields = append(args.MessageFields, fieldArg)
+		args.TaggedFields = append(args.TaggedFields, fieldArg)
 		fields = append(fields, fieldArg.FieldNameCamelCase)
+		if fieldArg.FieldPointer {
+			args.HasPointerFields = true
+		}
+		if fieldArg.FieldOptional {
+			args.CountConditions = append(args.CountConditions, fieldArg.FieldCondition)
+			args.HasCustomJSON = true
+		} else {
+			args.NumRequiredFields++
+		}
+		if fieldArg.FieldJSONName != fieldArg.FieldNameCamelCase {
+			// the default macro keys fields by their member name
+			args.HasCustomJSON = true
+		}
 	}
 	args.MessageFieldsCommaSeparated = strings.Join(fields, ", ")
 
+	for _, field := range msg.FieldsByIndex() {
+		elementType := field.DataTypeDefinition.GetElementType()
+		if elementType.Type != parse.DataTypeQuant {
+			continue
+		}
+		name := fmt.Sprintf("%sPrecision", util.EnsureCamelCase(field.Name))
+		args.MessageConsts = append(args.MessageConsts, MessageConstArgs{
+			Name:    name,
+			Type:    "float64_t",
+			Value:   strconv.FormatFloat(elementType.QuantPrecision(), 'g', -1, 64),
+			Comment: fmt.Sprintf("the distance between two adjacent values of the %s field", field.Name),
+		})
+	}
+	return args, nil
+}
+
+func generateMessageDeclarationCppCode(msg *parse.MessageDefinition) (string, error) {
+	args, err := getMessageArgs(msg)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	err = messageDeclarationTemplate.Execute(buf, args)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func generateMessageCppCode(msg *parse.MessageDefinition) (string, error) {
+	args, err := getMessageArgs(msg)
+	if err != nil {
+		return "", err
+	}
+
 	buf := &bytes.Buffer{}
-	err := messageTemplate.Execute(buf, args)
+	err = messageTemplate.Execute(buf, args)
 	if err != nil {
 		return "", err
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kbirk/scg/internal/parse"
@@ -25,3 +26,276 @@ func TestGenerateMessagesCpp(t *testing.T) {
 
 	fmt.Println("done")
 }
+
+func TestGenerateMessageRecursiveCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Node",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Children": {
+				Name:      "Children",
+				Index:     0,
+				Recursive: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeList,
+					SubType: &parse.DataTypeDefinition{
+						Type:       parse.DataTypeCustom,
+						CustomType: "Node",
+					},
+				},
+			},
+			"Next": {
+				Name:      "Next",
+				Index:     1,
+				Optional:  true,
+				Recursive: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type:       parse.DataTypeCustom,
+					CustomType: "Node",
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "std::vector<Node> children;")
+	assert.Contains(t, declaration, "std::unique_ptr<Node> next;")
+	assert.Contains(t, declaration, "inline Node(const Node& other);")
+	assert.Contains(t, declaration, "inline Node& operator=(const Node& other);")
+	assert.Contains(t, declaration, "inline void to_json(nlohmann::json& j, const Node& m);")
+	assert.Contains(t, declaration, "inline uint32_t bit_size(const Node& value);")
+	assert.Contains(t, declaration, "inline bool operator==(const Node& a, const Node& b);")
+	assert.Contains(t, declaration, "inline uint32_t bit_size_delta(const Node& value, const Node& base);")
+
+	code, err := generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "auto depthErr = reader.descend();")
+	assert.Contains(t, code, ", next(other.next ? std::make_unique<Node>(*other.next) : nullptr)")
+	assert.Contains(t, code, "m.next = std::make_unique<Node>(j.at(\"next\").get<Node>());")
+
+	msg.Tagged = true
+
+	code, err = generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "value.next = std::make_unique<Node>();")
+	assert.Contains(t, code, "scg::serialize::DepthGuard<ReaderType> depthGuard(reader);")
+}
+
+func TestGenerateMessageDeltaCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Snapshot",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Tick": {
+				Name:  "Tick",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+			"Name": {
+				Name:     "Name",
+				Index:    1,
+				Optional: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "inline std::vector<uint8_t> toBytesDelta(const Snapshot& baseline) const;")
+	assert.Contains(t, declaration, "inline scg::error::Error fromBytesDelta(const std::vector<uint8_t>& data);")
+
+	code, err := generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "scg::serialize::equal(a.tick, b.tick) &&\n\t\tscg::serialize::equal(a.name, b.name);")
+	assert.Contains(t, code, "scg::serialize::serialize_delta_field(writer, value.name, base.name);")
+	assert.Contains(t, code, "err = scg::serialize::deserialize_delta_field(value.tick, reader);")
+	assert.Contains(t, code, "size += scg::serialize::bit_size_delta_field(value.tick, base.tick);")
+	assert.NotContains(t, code, "reader.descend()")
+
+	empty, err := generateMessageCppCode(&parse.MessageDefinition{
+		Name: "Empty",
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, empty, "inline bool operator==(const Empty& a, const Empty& b)\n{\n\treturn true;\n}")
+	assert.Contains(t, empty, "inline scg::error::Error deserialize_delta(Empty& value, ReaderType& reader)\n{\n\treturn nullptr;\n}")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageNestedCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name:   "Order",
+		Nested: []string{"Order.Status", "Order.Line"},
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Lines": {
+				Name:  "Lines",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeList,
+					SubType: &parse.DataTypeDefinition{
+						Type:       parse.DataTypeCustom,
+						CustomType: "Order.Line",
+					},
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "using Status = Order_Status;")
+	assert.Contains(t, declaration, "using Line = Order_Line;")
+	assert.Contains(t, declaration, "std::vector<Order_Line> lines;")
+}
+
+func TestGenerateMessageOptionsCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "User",
+		Options: parse.Options{
+			parse.OptionDeprecated: {Name: parse.OptionDeprecated, Value: "true"},
+		},
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"email_address": {
+				Name:  "email_address",
+				Index: 0,
+				Options: parse.Options{
+					parse.OptionJSONName:   {Name: parse.OptionJSONName, Value: "mail", Quoted: true},
+					parse.OptionDeprecated: {Name: parse.OptionDeprecated, Value: "true"},
+				},
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+			"display_name": {
+				Name:  "display_name",
+				Index: 1,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "// Deprecated: Marked as deprecated in the schema.\nstruct [[deprecated]] User")
+	assert.Contains(t, declaration, "// Deprecated: Marked as deprecated in the schema.\n\t[[deprecated]] std::string emailAddress")
+
+	code, err := generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	// a renamed field cannot use the default macro, which keys by member name
+	assert.NotContains(t, code, "NLOHMANN_DEFINE_TYPE_NON_INTRUSIVE")
+	assert.Contains(t, code, "j[\"mail\"] = m.emailAddress;")
+	assert.Contains(t, code, "j.at(\"mail\").get_to(m.emailAddress);")
+	assert.Contains(t, code, "j[\"displayName\"] = m.displayName;")
+}
+
+func TestGenerateMessageQuantCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Transform",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"positions": {
+				Name:  "positions",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeList,
+					SubType: &parse.DataTypeDefinition{
+						Type:     parse.DataTypeQuant,
+						SubType:  &parse.DataTypeDefinition{Type: parse.DataTypeFloat32},
+						QuantMin: -0.25,
+						QuantMax: 100,
+						Bits:     12,
+					},
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "std::vector<scg::type::quant<float32_t, -1, 4, 100, 1, 12>> positions;")
+	assert.Contains(t, declaration, "static constexpr float64_t positionsPrecision = 0.02448107448107448;")
+
+	// bounds must be exact ratios of integers
+	msg.Fields["positions"].DataTypeDefinition.SubType.QuantMin = 1e-30
+	_, err = generateMessageDeclarationCppCode(msg)
+	assert.NotNil(t, err)
+}
+
+func TestGenerateMessageHashCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Snapshot",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Tick": {
+				Name:  "Tick",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "inline std::array<uint8_t, 32> sum256() const;")
+	assert.Contains(t, declaration, "template <typename HasherType>\n\tinline void hashTo(HasherType& hasher) const;")
+
+	code, err := generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "std::array<uint8_t, 32> Snapshot::sum256() const")
+	assert.Contains(t, code, "scg::serialize::HashWriter<HasherType> writer(hasher);")
+
+	// empty messages hash the empty encoding
+	empty, err := generateMessageCppCode(&parse.MessageDefinition{
+		Name: "Empty",
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, empty, "void Empty::hashTo(HasherType& hasher) const")
+}
+
+func TestGenerateMessageNameCpp(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "NotFoundDetail",
+		File: &parse.File{
+			Package: &parse.PackageDeclaration{
+				Name: "users",
+			},
+		},
+	}
+
+	declaration, err := generateMessageDeclarationCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, declaration, "static inline const char* messageName();")
+
+	code, err := generateMessageCppCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "const char* NotFoundDetail::messageName()\n{\n\treturn \"users.NotFoundDetail\";\n}")
+}
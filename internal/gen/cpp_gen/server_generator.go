@@ -121,7 +121,7 @@ public:
 		{{.MethodRequestStructName}} req;
 		auto err = reader.read(req);
 		if (err) {
-			return scg::rpc::respondWithError(requestID, err);
+			return scg::rpc::respondWithError(requestID, scg::rpc::Status(scg::rpc::Code::INVALID_ARGUMENT, err.message()).toError());
 		}
 
 		auto handler = [this, &req](scg::context::Context& ctx, const scg::type::Message& r) -> std::pair<std::shared_ptr<scg::type::Message>, scg::error::Error> {
@@ -150,14 +150,14 @@ public:
 		uint64_t methodID = 0;
 		auto err = reader.read(methodID);
 		if (err) {
-			return scg::rpc::respondWithError(requestID, err);
+			return scg::rpc::respondWithError(requestID, scg::rpc::Status(scg::rpc::Code::INVALID_ARGUMENT, err.message()).toError());
 		}
 
 		switch (methodID) { {{- range .ServerMethods}}
 		case {{.MethodIDVarName}}:
 			return handle{{.MethodNamePascalCase}}(ctx, middleware, requestID, reader);{{end}}
 		default:
-			return scg::rpc::respondWithError(requestID, scg::error::Error("Unrecognized method ID: " + std::to_string(methodID)));
+			return scg::rpc::respondWithError(requestID, scg::rpc::Status(scg::rpc::Code::UNIMPLEMENTED, "Unrecognized method ID: " + std::to_string(methodID)).toError());
 		}
 	}
 
@@ -173,7 +173,7 @@ public:
 			{{.ReqStructName}} req;
 			auto derr = reader.read(req);
 			if (derr) {
-				return derr;
+				return scg::rpc::Status(scg::rpc::Code::INVALID_ARGUMENT, derr.message()).toError();
 			}
 			return impl_->{{.MethodNameCamelCase}}(req, std::make_shared<{{.StreamTypeName}}>(stream));{{else}}auto [resp, uerr] = impl_->{{.MethodNameCamelCase}}(std::make_shared<{{.StreamTypeName}}>(stream));
 			if (uerr) {
@@ -182,7 +182,7 @@ public:
 			return stream->send(resp);{{end}}
 		}{{end}}
 		default:
-			return scg::error::Error("Unrecognized stream method ID: " + std::to_string(methodID));
+			return scg::rpc::Status(scg::rpc::Code::UNIMPLEMENTED, "Unrecognized stream method ID: " + std::to_string(methodID)).toError();
 		}
 	}
 
@@ -15,6 +15,8 @@ type ClientMethodArgs struct {
 	MethodID                 uint64
 	MethodRequestStructName  string
 	MethodResponseStructName string
+	Deprecated               bool
+	Idempotent               bool
 }
 
 type ClientStreamMethodArgs struct {
@@ -24,6 +26,7 @@ type ClientStreamMethodArgs struct {
 	StreamTypeName       string
 	ReqStructName        string // request (argument) message
 	RespStructName       string // response (return) message
+	Deprecated           bool
 }
 
 type ClientArgs struct {
@@ -33,6 +36,7 @@ type ClientArgs struct {
 	ServiceID            uint64
 	ClientMethods        []ClientMethodArgs
 	ClientStreamMethods  []ClientStreamMethodArgs
+	Deprecated           bool
 }
 
 const clientTemplateStr = `
@@ -43,20 +47,28 @@ const clientTemplateStr = `
 type {{.ClientNamePascalCase}}Api interface { {{- range .ClientMethods}}
 	{{.MethodNamePascalCase}}(ctx context.Context, req *{{.MethodRequestStructName}}) (*{{.MethodResponseStructName}}, error){{end}}
 }
-
+{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
 type {{.ClientNamePascalCase}}Client struct {
-	client *rpc.Client
+	client rpc.Caller
 }
 
 var _ {{.ClientNamePascalCase}}Api = (*{{.ClientNamePascalCase}}Client)(nil) // compile-time conformance
 
-func New{{.ClientNamePascalCase}}Client(client *rpc.Client) *{{.ClientNamePascalCase}}Client {
+func New{{.ClientNamePascalCase}}Client(client rpc.Caller) *{{.ClientNamePascalCase}}Client {
 	return &{{.ClientNamePascalCase}}Client{
 		client: client,
 	}
 }
-
-{{range .ClientMethods}}
+{{if .ClientMethods}}
+// init registers the idempotency of each unary method, which the retry policy
+// of a client looks up by service and method ID.
+func init() { {{- range .ClientMethods}}
+	rpc.RegisterMethodInfo({{$.ServiceIDVarName}}, {{.MethodIDVarName}}, rpc.MethodInfo{Idempotent: {{.Idempotent}}}){{end}}
+}
+{{end}}
+{{range .ClientMethods}}{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
 func (c *{{$.ClientNamePascalCase}}Client) {{.MethodNamePascalCase}}(ctx context.Context, req *{{.MethodRequestStructName}}) (*{{.MethodResponseStructName}}, error) {
 
 	handler := func (ctx context.Context, req rpc.Message) (rpc.Message, error) {
@@ -152,7 +164,8 @@ func (s *{{.StreamTypeName}}) CloseAndRecv() (*{{.RespStructName}}, error) {
 func (s *{{.StreamTypeName}}) Context() context.Context {
 	return s.stream.Context()
 }
-{{if eq .Kind "server"}}
+{{if eq .Kind "server"}}{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
 func (c *{{$.ClientNamePascalCase}}Client) {{.MethodNamePascalCase}}(ctx context.Context, req *{{.ReqStructName}}) (*{{.StreamTypeName}}, error) {
 	stream, err := c.client.OpenStream(ctx, {{$.ServiceIDVarName}}, {{.MethodIDVarName}})
 	if err != nil {
@@ -166,7 +179,8 @@ func (c *{{$.ClientNamePascalCase}}Client) {{.MethodNamePascalCase}}(ctx context
 	}
 	return &{{.StreamTypeName}}{stream: stream}, nil
 }
-{{else}}
+{{else}}{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
 func (c *{{$.ClientNamePascalCase}}Client) {{.MethodNamePascalCase}}(ctx context.Context) (*{{.StreamTypeName}}, error) {
 	stream, err := c.client.OpenStream(ctx, {{$.ServiceIDVarName}}, {{.MethodIDVarName}})
 	if err != nil {
@@ -196,6 +210,7 @@ func generateClientGoCode(pkg *parse.Package, svc *parse.ServiceDefinition) (str
 		ServiceID:            serviceID,
 		ClientMethods:        []ClientMethodArgs{},
 		ClientStreamMethods:  []ClientStreamMethodArgs{},
+		Deprecated:           svc.Options.Bool(parse.OptionDeprecated),
 	}
 
 	for name, method := range svc.Methods {
@@ -216,6 +231,7 @@ func generateClientGoCode(pkg *parse.Package, svc *parse.ServiceDefinition) (str
 				StreamTypeName:       streamClientTypeName(svc.Name, name),
 				ReqStructName:        methodArgType,
 				RespStructName:       methodRetType,
+				Deprecated:           method.Options.Bool(parse.OptionDeprecated),
 			})
 			continue
 		}
@@ -227,6 +243,8 @@ func generateClientGoCode(pkg *parse.Package, svc *parse.ServiceDefinition) (str
 			MethodID:                 methodID,
 			MethodRequestStructName:  methodArgType,
 			MethodResponseStructName: methodRetType,
+			Deprecated:               method.Options.Bool(parse.OptionDeprecated),
+			Idempotent:               method.Options.Bool(parse.OptionIdempotent),
 		})
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kbirk/scg/internal/parse"
@@ -25,3 +26,29 @@ func TestGenerateClientGo(t *testing.T) {
 
 	fmt.Println("done")
 }
+
+func TestGenerateClientGoMethodInfo(t *testing.T) {
+
+	p, err := parse.NewParseFromFiles(".", map[string]string{
+		"users.scg": `
+			package users;
+
+			message User {
+				string name = 0;
+			}
+
+			service Users {
+				rpc Get (User) returns (User) [idempotent];
+				rpc Set (User) returns (User);
+			}
+		`,
+	})
+	require.Nil(t, err)
+
+	pkg := p.Packages["users"]
+	code, err := generateClientGoCode(pkg, pkg.ServiceDefinitions["Users"])
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "rpc.RegisterMethodInfo(usersServerID, usersServer_GetID, rpc.MethodInfo{Idempotent: true})")
+	assert.Contains(t, code, "rpc.RegisterMethodInfo(usersServerID, usersServer_SetID, rpc.MethodInfo{Idempotent: false})")
+}
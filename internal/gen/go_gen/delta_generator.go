@@ -0,0 +1,1195 @@
+package go_gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kbirk/scg/internal/parse"
+	"github.com/kbirk/scg/internal/util"
+)
+
+// A delta encodes a message against a baseline of the same type. Each field is
+// written in index order as a bit flagging whether it differs from the
+// baseline, followed by its delta when it does. Nested messages, lists, arrays,
+// maps, sets and oneofs are diffed recursively, all other values are written in
+// full:
+//
+//   - optional: a presence bit, then the delta against the baseline value if
+//     the baseline is also present, otherwise the full value
+//   - list: the length, then for each element within the length of the
+//     baseline a changed bit and its delta, and the remaining elements in full
+//   - array: a changed bit for each element, followed by its delta
+//   - map: the removed keys, then the added or changed entries, each a key
+//     followed by the delta against the baseline value or the full value
+//   - set: the removed elements, then the added elements
+//   - oneof: the discriminator, then the delta against the baseline member if
+//     it is the same member, otherwise the full value
+//
+// Bytes, and lists of bytes, are written in full like strings. A deterministic
+// writer writes the keys of maps and sets in ascending order.
+
+// DeltaFieldArgs describes the equality check and the delta methods of a single
+// field, or of a oneof as a whole.
+type DeltaFieldArgs struct {
+	FieldName       string
+	BaseName        string
+	ElementType     string
+	Optional        bool
+	Diffed          bool
+	Equal           string
+	MethodCall      string
+	DeltaMethodCall string
+}
+
+type DeltaMethodArgs struct {
+	MessageNameFirstLetter string
+	MessageNamePascalCase  string
+	Fields                 []DeltaFieldArgs
+	HasOptionalFields      bool
+	Recursive              bool
+}
+
+const messageEqualMethodTemplateStr = `
+// Equal returns true if the message holds the same values as the other message.
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Equal(other *{{.MessageNamePascalCase}}) bool {
+	if {{.MessageNameFirstLetter}} == nil || other == nil {
+		return {{.MessageNameFirstLetter}} == other
+	}{{range .Fields}}
+	if !({{.Equal}}) {
+		return false
+	}{{end}}
+	return true
+}`
+
+const messageBitSizeDeltaMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) BitSizeDelta(baseline *{{.MessageNamePascalCase}}) int {
+	{{- if .Fields}}
+	if baseline == nil {
+		baseline = &{{.MessageNamePascalCase}}{}
+	}
+	var changed bool{{end}}
+	size := 0{{range .Fields}}
+	changed = !({{.Equal}})
+	size += serialize.BitSizeBool(changed)
+	if changed { {{- if .Optional}}
+		size += serialize.BitSizeBool({{.FieldName}} != nil){{if .Diffed}}
+		if {{.FieldName}} != nil && {{.BaseName}} != nil {
+			size += {{.DeltaMethodCall}}
+		} else if {{.FieldName}} != nil {
+			size += {{.MethodCall}}
+		}{{else}}
+		if {{.FieldName}} != nil {
+			size += {{.MethodCall}}
+		}{{end}}{{else}}
+		size += {{.DeltaMethodCall}}{{end}}
+	}{{end}}
+	return size
+}`
+
+const messageSerializeDeltaMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) SerializeDelta(writer *serialize.Writer, baseline *{{.MessageNamePascalCase}}) {
+	{{- if .Fields}}
+	if baseline == nil {
+		baseline = &{{.MessageNamePascalCase}}{}
+	}
+	var changed bool{{end}}{{range .Fields}}
+	changed = !({{.Equal}})
+	serialize.SerializeBool(writer, changed)
+	if changed { {{- if .Optional}}
+		serialize.SerializeBool(writer, {{.FieldName}} != nil){{if .Diffed}}
+		if {{.FieldName}} != nil && {{.BaseName}} != nil {
+			{{.DeltaMethodCall}}
+		} else if {{.FieldName}} != nil {
+			{{.MethodCall}}
+		}{{else}}
+		if {{.FieldName}} != nil {
+			{{.MethodCall}}
+		}{{end}}{{else}}
+		{{.DeltaMethodCall}}{{end}}
+	}{{end}}
+}`
+
+const messageDeserializeDeltaMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) DeserializeDelta(reader *serialize.Reader, baseline *{{.MessageNamePascalCase}}) error {
+	{{- template "descend" .}}
+	{{- if .Fields}}
+	if baseline == nil {
+		baseline = &{{.MessageNamePascalCase}}{}
+	}{{if not .Recursive}}
+	var err error{{end}}
+	var changed bool{{if .HasOptionalFields}}
+	var present bool{{end}}{{end}}{{range .Fields}}
+	err = serialize.DeserializeBool(&changed, reader)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		{{.FieldName}} = {{.BaseName}}
+	} else { {{- if .Optional}}
+		err = serialize.DeserializeBool(&present, reader)
+		if err != nil {
+			return err
+		}
+		var value *{{.ElementType}}
+		if present {
+			value = new({{.ElementType}}){{if .Diffed}}
+			if {{.BaseName}} != nil {
+				err = {{.DeltaMethodCall}}
+			} else {
+				err = {{.MethodCall}}
+			}{{else}}
+			err = {{.MethodCall}}{{end}}
+			if err != nil {
+				return err
+			}
+		}
+		{{.FieldName}} = value{{else}}
+		err = {{.DeltaMethodCall}}
+		if err != nil {
+			return err
+		}{{end}}
+	}{{end}}
+	return nil
+}
+
+// ToBytesDelta returns the delta of the message against the baseline. A nil
+// baseline is treated as an empty message.
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) ToBytesDelta(baseline *{{.MessageNamePascalCase}}) []byte {
+	size := {{.MessageNameFirstLetter}}.BitSizeDelta(baseline)
+	writer := serialize.NewWriter(serialize.BitsToBytes(size))
+	{{.MessageNameFirstLetter}}.SerializeDelta(writer, baseline)
+	return writer.Bytes()
+}
+
+// FromBytesDelta applies a delta to the baseline and stores the result in the
+// message. Fields left unchanged by the delta share their slices, maps and
+// pointers with the baseline.
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) FromBytesDelta(bs []byte, baseline *{{.MessageNamePascalCase}}) error {
+	return {{.MessageNameFirstLetter}}.DeserializeDelta(serialize.NewReader(bs), baseline)
+}`
+
+// DeltaContainerMethodArgs describes a helper comparing or diffing a container.
+// The element calls operate on the variables named by the template of the
+// container and method.
+type DeltaContainerMethodArgs struct {
+	FullMethodName       string
+	ArgType              string
+	KeyType              string
+	ValueType            string
+	ValueDiffed          bool
+	KeyMethodCall        string
+	KeySort              string
+	ValueEqual           string
+	ValueMethodCall      string
+	ValueDeltaMethodCall string
+}
+
+const listEqualMethodTemplateStr = `
+func {{.FullMethodName}}(a {{.ArgType}}, b {{.ArgType}}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !({{.ValueEqual}}) {
+			return false
+		}
+	}
+	return true
+}`
+
+const listBitSizeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}, base {{.ArgType}}) int {
+	size := serialize.BitSizeUInt32(uint32(len(arg)))
+	for i, v := range arg {
+		if i >= len(base) {
+			size += {{.ValueMethodCall}}
+			continue
+		}
+		changed := !({{.ValueEqual}})
+		size += serialize.BitSizeBool(changed)
+		if changed {
+			size += {{.ValueDeltaMethodCall}}
+		}
+	}
+	return size
+}`
+
+const listSerializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.ArgType}}, base {{.ArgType}}) {
+	serialize.SerializeUInt32(writer, uint32(len(arg)))
+	for i, v := range arg {
+		if i >= len(base) {
+			{{.ValueMethodCall}}
+			continue
+		}
+		changed := !({{.ValueEqual}})
+		serialize.SerializeBool(writer, changed)
+		if changed {
+			{{.ValueDeltaMethodCall}}
+		}
+	}
+}`
+
+const listDeserializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, base {{.ArgType}}, reader *serialize.Reader) error {
+	var length uint32
+	err := serialize.DeserializeUInt32(&length, reader)
+	if err != nil {
+		return err
+	}
+	err = serialize.CheckElements[{{.ValueType}}](reader, length)
+	if err != nil {
+		return err
+	}
+
+	// Bound the initial allocation as for full lists, elements carried over
+	// from the baseline take a single bit each.
+	capHint := int(length)
+	if rem := len(base) + reader.RemainingBytes(); capHint > rem {
+		capHint = rem
+	}
+	result := make({{.ArgType}}, 0, capHint)
+
+	for i := 0; i < int(length); i++ {
+		var v {{.ValueType}}
+		if i >= len(base) {
+			err := {{.ValueMethodCall}}
+			if err != nil {
+				return err
+			}
+			result = append(result, v)
+			continue
+		}
+		var changed bool
+		err := serialize.DeserializeBool(&changed, reader)
+		if err != nil {
+			return err
+		}
+		if changed {
+			err = {{.ValueDeltaMethodCall}}
+			if err != nil {
+				return err
+			}
+		} else {
+			v = base[i]
+		}
+		result = append(result, v)
+	}
+	*arg = result
+	return nil
+}`
+
+const arrayEqualMethodTemplateStr = `
+func {{.FullMethodName}}(a *{{.ArgType}}, b *{{.ArgType}}) bool {
+	for i := range a {
+		if !({{.ValueEqual}}) {
+			return false
+		}
+	}
+	return true
+}`
+
+const arrayBitSizeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, base *{{.ArgType}}) int {
+	size := 0
+	for i := range arg {
+		changed := !({{.ValueEqual}})
+		size += serialize.BitSizeBool(changed)
+		if changed {
+			size += {{.ValueDeltaMethodCall}}
+		}
+	}
+	return size
+}`
+
+const arraySerializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg *{{.ArgType}}, base *{{.ArgType}}) {
+	for i := range arg {
+		changed := !({{.ValueEqual}})
+		serialize.SerializeBool(writer, changed)
+		if changed {
+			{{.ValueDeltaMethodCall}}
+		}
+	}
+}`
+
+const arrayDeserializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, base *{{.ArgType}}, reader *serialize.Reader) error {
+	for i := range arg {
+		var changed bool
+		err := serialize.DeserializeBool(&changed, reader)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			arg[i] = base[i]
+			continue
+		}
+		var v {{.ValueType}}
+		err = {{.ValueDeltaMethodCall}}
+		if err != nil {
+			return err
+		}
+		arg[i] = v
+	}
+	return nil
+}`
+
+const mapEqualMethodTemplateStr = `
+func {{.FullMethodName}}(a {{.ArgType}}, b {{.ArgType}}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		bv, ok := b[k]
+		if !ok || !({{.ValueEqual}}) {
+			return false
+		}
+	}
+	return true
+}`
+
+const mapBitSizeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}, base {{.ArgType}}) int {
+	size := 0
+	removed := 0
+	for k := range base {
+		if _, ok := arg[k]; !ok {
+			removed++
+			size += {{.KeyMethodCall}}
+		}
+	}
+	upserted := 0
+	for k, v := range arg {
+		bv, ok := base[k]
+		if !ok {
+			upserted++
+			size += {{.KeyMethodCall}} + {{.ValueMethodCall}}
+		} else if !({{.ValueEqual}}) {
+			upserted++
+			size += {{.KeyMethodCall}} + {{.ValueDeltaMethodCall}}
+		}
+	}
+	return serialize.BitSizeUInt32(uint32(removed)) + serialize.BitSizeUInt32(uint32(upserted)) + size
+}`
+
+const mapSerializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.ArgType}}, base {{.ArgType}}) {
+	removed := make([]{{.KeyType}}, 0)
+	for k := range base {
+		if _, ok := arg[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	if writer.Deterministic() {
+		serialize.Sort{{.KeySort}}(removed)
+	}
+	serialize.SerializeUInt32(writer, uint32(len(removed)))
+	for _, k := range removed {
+		{{.KeyMethodCall}}
+	}
+	upserted := make([]{{.KeyType}}, 0, len(arg))
+	for k, v := range arg {
+		if bv, ok := base[k]; !ok || !({{.ValueEqual}}) {
+			upserted = append(upserted, k)
+		}
+	}
+	if writer.Deterministic() {
+		serialize.Sort{{.KeySort}}(upserted)
+	}
+	serialize.SerializeUInt32(writer, uint32(len(upserted)))
+	for _, k := range upserted {
+		v := arg[k]
+		{{.KeyMethodCall}}{{if .ValueDiffed}}
+		if bv, ok := base[k]; ok {
+			{{.ValueDeltaMethodCall}}
+		} else {
+			{{.ValueMethodCall}}
+		}{{else}}
+		{{.ValueMethodCall}}{{end}}
+	}
+}`
+
+const mapDeserializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, base {{.ArgType}}, reader *serialize.Reader) error {
+	result := make({{.ArgType}}, len(base))
+	for k, v := range base {
+		result[k] = v
+	}
+
+	var removed uint32
+	err := serialize.DeserializeUInt32(&removed, reader)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(removed); i++ {
+		var k {{.KeyType}}
+		err := {{.KeyMethodCall}}
+		if err != nil {
+			return err
+		}
+		delete(result, k)
+	}
+
+	var upserted uint32
+	err = serialize.DeserializeUInt32(&upserted, reader)
+	if err != nil {
+		return err
+	}
+	err = serialize.CheckEntries[{{.KeyType}}, {{.ValueType}}](reader, upserted)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(upserted); i++ {
+		var k {{.KeyType}}
+		err := {{.KeyMethodCall}}
+		if err != nil {
+			return err
+		}
+		var v {{.ValueType}}{{if .ValueDiffed}}
+		if bv, ok := base[k]; ok {
+			err = {{.ValueDeltaMethodCall}}
+		} else {
+			err = {{.ValueMethodCall}}
+		}{{else}}
+		err = {{.ValueMethodCall}}{{end}}
+		if err != nil {
+			return err
+		}
+		result[k] = v
+	}
+	*arg = result
+	return nil
+}`
+
+const setEqualMethodTemplateStr = `
+func {{.FullMethodName}}(a {{.ArgType}}, b {{.ArgType}}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}`
+
+const setBitSizeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}, base {{.ArgType}}) int {
+	size := 0
+	removed := 0
+	for k := range base {
+		if _, ok := arg[k]; !ok {
+			removed++
+			size += {{.KeyMethodCall}}
+		}
+	}
+	added := 0
+	for k := range arg {
+		if _, ok := base[k]; !ok {
+			added++
+			size += {{.KeyMethodCall}}
+		}
+	}
+	return serialize.BitSizeUInt32(uint32(removed)) + serialize.BitSizeUInt32(uint32(added)) + size
+}`
+
+const setSerializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.ArgType}}, base {{.ArgType}}) {
+	removed := make([]{{.KeyType}}, 0)
+	for k := range base {
+		if _, ok := arg[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	if writer.Deterministic() {
+		serialize.Sort{{.KeySort}}(removed)
+	}
+	serialize.SerializeUInt32(writer, uint32(len(removed)))
+	for _, k := range removed {
+		{{.KeyMethodCall}}
+	}
+	added := make([]{{.KeyType}}, 0)
+	for k := range arg {
+		if _, ok := base[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	if writer.Deterministic() {
+		serialize.Sort{{.KeySort}}(added)
+	}
+	serialize.SerializeUInt32(writer, uint32(len(added)))
+	for _, k := range added {
+		{{.KeyMethodCall}}
+	}
+}`
+
+const setDeserializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, base {{.ArgType}}, reader *serialize.Reader) error {
+	result := make({{.ArgType}}, len(base))
+	for k := range base {
+		result[k] = struct{}{}
+	}
+
+	var removed uint32
+	err := serialize.DeserializeUInt32(&removed, reader)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(removed); i++ {
+		var k {{.KeyType}}
+		err := {{.KeyMethodCall}}
+		if err != nil {
+			return err
+		}
+		delete(result, k)
+	}
+
+	var added uint32
+	err = serialize.DeserializeUInt32(&added, reader)
+	if err != nil {
+		return err
+	}
+	err = serialize.CheckElements[{{.KeyType}}](reader, added)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(added); i++ {
+		var k {{.KeyType}}
+		err := {{.KeyMethodCall}}
+		if err != nil {
+			return err
+		}
+		result[k] = struct{}{}
+	}
+	*arg = result
+	return nil
+}`
+
+type OneofDeltaMemberMethodArgs struct {
+	WrapperType          string
+	Discriminator        int
+	ValueDiffed          bool
+	ValueEqual           string
+	ValueMethodCall      string
+	ValueDeltaMethodCall string
+}
+
+type OneofDeltaMethodArgs struct {
+	FullMethodName string
+	OneofName      string
+	InterfaceType  string
+	Members        []OneofDeltaMemberMethodArgs
+}
+
+const oneofEqualMethodTemplateStr = `
+func {{.FullMethodName}}(a {{.InterfaceType}}, b {{.InterfaceType}}) bool {
+	switch member := a.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:
+		other, ok := b.(*{{.WrapperType}})
+		return ok && {{.ValueEqual}}{{end}}
+	}
+	return b == nil
+}`
+
+const oneofBitSizeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.InterfaceType}}, base {{.InterfaceType}}) int {
+	switch member := arg.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:{{if .ValueDiffed}}
+		if other, ok := base.(*{{.WrapperType}}); ok {
+			return serialize.BitSizeUInt32({{.Discriminator}}) + {{.ValueDeltaMethodCall}}
+		}{{end}}
+		return serialize.BitSizeUInt32({{.Discriminator}}) + {{.ValueMethodCall}}{{end}}
+	}
+	return serialize.BitSizeUInt32(0)
+}`
+
+const oneofSerializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.InterfaceType}}, base {{.InterfaceType}}) {
+	switch member := arg.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:
+		serialize.SerializeUInt32(writer, {{.Discriminator}}){{if .ValueDiffed}}
+		if other, ok := base.(*{{.WrapperType}}); ok {
+			{{.ValueDeltaMethodCall}}
+		} else {
+			{{.ValueMethodCall}}
+		}{{else}}
+		{{.ValueMethodCall}}{{end}}{{end}}
+	default:
+		serialize.SerializeUInt32(writer, 0)
+	}
+}`
+
+const oneofDeserializeDeltaMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.InterfaceType}}, base {{.InterfaceType}}, reader *serialize.Reader) error {
+	var discriminator uint32
+	err := serialize.DeserializeUInt32(&discriminator, reader)
+	if err != nil {
+		return err
+	}
+	switch discriminator {
+	case 0:
+		*arg = nil{{range .Members}}
+	case {{.Discriminator}}:
+		member := &{{.WrapperType}}{}{{if .ValueDiffed}}
+		if other, ok := base.(*{{.WrapperType}}); ok {
+			err = {{.ValueDeltaMethodCall}}
+		} else {
+			err = {{.ValueMethodCall}}
+		}{{else}}
+		err = {{.ValueMethodCall}}{{end}}
+		if err != nil {
+			return err
+		}
+		*arg = member{{end}}
+	default:
+		return fmt.Errorf("oneof {{.OneofName}} has unrecognized discriminator %d", discriminator)
+	}
+	return nil
+}`
+
+var (
+	messageEqualMethodTemplate            = template.Must(template.New("messageEqualMethodTemplateGo").Parse(messageEqualMethodTemplateStr))
+	messageBitSizeDeltaMethodTemplate     = template.Must(template.New("messageBitSizeDeltaMethodTemplateGo").Parse(messageBitSizeDeltaMethodTemplateStr))
+	messageSerializeDeltaMethodTemplate   = template.Must(template.New("messageSerializeDeltaMethodTemplateGo").Parse(messageSerializeDeltaMethodTemplateStr))
+	messageDeserializeDeltaMethodTemplate = template.Must(template.New("messageDeserializeDeltaMethodTemplateGo").Parse(messageDeserializeDeltaMethodTemplateStr + messageDescendTemplateStr))
+	// container methods
+	listEqualMethodTemplate             = template.Must(template.New("listEqualMethodTemplateGo").Parse(listEqualMethodTemplateStr))
+	listBitSizeDeltaMethodTemplate      = template.Must(template.New("listBitSizeDeltaMethodTemplateGo").Parse(listBitSizeDeltaMethodTemplateStr))
+	listSerializeDeltaMethodTemplate    = template.Must(template.New("listSerializeDeltaMethodTemplateGo").Parse(listSerializeDeltaMethodTemplateStr))
+	listDeserializeDeltaMethodTemplate  = template.Must(template.New("listDeserializeDeltaMethodTemplateGo").Parse(listDeserializeDeltaMethodTemplateStr))
+	arrayEqualMethodTemplate            = template.Must(template.New("arrayEqualMethodTemplateGo").Parse(arrayEqualMethodTemplateStr))
+	arrayBitSizeDeltaMethodTemplate     = template.Must(template.New("arrayBitSizeDeltaMethodTemplateGo").Parse(arrayBitSizeDeltaMethodTemplateStr))
+	arraySerializeDeltaMethodTemplate   = template.Must(template.New("arraySerializeDeltaMethodTemplateGo").Parse(arraySerializeDeltaMethodTemplateStr))
+	arrayDeserializeDeltaMethodTemplate = template.Must(template.New("arrayDeserializeDeltaMethodTemplateGo").Parse(arrayDeserializeDeltaMethodTemplateStr))
+	mapEqualMethodTemplate              = template.Must(template.New("mapEqualMethodTemplateGo").Parse(mapEqualMethodTemplateStr))
+	mapBitSizeDeltaMethodTemplate       = template.Must(template.New("mapBitSizeDeltaMethodTemplateGo").Parse(mapBitSizeDeltaMethodTemplateStr))
+	mapSerializeDeltaMethodTemplate     = template.Must(template.New("mapSerializeDeltaMethodTemplateGo").Parse(mapSerializeDeltaMethodTemplateStr))
+	mapDeserializeDeltaMethodTemplate   = template.Must(template.New("mapDeserializeDeltaMethodTemplateGo").Parse(mapDeserializeDeltaMethodTemplateStr))
+	setEqualMethodTemplate              = template.Must(template.New("setEqualMethodTemplateGo").Parse(setEqualMethodTemplateStr))
+	setBitSizeDeltaMethodTemplate       = template.Must(template.New("setBitSizeDeltaMethodTemplateGo").Parse(setBitSizeDeltaMethodTemplateStr))
+	setSerializeDeltaMethodTemplate     = template.Must(template.New("setSerializeDeltaMethodTemplateGo").Parse(setSerializeDeltaMethodTemplateStr))
+	setDeserializeDeltaMethodTemplate   = template.Must(template.New("setDeserializeDeltaMethodTemplateGo").Parse(setDeserializeDeltaMethodTemplateStr))
+	// oneof methods
+	oneofEqualMethodTemplate            = template.Must(template.New("oneofEqualMethodTemplateGo").Parse(oneofEqualMethodTemplateStr))
+	oneofBitSizeDeltaMethodTemplate     = template.Must(template.New("oneofBitSizeDeltaMethodTemplateGo").Parse(oneofBitSizeDeltaMethodTemplateStr))
+	oneofSerializeDeltaMethodTemplate   = template.Must(template.New("oneofSerializeDeltaMethodTemplateGo").Parse(oneofSerializeDeltaMethodTemplateStr))
+	oneofDeserializeDeltaMethodTemplate = template.Must(template.New("oneofDeserializeDeltaMethodTemplateGo").Parse(oneofDeserializeDeltaMethodTemplateStr))
+)
+
+// the methods a delta is made of, and the comparison deciding which values are
+// written
+const (
+	deltaMethodEqual       = "Equal"
+	deltaMethodBitSize     = "BitSize"
+	deltaMethodSerialize   = "Serialize"
+	deltaMethodDeserialize = "Deserialize"
+)
+
+// isDeltaLeafType returns true if values of the type are written in full rather
+// than diffed against the baseline.
+func isDeltaLeafType(dataType *parse.DataTypeDefinition) bool {
+	switch dataType.Type {
+	case parse.DataTypeList:
+		// lists of bytes are diffed as a whole, as bytes are
+		return dataType.SubType.Type == parse.DataTypeByte || dataType.SubType.Type == parse.DataTypeUInt8
+	case parse.DataTypeMap, parse.DataTypeArray, parse.DataTypeSet:
+		return false
+	case parse.DataTypeCustom:
+		return !dataType.CustomTypeIsMessage
+	}
+	return true
+}
+
+func generateFieldMethodCall(method string, messageName string, varName string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
+	switch method {
+	case deltaMethodBitSize:
+		return generateFieldBitSizeMethodCall(messageName, varName, dataType)
+	case deltaMethodSerialize:
+		return generateFieldSerializationMethodCall(messageName, varName, dataType)
+	case deltaMethodDeserialize:
+		return generateFieldDeserializationMethodCall(messageName, varName, dataType)
+	}
+	return "", nil, fmt.Errorf("unrecognized method: %s", method)
+}
+
+func generateKeyFieldMethodCall(method string, varName string, dataType parse.DataTypeComparable) (string, error) {
+	switch method {
+	case deltaMethodBitSize:
+		return generateKeyFieldBitSizeMethodCall(varName, dataType)
+	case deltaMethodSerialize:
+		return generateKeyFieldSerializationMethodCall(varName, dataType)
+	case deltaMethodDeserialize:
+		return generateKeyFieldDeserializationMethodCall(varName, dataType)
+	}
+	return "", fmt.Errorf("unrecognized method: %s", method)
+}
+
+// addressOf returns an expression taking the address of the named value, which
+// for a dereferenced pointer is the pointer itself.
+func addressOf(name string) string {
+	if strings.HasPrefix(name, "(*") && strings.HasSuffix(name, ")") {
+		return name[2 : len(name)-1]
+	}
+	return "&" + name
+}
+
+// generateFieldEqualExpr returns an expression comparing two values of the type.
+func generateFieldEqualExpr(messageName string, a string, b string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
+	switch dataType.Type {
+	case parse.DataTypeBytes:
+		return fmt.Sprintf("string(%s) == string(%s)", a, b), nil, nil
+	case parse.DataTypeTimestamp:
+		return fmt.Sprintf("%s.Equal(%s)", a, b), nil, nil
+	case parse.DataTypeCustom:
+		if dataType.CustomTypeIsMessage {
+			return fmt.Sprintf("%s.Equal(%s)", a, addressOf(b)), nil, nil
+		}
+	case parse.DataTypeMap,
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet:
+		return generateEqualContainerMethod(messageName, a, b, dataType)
+	}
+	return fmt.Sprintf("%s == %s", a, b), nil, nil
+}
+
+func generateEqualContainerMethod(messageName string, a string, b string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
+
+	fullTypeName, err := getDataTypeDefinitionMethodSuffix(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	methodFullName := fmt.Sprintf("%s_Equal%s", util.EnsureCamelCase(messageName), fullTypeName)
+
+	argType, err := mapDataTypeDefinitionToGoType(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := DeltaContainerMethodArgs{
+		FullMethodName: methodFullName,
+		ArgType:        argType,
+	}
+
+	var tmpl *template.Template
+	methodCode := map[string]string{}
+
+	switch dataType.Type {
+	case parse.DataTypeList:
+		tmpl = listEqualMethodTemplate
+		args.ValueEqual, methodCode, err = generateFieldEqualExpr(messageName, "a[i]", "b[i]", dataType.SubType)
+	case parse.DataTypeArray:
+		tmpl = arrayEqualMethodTemplate
+		args.ValueEqual, methodCode, err = generateFieldEqualExpr(messageName, "a[i]", "b[i]", dataType.SubType)
+	case parse.DataTypeMap:
+		tmpl = mapEqualMethodTemplate
+		args.ValueEqual, methodCode, err = generateFieldEqualExpr(messageName, "v", "bv", dataType.SubType)
+	case parse.DataTypeSet:
+		tmpl = setEqualMethodTemplate
+	default:
+		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	code := map[string]string{
+		methodFullName: buf.String(),
+	}
+	code = util.MergeMap(code, methodCode)
+
+	if dataType.Type == parse.DataTypeArray {
+		return fmt.Sprintf("%s(%s, %s)", methodFullName, addressOf(a), addressOf(b)), code, nil
+	}
+	return fmt.Sprintf("%s(%s, %s)", methodFullName, a, b), code, nil
+}
+
+// generateFieldDeltaMethodCall returns the call of the delta method of the value
+// against the baseline value. Values that are not diffed use the full method.
+func generateFieldDeltaMethodCall(method string, messageName string, varName string, baseName string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
+
+	if isDeltaLeafType(dataType) {
+		// the helpers of full values are emitted with the full methods
+		call, _, err := generateFieldMethodCall(method, messageName, varName, dataType)
+		return call, nil, err
+	}
+
+	if dataType.Type == parse.DataTypeCustom {
+		switch method {
+		case deltaMethodBitSize:
+			return fmt.Sprintf("%s.BitSizeDelta(%s)", varName, addressOf(baseName)), nil, nil
+		case deltaMethodSerialize:
+			return fmt.Sprintf("%s.SerializeDelta(writer, %s)", varName, addressOf(baseName)), nil, nil
+		case deltaMethodDeserialize:
+			return fmt.Sprintf("%s.DeserializeDelta(reader, %s)", varName, addressOf(baseName)), nil, nil
+		}
+		return "", nil, fmt.Errorf("unrecognized method: %s", method)
+	}
+
+	methodFullName, code, err := generateDeltaContainerMethod(method, messageName, dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// arrays are passed by pointer to avoid copying the elements
+	if dataType.Type == parse.DataTypeArray {
+		varName = addressOf(varName)
+		baseName = addressOf(baseName)
+	}
+
+	switch method {
+	case deltaMethodBitSize:
+		return fmt.Sprintf("%s(%s, %s)", methodFullName, varName, baseName), code, nil
+	case deltaMethodSerialize:
+		return fmt.Sprintf("%s(writer, %s, %s)", methodFullName, varName, baseName), code, nil
+	}
+	if dataType.Type == parse.DataTypeArray {
+		return fmt.Sprintf("%s(%s, %s, reader)", methodFullName, varName, baseName), code, nil
+	}
+	return fmt.Sprintf("%s(%s, %s, reader)", methodFullName, addressOf(varName), baseName), code, nil
+}
+
+func generateDeltaContainerMethod(method string, messageName string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
+
+	fullTypeName, err := getDataTypeDefinitionMethodSuffix(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	methodFullName := fmt.Sprintf("%s_%sDelta%s", util.EnsureCamelCase(messageName), method, fullTypeName)
+
+	argType, err := mapDataTypeDefinitionToGoType(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := DeltaContainerMethodArgs{
+		FullMethodName: methodFullName,
+		ArgType:        argType,
+	}
+
+	methodCode := map[string]string{}
+
+	if dataType.Key != nil {
+		args.KeyType, err = mapDataTypeComparableDefinitionToGoType(dataType.Key)
+		if err != nil {
+			return "", nil, err
+		}
+		args.KeyMethodCall, err = generateKeyFieldMethodCall(method, "k", dataType.Key.Type)
+		if err != nil {
+			return "", nil, err
+		}
+		args.KeySort = getKeySortSuffix(dataType.Key)
+	}
+
+	// sets have no values, only keys
+	if dataType.SubType != nil {
+		args.ValueType, err = mapDataTypeDefinitionToGoType(dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
+		args.ValueDiffed = !isDeltaLeafType(dataType.SubType)
+
+		// the names of the element and of the baseline element
+		varName, baseName := "v", "base[i]"
+		if dataType.Type == parse.DataTypeMap {
+			baseName = "bv"
+		} else if dataType.Type == parse.DataTypeArray && method != deltaMethodDeserialize {
+			varName = "arg[i]"
+		}
+
+		equal, code, err := generateFieldEqualExpr(messageName, varName, baseName, dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
+		args.ValueEqual = equal
+		methodCode = util.MergeMap(methodCode, code)
+
+		// the helpers of full values are emitted with the full methods
+		full, _, err := generateFieldMethodCall(method, messageName, varName, dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
+		args.ValueMethodCall = full
+
+		delta, code, err := generateFieldDeltaMethodCall(method, messageName, varName, baseName, dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
+		args.ValueDeltaMethodCall = delta
+		methodCode = util.MergeMap(methodCode, code)
+	}
+
+	templates := map[parse.DataType][3]*template.Template{
+		parse.DataTypeList:  {listBitSizeDeltaMethodTemplate, listSerializeDeltaMethodTemplate, listDeserializeDeltaMethodTemplate},
+		parse.DataTypeArray: {arrayBitSizeDeltaMethodTemplate, arraySerializeDeltaMethodTemplate, arrayDeserializeDeltaMethodTemplate},
+		parse.DataTypeMap:   {mapBitSizeDeltaMethodTemplate, mapSerializeDeltaMethodTemplate, mapDeserializeDeltaMethodTemplate},
+		parse.DataTypeSet:   {setBitSizeDeltaMethodTemplate, setSerializeDeltaMethodTemplate, setDeserializeDeltaMethodTemplate},
+	}
+	containerTemplates, ok := templates[dataType.Type]
+	if !ok {
+		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
+	}
+
+	var tmpl *template.Template
+	switch method {
+	case deltaMethodBitSize:
+		tmpl = containerTemplates[0]
+	case deltaMethodSerialize:
+		tmpl = containerTemplates[1]
+	case deltaMethodDeserialize:
+		tmpl = containerTemplates[2]
+	default:
+		return "", nil, fmt.Errorf("unrecognized method: %s", method)
+	}
+
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	methodCode[methodFullName] = buf.String()
+
+	return methodFullName, methodCode, nil
+}
+
+// generateOneofDeltaMethod generates the helper comparing or diffing a oneof,
+// and returns its name.
+func generateOneofDeltaMethod(msg *parse.MessageDefinition, oneof *parse.OneofDefinition, method string, tmpl *template.Template) (string, map[string]string, error) {
+
+	prefix := method
+	if method != deltaMethodEqual {
+		prefix = method + "Delta"
+	}
+	methodFullName := fmt.Sprintf("%s_%sOneof%s", util.EnsureCamelCase(msg.Name), prefix, util.EnsurePascalCase(oneof.Name))
+
+	args := OneofDeltaMethodArgs{
+		FullMethodName: methodFullName,
+		OneofName:      oneof.Name,
+		InterfaceType:  oneofInterfaceType(msg, oneof),
+	}
+
+	methodCode := map[string]string{}
+
+	for i, field := range msg.OneofFieldsByIndex(oneof.Name) {
+		varName := fmt.Sprintf("member.%s", util.EnsurePascalCase(field.Name))
+		baseName := fmt.Sprintf("other.%s", util.EnsurePascalCase(field.Name))
+
+		member := OneofDeltaMemberMethodArgs{
+			WrapperType:   oneofWrapperType(msg, field),
+			Discriminator: i + 1,
+			ValueDiffed:   !isDeltaLeafType(field.DataTypeDefinition),
+		}
+
+		equal, code, err := generateFieldEqualExpr(msg.Name, varName, baseName, field.DataTypeDefinition)
+		if err != nil {
+			return "", nil, err
+		}
+		member.ValueEqual = equal
+		methodCode = util.MergeMap(methodCode, code)
+
+		if method != deltaMethodEqual {
+			full, _, err := generateFieldMethodCall(method, msg.Name, varName, field.DataTypeDefinition)
+			if err != nil {
+				return "", nil, err
+			}
+			member.ValueMethodCall = full
+
+			delta, code, err := generateFieldDeltaMethodCall(method, msg.Name, varName, baseName, field.DataTypeDefinition)
+			if err != nil {
+				return "", nil, err
+			}
+			member.ValueDeltaMethodCall = delta
+			methodCode = util.MergeMap(methodCode, code)
+		}
+
+		args.Members = append(args.Members, member)
+	}
+
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	methodCode[methodFullName] = buf.String()
+
+	return methodFullName, methodCode, nil
+}
+
+// getDeltaFieldArgs returns the fields of the message in index order, with each
+// oneof at the position of its first member. The values are compared against
+// those of the named message.
+func getDeltaFieldArgs(msg *parse.MessageDefinition, baseVar string, method string) ([]DeltaFieldArgs, map[string]string, error) {
+
+	methodCode := map[string]string{}
+	fields := []DeltaFieldArgs{}
+
+	oneofTemplates := map[string]*template.Template{
+		deltaMethodEqual:       oneofEqualMethodTemplate,
+		deltaMethodBitSize:     oneofBitSizeDeltaMethodTemplate,
+		deltaMethodSerialize:   oneofSerializeDeltaMethodTemplate,
+		deltaMethodDeserialize: oneofDeserializeDeltaMethodTemplate,
+	}
+
+	for _, field := range msg.FieldsByIndex() {
+
+		if field.Oneof != "" {
+			if !msg.IsFirstOneofField(field) {
+				continue
+			}
+			oneof := msg.Oneofs[field.Oneof]
+			fieldName := oneofFieldName(msg, oneof)
+			baseName := fmt.Sprintf("%s.%s", baseVar, util.EnsurePascalCase(oneof.Name))
+
+			equalName, code, err := generateOneofDeltaMethod(msg, oneof, deltaMethodEqual, oneofEqualMethodTemplate)
+			if err != nil {
+				return nil, nil, err
+			}
+			methodCode = util.MergeMap(methodCode, code)
+
+			args := DeltaFieldArgs{
+				FieldName: fieldName,
+				BaseName:  baseName,
+				Equal:     fmt.Sprintf("%s(%s, %s)", equalName, fieldName, baseName),
+			}
+
+			if method != deltaMethodEqual {
+				methodName, code, err := generateOneofDeltaMethod(msg, oneof, method, oneofTemplates[method])
+				if err != nil {
+					return nil, nil, err
+				}
+				methodCode = util.MergeMap(methodCode, code)
+
+				switch method {
+				case deltaMethodBitSize:
+					args.DeltaMethodCall = fmt.Sprintf("%s(%s, %s)", methodName, fieldName, baseName)
+				case deltaMethodSerialize:
+					args.DeltaMethodCall = fmt.Sprintf("%s(writer, %s, %s)", methodName, fieldName, baseName)
+				case deltaMethodDeserialize:
+					args.DeltaMethodCall = fmt.Sprintf("%s(&%s, %s, reader)", methodName, fieldName, baseName)
+				}
+			}
+
+			fields = append(fields, args)
+			continue
+		}
+
+		callArgs, err := getFieldMethodCallArgs(msg, field)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		args := DeltaFieldArgs{
+			FieldName:   callArgs.FieldName,
+			BaseName:    fmt.Sprintf("%s.%s", baseVar, util.EnsurePascalCase(field.Name)),
+			ElementType: callArgs.ElementType,
+			Optional:    field.Optional,
+			Diffed:      !isDeltaLeafType(field.DataTypeDefinition),
+		}
+
+		varName := fieldValueName(callArgs)
+		baseName := args.BaseName
+		if field.Optional {
+			varName = "(*value)"
+			if method != deltaMethodDeserialize {
+				varName = fmt.Sprintf("(*%s)", args.FieldName)
+			}
+			baseName = fmt.Sprintf("(*%s)", args.BaseName)
+		}
+
+		equal, code, err := generateFieldEqualExpr(msg.Name, fieldValueName(callArgs), baseName, field.DataTypeDefinition)
+		if err != nil {
+			return nil, nil, err
+		}
+		methodCode = util.MergeMap(methodCode, code)
+		if field.Optional {
+			// both absent, or both present and equal
+			equal = fmt.Sprintf("(%s == nil && %s == nil) || (%s != nil && %s != nil && %s)", args.FieldName, args.BaseName, args.FieldName, args.BaseName, equal)
+		}
+		args.Equal = equal
+
+		if method != deltaMethodEqual {
+			if field.Optional {
+				full, _, err := generateFieldMethodCall(method, msg.Name, varName, field.DataTypeDefinition)
+				if err != nil {
+					return nil, nil, err
+				}
+				args.MethodCall = full
+			}
+
+			delta, code, err := generateFieldDeltaMethodCall(method, msg.Name, varName, baseName, field.DataTypeDefinition)
+			if err != nil {
+				return nil, nil, err
+			}
+			args.DeltaMethodCall = delta
+			methodCode = util.MergeMap(methodCode, code)
+		}
+
+		fields = append(fields, args)
+	}
+
+	return fields, methodCode, nil
+}
+
+// generateMessageDeltaMethods generates the Equal method of the message, and the
+// methods encoding it as a delta against a baseline.
+func generateMessageDeltaMethods(msg *parse.MessageDefinition) (string, error) {
+
+	additionalFunctionCode := map[string]string{}
+	methods := ""
+
+	for _, method := range []struct {
+		name    string
+		baseVar string
+		tmpl    *template.Template
+	}{
+		{deltaMethodEqual, "other", messageEqualMethodTemplate},
+		{deltaMethodBitSize, "baseline", messageBitSizeDeltaMethodTemplate},
+		{deltaMethodSerialize, "baseline", messageSerializeDeltaMethodTemplate},
+		{deltaMethodDeserialize, "baseline", messageDeserializeDeltaMethodTemplate},
+	} {
+		args := DeltaMethodArgs{
+			MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
+			MessageNamePascalCase:  util.EnsurePascalCase(msg.Name),
+			Recursive:              msg.IsRecursive(),
+		}
+
+		fields, methodCode, err := getDeltaFieldArgs(msg, method.baseVar, method.name)
+		if err != nil {
+			return "", err
+		}
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+		args.Fields = fields
+		for _, field := range fields {
+			if field.Optional {
+				args.HasOptionalFields = true
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		err = method.tmpl.Execute(buf, args)
+		if err != nil {
+			return "", err
+		}
+		methods += buf.String() + "\n"
+	}
+
+	code := ""
+	for _, c := range valuesSortedByKey(additionalFunctionCode) {
+		code += c + "\n"
+	}
+	return code + methods, nil
+}
@@ -0,0 +1,131 @@
+package go_gen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbirk/scg/internal/parse"
+)
+
+func TestGenerateMessageDeltaMethods(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Snapshot",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Tick": {
+				Name:  "Tick",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+			"Players": {
+				Name:  "Players",
+				Index: 1,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeMap,
+					Key: &parse.DataTypeComparableDefinition{
+						Type: parse.DataTypeComparableString,
+					},
+					SubType: &parse.DataTypeDefinition{
+						Type:                parse.DataTypeCustom,
+						CustomType:          "Player",
+						CustomTypeIsMessage: true,
+					},
+				},
+			},
+			"Status": {
+				Name:     "Status",
+				Index:    2,
+				Optional: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type:       parse.DataTypeCustom,
+					CustomType: "Status",
+				},
+			},
+			"Payload": {
+				Name:  "Payload",
+				Index: 3,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeBytes,
+				},
+			},
+		},
+	}
+
+	code, err := generateMessageDeltaMethods(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "func (s *Snapshot) Equal(other *Snapshot) bool {")
+	assert.Contains(t, code, "func (s *Snapshot) BitSizeDelta(baseline *Snapshot) int {")
+	assert.Contains(t, code, "func (s *Snapshot) SerializeDelta(writer *serialize.Writer, baseline *Snapshot) {")
+	assert.Contains(t, code, "func (s *Snapshot) DeserializeDelta(reader *serialize.Reader, baseline *Snapshot) error {")
+
+	// messages are diffed, other values are compared and written in full
+	assert.Contains(t, code, "changed = !(s.Tick == baseline.Tick)")
+	assert.Contains(t, code, "!(v.Equal(&bv))")
+	assert.Contains(t, code, "v.SerializeDelta(writer, &bv)")
+	assert.Contains(t, code, "err = v.DeserializeDelta(reader, &bv)")
+	assert.Contains(t, code, "snapshot_SerializeDeltaMapStringPlayer(writer, s.Players, baseline.Players)")
+	assert.Contains(t, code, "serialize.CheckEntries[string, Player](reader, upserted)")
+	assert.Contains(t, code, "string(s.Payload) == string(other.Payload)")
+	assert.Contains(t, code, "serialize.SerializeBytes(writer, s.Payload)")
+
+	// enums and typedefs are not diffed, so the baseline of an optional one is
+	// never needed
+	assert.Contains(t, code, "(s.Status == nil && other.Status == nil) || (s.Status != nil && other.Status != nil && (*s.Status) == (*other.Status))")
+	assert.NotContains(t, code, "Status.SerializeDelta")
+
+	// the helpers of full values are emitted with the full methods
+	assert.NotContains(t, code, "func snapshot_SerializeMapStringPlayer(")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageDeltaMethodsOneof(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Event",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Move": {
+				Name:  "Move",
+				Index: 0,
+				Oneof: "Action",
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type:                parse.DataTypeCustom,
+					CustomType:          "Move",
+					CustomTypeIsMessage: true,
+				},
+			},
+			"Say": {
+				Name:  "Say",
+				Index: 1,
+				Oneof: "Action",
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+		Oneofs: map[string]*parse.OneofDefinition{
+			"Action": {
+				Name: "Action",
+			},
+		},
+	}
+
+	code, err := generateMessageDeltaMethods(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "func event_EqualOneofAction(a Event_Action, b Event_Action) bool {")
+	assert.Contains(t, code, "func event_SerializeDeltaOneofAction(writer *serialize.Writer, arg Event_Action, base Event_Action) {")
+	assert.Contains(t, code, "if other, ok := base.(*Event_Action_Move); ok {")
+	assert.NotContains(t, code, "base.(*Event_Action_Say); ok")
+	assert.Contains(t, code, "err = event_DeserializeDeltaOneofAction(&e.Action, baseline.Action, reader)")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
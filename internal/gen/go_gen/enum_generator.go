@@ -13,6 +13,7 @@ type EnumValueArgs struct {
 	ValueNamePascalCase string
 	ValueString         string
 	Index               int
+	Deprecated          bool
 }
 
 type EnumArgs struct {
@@ -21,9 +22,11 @@ type EnumArgs struct {
 	EnumUnderlyingType           string
 	EnumUnderlyingTypePascalCase string
 	EnumValueArgs                []EnumValueArgs
+	Deprecated                   bool
 }
 
-const enumTemplateStr = `
+const enumTemplateStr = `{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
 type {{.EnumNamePascalCase}} {{.EnumUnderlyingType}}
 
 func ({{.EnumNameNameFirstLetter}} *{{.EnumNamePascalCase}}) BitSize() int {
@@ -60,7 +63,8 @@ func ({{.EnumNameNameFirstLetter}} *{{.EnumNamePascalCase}}) Scan(src interface{
 }
 
 const (
-{{- range .EnumValueArgs}}
+{{- range .EnumValueArgs}}{{if .Deprecated}}
+	// Deprecated: Marked as deprecated in the schema.{{end}}
 	{{$.EnumNamePascalCase}}_{{.ValueNamePascalCase}} {{$.EnumNamePascalCase}} = {{.Index}}
 {{- end}}
 {{- range .EnumValueArgs}}
@@ -105,6 +109,7 @@ func generateEnumGoCode(enum *parse.EnumDefinition) (string, error) {
 			ValueNamePascalCase: util.EnsurePascalCase(v.Name),
 			ValueString:         v.Value,
 			Index:               i,
+			Deprecated:          v.Options.Bool(parse.OptionDeprecated),
 		})
 	}
 
@@ -114,6 +119,7 @@ func generateEnumGoCode(enum *parse.EnumDefinition) (string, error) {
 		EnumUnderlyingType:           typeName,
 		EnumUnderlyingTypePascalCase: typeNamePascalCase,
 		EnumValueArgs:                enumValueArgs,
+		Deprecated:                   enum.Options.Bool(parse.OptionDeprecated),
 	}
 
 	buf := &bytes.Buffer{}
@@ -11,6 +11,11 @@ import (
 func GenerateGoCode(basePackage, outputDir string, p *parse.Parse) error {
 	for path, file := range p.Files {
 
+		if file.External {
+			// only loaded to satisfy an import
+			continue
+		}
+
 		pkg, ok := p.Packages[file.Package.Name]
 		if !ok {
 			return fmt.Errorf("package not found: %s", file.Package.Name)
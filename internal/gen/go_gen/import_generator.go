@@ -27,17 +27,28 @@ import ( {{ range .STDPackages }}
 
 var (
 	messageImportsSTD = []string{
+		"crypto/sha256",
 		"encoding/json",
+		"hash",
 	}
 	timestampImportsSTD = []string{
 		"time",
 	}
+	durationImportsSTD = []string{
+		"time",
+	}
 	uuidImports = []string{
 		"github.com/google/uuid",
 	}
 	messageImportsSCG = []string{
 		"github.com/kbirk/scg/pkg/serialize",
 	}
+	oneofImportsSTD = []string{
+		"fmt",
+	}
+	setImportsSTD = []string{
+		"fmt",
+	}
 	typedefImportsSTD = []string{
 		"database/sql/driver",
 		"fmt",
@@ -67,7 +78,7 @@ var (
 )
 
 func hasTimestampType(dataType *parse.DataTypeDefinition) bool {
-	if dataType.Type == parse.DataTypeList {
+	if dataType.Type == parse.DataTypeList || dataType.Type == parse.DataTypeArray {
 		return hasTimestampType(dataType.SubType)
 	}
 
@@ -78,8 +89,18 @@ func hasTimestampType(dataType *parse.DataTypeDefinition) bool {
 	return dataType.Type == parse.DataTypeTimestamp
 }
 
+// hasDurationType reports whether the type is, or is a container of, durations,
+// which are also marshalled to JSON through serialize.JSONDuration.
+func hasDurationType(dataType *parse.DataTypeDefinition) bool {
+	if dataType.Type == parse.DataTypeList || dataType.Type == parse.DataTypeArray || dataType.Type == parse.DataTypeMap {
+		return hasDurationType(dataType.SubType)
+	}
+
+	return dataType.Type == parse.DataTypeDuration
+}
+
 func hasUUIDType(dataType *parse.DataTypeDefinition) bool {
-	if dataType.Type == parse.DataTypeList {
+	if dataType.Type == parse.DataTypeList || dataType.Type == parse.DataTypeArray {
 		return hasUUIDType(dataType.SubType)
 	}
 
@@ -90,9 +111,21 @@ func hasUUIDType(dataType *parse.DataTypeDefinition) bool {
 		return hasUUIDType(dataType.SubType)
 	}
 
+	if dataType.Type == parse.DataTypeSet {
+		return dataType.Key.Type == parse.DataTypeComparableUUID
+	}
+
 	return dataType.Type == parse.DataTypeUUID
 }
 
+func hasSetType(dataType *parse.DataTypeDefinition) bool {
+	if dataType.Type == parse.DataTypeList || dataType.Type == parse.DataTypeArray || dataType.Type == parse.DataTypeMap {
+		return hasSetType(dataType.SubType)
+	}
+
+	return dataType.Type == parse.DataTypeSet
+}
+
 func generateImportsGoCode(basePackage string, file *parse.File) (string, error) {
 
 	args := ImportArgs{}
@@ -110,6 +143,14 @@ func generateImportsGoCode(basePackage string, file *parse.File) (string, error)
 				}
 			}
 		}
+		// check if duration type
+		for _, msg := range file.MessageDefinitions {
+			for _, field := range msg.Fields {
+				if hasDurationType(field.DataTypeDefinition) {
+					args.STDPackages = append(args.STDPackages, durationImportsSTD...)
+				}
+			}
+		}
 		// check if uuid type
 		for _, msg := range file.MessageDefinitions {
 			for _, field := range msg.Fields {
@@ -118,6 +159,20 @@ func generateImportsGoCode(basePackage string, file *parse.File) (string, error)
 				}
 			}
 		}
+		// check if oneof
+		for _, msg := range file.MessageDefinitions {
+			if len(msg.Oneofs) > 0 {
+				args.STDPackages = append(args.STDPackages, oneofImportsSTD...)
+			}
+		}
+		// check if set type
+		for _, msg := range file.MessageDefinitions {
+			for _, field := range msg.Fields {
+				if hasSetType(field.DataTypeDefinition) {
+					args.STDPackages = append(args.STDPackages, setImportsSTD...)
+				}
+			}
+		}
 	}
 
 	if len(file.Typedefs) > 0 {
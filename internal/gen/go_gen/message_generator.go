@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/kbirk/scg/internal/parse"
@@ -12,23 +14,50 @@ import (
 
 type MessageFieldArgs struct {
 	FieldNamePascalCase string
-	FieldNameSnakeCase  string
+	FieldJSONName       string
 	FieldType           string
+	FieldJSONOptions    string
+	Deprecated          bool
+}
+
+// MessageConstArgs describes a constant generated alongside a message, such as
+// the precision of a quant field.
+type MessageConstArgs struct {
+	Name    string
+	Value   string
+	Comment string
 }
 
 type MessageArgs struct {
 	MessageNamePascalCase  string
+	MessageFullName        string
 	MessageNameFirstLetter string
+	Deprecated             bool
 	MessageFields          []MessageFieldArgs
+	MessageConsts          []MessageConstArgs
+	Tagged                 bool
+	OneofCode              string
+	JSONCode               string
 	BitSizeCode            string
 	SerializeCode          string
 	DeserializeCode        string
+	DeltaCode              string
 }
 
-const messageTemplateStr = `
-type {{.MessageNamePascalCase}} struct { {{- range .MessageFields}}
-	{{.FieldNamePascalCase}} {{.FieldType}} ` + "`json:\"{{.FieldNameSnakeCase}}\"`" + `{{end}}
+const messageTemplateStr = `{{if .Deprecated}}
+// Deprecated: Marked as deprecated in the schema.{{end}}
+type {{.MessageNamePascalCase}} struct { {{- range .MessageFields}}{{if .Deprecated}}
+	// Deprecated: Marked as deprecated in the schema.{{end}}
+	{{.FieldNamePascalCase}} {{.FieldType}} ` + "`json:\"{{.FieldJSONName}}{{.FieldJSONOptions}}\"`" + `{{end}}
 }
+{{- if .MessageConsts}}
+
+const ( {{- range .MessageConsts}}
+	// {{.Comment}}
+	{{.Name}} = {{.Value}}{{end}}
+)
+{{- end}}
+{{.OneofCode}}{{.JSONCode}}
 
 func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) ToJSON() ([]byte, error) {
 	jsonData, err := json.Marshal({{.MessageNameFirstLetter}})
@@ -45,7 +74,7 @@ func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) FromJSON(data []b
 	}
 	return nil
 }
-{{- if gt (len .MessageFields) 0 }}
+{{- if or (gt (len .MessageFields) 0) .Tagged }}
 func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) ToBytes() []byte {
 	size := {{.MessageNameFirstLetter}}.BitSize()
 	writer := serialize.NewWriter(serialize.BitsToBytes(size))
@@ -66,49 +95,329 @@ func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) FromBytes(bs []by
 }
 {{- end }}
 
+// MessageName returns the name of the message qualified by its package, which
+// identifies it as a detail of an rpc.Status.
+func (*{{.MessageNamePascalCase}}) MessageName() string {
+	return "{{.MessageFullName}}"
+}
+
+// Sum256 returns the SHA-256 digest of the deterministic encoding of the
+// message, which matches the digest computed by the C++ sum256 method.
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Sum256() [32]byte {
+	hasher := sha256.New()
+	{{.MessageNameFirstLetter}}.HashTo(hasher)
+	var sum [32]byte
+	hasher.Sum(sum[:0])
+	return sum
+}
+
+// HashTo writes the deterministic encoding of the message to the hasher,
+// without encoding it into a buffer first.
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) HashTo(hasher hash.Hash) {
+	writer := serialize.NewHashWriter(hasher)
+	{{.MessageNameFirstLetter}}.Serialize(writer)
+	writer.Flush()
+}
+
 {{.BitSizeCode}}
 {{.SerializeCode}}
 {{.DeserializeCode}}
+{{.DeltaCode}}
+`
+
+type OneofMemberArgs struct {
+	FieldNamePascalCase string
+	FieldJSONName       string
+	FieldType           string
+	JSONFieldType       string
+	WrapperType         string
+	ToJSON              string
+	FromJSON            string
+}
+
+// OneofArgs describes a oneof of a message. The oneof is held in a single field
+// of a sealed interface type, implemented by one wrapper type per member.
+type OneofArgs struct {
+	FieldNamePascalCase string
+	FieldNameSnakeCase  string
+	InterfaceType       string
+	JSONType            string
+	Members             []OneofMemberArgs
+}
+
+type OneofTypesArgs struct {
+	Oneofs []OneofArgs
+}
+
+const messageOneofTypesTemplateStr = `{{- range .Oneofs}}
+type {{.InterfaceType}} interface {
+	is{{.InterfaceType}}()
+}
+{{- $oneof := .}}{{range .Members}}
+
+type {{.WrapperType}} struct {
+	{{.FieldNamePascalCase}} {{.FieldType}}
+}
+
+func (*{{.WrapperType}}) is{{$oneof.InterfaceType}}() {}
+{{- end}}
+
+type {{.JSONType}} struct { {{- range .Members}}
+	{{.FieldNamePascalCase}} *{{.JSONFieldType}} ` + "`json:\"{{.FieldJSONName}},omitempty\"`" + `{{end}}
+}
+{{end}}`
+
+// JSONFieldArgs describes a field that is shadowed in the generated JSON
+// methods because its Go type does not marshal to the schema's JSON form.
+// AuxValue initializes the shadow field from the message and Result, when set,
+// assigns the unmarshalled shadow field back to the message.
+type JSONFieldArgs struct {
+	FieldNamePascalCase string
+	FieldJSONName       string
+	FieldJSONOptions    string
+	JSONType            string
+	AuxValue            string
+	Result              string
+}
+
+type JSONMethodsArgs struct {
+	MessageNamePascalCase  string
+	MessageNameFirstLetter string
+	Fields                 []JSONFieldArgs
+	Oneofs                 []OneofArgs
+}
+
+const messageJSONMethodsTemplateStr = `{{- define "auxFields"}}
+		*alias{{range .Fields}}
+		{{.FieldNamePascalCase}} {{.JSONType}} ` + "`json:\"{{.FieldJSONName}}{{.FieldJSONOptions}}\"`" + `{{end}}{{range .Oneofs}}
+		{{.FieldNamePascalCase}} *{{.JSONType}} ` + "`json:\"{{.FieldNameSnakeCase}},omitempty\"`" + `{{end}}
+{{- end}}
+func ({{.MessageNameFirstLetter}} {{.MessageNamePascalCase}}) MarshalJSON() ([]byte, error) {
+	type alias {{.MessageNamePascalCase}}
+	aux := struct { {{- template "auxFields" .}}
+	}{
+		alias: (*alias)(&{{.MessageNameFirstLetter}}),{{range .Fields}}
+		{{.FieldNamePascalCase}}: {{.AuxValue}},{{end}}
+	}{{range .Oneofs}}{{$oneof := .}}
+	switch member := {{$.MessageNameFirstLetter}}.{{.FieldNamePascalCase}}.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:{{if .ToJSON}}
+		value := {{.ToJSON}}
+		aux.{{$oneof.FieldNamePascalCase}} = &{{$oneof.JSONType}}{ {{- .FieldNamePascalCase}}: &value}{{else}}
+		aux.{{$oneof.FieldNamePascalCase}} = &{{$oneof.JSONType}}{ {{- .FieldNamePascalCase}}: &member.{{.FieldNamePascalCase}}}{{end}}{{end}}
+	}{{end}}
+	return json.Marshal(aux)
+}
+
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) UnmarshalJSON(data []byte) error {
+	type alias {{.MessageNamePascalCase}}
+	aux := struct { {{- template "auxFields" .}}
+	}{
+		alias: (*alias)({{.MessageNameFirstLetter}}),{{range .Fields}}
+		{{.FieldNamePascalCase}}: {{.AuxValue}},{{end}}
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}{{range .Fields}}{{if .Result}}
+	{{$.MessageNameFirstLetter}}.{{.FieldNamePascalCase}} = {{.Result}}{{end}}{{end}}{{range .Oneofs}}{{$oneof := .}}
+	{{$.MessageNameFirstLetter}}.{{.FieldNamePascalCase}} = nil
+	if aux.{{.FieldNamePascalCase}} != nil { {{- range $i, $member := .Members}}
+		{{if $i}}} else {{end}}if aux.{{$oneof.FieldNamePascalCase}}.{{.FieldNamePascalCase}} != nil {
+			{{$.MessageNameFirstLetter}}.{{$oneof.FieldNamePascalCase}} = &{{.WrapperType}}{ {{- .FieldNamePascalCase}}: {{if .FromJSON}}{{.FromJSON}}{{else}}*aux.{{$oneof.FieldNamePascalCase}}.{{.FieldNamePascalCase}}{{end}}}{{end}}
+		}
+	}{{end}}
+	return nil
+}
 `
 
+// FieldMethodCallArgs describes the (de)serialization call for a single field.
+// Optional fields are held by pointer, so the method call operates on the
+// dereferenced field and is only made when the field is present.
+type FieldMethodCallArgs struct {
+	FieldName   string
+	ElementType string
+	Optional    bool
+	MethodCall  string
+}
+
 type BitSizeMethodArgs struct {
 	MessageNameFirstLetter  string
 	MessageNamePascalCase   string
-	FieldBitSizeMethodCalls []string
+	FieldBitSizeMethodCalls []FieldMethodCallArgs
 }
 
 type SerializeMethodArgs struct {
 	MessageNameFirstLetter    string
 	MessageNamePascalCase     string
-	FieldSerializeMethodCalls []string
+	FieldSerializeMethodCalls []FieldMethodCallArgs
 }
 
 type DeserializeMethodArgs struct {
 	MessageNameFirstLetter      string
 	MessageNamePascalCase       string
-	FieldDeserializeMethodCalls []string
+	FieldDeserializeMethodCalls []FieldMethodCallArgs
+	HasOptionalFields           bool
+	Recursive                   bool
 }
 
+// bounds the nesting of recursive messages
+const messageDescendTemplateStr = `
+{{- define "descend"}}{{if .Recursive}}
+	err := reader.Descend()
+	if err != nil {
+		return err
+	}
+	defer reader.Ascend()
+{{end}}{{end}}`
+
 const messageBitSizeMethodTemplateStr = `
 func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) BitSize() int {
-	size := 0{{range .FieldBitSizeMethodCalls}}
-	size += {{.}}{{end}}
+	size := 0{{range .FieldBitSizeMethodCalls}}{{if .Optional}}
+	size += serialize.BitSizeBool({{.FieldName}} != nil)
+	if {{.FieldName}} != nil {
+		size += {{.MethodCall}}
+	}{{else}}
+	size += {{.MethodCall}}{{end}}{{end}}
 	return size
 }`
 
 const messageSerializeMethodTemplateStr = `
 func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Serialize(writer *serialize.Writer) {
-	{{- range .FieldSerializeMethodCalls}}
-	{{.}}{{end}}
+	{{- range .FieldSerializeMethodCalls}}{{if .Optional}}
+	serialize.SerializeBool(writer, {{.FieldName}} != nil)
+	if {{.FieldName}} != nil {
+		{{.MethodCall}}
+	}{{else}}
+	{{.MethodCall}}{{end}}{{end}}
 }`
 
 const messageDeserializeMethodTemplateStr = `
 func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Deserialize(reader *serialize.Reader) error {
-	{{- if gt (len .FieldDeserializeMethodCalls) 0 }}var err error{{end}}{{range .FieldDeserializeMethodCalls}}
-	err = {{.}}
+	{{- template "descend" .}}
+	{{- if and (gt (len .FieldDeserializeMethodCalls) 0) (not .Recursive) }}var err error{{end}}{{if .HasOptionalFields}}
+	var present bool{{end}}{{range .FieldDeserializeMethodCalls}}{{if .Optional}}
+	err = serialize.DeserializeBool(&present, reader)
+	if err != nil {
+		return err
+	}
+	{{.FieldName}} = nil
+	if present {
+		{{.FieldName}} = new({{.ElementType}})
+		err = {{.MethodCall}}
+		if err != nil {
+			return err
+		}
+	}{{else}}
+	err = {{.MethodCall}}
 	if err != nil {
 		return err
+	}{{end}}{{end}}
+	return nil
+}`
+
+// TaggedFieldArgs describes a single field of a tagged message. Fields with a
+// condition (optional fields and oneof members) are only written when it holds,
+// and the prelude prepares the field to receive the value when it is read.
+type TaggedFieldArgs struct {
+	Index                 uint32
+	WireKind              string
+	Delimited             bool
+	Condition             string
+	Prelude               string
+	BitSizeMethodCall     string
+	SerializeMethodCall   string
+	DeserializeMethodCall string
+}
+
+// TaggedMethodArgs holds the fields of a tagged message. Absent optional fields
+// and unset oneofs are omitted from the payload entirely, so the number of
+// fields written is only known at runtime when the message has any.
+type TaggedMethodArgs struct {
+	MessageNameFirstLetter string
+	MessageNamePascalCase  string
+	Fields                 []TaggedFieldArgs
+	NumRequiredFields      int
+	CountConditions        []string
+	Recursive              bool
+}
+
+const messageTaggedFieldCountTemplateStr = `
+{{- define "fieldCount"}}
+	numFields := uint32({{.NumRequiredFields}}){{range .CountConditions}}
+	if {{.}} {
+		numFields++
 	}{{end}}
+{{- end}}`
+
+const messageTaggedBitSizeMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) BitSize() int {
+	{{- template "fieldCount" .}}
+	size := serialize.BitSizeUInt32(numFields){{range .Fields}}{{if .Condition}}
+	if {{.Condition}} { {{- end}}{{if .Delimited}}
+	{
+		numBits := {{.BitSizeMethodCall}}
+		size += serialize.BitSizeFieldTag({{.Index}}) + serialize.BitSizeDelimited(numBits) + numBits
+	}{{else}}
+	size += serialize.BitSizeFieldTag({{.Index}}) + {{.BitSizeMethodCall}}{{end}}{{if .Condition}}
+	}{{end}}{{end}}
+	return size
+}`
+
+const messageTaggedSerializeMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Serialize(writer *serialize.Writer) {
+	{{- template "fieldCount" .}}
+	serialize.SerializeUInt32(writer, numFields){{range .Fields}}{{if .Condition}}
+	if {{.Condition}} { {{- end}}
+	serialize.SerializeFieldTag(writer, {{.Index}}, serialize.WireKind{{.WireKind}}){{if .Delimited}}
+	serialize.SerializeDelimited(writer, {{.BitSizeMethodCall}}){{end}}
+	{{.SerializeMethodCall}}{{if .Condition}}
+	}{{end}}{{end}}
+}`
+
+const messageTaggedDeserializeMethodTemplateStr = `
+func ({{.MessageNameFirstLetter}} *{{.MessageNamePascalCase}}) Deserialize(reader *serialize.Reader) error {
+	{{- template "descend" .}}
+	// fields missing from the payload retain their zero value
+	*{{.MessageNameFirstLetter}} = {{.MessageNamePascalCase}}{}
+
+	var numFields uint32
+	{{if .Recursive}}err = {{else}}err := {{end}}serialize.DeserializeUInt32(&numFields, reader)
+	if err != nil {
+		return err
+	}
+	for fieldNum := uint32(0); fieldNum < numFields; fieldNum++ {
+		var fieldIndex uint32
+		var wireKind serialize.WireKind
+		err = serialize.DeserializeFieldTag(&fieldIndex, &wireKind, reader)
+		if err != nil {
+			return err
+		}
+		switch fieldIndex { {{- range .Fields}}
+		case {{.Index}}:
+			err = serialize.CheckWireKind(fieldIndex, wireKind, serialize.WireKind{{.WireKind}})
+			if err != nil {
+				return err
+			}{{if .Prelude}}
+			{{.Prelude}}{{end}}{{if .Delimited}}
+			var end uint32
+			err = serialize.DeserializeDelimited(&end, reader)
+			if err != nil {
+				return err
+			}
+			err = {{.DeserializeMethodCall}}
+			if err != nil {
+				return err
+			}
+			err = serialize.FinishDelimited(reader, end){{else}}
+			err = {{.DeserializeMethodCall}}{{end}}{{end}}
+		default:
+			// unknown field written by a newer schema
+			err = serialize.SkipField(reader, wireKind)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }`
 
@@ -124,6 +433,7 @@ type SerializeContainerMethodArgs struct {
 	ArgType                      string
 	KeyTypeSerializeMethodCall   string
 	ValueTypeSerializeMethodCall string
+	KeySort                      string
 }
 
 type DeserializeContainerMethodArgs struct {
@@ -144,9 +454,19 @@ func {{.FullMethodName}}(arg {{.ArgType}}) int {
 	return size
 }`
 
+// a deterministic writer writes the entries of maps, and the elements of sets,
+// in ascending order of their keys
 const mapSerializeMethodTemplateStr = `
 func {{.FullMethodName}}(writer *serialize.Writer, arg {{.ArgType}}) error {
 	serialize.SerializeUInt32(writer, uint32(len(arg)))
+	if writer.Deterministic() {
+		for _, k := range serialize.Sorted{{.KeySort}}(arg) {
+			v := arg[k]
+			{{.KeyTypeSerializeMethodCall}}
+			{{.ValueTypeSerializeMethodCall}}
+		}
+		return nil
+	}
 	for k, v := range arg {
 		{{.KeyTypeSerializeMethodCall}}
 		{{.ValueTypeSerializeMethodCall}}
@@ -161,6 +481,10 @@ func {{.FullMethodName}}(arg *{{.ArgType}}, reader *serialize.Reader) error {
 	if err != nil {
 		return err
 	}
+	err = serialize.CheckEntries[{{.KeyType}}, {{.ValueType}}](reader, length)
+	if err != nil {
+		return err
+	}
 
 	// Bound the initial allocation hint against the bytes actually present so a
 	// hostile length cannot force a huge allocation before the entries are read;
@@ -213,6 +537,10 @@ func {{.FullMethodName}}(arg *{{.ArgType}}, reader *serialize.Reader) error {
 	if err != nil {
 		return err
 	}
+	err = serialize.CheckElements[{{.ValueType}}](reader, length)
+	if err != nil {
+		return err
+	}
 
 	// Bound the initial allocation against the bytes actually present so a
 	// hostile length cannot force a huge allocation before the elements are
@@ -235,18 +563,259 @@ func {{.FullMethodName}}(arg *{{.ArgType}}, reader *serialize.Reader) error {
 	return nil
 }`
 
+// arrays hold a fixed number of elements, so no length is written, and they
+// are passed by pointer to avoid copying the elements
+const arrayBitSizeMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}) int {
+	size := 0
+	for _, v := range arg {
+		size += {{.ValueTypeBitSizeMethodCall}}
+	}
+	return size
+}`
+
+const arraySerializeMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg *{{.ArgType}}) error {
+	for _, v := range arg {
+		{{.ValueTypeSerializeMethodCall}}
+	}
+	return nil
+}`
+
+const arrayDeserializeMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, reader *serialize.Reader) error {
+	for i := range arg {
+		var v {{.ValueType}}
+		err := {{.ValueTypeDeserializeMethodCall}}
+		if err != nil {
+			return err
+		}
+		arg[i] = v
+	}
+	return nil
+}`
+
+// sets are written as a list of their elements
+const setBitSizeMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) int {
+	size := serialize.BitSizeUInt32(uint32(len(arg)))
+	for k := range arg {
+		size += {{.KeyTypeBitSizeMethodCall}}
+	}
+	return size
+}`
+
+const setSerializeMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.ArgType}}) error {
+	serialize.SerializeUInt32(writer, uint32(len(arg)))
+	if writer.Deterministic() {
+		for _, k := range serialize.Sorted{{.KeySort}}(arg) {
+			{{.KeyTypeSerializeMethodCall}}
+		}
+		return nil
+	}
+	for k := range arg {
+		{{.KeyTypeSerializeMethodCall}}
+	}
+	return nil
+}`
+
+const setDeserializeMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.ArgType}}, reader *serialize.Reader) error {
+	var length uint32
+	err := serialize.DeserializeUInt32(&length, reader)
+	if err != nil {
+		return err
+	}
+	err = serialize.CheckElements[{{.KeyType}}](reader, length)
+	if err != nil {
+		return err
+	}
+
+	// Bound the initial allocation hint against the bytes actually present, as
+	// for maps.
+	capHint := int(length)
+	if rem := reader.RemainingBytes(); capHint > rem {
+		capHint = rem
+	}
+	result := make({{.ArgType}}, capHint)
+
+	for i := 0; i < int(length); i++ {
+		var k {{.KeyType}}
+		err := {{.KeyTypeDeserializeMethodCall}}
+		if err != nil {
+			return err
+		}
+		if _, ok := result[k]; ok {
+			return fmt.Errorf("set contains duplicate element %v", k)
+		}
+		result[k] = struct{}{}
+	}
+	*arg = result
+	return nil
+}`
+
+// JSONConversionMethodArgs describes a helper converting a container holding
+// durations or sets to or from its JSON representation, element by element.
+type JSONConversionMethodArgs struct {
+	FullMethodName  string
+	ArgType         string
+	ResultType      string
+	ValueConversion string
+}
+
+const listJSONConversionMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) {{.ResultType}} {
+	if arg == nil {
+		return nil
+	}
+	result := make({{.ResultType}}, len(arg))
+	for i, v := range arg {
+		result[i] = {{.ValueConversion}}
+	}
+	return result
+}`
+
+const arrayJSONConversionMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) {{.ResultType}} {
+	var result {{.ResultType}}
+	for i, v := range arg {
+		result[i] = {{.ValueConversion}}
+	}
+	return result
+}`
+
+// sets are held as maps, but are marshalled as an array of their elements
+const setToJSONConversionMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) {{.ResultType}} {
+	if arg == nil {
+		return nil
+	}
+	result := make({{.ResultType}}, 0, len(arg))
+	for k := range arg {
+		result = append(result, k)
+	}
+	return result
+}`
+
+const setFromJSONConversionMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) {{.ResultType}} {
+	if arg == nil {
+		return nil
+	}
+	result := make({{.ResultType}}, len(arg))
+	for _, v := range arg {
+		result[v] = struct{}{}
+	}
+	return result
+}`
+
+const mapJSONConversionMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.ArgType}}) {{.ResultType}} {
+	if arg == nil {
+		return nil
+	}
+	result := make({{.ResultType}}, len(arg))
+	for k, v := range arg {
+		result[k] = {{.ValueConversion}}
+	}
+	return result
+}`
+
+type OneofMemberMethodArgs struct {
+	WrapperType   string
+	Discriminator int
+	MethodCall    string
+}
+
+// OneofMethodArgs describes the helpers that (de)serialize a oneof of an
+// untagged message. The oneof is written as a discriminator, zero when unset or
+// the 1-based position of the set member in index order, followed by the value
+// of the member.
+type OneofMethodArgs struct {
+	FullMethodName string
+	OneofName      string
+	InterfaceType  string
+	Members        []OneofMemberMethodArgs
+}
+
+const oneofBitSizeMethodTemplateStr = `
+func {{.FullMethodName}}(arg {{.InterfaceType}}) int {
+	switch member := arg.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:
+		return serialize.BitSizeUInt32({{.Discriminator}}) + {{.MethodCall}}{{end}}
+	}
+	return serialize.BitSizeUInt32(0)
+}`
+
+const oneofSerializeMethodTemplateStr = `
+func {{.FullMethodName}}(writer *serialize.Writer, arg {{.InterfaceType}}) {
+	switch member := arg.(type) { {{- range .Members}}
+	case *{{.WrapperType}}:
+		serialize.SerializeUInt32(writer, {{.Discriminator}})
+		{{.MethodCall}}{{end}}
+	default:
+		serialize.SerializeUInt32(writer, 0)
+	}
+}`
+
+const oneofDeserializeMethodTemplateStr = `
+func {{.FullMethodName}}(arg *{{.InterfaceType}}, reader *serialize.Reader) error {
+	var discriminator uint32
+	err := serialize.DeserializeUInt32(&discriminator, reader)
+	if err != nil {
+		return err
+	}
+	switch discriminator {
+	case 0:
+		*arg = nil{{range .Members}}
+	case {{.Discriminator}}:
+		member := &{{.WrapperType}}{}
+		err = {{.MethodCall}}
+		if err != nil {
+			return err
+		}
+		*arg = member{{end}}
+	default:
+		return fmt.Errorf("oneof {{.OneofName}} has unrecognized discriminator %d", discriminator)
+	}
+	return nil
+}`
+
 var (
 	messageTemplate                  = template.Must(template.New("messageTemplateGo").Parse(messageTemplateStr))
 	messageBitSizeMethodTemplate     = template.Must(template.New("messageBitSizeMethodTemplateGo").Parse(messageBitSizeMethodTemplateStr))
 	messageSerializeMethodTemplate   = template.Must(template.New("messageSerializeMethodTemplateGo").Parse(messageSerializeMethodTemplateStr))
-	messageDeserializeMethodTemplate = template.Must(template.New("messageDeserializeMethodTemplateGo").Parse(messageDeserializeMethodTemplateStr))
+	messageDeserializeMethodTemplate = template.Must(template.New("messageDeserializeMethodTemplateGo").Parse(messageDeserializeMethodTemplateStr + messageDescendTemplateStr))
+	// tagged message methods
+	messageTaggedBitSizeMethodTemplate     = template.Must(template.New("messageTaggedBitSizeMethodTemplateGo").Parse(messageTaggedBitSizeMethodTemplateStr + messageTaggedFieldCountTemplateStr))
+	messageTaggedSerializeMethodTemplate   = template.Must(template.New("messageTaggedSerializeMethodTemplateGo").Parse(messageTaggedSerializeMethodTemplateStr + messageTaggedFieldCountTemplateStr))
+	messageTaggedDeserializeMethodTemplate = template.Must(template.New("messageTaggedDeserializeMethodTemplateGo").Parse(messageTaggedDeserializeMethodTemplateStr + messageDescendTemplateStr))
+	// oneof methods
+	messageOneofTypesTemplate      = template.Must(template.New("messageOneofTypesTemplateGo").Parse(messageOneofTypesTemplateStr))
+	messageJSONMethodsTemplate     = template.Must(template.New("messageJSONMethodsTemplateGo").Parse(messageJSONMethodsTemplateStr))
+	oneofBitSizeMethodTemplate     = template.Must(template.New("oneofBitSizeMethodTemplateGo").Parse(oneofBitSizeMethodTemplateStr))
+	oneofSerializeMethodTemplate   = template.Must(template.New("oneofSerializeMethodTemplateGo").Parse(oneofSerializeMethodTemplateStr))
+	oneofDeserializeMethodTemplate = template.Must(template.New("oneofDeserializeMethodTemplateGo").Parse(oneofDeserializeMethodTemplateStr))
 	// container methods
-	mapBitSizeMethodTemplate      = template.Must(template.New("mapBitSizeMethodTemplateGo").Parse(mapBitSizeMethodTemplateStr))
-	listBitSizeMethodTemplate     = template.Must(template.New("listBitSizeMethodTemplateGo").Parse(listBitSizeMethodTemplateStr))
-	mapSerializeMethodTemplate    = template.Must(template.New("mapSerializeMethodTemplateGo").Parse(mapSerializeMethodTemplateStr))
-	listSerializeMethodTemplate   = template.Must(template.New("listSerializeMethodTemplateGo").Parse(listSerializeMethodTemplateStr))
-	mapDeserializeMethodTemplate  = template.Must(template.New("mapDeserializeMethodTemplateGo").Parse(mapDeserializeMethodTemplateStr))
-	listDeserializeMethodTemplate = template.Must(template.New("listDeserializeMethodTemplateGo").Parse(listDeserializeMethodTemplateStr))
+	mapBitSizeMethodTemplate       = template.Must(template.New("mapBitSizeMethodTemplateGo").Parse(mapBitSizeMethodTemplateStr))
+	listBitSizeMethodTemplate      = template.Must(template.New("listBitSizeMethodTemplateGo").Parse(listBitSizeMethodTemplateStr))
+	mapSerializeMethodTemplate     = template.Must(template.New("mapSerializeMethodTemplateGo").Parse(mapSerializeMethodTemplateStr))
+	listSerializeMethodTemplate    = template.Must(template.New("listSerializeMethodTemplateGo").Parse(listSerializeMethodTemplateStr))
+	mapDeserializeMethodTemplate   = template.Must(template.New("mapDeserializeMethodTemplateGo").Parse(mapDeserializeMethodTemplateStr))
+	listDeserializeMethodTemplate  = template.Must(template.New("listDeserializeMethodTemplateGo").Parse(listDeserializeMethodTemplateStr))
+	arrayBitSizeMethodTemplate     = template.Must(template.New("arrayBitSizeMethodTemplateGo").Parse(arrayBitSizeMethodTemplateStr))
+	arraySerializeMethodTemplate   = template.Must(template.New("arraySerializeMethodTemplateGo").Parse(arraySerializeMethodTemplateStr))
+	arrayDeserializeMethodTemplate = template.Must(template.New("arrayDeserializeMethodTemplateGo").Parse(arrayDeserializeMethodTemplateStr))
+	setBitSizeMethodTemplate       = template.Must(template.New("setBitSizeMethodTemplateGo").Parse(setBitSizeMethodTemplateStr))
+	setSerializeMethodTemplate     = template.Must(template.New("setSerializeMethodTemplateGo").Parse(setSerializeMethodTemplateStr))
+	setDeserializeMethodTemplate   = template.Must(template.New("setDeserializeMethodTemplateGo").Parse(setDeserializeMethodTemplateStr))
+	// json conversion methods
+	listJSONConversionMethodTemplate    = template.Must(template.New("listJSONConversionMethodTemplateGo").Parse(listJSONConversionMethodTemplateStr))
+	arrayJSONConversionMethodTemplate   = template.Must(template.New("arrayJSONConversionMethodTemplateGo").Parse(arrayJSONConversionMethodTemplateStr))
+	mapJSONConversionMethodTemplate     = template.Must(template.New("mapJSONConversionMethodTemplateGo").Parse(mapJSONConversionMethodTemplateStr))
+	setToJSONConversionMethodTemplate   = template.Must(template.New("setToJSONConversionMethodTemplateGo").Parse(setToJSONConversionMethodTemplateStr))
+	setFromJSONConversionMethodTemplate = template.Must(template.New("setFromJSONConversionMethodTemplateGo").Parse(setFromJSONConversionMethodTemplateStr))
 )
 
 func mapDataTypeToGoType(dataType parse.DataType) (string, error) {
@@ -273,8 +842,12 @@ func mapDataTypeToGoType(dataType parse.DataType) (string, error) {
 		return "int64", nil
 	case parse.DataTypeString:
 		return "string", nil
+	case parse.DataTypeBytes:
+		return "[]byte", nil
 	case parse.DataTypeTimestamp:
 		return "time.Time", nil
+	case parse.DataTypeDuration:
+		return "time.Duration", nil
 	case parse.DataTypeUUID:
 		return "uuid.UUID", nil
 	case parse.DataTypeFloat32:
@@ -285,9 +858,45 @@ func mapDataTypeToGoType(dataType parse.DataType) (string, error) {
 	return "", fmt.Errorf("unrecognized type: %v", dataType)
 }
 
+// mapBitIntToGoType returns the smallest native integer type holding a uint<N>
+// or int<N>.
+func mapBitIntToGoType(signed bool, numBits uint32) string {
+	size := 64
+	switch {
+	case numBits <= 8:
+		size = 8
+	case numBits <= 16:
+		size = 16
+	case numBits <= 32:
+		size = 32
+	}
+	if signed {
+		return fmt.Sprintf("int%d", size)
+	}
+	return fmt.Sprintf("uint%d", size)
+}
+
+// formatFloatLiteral formats a float as the shortest Go literal that parses back
+// to the same value.
+func formatFloatLiteral(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// formatFloatIdentifier formats a float for use within an identifier, for
+// example -0.5 becomes Neg0p5.
+func formatFloatIdentifier(val float64) string {
+	return strings.NewReplacer("-", "Neg", "+", "", ".", "p").Replace(formatFloatLiteral(val))
+}
+
 func mapDataTypeDefinitionToGoType(dataType *parse.DataTypeDefinition) (string, error) {
 
 	switch dataType.Type {
+	case parse.DataTypeUIntN:
+		return mapBitIntToGoType(false, dataType.Bits), nil
+	case parse.DataTypeIntN:
+		return mapBitIntToGoType(true, dataType.Bits), nil
+	case parse.DataTypeQuant:
+		return mapDataTypeDefinitionToGoType(dataType.SubType)
 	case parse.DataTypeMap:
 		key, err := mapDataTypeComparableDefinitionToGoType(dataType.Key)
 		if err != nil {
@@ -305,6 +914,19 @@ func mapDataTypeDefinitionToGoType(dataType *parse.DataTypeDefinition) (string,
 			return "", err
 		}
 		return fmt.Sprintf("[]%s", subtype), nil
+	case parse.DataTypeArray:
+
+		subtype, err := mapDataTypeDefinitionToGoType(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", dataType.Length, subtype), nil
+	case parse.DataTypeSet:
+		key, err := mapDataTypeComparableDefinitionToGoType(dataType.Key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("map[%s]struct{}", key), nil
 	case parse.DataTypeCustom:
 		if dataType.ImportedFromOtherPackage {
 			return fmt.Sprintf("%s.%s", convertPackageNameToGoPackage(dataType.CustomTypePackage), util.EnsurePascalCase(dataType.CustomType)), nil
@@ -360,6 +982,9 @@ func mapDataTypeComparableDefinitionToGoType(dataType *parse.DataTypeComparableD
 
 type FunctionCallArgs struct {
 	VariableName string
+	NumBits      uint32
+	QuantMin     string
+	QuantMax     string
 }
 
 const (
@@ -374,10 +999,15 @@ const (
 	byteSizeInt32TemplateStr     = `serialize.BitSizeInt32({{.VariableName}})`
 	byteSizeUInt64TemplateStr    = `serialize.BitSizeUInt64({{.VariableName}})`
 	byteSizeInt64TemplateStr     = `serialize.BitSizeInt64({{.VariableName}})`
+	byteSizeUIntNTemplateStr     = `serialize.BitSizeUIntN({{.VariableName}}, {{.NumBits}})`
+	byteSizeIntNTemplateStr      = `serialize.BitSizeIntN({{.VariableName}}, {{.NumBits}})`
+	byteSizeQuantTemplateStr     = `serialize.BitSizeQuant({{.VariableName}}, {{.QuantMin}}, {{.QuantMax}}, {{.NumBits}})`
 	byteSizeFloat32TemplateStr   = `serialize.BitSizeFloat32({{.VariableName}})`
 	byteSizeFloat64TemplateStr   = `serialize.BitSizeFloat64({{.VariableName}})`
 	byteSizeStringTemplateStr    = `serialize.BitSizeString({{.VariableName}})`
+	byteSizeBytesTemplateStr     = `serialize.BitSizeBytes({{.VariableName}})`
 	byteSizeTimestampTemplateStr = `serialize.BitSizeTime({{.VariableName}})`
+	byteSizeDurationTemplateStr  = `serialize.BitSizeDuration({{.VariableName}})`
 	byteSizeUUIDTemplateStr      = `serialize.BitSizeUUID({{.VariableName}})`
 	byteSizeCustomTemplateStr    = `{{.VariableName}}.BitSize()`
 	// serialization templates
@@ -391,10 +1021,15 @@ const (
 	serializeInt32TemplateStr     = `serialize.SerializeInt32(writer, {{.VariableName}})`
 	serializeUInt64TemplateStr    = `serialize.SerializeUInt64(writer, {{.VariableName}})`
 	serializeInt64TemplateStr     = `serialize.SerializeInt64(writer, {{.VariableName}})`
+	serializeUIntNTemplateStr     = `serialize.SerializeUIntN(writer, {{.VariableName}}, {{.NumBits}})`
+	serializeIntNTemplateStr      = `serialize.SerializeIntN(writer, {{.VariableName}}, {{.NumBits}})`
+	serializeQuantTemplateStr     = `serialize.SerializeQuant(writer, {{.VariableName}}, {{.QuantMin}}, {{.QuantMax}}, {{.NumBits}})`
 	serializeFloat32TemplateStr   = `serialize.SerializeFloat32(writer, {{.VariableName}})`
 	serializeFloat64TemplateStr   = `serialize.SerializeFloat64(writer, {{.VariableName}})`
 	serializeStringTemplateStr    = `serialize.SerializeString(writer, {{.VariableName}})`
+	serializeBytesTemplateStr     = `serialize.SerializeBytes(writer, {{.VariableName}})`
 	serializeTimestampTemplateStr = `serialize.SerializeTime(writer, {{.VariableName}})`
+	serializeDurationTemplateStr  = `serialize.SerializeDuration(writer, {{.VariableName}})`
 	serializeUUIDTemplateStr      = `serialize.SerializeUUID(writer, {{.VariableName}})`
 	serializeCustomTemplateStr    = `{{.VariableName}}.Serialize(writer)`
 	// deserialization templates
@@ -408,10 +1043,15 @@ const (
 	deserializeInt32TemplateStr     = `serialize.DeserializeInt32(&{{.VariableName}}, reader)`
 	deserializeUInt64TemplateStr    = `serialize.DeserializeUInt64(&{{.VariableName}}, reader)`
 	deserializeInt64TemplateStr     = `serialize.DeserializeInt64(&{{.VariableName}}, reader)`
+	deserializeUIntNTemplateStr     = `serialize.DeserializeUIntN(&{{.VariableName}}, reader, {{.NumBits}})`
+	deserializeIntNTemplateStr      = `serialize.DeserializeIntN(&{{.VariableName}}, reader, {{.NumBits}})`
+	deserializeQuantTemplateStr     = `serialize.DeserializeQuant(&{{.VariableName}}, reader, {{.QuantMin}}, {{.QuantMax}}, {{.NumBits}})`
 	deserializeFloat32TemplateStr   = `serialize.DeserializeFloat32(&{{.VariableName}}, reader)`
 	deserializeFloat64TemplateStr   = `serialize.DeserializeFloat64(&{{.VariableName}}, reader)`
 	deserializeStringTemplateStr    = `serialize.DeserializeString(&{{.VariableName}}, reader)`
+	deserializeBytesTemplateStr     = `serialize.DeserializeBytes(&{{.VariableName}}, reader)`
 	deserializeTimestampTemplateStr = `serialize.DeserializeTime(&{{.VariableName}}, reader)`
+	deserializeDurationTemplateStr  = `serialize.DeserializeDuration(&{{.VariableName}}, reader)`
 	deserializeUUIDTemplateStr      = `serialize.DeserializeUUID(&{{.VariableName}}, reader)`
 	deserializeCustomTemplateStr    = `{{.VariableName}}.Deserialize(reader)`
 )
@@ -428,10 +1068,15 @@ var (
 	byteSizeInt32Template     = template.Must(template.New("byteSizeInt32TemplateGo").Parse(byteSizeInt32TemplateStr))
 	byteSizeUInt64Template    = template.Must(template.New("byteSizeUInt64TemplateGo").Parse(byteSizeUInt64TemplateStr))
 	byteSizeInt64Template     = template.Must(template.New("byteSizeInt64TemplateGo").Parse(byteSizeInt64TemplateStr))
+	byteSizeUIntNTemplate     = template.Must(template.New("byteSizeUIntNTemplateGo").Parse(byteSizeUIntNTemplateStr))
+	byteSizeIntNTemplate      = template.Must(template.New("byteSizeIntNTemplateGo").Parse(byteSizeIntNTemplateStr))
+	byteSizeQuantTemplate     = template.Must(template.New("byteSizeQuantTemplateGo").Parse(byteSizeQuantTemplateStr))
 	byteSizeFloat32Template   = template.Must(template.New("byteSizeFloat32TemplateGo").Parse(byteSizeFloat32TemplateStr))
 	byteSizeFloat64Template   = template.Must(template.New("byteSizeFloat64TemplateGo").Parse(byteSizeFloat64TemplateStr))
 	byteSizeStringTemplate    = template.Must(template.New("byteSizeStringTemplateGo").Parse(byteSizeStringTemplateStr))
+	byteSizeBytesTemplate     = template.Must(template.New("byteSizeBytesTemplateGo").Parse(byteSizeBytesTemplateStr))
 	byteSizeTimestampTemplate = template.Must(template.New("byteSizeTimestampTemplateGo").Parse(byteSizeTimestampTemplateStr))
+	byteSizeDurationTemplate  = template.Must(template.New("byteSizeDurationTemplateGo").Parse(byteSizeDurationTemplateStr))
 	byteSizeUUIDTemplate      = template.Must(template.New("byteSizeUUIDTemplateGo").Parse(byteSizeUUIDTemplateStr))
 	byteSizeCustomTemplate    = template.Must(template.New("byteSizeCustomTemplateGo").Parse(byteSizeCustomTemplateStr))
 	// serialization templates
@@ -445,10 +1090,15 @@ var (
 	serializeInt32Template     = template.Must(template.New("serializeInt32TemplateGo").Parse(serializeInt32TemplateStr))
 	serializeUInt64Template    = template.Must(template.New("serializeUInt64TemplateGo").Parse(serializeUInt64TemplateStr))
 	serializeInt64Template     = template.Must(template.New("serializeInt64TemplateGo").Parse(serializeInt64TemplateStr))
+	serializeUIntNTemplate     = template.Must(template.New("serializeUIntNTemplateGo").Parse(serializeUIntNTemplateStr))
+	serializeIntNTemplate      = template.Must(template.New("serializeIntNTemplateGo").Parse(serializeIntNTemplateStr))
+	serializeQuantTemplate     = template.Must(template.New("serializeQuantTemplateGo").Parse(serializeQuantTemplateStr))
 	serializeFloat32Template   = template.Must(template.New("serializeFloat32TemplateGo").Parse(serializeFloat32TemplateStr))
 	serializeFloat64Template   = template.Must(template.New("serializeFloat64TemplateGo").Parse(serializeFloat64TemplateStr))
 	serializeStringTemplate    = template.Must(template.New("serializeStringTemplateGo").Parse(serializeStringTemplateStr))
+	serializeBytesTemplate     = template.Must(template.New("serializeBytesTemplateGo").Parse(serializeBytesTemplateStr))
 	serializeTimestampTemplate = template.Must(template.New("serializeTimestampTemplateGo").Parse(serializeTimestampTemplateStr))
+	serializeDurationTemplate  = template.Must(template.New("serializeDurationTemplateGo").Parse(serializeDurationTemplateStr))
 	serializeUUIDTemplate      = template.Must(template.New("serializeUUIDTemplateGo").Parse(serializeUUIDTemplateStr))
 	serializeCustomTemplate    = template.Must(template.New("serializeCustomTemplateGo").Parse(serializeCustomTemplateStr))
 	// deserialization templates
@@ -462,10 +1112,15 @@ var (
 	deserializeInt32Template     = template.Must(template.New("deserializeInt32TemplateGo").Parse(deserializeInt32TemplateStr))
 	deserializeUInt64Template    = template.Must(template.New("deserializeUInt64TemplateGo").Parse(deserializeUInt64TemplateStr))
 	deserializeInt64Template     = template.Must(template.New("deserializeInt64TemplateGo").Parse(deserializeInt64TemplateStr))
+	deserializeUIntNTemplate     = template.Must(template.New("deserializeUIntNTemplateGo").Parse(deserializeUIntNTemplateStr))
+	deserializeIntNTemplate      = template.Must(template.New("deserializeIntNTemplateGo").Parse(deserializeIntNTemplateStr))
+	deserializeQuantTemplate     = template.Must(template.New("deserializeQuantTemplateGo").Parse(deserializeQuantTemplateStr))
 	deserializeFloat32Template   = template.Must(template.New("deserializeFloat32TemplateGo").Parse(deserializeFloat32TemplateStr))
 	deserializeFloat64Template   = template.Must(template.New("deserializeFloat64TemplateGo").Parse(deserializeFloat64TemplateStr))
 	deserializeStringTemplate    = template.Must(template.New("deserializeStringTemplateGo").Parse(deserializeStringTemplateStr))
+	deserializeBytesTemplate     = template.Must(template.New("deserializeBytesTemplateGo").Parse(deserializeBytesTemplateStr))
 	deserializeTimestampTemplate = template.Must(template.New("deserializeTimestampTemplateGo").Parse(deserializeTimestampTemplateStr))
+	deserializeDurationTemplate  = template.Must(template.New("deserializeDurationTemplateGo").Parse(deserializeDurationTemplateStr))
 	deserializeUUIDTemplate      = template.Must(template.New("deserializeUUIDTemplateGo").Parse(deserializeUUIDTemplateStr))
 	deserializeCustomTemplate    = template.Must(template.New("deserializeCustomTemplateGo").Parse(deserializeCustomTemplateStr))
 )
@@ -535,8 +1190,12 @@ func getDataTypeMethodSuffix(dataType parse.DataType) (string, error) {
 		return "Int64", nil
 	case parse.DataTypeString:
 		return "String", nil
+	case parse.DataTypeBytes:
+		return "Bytes", nil
 	case parse.DataTypeTimestamp:
 		return "Time", nil
+	case parse.DataTypeDuration:
+		return "Duration", nil
 	case parse.DataTypeFloat32:
 		return "Float32", nil
 	case parse.DataTypeFloat64:
@@ -568,6 +1227,38 @@ func getDataTypeDefinitionMethodSuffix(dataType *parse.DataTypeDefinition) (stri
 		}
 		return fmt.Sprintf("List%s", subNames), nil
 
+	case parse.DataTypeArray:
+
+		subNames, err := getDataTypeDefinitionMethodSuffix(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Array%d%s", dataType.Length, subNames), nil
+
+	case parse.DataTypeSet:
+
+		key, err := getDataTypeComparableDefinitionMethodSuffix(dataType.Key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Set%s", key), nil
+
+	case parse.DataTypeUIntN:
+		return fmt.Sprintf("UInt%dBits", dataType.Bits), nil
+
+	case parse.DataTypeIntN:
+		return fmt.Sprintf("Int%dBits", dataType.Bits), nil
+
+	case parse.DataTypeQuant:
+		subNames, err := getDataTypeMethodSuffix(dataType.SubType.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Quant%s%sTo%sBits%d", subNames,
+			formatFloatIdentifier(dataType.QuantMin),
+			formatFloatIdentifier(dataType.QuantMax),
+			dataType.Bits), nil
+
 	case parse.DataTypeCustom:
 		if dataType.ImportedFromOtherPackage {
 			return fmt.Sprintf("%sPkg%s", util.EnsurePascalCase(dataType.CustomTypePackage), util.EnsurePascalCase(dataType.CustomType)), nil
@@ -595,9 +1286,14 @@ func generateBitSizeContainerMethod(messageName string, varName string, dataType
 		return "", nil, err
 	}
 
-	valueMethodCall, methodCode, err := generateFieldBitSizeMethodCall(messageName, "v", dataType.SubType)
-	if err != nil {
-		return "", nil, err
+	// sets have no values, only keys
+	valueMethodCall := ""
+	methodCode := map[string]string{}
+	if dataType.SubType != nil {
+		valueMethodCall, methodCode, err = generateFieldBitSizeMethodCall(messageName, "v", dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	args := BitSizeContainerMethodArgs{
@@ -616,6 +1312,15 @@ func generateBitSizeContainerMethod(messageName string, varName string, dataType
 			return "", nil, err
 		}
 
+	} else if dataType.Type == parse.DataTypeArray {
+
+		// array
+
+		err = arrayBitSizeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
 	} else if dataType.Type == parse.DataTypeMap {
 
 		// map
@@ -631,14 +1336,32 @@ func generateBitSizeContainerMethod(messageName string, varName string, dataType
 			return "", nil, err
 		}
 
-	} else {
-		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
-	}
+	} else if dataType.Type == parse.DataTypeSet {
 
-	serializationMethodCode[methodFullName] = buf.String()
+		// set
+
+		keyMethodCall, err := generateKeyFieldBitSizeMethodCall("k", dataType.Key.Type)
+		if err != nil {
+			return "", nil, err
+		}
+		args.KeyTypeBitSizeMethodCall = keyMethodCall
+
+		err = setBitSizeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
+	} else {
+		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
+	}
+
+	serializationMethodCode[methodFullName] = buf.String()
 	serializationMethodCode = util.MergeMap(serializationMethodCode, methodCode)
 
 	fullMethodCall := fmt.Sprintf("%s(%s)", methodFullName, varName)
+	if dataType.Type == parse.DataTypeArray {
+		fullMethodCall = fmt.Sprintf("%s(&%s)", methodFullName, varName)
+	}
 
 	return fullMethodCall, serializationMethodCode, nil
 }
@@ -659,9 +1382,14 @@ func generateSerializeContainerMethod(messageName string, varName string, dataTy
 		return "", nil, err
 	}
 
-	valueMethodCall, methodCode, err := generateFieldSerializationMethodCall(messageName, "v", dataType.SubType)
-	if err != nil {
-		return "", nil, err
+	// sets have no values, only keys
+	valueMethodCall := ""
+	methodCode := map[string]string{}
+	if dataType.SubType != nil {
+		valueMethodCall, methodCode, err = generateFieldSerializationMethodCall(messageName, "v", dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	args := SerializeContainerMethodArgs{
@@ -680,6 +1408,15 @@ func generateSerializeContainerMethod(messageName string, varName string, dataTy
 			return "", nil, err
 		}
 
+	} else if dataType.Type == parse.DataTypeArray {
+
+		// array
+
+		err = arraySerializeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
 	} else if dataType.Type == parse.DataTypeMap {
 
 		// map
@@ -689,12 +1426,29 @@ func generateSerializeContainerMethod(messageName string, varName string, dataTy
 			return "", nil, err
 		}
 		args.KeyTypeSerializeMethodCall = keyMethodCall
+		args.KeySort = getKeySortSuffix(dataType.Key)
 
 		err = mapSerializeMethodTemplate.Execute(buf, args)
 		if err != nil {
 			return "", nil, err
 		}
 
+	} else if dataType.Type == parse.DataTypeSet {
+
+		// set
+
+		keyMethodCall, err := generateKeyFieldSerializationMethodCall("k", dataType.Key.Type)
+		if err != nil {
+			return "", nil, err
+		}
+		args.KeyTypeSerializeMethodCall = keyMethodCall
+		args.KeySort = getKeySortSuffix(dataType.Key)
+
+		err = setSerializeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
 	} else {
 		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
 	}
@@ -703,10 +1457,22 @@ func generateSerializeContainerMethod(messageName string, varName string, dataTy
 	serializationMethodCode = util.MergeMap(serializationMethodCode, methodCode)
 
 	fullMethodCall := fmt.Sprintf("%s(writer, %s)", methodFullName, varName)
+	if dataType.Type == parse.DataTypeArray {
+		fullMethodCall = fmt.Sprintf("%s(writer, &%s)", methodFullName, varName)
+	}
 
 	return fullMethodCall, serializationMethodCode, nil
 }
 
+// getKeySortSuffix returns the suffix of the serialize functions sorting keys of
+// the type, as uuids are byte arrays rather than ordered types.
+func getKeySortSuffix(key *parse.DataTypeComparableDefinition) string {
+	if key.Type == parse.DataTypeComparableUUID || key.CustomTypeIsUUID {
+		return "UUIDKeys"
+	}
+	return "Keys"
+}
+
 func generateDeserializeContainerMethod(messageName string, varName string, dataType *parse.DataTypeDefinition) (string, map[string]string, error) {
 
 	fullTypeName, err := getDataTypeDefinitionMethodSuffix(dataType)
@@ -723,14 +1489,22 @@ func generateDeserializeContainerMethod(messageName string, varName string, data
 		return "", nil, err
 	}
 
-	valueMethodCall, methodCode, err := generateFieldDeserializationMethodCall(messageName, "v", dataType.SubType)
-	if err != nil {
-		return "", nil, err
+	// sets have no values, only keys
+	valueMethodCall := ""
+	methodCode := map[string]string{}
+	if dataType.SubType != nil {
+		valueMethodCall, methodCode, err = generateFieldDeserializationMethodCall(messageName, "v", dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
-	valueType, err := mapDataTypeDefinitionToGoType(dataType.SubType)
-	if err != nil {
-		return "", nil, err
+	valueType := ""
+	if dataType.SubType != nil {
+		valueType, err = mapDataTypeDefinitionToGoType(dataType.SubType)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	args := DeserializeContainerMethodArgs{
@@ -750,6 +1524,15 @@ func generateDeserializeContainerMethod(messageName string, varName string, data
 			return "", nil, err
 		}
 
+	} else if dataType.Type == parse.DataTypeArray {
+
+		// array
+
+		err = arrayDeserializeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
 	} else if dataType.Type == parse.DataTypeMap {
 
 		// map
@@ -771,6 +1554,27 @@ func generateDeserializeContainerMethod(messageName string, varName string, data
 			return "", nil, err
 		}
 
+	} else if dataType.Type == parse.DataTypeSet {
+
+		// set
+
+		keyType, err := mapDataTypeComparableDefinitionToGoType(dataType.Key)
+		if err != nil {
+			return "", nil, err
+		}
+
+		keyMethodCall, err := generateKeyFieldDeserializationMethodCall("k", dataType.Key.Type)
+		if err != nil {
+			return "", nil, err
+		}
+		args.KeyTypeDeserializeMethodCall = keyMethodCall
+		args.KeyType = keyType
+
+		err = setDeserializeMethodTemplate.Execute(buf, args)
+		if err != nil {
+			return "", nil, err
+		}
+
 	} else {
 		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
 	}
@@ -956,20 +1760,37 @@ func generateFieldBitSizeMethodCall(messageName string, varName string, dataType
 		tmpl = byteSizeInt32Template
 	case parse.DataTypeInt64:
 		tmpl = byteSizeInt64Template
+	case parse.DataTypeUIntN:
+		tmpl = byteSizeUIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeIntN:
+		tmpl = byteSizeIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeQuant:
+		tmpl = byteSizeQuantTemplate
+		args.NumBits = dataType.Bits
+		args.QuantMin = formatFloatLiteral(dataType.QuantMin)
+		args.QuantMax = formatFloatLiteral(dataType.QuantMax)
 	case parse.DataTypeFloat32:
 		tmpl = byteSizeFloat32Template
 	case parse.DataTypeFloat64:
 		tmpl = byteSizeFloat64Template
 	case parse.DataTypeString:
 		tmpl = byteSizeStringTemplate
+	case parse.DataTypeBytes:
+		tmpl = byteSizeBytesTemplate
 	case parse.DataTypeTimestamp:
 		tmpl = byteSizeTimestampTemplate
+	case parse.DataTypeDuration:
+		tmpl = byteSizeDurationTemplate
 	case parse.DataTypeUUID:
 		tmpl = byteSizeUUIDTemplate
 	case parse.DataTypeCustom:
 		tmpl = byteSizeCustomTemplate
 	case parse.DataTypeMap,
-		parse.DataTypeList:
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet:
 		// special case for containers
 		return generateBitSizeContainerMethod(messageName, varName, dataType)
 
@@ -1015,20 +1836,37 @@ func generateFieldSerializationMethodCall(messageName string, varName string, da
 		tmpl = serializeInt32Template
 	case parse.DataTypeInt64:
 		tmpl = serializeInt64Template
+	case parse.DataTypeUIntN:
+		tmpl = serializeUIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeIntN:
+		tmpl = serializeIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeQuant:
+		tmpl = serializeQuantTemplate
+		args.NumBits = dataType.Bits
+		args.QuantMin = formatFloatLiteral(dataType.QuantMin)
+		args.QuantMax = formatFloatLiteral(dataType.QuantMax)
 	case parse.DataTypeFloat32:
 		tmpl = serializeFloat32Template
 	case parse.DataTypeFloat64:
 		tmpl = serializeFloat64Template
 	case parse.DataTypeString:
 		tmpl = serializeStringTemplate
+	case parse.DataTypeBytes:
+		tmpl = serializeBytesTemplate
 	case parse.DataTypeTimestamp:
 		tmpl = serializeTimestampTemplate
+	case parse.DataTypeDuration:
+		tmpl = serializeDurationTemplate
 	case parse.DataTypeUUID:
 		tmpl = serializeUUIDTemplate
 	case parse.DataTypeCustom:
 		tmpl = serializeCustomTemplate
 	case parse.DataTypeMap,
-		parse.DataTypeList:
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet:
 		// special case for containers
 		return generateSerializeContainerMethod(messageName, varName, dataType)
 
@@ -1074,20 +1912,37 @@ func generateFieldDeserializationMethodCall(messageName string, varName string,
 		tmpl = deserializeInt32Template
 	case parse.DataTypeInt64:
 		tmpl = deserializeInt64Template
+	case parse.DataTypeUIntN:
+		tmpl = deserializeUIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeIntN:
+		tmpl = deserializeIntNTemplate
+		args.NumBits = dataType.Bits
+	case parse.DataTypeQuant:
+		tmpl = deserializeQuantTemplate
+		args.NumBits = dataType.Bits
+		args.QuantMin = formatFloatLiteral(dataType.QuantMin)
+		args.QuantMax = formatFloatLiteral(dataType.QuantMax)
 	case parse.DataTypeFloat32:
 		tmpl = deserializeFloat32Template
 	case parse.DataTypeFloat64:
 		tmpl = deserializeFloat64Template
 	case parse.DataTypeString:
 		tmpl = deserializeStringTemplate
+	case parse.DataTypeBytes:
+		tmpl = deserializeBytesTemplate
 	case parse.DataTypeTimestamp:
 		tmpl = deserializeTimestampTemplate
+	case parse.DataTypeDuration:
+		tmpl = deserializeDurationTemplate
 	case parse.DataTypeUUID:
 		tmpl = deserializeUUIDTemplate
 	case parse.DataTypeCustom:
 		tmpl = deserializeCustomTemplate
 	case parse.DataTypeMap,
-		parse.DataTypeList:
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet:
 		// special case for containers
 		return generateDeserializeContainerMethod(messageName, varName, dataType)
 
@@ -1120,6 +1975,100 @@ func valuesSortedByKey(m map[string]string) []string {
 	return values
 }
 
+func getFieldMethodCallArgs(msg *parse.MessageDefinition, field *parse.MessageFieldDefinition) (FieldMethodCallArgs, error) {
+	goType, err := mapDataTypeDefinitionToGoType(field.DataTypeDefinition)
+	if err != nil {
+		return FieldMethodCallArgs{}, err
+	}
+	return FieldMethodCallArgs{
+		FieldName:   fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), util.EnsurePascalCase(field.Name)),
+		ElementType: goType,
+		Optional:    field.Optional,
+	}, nil
+}
+
+// fieldValueName returns the expression holding the value of the field, which
+// for optional fields is the dereferenced pointer.
+func fieldValueName(args FieldMethodCallArgs) string {
+	if args.Optional {
+		return fmt.Sprintf("(*%s)", args.FieldName)
+	}
+	return args.FieldName
+}
+
+func oneofInterfaceType(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) string {
+	return fmt.Sprintf("%s_%s", util.EnsurePascalCase(msg.Name), util.EnsurePascalCase(oneof.Name))
+}
+
+func oneofWrapperType(msg *parse.MessageDefinition, field *parse.MessageFieldDefinition) string {
+	return fmt.Sprintf("%s_%s", oneofInterfaceType(msg, msg.Oneofs[field.Oneof]), util.EnsurePascalCase(field.Name))
+}
+
+func oneofFieldName(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) string {
+	return fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), util.EnsurePascalCase(oneof.Name))
+}
+
+func generateOneofMethod(msg *parse.MessageDefinition, oneof *parse.OneofDefinition, method string, tmpl *template.Template, generateFieldMethodCall func(string, string, *parse.DataTypeDefinition) (string, map[string]string, error)) (string, map[string]string, error) {
+
+	methodFullName := fmt.Sprintf("%s_%sOneof%s", util.EnsureCamelCase(msg.Name), method, util.EnsurePascalCase(oneof.Name))
+
+	args := OneofMethodArgs{
+		FullMethodName: methodFullName,
+		OneofName:      oneof.Name,
+		InterfaceType:  oneofInterfaceType(msg, oneof),
+	}
+
+	methodCode := map[string]string{}
+
+	for i, field := range msg.OneofFieldsByIndex(oneof.Name) {
+		methodCall, code, err := generateFieldMethodCall(msg.Name, fmt.Sprintf("member.%s", util.EnsurePascalCase(field.Name)), field.DataTypeDefinition)
+		if err != nil {
+			return "", nil, err
+		}
+		methodCode = util.MergeMap(methodCode, code)
+
+		args.Members = append(args.Members, OneofMemberMethodArgs{
+			WrapperType:   oneofWrapperType(msg, field),
+			Discriminator: i + 1,
+			MethodCall:    methodCall,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	methodCode[methodFullName] = buf.String()
+
+	return methodFullName, methodCode, nil
+}
+
+func generateOneofBitSizeMethod(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) (string, map[string]string, error) {
+	methodName, methodCode, err := generateOneofMethod(msg, oneof, "BitSize", oneofBitSizeMethodTemplate, generateFieldBitSizeMethodCall)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s(%s)", methodName, oneofFieldName(msg, oneof)), methodCode, nil
+}
+
+func generateOneofSerializeMethod(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) (string, map[string]string, error) {
+	methodName, methodCode, err := generateOneofMethod(msg, oneof, "Serialize", oneofSerializeMethodTemplate, generateFieldSerializationMethodCall)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s(writer, %s)", methodName, oneofFieldName(msg, oneof)), methodCode, nil
+}
+
+func generateOneofDeserializeMethod(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) (string, map[string]string, error) {
+	methodName, methodCode, err := generateOneofMethod(msg, oneof, "Deserialize", oneofDeserializeMethodTemplate, generateFieldDeserializationMethodCall)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s(&%s, reader)", methodName, oneofFieldName(msg, oneof)), methodCode, nil
+}
+
 func generateMessageBitSizeMethod(msg *parse.MessageDefinition) (string, error) {
 	args := BitSizeMethodArgs{
 		MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
@@ -1130,14 +2079,36 @@ func generateMessageBitSizeMethod(msg *parse.MessageDefinition) (string, error)
 
 	for _, field := range msg.FieldsByIndex() {
 
-		fieldName := fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), util.EnsurePascalCase(field.Name))
+		if field.Oneof != "" {
+			// the oneof as a whole is encoded at the position of its first member
+			if !msg.IsFirstOneofField(field) {
+				continue
+			}
+			oneof := msg.Oneofs[field.Oneof]
+			methodCall, methodCode, err := generateOneofBitSizeMethod(msg, oneof)
+			if err != nil {
+				return "", err
+			}
+			additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+			args.FieldBitSizeMethodCalls = append(args.FieldBitSizeMethodCalls, FieldMethodCallArgs{
+				FieldName:  oneofFieldName(msg, oneof),
+				MethodCall: methodCall,
+			})
+			continue
+		}
 
-		fieldBitSizeMethodCall, methodCode, err := generateFieldBitSizeMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		callArgs, err := getFieldMethodCallArgs(msg, field)
+		if err != nil {
+			return "", err
+		}
+
+		fieldBitSizeMethodCall, methodCode, err := generateFieldBitSizeMethodCall(msg.Name, fieldValueName(callArgs), field.DataTypeDefinition)
 		if err != nil {
 			return "", err
 		}
 		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
-		args.FieldBitSizeMethodCalls = append(args.FieldBitSizeMethodCalls, fieldBitSizeMethodCall)
+		callArgs.MethodCall = fieldBitSizeMethodCall
+		args.FieldBitSizeMethodCalls = append(args.FieldBitSizeMethodCalls, callArgs)
 	}
 
 	buf := &bytes.Buffer{}
@@ -1163,14 +2134,36 @@ func generateMessageSerializationMethod(msg *parse.MessageDefinition) (string, e
 
 	for _, field := range msg.FieldsByIndex() {
 
-		fieldName := fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), util.EnsurePascalCase(field.Name))
+		if field.Oneof != "" {
+			// the oneof as a whole is encoded at the position of its first member
+			if !msg.IsFirstOneofField(field) {
+				continue
+			}
+			oneof := msg.Oneofs[field.Oneof]
+			methodCall, methodCode, err := generateOneofSerializeMethod(msg, oneof)
+			if err != nil {
+				return "", err
+			}
+			additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+			args.FieldSerializeMethodCalls = append(args.FieldSerializeMethodCalls, FieldMethodCallArgs{
+				FieldName:  oneofFieldName(msg, oneof),
+				MethodCall: methodCall,
+			})
+			continue
+		}
 
-		fieldSerializeMethodCall, methodCode, err := generateFieldSerializationMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		callArgs, err := getFieldMethodCallArgs(msg, field)
+		if err != nil {
+			return "", err
+		}
+
+		fieldSerializeMethodCall, methodCode, err := generateFieldSerializationMethodCall(msg.Name, fieldValueName(callArgs), field.DataTypeDefinition)
 		if err != nil {
 			return "", err
 		}
 		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
-		args.FieldSerializeMethodCalls = append(args.FieldSerializeMethodCalls, fieldSerializeMethodCall)
+		callArgs.MethodCall = fieldSerializeMethodCall
+		args.FieldSerializeMethodCalls = append(args.FieldSerializeMethodCalls, callArgs)
 	}
 
 	buf := &bytes.Buffer{}
@@ -1190,20 +2183,46 @@ func generateMessageDeserializationMethod(msg *parse.MessageDefinition) (string,
 	args := DeserializeMethodArgs{
 		MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
 		MessageNamePascalCase:  util.EnsurePascalCase(msg.Name),
+		Recursive:              msg.IsRecursive(),
 	}
 
 	additionalFunctionCode := map[string]string{}
 
 	for _, field := range msg.FieldsByIndex() {
 
-		fieldName := fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), util.EnsurePascalCase(field.Name))
+		if field.Oneof != "" {
+			// the oneof as a whole is encoded at the position of its first member
+			if !msg.IsFirstOneofField(field) {
+				continue
+			}
+			oneof := msg.Oneofs[field.Oneof]
+			methodCall, methodCode, err := generateOneofDeserializeMethod(msg, oneof)
+			if err != nil {
+				return "", err
+			}
+			additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+			args.FieldDeserializeMethodCalls = append(args.FieldDeserializeMethodCalls, FieldMethodCallArgs{
+				FieldName:  oneofFieldName(msg, oneof),
+				MethodCall: methodCall,
+			})
+			continue
+		}
+
+		callArgs, err := getFieldMethodCallArgs(msg, field)
+		if err != nil {
+			return "", err
+		}
 
-		fieldDeserializeMethodCall, methodCode, err := generateFieldDeserializationMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		fieldDeserializeMethodCall, methodCode, err := generateFieldDeserializationMethodCall(msg.Name, fieldValueName(callArgs), field.DataTypeDefinition)
 		if err != nil {
 			return "", err
 		}
 		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
-		args.FieldDeserializeMethodCalls = append(args.FieldDeserializeMethodCalls, fieldDeserializeMethodCall)
+		callArgs.MethodCall = fieldDeserializeMethodCall
+		args.FieldDeserializeMethodCalls = append(args.FieldDeserializeMethodCalls, callArgs)
+		if field.Optional {
+			args.HasOptionalFields = true
+		}
 	}
 
 	buf := &bytes.Buffer{}
@@ -1219,26 +2238,486 @@ func generateMessageDeserializationMethod(msg *parse.MessageDefinition) (string,
 	return code + "\n" + buf.String(), nil
 }
 
+func getDataTypeWireKind(dataType *parse.DataTypeDefinition) (string, error) {
+	switch dataType.Type {
+	case parse.DataTypeBool:
+		return "Bit", nil
+	case parse.DataTypeByte,
+		parse.DataTypeUInt8,
+		parse.DataTypeInt8:
+		return "Byte", nil
+	case parse.DataTypeUInt16:
+		return "VarUInt16", nil
+	case parse.DataTypeUInt32:
+		return "VarUInt32", nil
+	case parse.DataTypeUInt64:
+		return "VarUInt64", nil
+	case parse.DataTypeInt16:
+		return "VarInt16", nil
+	case parse.DataTypeInt32:
+		return "VarInt32", nil
+	case parse.DataTypeInt64,
+		parse.DataTypeDuration:
+		return "VarInt64", nil
+	case parse.DataTypeFloat32:
+		return "Fixed32", nil
+	case parse.DataTypeFloat64:
+		return "Fixed64", nil
+	case parse.DataTypeUUID:
+		return "Fixed128", nil
+	case parse.DataTypeString,
+		parse.DataTypeBytes,
+		parse.DataTypeTimestamp,
+		parse.DataTypeUIntN,
+		parse.DataTypeIntN,
+		parse.DataTypeQuant,
+		parse.DataTypeMap,
+		parse.DataTypeList,
+		parse.DataTypeArray,
+		parse.DataTypeSet,
+		parse.DataTypeCustom:
+		return "Delimited", nil
+	}
+	return "", fmt.Errorf("unrecognized type: %v", dataType.Type)
+}
+
+func generateMessageTaggedMethods(msg *parse.MessageDefinition) (string, string, string, error) {
+	args := TaggedMethodArgs{
+		MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
+		MessageNamePascalCase:  util.EnsurePascalCase(msg.Name),
+		Recursive:              msg.IsRecursive(),
+	}
+
+	additionalFunctionCode := map[string]string{}
+
+	for _, field := range msg.FieldsByIndex() {
+
+		callArgs, err := getFieldMethodCallArgs(msg, field)
+		if err != nil {
+			return "", "", "", err
+		}
+		fieldName := fieldValueName(callArgs)
+
+		condition := ""
+		prelude := ""
+		if field.Oneof != "" {
+			// each member is written as a regular field when it is the one set
+			oneof := msg.Oneofs[field.Oneof]
+			wrapperType := oneofWrapperType(msg, field)
+			fieldName = fmt.Sprintf("member.%s", util.EnsurePascalCase(field.Name))
+			condition = fmt.Sprintf("member, ok := %s.(*%s); ok", oneofFieldName(msg, oneof), wrapperType)
+			prelude = fmt.Sprintf("member := &%s{}\n%s = member", wrapperType, oneofFieldName(msg, oneof))
+			if msg.IsFirstOneofField(field) {
+				args.CountConditions = append(args.CountConditions, fmt.Sprintf("%s != nil", oneofFieldName(msg, oneof)))
+			}
+		} else if field.Optional {
+			condition = fmt.Sprintf("%s != nil", callArgs.FieldName)
+			prelude = fmt.Sprintf("%s = new(%s)", callArgs.FieldName, callArgs.ElementType)
+			args.CountConditions = append(args.CountConditions, condition)
+		} else {
+			args.NumRequiredFields++
+		}
+
+		wireKind, err := getDataTypeWireKind(field.DataTypeDefinition)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		bitSizeMethodCall, bitSizeMethodCode, err := generateFieldBitSizeMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		if err != nil {
+			return "", "", "", err
+		}
+		serializeMethodCall, serializeMethodCode, err := generateFieldSerializationMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		if err != nil {
+			return "", "", "", err
+		}
+		deserializeMethodCall, deserializeMethodCode, err := generateFieldDeserializationMethodCall(msg.Name, fieldName, field.DataTypeDefinition)
+		if err != nil {
+			return "", "", "", err
+		}
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, bitSizeMethodCode)
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, serializeMethodCode)
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, deserializeMethodCode)
+
+		args.Fields = append(args.Fields, TaggedFieldArgs{
+			Index:                 field.Index,
+			WireKind:              wireKind,
+			Delimited:             wireKind == "Delimited",
+			Condition:             condition,
+			Prelude:               prelude,
+			BitSizeMethodCall:     bitSizeMethodCall,
+			SerializeMethodCall:   serializeMethodCall,
+			DeserializeMethodCall: deserializeMethodCall,
+		})
+	}
+
+	bitSizeBuf := &bytes.Buffer{}
+	err := messageTaggedBitSizeMethodTemplate.Execute(bitSizeBuf, args)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	serializeBuf := &bytes.Buffer{}
+	err = messageTaggedSerializeMethodTemplate.Execute(serializeBuf, args)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	deserializeBuf := &bytes.Buffer{}
+	err = messageTaggedDeserializeMethodTemplate.Execute(deserializeBuf, args)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	code := ""
+	for _, c := range valuesSortedByKey(additionalFunctionCode) {
+		code += c + "\n"
+	}
+	return code + "\n" + bitSizeBuf.String(), serializeBuf.String(), deserializeBuf.String(), nil
+}
+
+// hasJSONConversion reports whether the type is, or is a container of, a type
+// whose Go representation does not marshal to the JSON form shared with C++:
+// durations, which are marshalled through serialize.JSONDuration, and sets,
+// which are marshalled as arrays rather than objects.
+func hasJSONConversion(dataType *parse.DataTypeDefinition) bool {
+	return hasDurationType(dataType) || hasSetType(dataType)
+}
+
+func mapDataTypeDefinitionToGoJSONType(dataType *parse.DataTypeDefinition) (string, error) {
+	if !hasJSONConversion(dataType) {
+		return mapDataTypeDefinitionToGoType(dataType)
+	}
+
+	switch dataType.Type {
+	case parse.DataTypeSet:
+		key, err := mapDataTypeComparableDefinitionToGoType(dataType.Key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[]%s", key), nil
+	case parse.DataTypeMap:
+		key, err := mapDataTypeComparableDefinitionToGoType(dataType.Key)
+		if err != nil {
+			return "", err
+		}
+		subtype, err := mapDataTypeDefinitionToGoJSONType(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("map[%s]%s", key, subtype), nil
+	case parse.DataTypeList:
+		subtype, err := mapDataTypeDefinitionToGoJSONType(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[]%s", subtype), nil
+	case parse.DataTypeArray:
+		subtype, err := mapDataTypeDefinitionToGoJSONType(dataType.SubType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", dataType.Length, subtype), nil
+	}
+	return "serialize.JSONDuration", nil
+}
+
+// generateJSONConversion returns an expression converting the variable to its
+// JSON representation, or back when fromJSON is set, along with the code of any
+// container helpers it calls.
+func generateJSONConversion(messageName string, varName string, dataType *parse.DataTypeDefinition, fromJSON bool) (string, map[string]string, error) {
+
+	if dataType.Type == parse.DataTypeDuration {
+		if fromJSON {
+			return fmt.Sprintf("time.Duration(%s)", varName), nil, nil
+		}
+		return fmt.Sprintf("serialize.JSONDuration(%s)", varName), nil, nil
+	}
+
+	goType, err := mapDataTypeDefinitionToGoType(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+	jsonType, err := mapDataTypeDefinitionToGoJSONType(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+	suffix, err := getDataTypeDefinitionMethodSuffix(dataType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	direction := "ToJSON"
+	args := JSONConversionMethodArgs{
+		ArgType:    goType,
+		ResultType: jsonType,
+	}
+	if fromJSON {
+		direction = "FromJSON"
+		args.ArgType = jsonType
+		args.ResultType = goType
+	}
+	args.FullMethodName = fmt.Sprintf("%s_%s%s", util.EnsureCamelCase(messageName), direction, suffix)
+
+	var methodCode map[string]string
+	if dataType.Type != parse.DataTypeSet {
+		// the elements of a set are comparable, so are marshalled as they are
+		args.ValueConversion, methodCode, err = generateJSONConversion(messageName, "v", dataType.SubType, fromJSON)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var tmpl *template.Template
+	switch dataType.Type {
+	case parse.DataTypeList:
+		tmpl = listJSONConversionMethodTemplate
+	case parse.DataTypeArray:
+		tmpl = arrayJSONConversionMethodTemplate
+	case parse.DataTypeMap:
+		tmpl = mapJSONConversionMethodTemplate
+	case parse.DataTypeSet:
+		tmpl = setToJSONConversionMethodTemplate
+		if fromJSON {
+			tmpl = setFromJSONConversionMethodTemplate
+		}
+	default:
+		return "", nil, fmt.Errorf("unrecognized type: %v", dataType.Type)
+	}
+
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if methodCode == nil {
+		methodCode = map[string]string{}
+	}
+	methodCode[args.FullMethodName] = buf.String()
+
+	return fmt.Sprintf("%s(%s)", args.FullMethodName, varName), methodCode, nil
+}
+
 func getMessageFieldArg(field *parse.MessageFieldDefinition) (MessageFieldArgs, error) {
 	goType, err := mapDataTypeDefinitionToGoType(field.DataTypeDefinition)
 	if err != nil {
 		return MessageFieldArgs{}, err
 	}
+	if field.Optional {
+		// optional fields are held by pointer so absence is distinguishable
+		// from the zero value
+		return MessageFieldArgs{
+			FieldNamePascalCase: util.EnsurePascalCase(field.Name),
+			FieldJSONName:       getFieldJSONName(field),
+			FieldType:           "*" + goType,
+			FieldJSONOptions:    ",omitempty",
+			Deprecated:          field.Options.Bool(parse.OptionDeprecated),
+		}, nil
+	}
 	return MessageFieldArgs{
 		FieldNamePascalCase: util.EnsurePascalCase(field.Name),
-		FieldNameSnakeCase:  util.EnsureSnakeCase(field.Name),
+		FieldJSONName:       getFieldJSONName(field),
 		FieldType:           goType,
+		Deprecated:          field.Options.Bool(parse.OptionDeprecated),
 	}, nil
 }
 
+// getFieldJSONName returns the key of the field when marshalled to JSON, which
+// defaults to the snake case field name unless overridden by `json_name`.
+func getFieldJSONName(field *parse.MessageFieldDefinition) string {
+	jsonName := field.Options.String(parse.OptionJSONName)
+	if jsonName != "" {
+		return jsonName
+	}
+	return util.EnsureSnakeCase(field.Name)
+}
+
+func getOneofArgs(msg *parse.MessageDefinition, oneof *parse.OneofDefinition) (OneofArgs, map[string]string, error) {
+
+	oneofArgs := OneofArgs{
+		FieldNamePascalCase: util.EnsurePascalCase(oneof.Name),
+		FieldNameSnakeCase:  util.EnsureSnakeCase(oneof.Name),
+		InterfaceType:       oneofInterfaceType(msg, oneof),
+		JSONType:            fmt.Sprintf("%s_%sJSON", util.EnsureCamelCase(msg.Name), util.EnsurePascalCase(oneof.Name)),
+	}
+
+	methodCode := map[string]string{}
+
+	for _, field := range msg.OneofFieldsByIndex(oneof.Name) {
+		goType, err := mapDataTypeDefinitionToGoType(field.DataTypeDefinition)
+		if err != nil {
+			return OneofArgs{}, nil, err
+		}
+		memberArgs := OneofMemberArgs{
+			FieldNamePascalCase: util.EnsurePascalCase(field.Name),
+			FieldJSONName:       getFieldJSONName(field),
+			FieldType:           goType,
+			JSONFieldType:       goType,
+			WrapperType:         oneofWrapperType(msg, field),
+		}
+		if hasJSONConversion(field.DataTypeDefinition) {
+			memberArgs.JSONFieldType, err = mapDataTypeDefinitionToGoJSONType(field.DataTypeDefinition)
+			if err != nil {
+				return OneofArgs{}, nil, err
+			}
+			toJSON, toJSONCode, err := generateJSONConversion(msg.Name, fmt.Sprintf("member.%s", memberArgs.FieldNamePascalCase), field.DataTypeDefinition, false)
+			if err != nil {
+				return OneofArgs{}, nil, err
+			}
+			fromJSON, fromJSONCode, err := generateJSONConversion(msg.Name, fmt.Sprintf("*aux.%s.%s", oneofArgs.FieldNamePascalCase, memberArgs.FieldNamePascalCase), field.DataTypeDefinition, true)
+			if err != nil {
+				return OneofArgs{}, nil, err
+			}
+			methodCode = util.MergeMap(methodCode, toJSONCode)
+			methodCode = util.MergeMap(methodCode, fromJSONCode)
+			memberArgs.ToJSON = toJSON
+			memberArgs.FromJSON = fromJSON
+		}
+		oneofArgs.Members = append(oneofArgs.Members, memberArgs)
+	}
+
+	return oneofArgs, methodCode, nil
+}
+
+// getJSONFieldArgs returns the shadow field of a field holding durations or
+// sets. The field is aliased in place when it is a plain duration, otherwise it
+// is converted to its JSON type and back.
+func getJSONFieldArgs(msg *parse.MessageDefinition, field *parse.MessageFieldDefinition) (JSONFieldArgs, map[string]string, error) {
+
+	fieldName := util.EnsurePascalCase(field.Name)
+	messageFieldName := fmt.Sprintf("%s.%s", util.FirstLetterAsLowercase(msg.Name), fieldName)
+
+	args := JSONFieldArgs{
+		FieldNamePascalCase: fieldName,
+		FieldJSONName:       getFieldJSONName(field),
+	}
+
+	if field.DataTypeDefinition.Type == parse.DataTypeDuration {
+		args.JSONType = "*serialize.JSONDuration"
+		if field.Optional {
+			args.FieldJSONOptions = ",omitempty"
+			args.AuxValue = fmt.Sprintf("(*serialize.JSONDuration)(%s)", messageFieldName)
+			args.Result = fmt.Sprintf("(*time.Duration)(aux.%s)", fieldName)
+		} else {
+			args.AuxValue = fmt.Sprintf("(*serialize.JSONDuration)(&%s)", messageFieldName)
+		}
+		return args, nil, nil
+	}
+
+	jsonType, err := mapDataTypeDefinitionToGoJSONType(field.DataTypeDefinition)
+	if err != nil {
+		return JSONFieldArgs{}, nil, err
+	}
+	toJSON, toJSONCode, err := generateJSONConversion(msg.Name, messageFieldName, field.DataTypeDefinition, false)
+	if err != nil {
+		return JSONFieldArgs{}, nil, err
+	}
+	fromJSON, fromJSONCode, err := generateJSONConversion(msg.Name, fmt.Sprintf("aux.%s", fieldName), field.DataTypeDefinition, true)
+	if err != nil {
+		return JSONFieldArgs{}, nil, err
+	}
+
+	args.JSONType = jsonType
+	args.AuxValue = toJSON
+	args.Result = fromJSON
+
+	return args, util.MergeMap(toJSONCode, fromJSONCode), nil
+}
+
+func generateMessageOneofTypes(msg *parse.MessageDefinition) (string, error) {
+
+	args := OneofTypesArgs{}
+
+	for _, oneof := range msg.OneofsByIndex() {
+		oneofArgs, _, err := getOneofArgs(msg, oneof)
+		if err != nil {
+			return "", err
+		}
+		args.Oneofs = append(args.Oneofs, oneofArgs)
+	}
+
+	buf := &bytes.Buffer{}
+	err := messageOneofTypesTemplate.Execute(buf, args)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// generateMessageJSONMethods returns the MarshalJSON and UnmarshalJSON methods
+// of a message, or an empty string when the default encoding suffices. The
+// methods are needed to (un)marshal oneofs and fields holding durations or
+// sets.
+func generateMessageJSONMethods(msg *parse.MessageDefinition) (string, error) {
+
+	args := JSONMethodsArgs{
+		MessageNamePascalCase:  util.EnsurePascalCase(msg.Name),
+		MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
+	}
+
+	additionalFunctionCode := map[string]string{}
+
+	for _, field := range msg.FieldsByIndex() {
+		if field.Oneof != "" || !hasJSONConversion(field.DataTypeDefinition) {
+			continue
+		}
+		fieldArgs, methodCode, err := getJSONFieldArgs(msg, field)
+		if err != nil {
+			return "", err
+		}
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+		args.Fields = append(args.Fields, fieldArgs)
+	}
+
+	for _, oneof := range msg.OneofsByIndex() {
+		oneofArgs, methodCode, err := getOneofArgs(msg, oneof)
+		if err != nil {
+			return "", err
+		}
+		additionalFunctionCode = util.MergeMap(additionalFunctionCode, methodCode)
+		args.Oneofs = append(args.Oneofs, oneofArgs)
+	}
+
+	if len(args.Fields) == 0 && len(args.Oneofs) == 0 {
+		return "", nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := messageJSONMethodsTemplate.Execute(buf, args)
+	if err != nil {
+		return "", err
+	}
+
+	code := ""
+	for _, c := range valuesSortedByKey(additionalFunctionCode) {
+		code += c + "\n"
+	}
+	return code + buf.String(), nil
+}
+
 func generateMessageGoCode(msg *parse.MessageDefinition) (string, error) {
 
 	args := MessageArgs{
 		MessageNamePascalCase:  util.EnsurePascalCase(msg.Name),
+		MessageFullName:        msg.FullName(),
 		MessageNameFirstLetter: util.FirstLetterAsLowercase(msg.Name),
 		MessageFields:          []MessageFieldArgs{},
+		Deprecated:             msg.Options.Bool(parse.OptionDeprecated),
 	}
 	for _, field := range msg.FieldsByIndex() {
+		if field.Oneof != "" {
+			if msg.IsFirstOneofField(field) {
+				// oneofs are (un)marshalled by the generated JSON methods
+				oneof := msg.Oneofs[field.Oneof]
+				args.MessageFields = append(args.MessageFields, MessageFieldArgs{
+					FieldNamePascalCase: util.EnsurePascalCase(oneof.Name),
+					FieldJSONName:       "-",
+					FieldType:           oneofInterfaceType(msg, oneof),
+				})
+			}
+			continue
+		}
 		fieldArg, err := getMessageFieldArg(field)
 		if err != nil {
 			return "", err
@@ -1246,24 +2725,70 @@ func generateMessageGoCode(msg *parse.MessageDefinition) (string, error) {
 		args.MessageFields = append(args.MessageFields, fieldArg)
 	}
 
-	byteSizeCode, err := generateMessageBitSizeMethod(msg)
-	if err != nil {
-		return "", err
+	for _, field := range msg.FieldsByIndex() {
+		elementType := field.DataTypeDefinition.GetElementType()
+		if elementType.Type != parse.DataTypeQuant {
+			continue
+		}
+		name := fmt.Sprintf("%s_%sPrecision", util.EnsurePascalCase(msg.Name), util.EnsurePascalCase(field.Name))
+		args.MessageConsts = append(args.MessageConsts, MessageConstArgs{
+			Name:    name,
+			Value:   formatFloatLiteral(elementType.QuantPrecision()),
+			Comment: fmt.Sprintf("%s is the distance between two adjacent values of the %s field.", name, field.Name),
+		})
+	}
+
+	if len(msg.Oneofs) > 0 {
+		oneofCode, err := generateMessageOneofTypes(msg)
+		if err != nil {
+			return "", err
+		}
+		args.OneofCode = oneofCode
 	}
 
-	serializeCode, err := generateMessageSerializationMethod(msg)
+	jsonCode, err := generateMessageJSONMethods(msg)
 	if err != nil {
 		return "", err
 	}
+	args.JSONCode = jsonCode
+
+	if msg.Tagged {
+		byteSizeCode, serializeCode, deserializeCode, err := generateMessageTaggedMethods(msg)
+		if err != nil {
+			return "", err
+		}
+
+		args.Tagged = true
+		args.BitSizeCode = byteSizeCode
+		args.SerializeCode = serializeCode
+		args.DeserializeCode = deserializeCode
+	} else {
+		byteSizeCode, err := generateMessageBitSizeMethod(msg)
+		if err != nil {
+			return "", err
+		}
 
-	deserializeCode, err := generateMessageDeserializationMethod(msg)
+		serializeCode, err := generateMessageSerializationMethod(msg)
+		if err != nil {
+			return "", err
+		}
+
+		deserializeCode, err := generateMessageDeserializationMethod(msg)
+		if err != nil {
+			return "", err
+		}
+
+		args.BitSizeCode = byteSizeCode
+		args.SerializeCode = serializeCode
+		args.DeserializeCode = deserializeCode
+	}
+
+	// deltas use the same encoding for tagged and untagged messages
+	deltaCode, err := generateMessageDeltaMethods(msg)
 	if err != nil {
 		return "", err
 	}
-
-	args.BitSizeCode = byteSizeCode
-	args.SerializeCode = serializeCode
-	args.DeserializeCode = deserializeCode
+	args.DeltaCode = deltaCode
 
 	buf := &bytes.Buffer{}
 	err = messageTemplate.Execute(buf, args)
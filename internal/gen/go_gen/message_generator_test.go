@@ -64,6 +64,182 @@ func TestGetDataTypeDefinitionMethodSuffix(t *testing.T) {
 	assert.Equal(t, "MapOtherPkgTypedefTypeListMapUInt64ListCustomPkgCustomType", fullName)
 }
 
+func TestGetDataTypeDefinitionMethodSuffixArray(t *testing.T) {
+
+	// [][4][16]float32
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeList,
+		SubType: &parse.DataTypeDefinition{
+			Type:   parse.DataTypeArray,
+			Length: 4,
+			SubType: &parse.DataTypeDefinition{
+				Type:   parse.DataTypeArray,
+				Length: 16,
+				SubType: &parse.DataTypeDefinition{
+					Type: parse.DataTypeFloat32,
+				},
+			},
+		},
+	}
+
+	fullName, err := getDataTypeDefinitionMethodSuffix(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "ListArray4Array16Float32", fullName)
+
+	goType, err := mapDataTypeDefinitionToGoType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "[][4][16]float32", goType)
+}
+
+func TestGetDataTypeDefinitionMethodSuffixSet(t *testing.T) {
+
+	// map[string]map[uuid.UUID]struct{}
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeMap,
+		Key: &parse.DataTypeComparableDefinition{
+			Type: parse.DataTypeComparableString,
+		},
+		SubType: &parse.DataTypeDefinition{
+			Type: parse.DataTypeSet,
+			Key: &parse.DataTypeComparableDefinition{
+				Type: parse.DataTypeComparableUUID,
+			},
+		},
+	}
+
+	fullName, err := getDataTypeDefinitionMethodSuffix(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "MapStringSetUUID", fullName)
+
+	goType, err := mapDataTypeDefinitionToGoType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "map[string]map[uuid.UUID]struct{}", goType)
+
+	_, code, err := generateDeserializeContainerMethod("MyMessage", "t.SomeField", dt.SubType)
+	require.Nil(t, err)
+	assert.Contains(t, code["myMessage_DeserializeSetUUID"], "duplicate element")
+	assert.Contains(t, code["myMessage_DeserializeSetUUID"], "serialize.CheckElements[uuid.UUID](reader, length)")
+
+	_, code, err = generateSerializeContainerMethod("MyMessage", "t.SomeField", dt)
+	require.Nil(t, err)
+	assert.Contains(t, code["myMessage_SerializeMapStringSetUUID"], "serialize.SortedKeys(arg)")
+	assert.Contains(t, code["myMessage_SerializeSetUUID"], "serialize.SortedUUIDKeys(arg)")
+}
+
+func TestGetDataTypeDefinitionMethodSuffixBitInt(t *testing.T) {
+
+	// []int<12>
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeList,
+		SubType: &parse.DataTypeDefinition{
+			Type: parse.DataTypeIntN,
+			Bits: 12,
+		},
+	}
+
+	fullName, err := getDataTypeDefinitionMethodSuffix(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "ListInt12Bits", fullName)
+
+	goType, err := mapDataTypeDefinitionToGoType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "[]int16", goType)
+
+	_, code, err := generateSerializeContainerMethod("MyMessage", "t.SomeField", dt)
+	require.Nil(t, err)
+	assert.Contains(t, code["myMessage_SerializeListInt12Bits"], "serialize.SerializeIntN(writer, v, 12)")
+}
+
+func TestGetDataTypeDefinitionMethodSuffixQuant(t *testing.T) {
+
+	// []float32 quantized over [-0.5, 100]
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeList,
+		SubType: &parse.DataTypeDefinition{
+			Type:     parse.DataTypeQuant,
+			SubType:  &parse.DataTypeDefinition{Type: parse.DataTypeFloat32},
+			QuantMin: -0.5,
+			QuantMax: 100,
+			Bits:     12,
+		},
+	}
+
+	fullName, err := getDataTypeDefinitionMethodSuffix(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "ListQuantFloat32Neg0p5To100Bits12", fullName)
+
+	goType, err := mapDataTypeDefinitionToGoType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "[]float32", goType)
+
+	_, code, err := generateSerializeContainerMethod("MyMessage", "t.SomeField", dt)
+	require.Nil(t, err)
+	assert.Contains(t, code["myMessage_SerializeListQuantFloat32Neg0p5To100Bits12"], "serialize.SerializeQuant(writer, v, -0.5, 100, 12)")
+}
+
+func TestGenerateJSONConversionDuration(t *testing.T) {
+
+	// map[string][]time.Duration
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeMap,
+		Key: &parse.DataTypeComparableDefinition{
+			Type: parse.DataTypeComparableString,
+		},
+		SubType: &parse.DataTypeDefinition{
+			Type: parse.DataTypeList,
+			SubType: &parse.DataTypeDefinition{
+				Type: parse.DataTypeDuration,
+			},
+		},
+	}
+
+	jsonType, err := mapDataTypeDefinitionToGoJSONType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "map[string][]serialize.JSONDuration", jsonType)
+
+	call, code, err := generateJSONConversion("MyMessage", "t.SomeField", dt, false)
+	require.Nil(t, err)
+	assert.Equal(t, "myMessage_ToJSONMapStringListDuration(t.SomeField)", call)
+	assert.Contains(t, code["myMessage_ToJSONMapStringListDuration"], "result[k] = myMessage_ToJSONListDuration(v)")
+	assert.Contains(t, code["myMessage_ToJSONListDuration"], "result[i] = serialize.JSONDuration(v)")
+
+	call, _, err = generateJSONConversion("MyMessage", "v", dt.SubType.SubType, true)
+	require.Nil(t, err)
+	assert.Equal(t, "time.Duration(v)", call)
+}
+
+func TestGenerateJSONConversionSet(t *testing.T) {
+
+	// map[string]map[uuid.UUID]struct{}
+	dt := &parse.DataTypeDefinition{
+		Type: parse.DataTypeMap,
+		Key: &parse.DataTypeComparableDefinition{
+			Type: parse.DataTypeComparableString,
+		},
+		SubType: &parse.DataTypeDefinition{
+			Type: parse.DataTypeSet,
+			Key: &parse.DataTypeComparableDefinition{
+				Type: parse.DataTypeComparableUUID,
+			},
+		},
+	}
+
+	jsonType, err := mapDataTypeDefinitionToGoJSONType(dt)
+	require.Nil(t, err)
+	assert.Equal(t, "map[string][]uuid.UUID", jsonType)
+
+	call, code, err := generateJSONConversion("MyMessage", "t.SomeField", dt, false)
+	require.Nil(t, err)
+	assert.Equal(t, "myMessage_ToJSONMapStringSetUUID(t.SomeField)", call)
+	assert.Contains(t, code["myMessage_ToJSONMapStringSetUUID"], "result[k] = myMessage_ToJSONSetUUID(v)")
+	assert.Contains(t, code["myMessage_ToJSONSetUUID"], "result = append(result, k)")
+
+	call, code, err = generateJSONConversion("MyMessage", "aux.SomeField", dt, true)
+	require.Nil(t, err)
+	assert.Equal(t, "myMessage_FromJSONMapStringSetUUID(aux.SomeField)", call)
+	assert.Contains(t, code["myMessage_FromJSONSetUUID"], "result[v] = struct{}{}")
+}
+
 func TestGenerateSerializeContainerMethod(t *testing.T) {
 
 	// map[string][]map[uint64][]custom.CustomType
@@ -178,3 +354,282 @@ func TestGenerateMessageSerializeMethod(t *testing.T) {
 	fmt.Println(code)
 	fmt.Println("done")
 }
+
+func TestGenerateMessageTaggedMethods(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name:   "MyMessage",
+		Tagged: true,
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"SomeField": {
+				Name:  "SomeField",
+				Index: 1,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+			"AnotherField": {
+				Name:  "AnotherField",
+				Index: 4,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeList,
+					SubType: &parse.DataTypeDefinition{
+						Type: parse.DataTypeString,
+					},
+				},
+			},
+			"MaybeField": {
+				Name:     "MaybeField",
+				Index:    5,
+				Optional: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+	}
+
+	bitSizeCode, serializeCode, deserializeCode, err := generateMessageTaggedMethods(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, bitSizeCode, "serialize.BitSizeFieldTag(1)")
+	assert.Contains(t, bitSizeCode, "serialize.BitSizeFieldTag(4)")
+	assert.Contains(t, serializeCode, "numFields := uint32(2)")
+	assert.Contains(t, deserializeCode, "case 4:")
+	assert.Contains(t, deserializeCode, "serialize.SkipField(reader, wireKind)")
+	assert.Contains(t, serializeCode, "if m.MaybeField != nil {")
+	assert.Contains(t, deserializeCode, "m.MaybeField = new(string)")
+
+	fmt.Println(bitSizeCode)
+	fmt.Println(serializeCode)
+	fmt.Println(deserializeCode)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageOptionalField(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "MyMessage",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Count": {
+				Name:     "Count",
+				Index:    0,
+				Optional: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+			"Name": {
+				Name:  "Name",
+				Index: 1,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "Count *uint32 `json:\"count,omitempty\"`")
+	assert.Contains(t, code, "serialize.SerializeBool(writer, m.Count != nil)")
+	assert.Contains(t, code, "serialize.SerializeUInt32(writer, (*m.Count))")
+	assert.Contains(t, code, "m.Count = new(uint32)")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageOneof(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "MyMessage",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Name": {
+				Name:  "Name",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+			"Count": {
+				Name:  "Count",
+				Index: 1,
+				Oneof: "Value",
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+			"Label": {
+				Name:  "Label",
+				Index: 2,
+				Oneof: "Value",
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+		Oneofs: map[string]*parse.OneofDefinition{
+			"Value": {
+				Name: "Value",
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "Value MyMessage_Value `json:\"-\"`")
+	assert.Contains(t, code, "func (*MyMessage_Value_Count) isMyMessage_Value() {}")
+	assert.Contains(t, code, "size += myMessage_BitSizeOneofValue(m.Value)")
+	assert.Contains(t, code, "serialize.SerializeUInt32(writer, 2)")
+	assert.Contains(t, code, "err = myMessage_DeserializeOneofValue(&m.Value, reader)")
+
+	msg.Tagged = true
+
+	bitSizeCode, serializeCode, deserializeCode, err := generateMessageTaggedMethods(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, bitSizeCode, "if member, ok := m.Value.(*MyMessage_Value_Label); ok {")
+	assert.Contains(t, serializeCode, "numFields := uint32(1)")
+	assert.Contains(t, serializeCode, "if m.Value != nil {")
+	assert.Contains(t, deserializeCode, "member := &MyMessage_Value_Count{}")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageRecursive(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Node",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Children": {
+				Name:      "Children",
+				Index:     0,
+				Recursive: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeList,
+					SubType: &parse.DataTypeDefinition{
+						Type:       parse.DataTypeCustom,
+						CustomType: "Node",
+					},
+				},
+			},
+			"Next": {
+				Name:      "Next",
+				Index:     1,
+				Optional:  true,
+				Recursive: true,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type:       parse.DataTypeCustom,
+					CustomType: "Node",
+				},
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "Next *Node `json:\"next,omitempty\"`")
+	assert.Contains(t, code, "err := reader.Descend()")
+	assert.Contains(t, code, "defer reader.Ascend()")
+
+	msg.Tagged = true
+
+	_, _, deserializeCode, err := generateMessageTaggedMethods(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, deserializeCode, "err := reader.Descend()")
+	assert.Contains(t, deserializeCode, "err = serialize.DeserializeUInt32(&numFields, reader)")
+
+	fmt.Println(code)
+	fmt.Println("done")
+}
+
+func TestGenerateMessageOptions(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "User",
+		Options: parse.Options{
+			parse.OptionDeprecated: {Name: parse.OptionDeprecated, Value: "true"},
+		},
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"email_address": {
+				Name:  "email_address",
+				Index: 0,
+				Options: parse.Options{
+					parse.OptionJSONName:   {Name: parse.OptionJSONName, Value: "mail", Quoted: true},
+					parse.OptionDeprecated: {Name: parse.OptionDeprecated, Value: "true"},
+				},
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+			"display_name": {
+				Name:  "display_name",
+				Index: 1,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeString,
+				},
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "// Deprecated: Marked as deprecated in the schema.\ntype User struct")
+	assert.Contains(t, code, "// Deprecated: Marked as deprecated in the schema.\n\tEmailAddress string `json:\"mail\"`")
+	assert.Contains(t, code, "DisplayName string `json:\"display_name\"`")
+}
+
+func TestGenerateMessageHashMethods(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "Snapshot",
+		Fields: map[string]*parse.MessageFieldDefinition{
+			"Tick": {
+				Name:  "Tick",
+				Index: 0,
+				DataTypeDefinition: &parse.DataTypeDefinition{
+					Type: parse.DataTypeUInt32,
+				},
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "func (s *Snapshot) Sum256() [32]byte {")
+	assert.Contains(t, code, "func (s *Snapshot) HashTo(hasher hash.Hash) {")
+	assert.Contains(t, code, "writer := serialize.NewHashWriter(hasher)")
+
+	// empty messages hash the empty encoding
+	code, err = generateMessageGoCode(&parse.MessageDefinition{
+		Name: "Empty",
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "func (e *Empty) HashTo(hasher hash.Hash) {")
+}
+
+func TestGenerateMessageName(t *testing.T) {
+
+	msg := &parse.MessageDefinition{
+		Name: "NotFoundDetail",
+		File: &parse.File{
+			Package: &parse.PackageDeclaration{
+				Name: "users",
+			},
+		},
+	}
+
+	code, err := generateMessageGoCode(msg)
+	require.Nil(t, err)
+
+	assert.Contains(t, code, "func (*NotFoundDetail) MessageName() string {\n\treturn \"users.NotFoundDetail\"\n}")
+}
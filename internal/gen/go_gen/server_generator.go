@@ -65,7 +65,7 @@ func (s *{{$.ServerStubStructName}}) handle{{.MethodNamePascalCase}}(ctx context
 	req := &{{.MethodRequestStructName}}{}
 	err := req.Deserialize(reader)
 	if err != nil {
-		return rpc.RespondWithError(requestID, err)
+		return rpc.RespondWithError(requestID, rpc.Errorf(rpc.CodeInvalidArgument, "%v", err))
 	}
 
 	handler := func (ctx context.Context, req rpc.Message) (rpc.Message, error) {
@@ -88,14 +88,14 @@ func (s *{{$.ServerStubStructName}}) HandleWrapper(ctx context.Context, middlewa
 	var methodID uint64
 	err := serialize.DeserializeUInt64(&methodID, reader)
 	if err != nil {
-		return rpc.RespondWithError(requestID, err)
+		return rpc.RespondWithError(requestID, rpc.Errorf(rpc.CodeInvalidArgument, "%v", err))
 	}
 
 	switch methodID { {{- range .ServiceMethods}}
 	case {{.MethodIDVarName}}:
 		return s.handle{{.MethodNamePascalCase}}(ctx, middleware, requestID, reader){{end}}
 	default:
-		return rpc.RespondWithError(requestID, fmt.Errorf("unrecognized methodID %d", methodID))
+		return rpc.RespondWithError(requestID, rpc.Errorf(rpc.CodeUnimplemented, "unrecognized methodID %d", methodID))
 	}
 }
 
@@ -108,7 +108,7 @@ func (s *{{$.ServerStubStructName}}) HandleStreamWrapper(ctx context.Context, st
 		}
 		req := &{{.ReqStructName}}{}
 		if err := req.Deserialize(reader); err != nil {
-			return err
+			return rpc.Errorf(rpc.CodeInvalidArgument, "%v", err)
 		}
 		return s.impl.{{.MethodNamePascalCase}}(req, &{{.StreamTypeName}}{stream: stream}){{else}}resp, err := s.impl.{{.MethodNamePascalCase}}(&{{.StreamTypeName}}{stream: stream})
 		if err != nil {
@@ -116,7 +116,7 @@ func (s *{{$.ServerStubStructName}}) HandleStreamWrapper(ctx context.Context, st
 		}
 		return stream.Send(resp){{end}}{{end}}
 	default:
-		return fmt.Errorf("unrecognized stream methodID %d", methodID)
+		return rpc.NewStatus(rpc.CodeUnimplemented, fmt.Sprintf("unrecognized stream methodID %d", methodID))
 	}
 }
 {{range .ServiceStreamMethods}}
@@ -9,23 +9,26 @@ import (
 )
 
 var (
-	enumRegex      = regexp.MustCompile(`(?s)enum\s+([a-zA-Z][a-zA-Z_0-9]*)\s*{(.*?)}`)
-	enumValueRegex = regexp.MustCompile(`^(.+?)(?:\s+"([a-zA-Z][a-zA-Z_0-9]*)")?\s*=\s*(.+?)\s*;*$`)
+	enumRegex      = regexp.MustCompile(`(?s)enum\s+([a-zA-Z][a-zA-Z_0-9]*)\s*(?:\[([^\]]*)\])?\s*{(.*?)}`)
+	enumValueRegex = regexp.MustCompile(`^(.+?)(?:\s+"([a-zA-Z][a-zA-Z_0-9]*)")?\s*=\s*(.+?)\s*(?:\[(.*)\])?\s*;*$`)
 	enumNameRegex  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z_0-9]*$`)
 )
 
 type EnumValueDefinition struct {
-	Name  string
-	Value string
-	Index uint32
-	Token *Token
+	Name    string
+	Value   string
+	Index   uint32
+	Options Options
+	Token   *Token
 }
 
 type EnumDefinition struct {
-	Name   string
-	Values map[string]*EnumValueDefinition
-	File   *File
-	Token  *Token
+	Name     string
+	Values   map[string]*EnumValueDefinition
+	Options  Options
+	Reserved *ReservedDeclarations
+	File     *File
+	Token    *Token
 }
 
 func (m *EnumDefinition) ValuesByIndex() []*EnumValueDefinition {
@@ -72,11 +75,17 @@ func parseValueDefinition(input *Token) (*EnumValueDefinition, *ParsingError) {
 		}
 	}
 
+	options, perr := parseOptions(match.Captures[3])
+	if perr != nil {
+		return nil, perr
+	}
+
 	return &EnumValueDefinition{
-		Name:  name,
-		Index: uint32(index),
-		Value: stringValue,
-		Token: input,
+		Name:    name,
+		Index:   uint32(index),
+		Value:   stringValue,
+		Options: options,
+		Token:   input,
 	}, nil
 }
 
@@ -146,90 +155,134 @@ func parseEnumDefinitions(tokens []*Token) (map[string]*EnumDefinition, *Parsing
 			continue
 		}
 
-		match, perr := FindOneMatch(enumRegex, token)
-		if perr != nil || len(match.Captures) != 2 {
+		enum, perr := parseEnumDefinition(token)
+		if perr != nil {
+			return nil, perr
+		}
+
+		_, ok := enums[enum.Name]
+		if ok {
 			return nil, &ParsingError{
-				Message: "invalid enum definition",
+				Message: fmt.Sprintf("duplicate enum definition %s", enum.Name),
 				Token:   token,
 			}
 		}
+		enums[enum.Name] = enum
+	}
+
+	return enums, nil
+}
+
+func parseEnumDefinition(token *Token) (*EnumDefinition, *ParsingError) {
+
+	match, perr := FindOneMatch(enumRegex, token)
+	if perr != nil || len(match.Captures) != 3 {
+		return nil, &ParsingError{
+			Message: "invalid enum definition",
+			Token:   token,
+		}
+	}
+
+	options, perr := parseOptions(match.Captures[1])
+	if perr != nil {
+		return nil, perr
+	}
 
-		enum := &EnumDefinition{
-			Name:   match.Captures[0].Content,
-			Values: map[string]*EnumValueDefinition{},
-			Token:  token,
+	enum := &EnumDefinition{
+		Name:     match.Captures[0].Content,
+		Values:   map[string]*EnumValueDefinition{},
+		Options:  options,
+		Reserved: newReservedDeclarations(),
+		Token:    token,
+	}
+
+	values, perr := tokenizeEnumValues(match.Captures[2])
+	if perr != nil {
+		return nil, perr
+	}
+
+	definitions := []*EnumValueDefinition{}
+
+	for _, value := range values {
+		if isReservedDeclaration(value) {
+			perr := parseReservedDeclaration(value, enum.Reserved)
+			if perr != nil {
+				return nil, perr
+			}
+			continue
 		}
 
-		values, perr := tokenizeEnumValues(match.Captures[1])
+		valueDefinition, perr := parseValueDefinition(value)
 		if perr != nil {
 			return nil, perr
 		}
 
-		if len(values) == 0 {
+		_, ok := enum.Values[valueDefinition.Name]
+		if ok {
 			return nil, &ParsingError{
-				Message: "enum has no values",
-				Token:   match.Captures[1],
+				Message: fmt.Sprintf("duplicate value definition %s", valueDefinition.Name),
+				Token:   value,
 			}
 		}
 
-		for _, value := range values {
-			valueDefinition, perr := parseValueDefinition(value)
-			if perr != nil {
-				return nil, perr
-			}
-
-			_, ok := enum.Values[valueDefinition.Name]
-			if ok {
+		for _, existingValue := range enum.Values {
+			if existingValue.Name == valueDefinition.Name {
 				return nil, &ParsingError{
-					Message: fmt.Sprintf("duplicate value definition %s", valueDefinition.Name),
+					Message: fmt.Sprintf("duplicate enum string value definition %s", valueDefinition.Name),
 					Token:   value,
 				}
 			}
+		}
 
-			for _, existingValue := range enum.Values {
-				if existingValue.Name == valueDefinition.Name {
-					return nil, &ParsingError{
-						Message: fmt.Sprintf("duplicate enum string value definition %s", valueDefinition.Name),
-						Token:   value,
-					}
-				}
-			}
+		enum.Values[valueDefinition.Name] = valueDefinition
+		definitions = append(definitions, valueDefinition)
+	}
 
-			enum.Values[valueDefinition.Name] = valueDefinition
+	if len(enum.Values) == 0 {
+		return nil, &ParsingError{
+			Message: "enum has no values",
+			Token:   match.Captures[2],
 		}
+	}
 
-		// ensure enum indices are valid and sequential
-		indices := make(map[uint32]bool)
-		for _, value := range enum.Values {
-			// track indices
-			_, ok := indices[value.Index]
-			if ok {
-				return nil, &ParsingError{
-					Message: fmt.Sprintf("duplicate index %d in definition %s", value.Index, enum.Name),
-					Token:   value.Token,
-				}
+	// retired indices and names must not be reused
+	for _, value := range definitions {
+		if enum.Reserved.IsReservedIndex(value.Index) {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("value %s uses reserved index %d in definition %s", value.Name, value.Index, enum.Name),
+				Token:   value.Token,
 			}
-			indices[value.Index] = true
 		}
-		for i := 0; i < len(enum.Values); i++ {
-			_, ok := indices[uint32(i)]
-			if !ok {
-				return nil, &ParsingError{
-					Message: fmt.Sprintf("missing index %d in enum definition %s", i, enum.Name),
-					Token:   enum.Token,
-				}
+		if enum.Reserved.IsReservedName(value.Name) {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("value %s uses a reserved name in definition %s", value.Name, enum.Name),
+				Token:   value.Token,
 			}
 		}
+	}
 
-		_, ok := enums[enum.Name]
+	// ensure enum indices are valid and sequential
+	indices := make(map[uint32]bool)
+	for _, value := range enum.Values {
+		// track indices
+		_, ok := indices[value.Index]
 		if ok {
 			return nil, &ParsingError{
-				Message: fmt.Sprintf("duplicate enum definition %s", enum.Name),
-				Token:   token,
+				Message: fmt.Sprintf("duplicate index %d in definition %s", value.Index, enum.Name),
+				Token:   value.Token,
+			}
+		}
+		indices[value.Index] = true
+	}
+	for i := 0; i < len(enum.Values); i++ {
+		_, ok := indices[uint32(i)]
+		if !ok {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("missing index %d in enum definition %s", i, enum.Name),
+				Token:   enum.Token,
 			}
 		}
-		enums[enum.Name] = enum
 	}
 
-	return enums, nil
+	return enum, nil
 }
@@ -3,6 +3,7 @@ package parse
 import (
 	"path/filepath"
 	"sort"
+	"strings"
 )
 
 const (
@@ -28,12 +29,17 @@ type File struct {
 	RelativePath           string
 	FullPath               string
 	Package                *PackageDeclaration
+	Imports                []*ImportDeclaration
 	CustomTypeDependencies map[string]*CustomTypeDependency
 	Typedefs               map[string]*TypedefDeclaration
 	Consts                 map[string]*ConstDeclaration
 	Enums                  map[string]*EnumDefinition
 	ServiceDefinitions     map[string]*ServiceDefinition
 	MessageDefinitions     map[string]*MessageDefinition
+	Warnings               []*ParsingError
+	// External is set for files only loaded from an include root to satisfy
+	// an import, no code is generated for them.
+	External bool
 }
 
 func (f *File) GetPackageDependencies() []PackageDependency {
@@ -147,6 +153,12 @@ func (f *File) MessagesSortedByDependenciesAndKeys() []*MessageDefinition {
 
 	for _, msg := range f.MessagesSortedByKey() {
 		for _, field := range msg.FieldsByIndex() {
+			if field.Recursive && field.IsIndirect() {
+				// recursive references are held indirectly, so they do not
+				// require the type to be defined first, and would otherwise
+				// form a cycle
+				continue
+			}
 			elem := field.DataTypeDefinition.GetElementType()
 			if elem.Type == DataTypeCustom {
 
@@ -281,16 +293,26 @@ func populateDataTypePackageIfMissing(packageName string, dataType *DataTypeDefi
 		return nil
 	}
 
-	if dataType.Type == DataTypeList {
+	if dataType.Type == DataTypeList || dataType.Type == DataTypeArray {
 		if dataType.SubType == nil {
 			return &ParsingError{
-				Message: "internal parsing error: list subtype not found",
+				Message: "internal parsing error: list / array subtype not found",
 				Token:   dataType.Token,
 			}
 		}
 		return populateDataTypePackageIfMissing(packageName, dataType.SubType)
 	}
 
+	if dataType.Type == DataTypeSet {
+		if dataType.Key == nil {
+			return &ParsingError{
+				Message: "internal parsing error: set element not found",
+				Token:   dataType.Token,
+			}
+		}
+		return populateDataTypeComparablePackageIfMissing(packageName, dataType.Key)
+	}
+
 	if dataType.Type == DataTypeMap {
 		if dataType.Key == nil {
 			return &ParsingError{
@@ -313,6 +335,59 @@ func populateDataTypePackageIfMissing(packageName string, dataType *DataTypeDefi
 	return nil
 }
 
+// visitCustomTypes calls the provided function with the package and name of
+// every custom type referenced by the data type, including map keys and set
+// elements.
+func visitCustomTypes(dataType *DataTypeDefinition, fn func(pkg *string, name *string)) {
+	switch dataType.Type {
+	case DataTypeCustom:
+		fn(&dataType.CustomTypePackage, &dataType.CustomType)
+	case DataTypeList, DataTypeArray:
+		visitCustomTypes(dataType.SubType, fn)
+	case DataTypeSet:
+		if dataType.Key.Type == DataTypeComparableCustom {
+			fn(&dataType.Key.CustomTypePackage, &dataType.Key.CustomType)
+		}
+	case DataTypeMap:
+		if dataType.Key.Type == DataTypeComparableCustom {
+			fn(&dataType.Key.CustomTypePackage, &dataType.Key.CustomType)
+		}
+		visitCustomTypes(dataType.SubType, fn)
+	}
+}
+
+// qualifyNestedDataType rewrites references to types declared within a
+// message to their qualified name. Names are looked up from the innermost
+// enclosing message outwards, so `Line` within `Order` refers to `Order.Line`,
+// and `Order.Line` refers to it from anywhere in the file. References that do
+// not name a declaration within the file are left as they are.
+func qualifyNestedDataType(scope string, declared map[string]bool, dataType *DataTypeDefinition) {
+	visitCustomTypes(dataType, func(pkg *string, name *string) {
+		written := qualifyName(*pkg, *name)
+		for s := scope; ; s = parentScope(s) {
+			candidate := qualifyName(s, written)
+			if declared[candidate] {
+				*pkg = ""
+				*name = candidate
+				return
+			}
+			if s == "" {
+				return
+			}
+		}
+	})
+}
+
+// parentScope returns the scope enclosing the provided one, the top level
+// scope is empty.
+func parentScope(scope string) string {
+	i := strings.LastIndex(scope, ".")
+	if i < 0 {
+		return ""
+	}
+	return scope[:i]
+}
+
 func addCustomComparableTypeDependency(dependencies map[string]*CustomTypeDependency, dataType *DataTypeComparableDefinition) *ParsingError {
 	if dataType.Type == DataTypeComparableCustom {
 		if _, ok := dependencies[dataType.ToString()]; !ok {
@@ -328,7 +403,7 @@ func addCustomComparableTypeDependency(dependencies map[string]*CustomTypeDepend
 
 func addCustomTypeDependency(dependencies map[string]*CustomTypeDependency, dataType *DataTypeDefinition) *ParsingError {
 
-	if dataType.Type == DataTypeList {
+	if dataType.Type == DataTypeList || dataType.Type == DataTypeArray {
 		if dataType.SubType == nil {
 			return &ParsingError{
 				Message: "internal parsing error: list / map subtype not found",
@@ -338,6 +413,16 @@ func addCustomTypeDependency(dependencies map[string]*CustomTypeDependency, data
 		return addCustomTypeDependency(dependencies, dataType.SubType)
 	}
 
+	if dataType.Type == DataTypeSet {
+		if dataType.Key == nil {
+			return &ParsingError{
+				Message: "internal parsing error: set element not found",
+				Token:   dataType.Token,
+			}
+		}
+		return addCustomComparableTypeDependency(dependencies, dataType.Key)
+	}
+
 	if dataType.Type == DataTypeMap {
 		if dataType.Key == nil {
 			return &ParsingError{
@@ -391,6 +476,11 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 		return nil, perr
 	}
 
+	imports, perr := parseImportDeclarations(tokens)
+	if perr != nil {
+		return nil, perr
+	}
+
 	enums, perr := parseEnumDefinitions(tokens)
 	if perr != nil {
 		return nil, perr
@@ -406,12 +496,26 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 		return nil, perr
 	}
 
-	messageDefinitions, perr := parseMessageDefinitions(tokens)
+	messageDefinitions, nestedEnums, perr := parseMessageDefinitions(tokens)
 	if perr != nil {
 		return nil, perr
 	}
+	for k, v := range nestedEnums {
+		enums[k] = v
+	}
 
-	dependencies := make(map[string]*CustomTypeDependency)
+	// names declared within this file, including nested declarations, for
+	// resolving references relative to the enclosing message
+	declared := map[string]bool{}
+	for k := range enums {
+		declared[k] = true
+	}
+	for k := range typedefs {
+		declared[k] = true
+	}
+	for k := range messageDefinitions {
+		declared[k] = true
+	}
 
 	for _, enum := range enums {
 		enum.File = f
@@ -429,30 +533,30 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 		if perr != nil {
 			return nil, perr
 		}
-
-		// add custom type dependencies
-		err := addCustomComparableTypeDependency(dependencies, cosntDecl.DataTypeDefinition)
-		if err != nil {
-			return nil, err
-		}
 	}
 
 	for _, msg := range messageDefinitions {
 
 		msg.File = f
 
+		// a tagged package tags every message declared within it
+		if pkg.Tagged {
+			msg.Tagged = true
+		}
+		perr := validateSequentialIndices(msg)
+		if perr != nil {
+			return nil, perr
+		}
+
 		for _, field := range msg.Fields {
+			// resolve references to nested declarations
+			qualifyNestedDataType(msg.Name, declared, field.DataTypeDefinition)
+
 			// if package is omitted, use the file's package name
 			perr := populateDataTypePackageIfMissing(pkg.Name, field.DataTypeDefinition)
 			if perr != nil {
 				return nil, perr
 			}
-
-			// add custom type dependencies
-			perr = addCustomTypeDependency(dependencies, field.DataTypeDefinition)
-			if perr != nil {
-				return nil, perr
-			}
 		}
 	}
 
@@ -466,26 +570,81 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 		svc.File = f
 
 		for _, method := range svc.Methods {
+			// resolve references to nested declarations
+			qualifyNestedDataType("", declared, method.Argument)
+			qualifyNestedDataType("", declared, method.Return)
+
 			// if package is omitted, use the file's package name
 			perr := populateDataTypePackageIfMissing(pkg.Name, method.Argument)
 			if perr != nil {
 				return nil, perr
 			}
-			// add custom type dependencies
-			perr = addCustomTypeDependency(dependencies, method.Argument)
-			if perr != nil {
-				return nil, perr
-			}
 
 			// if package is omitted, use the file's package name
 			perr = populateDataTypePackageIfMissing(pkg.Name, method.Return)
 			if perr != nil {
 				return nil, perr
 			}
+		}
+	}
+
+	f.Content = input
+	f.Package = pkg
+	f.Imports = imports
+	f.Enums = enums
+	f.Consts = consts
+	f.Typedefs = typedefs
+	f.MessageDefinitions = messageDefinitions
+	f.ServiceDefinitions = serviceDefinitions
+
+	warnings, perr := validateFileOptions(f)
+	if perr != nil {
+		return nil, perr
+	}
+	f.Warnings = warnings
+
+	perr = collectCustomTypeDependencies(f)
+	if perr != nil {
+		return nil, perr
+	}
+
+	return f, nil
+}
+
+// collectCustomTypeDependencies records the custom types the file references
+// that are not defined within the file itself.
+func collectCustomTypeDependencies(f *File) *ParsingError {
+
+	dependencies := make(map[string]*CustomTypeDependency)
+
+	for _, cosntDecl := range f.Consts {
+		// add custom type dependencies
+		err := addCustomComparableTypeDependency(dependencies, cosntDecl.DataTypeDefinition)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range f.MessageDefinitions {
+		for _, field := range msg.Fields {
+			// add custom type dependencies
+			perr := addCustomTypeDependency(dependencies, field.DataTypeDefinition)
+			if perr != nil {
+				return perr
+			}
+		}
+	}
+
+	for _, svc := range f.ServiceDefinitions {
+		for _, method := range svc.Methods {
 			// add custom type dependencies
+			perr := addCustomTypeDependency(dependencies, method.Argument)
+			if perr != nil {
+				return perr
+			}
 			perr = addCustomTypeDependency(dependencies, method.Return)
 			if perr != nil {
-				return nil, perr
+				return perr
 			}
 		}
 	}
@@ -493,17 +652,17 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 	externalCustomTypeDependencies := make(map[string]*CustomTypeDependency)
 	for _, dep := range dependencies {
 		// check if the custom type is defined in this file
-		_, ok := enums[dep.CustomTypeName]
+		_, ok := f.Enums[dep.CustomTypeName]
 		if ok {
 			continue
 		}
 
-		_, ok = typedefs[dep.CustomTypeName]
+		_, ok = f.Typedefs[dep.CustomTypeName]
 		if ok {
 			continue
 		}
 
-		_, ok = messageDefinitions[dep.CustomTypeName]
+		_, ok = f.MessageDefinitions[dep.CustomTypeName]
 		if ok {
 			continue
 		}
@@ -512,14 +671,6 @@ func parseFileContent(path string, relativeDir string, input string) (*File, *Pa
 		externalCustomTypeDependencies[dep.CustomTypeName] = dep
 	}
 
-	f.Content = input
-	f.Package = pkg
 	f.CustomTypeDependencies = externalCustomTypeDependencies
-	f.Enums = enums
-	f.Consts = consts
-	f.Typedefs = typedefs
-	f.MessageDefinitions = messageDefinitions
-	f.ServiceDefinitions = serviceDefinitions
-
-	return f, nil
+	return nil
 }
@@ -0,0 +1,78 @@
+package parse
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	importRegex = regexp.MustCompile(`^import\s+"([^"]*)"\s*;$`)
+)
+
+// ImportDeclaration is an `import "common/types.scg";` statement. The path is
+// relative to one of the include roots, the first root containing the file is
+// used.
+type ImportDeclaration struct {
+	Path  string
+	File  *File
+	Used  bool
+	Token *Token
+}
+
+func parseImportDeclarations(tokens []*Token) ([]*ImportDeclaration, *ParsingError) {
+
+	imports := []*ImportDeclaration{}
+	seen := map[string]bool{}
+
+	for _, token := range tokens {
+		if token.Type != ImportTokenType {
+			continue
+		}
+
+		match, perr := FindOneMatch(importRegex, token)
+		if perr != nil || len(match.Captures) != 1 {
+			return nil, &ParsingError{
+				Message: "invalid import declaration, expected `import \"path/to/file.scg\";`",
+				Token:   token,
+			}
+		}
+
+		importPath := match.Captures[0].Content
+		if !strings.HasSuffix(importPath, ".scg") {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid import path `%s`, expected a .scg file", importPath),
+				Token:   token,
+			}
+		}
+
+		cleaned := filepath.ToSlash(filepath.Clean(filepath.FromSlash(importPath)))
+		if filepath.IsAbs(importPath) || strings.HasPrefix(cleaned, "/") || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid import path `%s`, imports must be relative to an include root", importPath),
+				Token:   token,
+			}
+		}
+
+		if seen[cleaned] {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("file %s imported multiple times", cleaned),
+				Token:   token,
+			}
+		}
+		seen[cleaned] = true
+
+		imports = append(imports, &ImportDeclaration{
+			Path:  cleaned,
+			Token: token,
+		})
+	}
+
+	return imports, nil
+}
+
+// getImportPath returns the path another file would use to import the file.
+func getImportPath(f *File) string {
+	return filepath.ToSlash(filepath.Join(f.RelativePath, f.Name))
+}
@@ -0,0 +1,215 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportParser(t *testing.T) {
+
+	f, err := parseFileContent("test.scg", ".", `
+		package test;
+
+		import "common/types.scg";
+		import "./other/../shared.scg"; # cleaned
+
+		message A {
+			common.Type a = 0;
+		}
+	`)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(f.Imports))
+	assert.Equal(t, "common/types.scg", f.Imports[0].Path)
+	assert.Equal(t, 3, f.Imports[0].Token.LineStart)
+	assert.Equal(t, "shared.scg", f.Imports[1].Path)
+}
+
+func TestImportErrs(t *testing.T) {
+
+	inputs := []string{
+		`import common/types.scg;`,
+		`import "common/types.scg"`,
+		`import "common/types.scg" "other.scg";`,
+		`import "common/types.txt";`,
+		`import "/common/types.scg";`,
+		`import "../common/types.scg";`,
+		`import "a.scg"; import "./a.scg";`,
+		`import;`,
+	}
+
+	for _, input := range inputs {
+		_, err := parseFileContent("test.scg", ".", "package test;\n"+input)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestImportVisibility(t *testing.T) {
+
+	common := `
+		package common;
+
+		message Type {
+			int32 v = 0;
+		}
+	`
+
+	// types of another package require an import
+	_, err := NewParseFromFiles(".", map[string]string{
+		"common/types.scg": common,
+		"a.scg": `
+			package a;
+
+			message A {
+				common.Type t = 0;
+			}
+		`,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "type common.Type referenced in a.scg is declared in common/types.scg, which is not imported")
+
+	// an import is not transitive
+	_, err = NewParseFromFiles(".", map[string]string{
+		"common/types.scg": common,
+		"b.scg": `
+			package b;
+
+			import "common/types.scg";
+
+			message B {
+				common.Type t = 0;
+			}
+		`,
+		"a.scg": `
+			package a;
+
+			import "b.scg";
+
+			message A {
+				b.B b = 0;
+				common.Type t = 1;
+			}
+		`,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "which is not imported")
+
+	// types of the same package are visible without an import
+	p, err := NewParseFromFiles(".", map[string]string{
+		"common/types.scg": common,
+		"common/more.scg": `
+			package common;
+
+			message More {
+				Type t = 0;
+			}
+		`,
+		"a.scg": `
+			package a;
+
+			import "common/types.scg";
+			import "common/more.scg";
+
+			message A {
+				common.Type t = 0;
+			}
+		`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(p.Warnings))
+	assert.Contains(t, p.Warnings[0].Message, "unused import common/more.scg")
+	assert.Equal(t, "a.scg", p.Warnings[0].Filename)
+
+	// missing imports are reported
+	_, err = NewParseFromFiles(".", map[string]string{
+		"a.scg": `
+			package a;
+
+			import "common/missing.scg";
+		`,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "imported file common/missing.scg not found")
+
+	_, err = NewParseFromFiles(".", map[string]string{
+		"a.scg": `
+			package a;
+
+			import "a.scg";
+		`,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "file a.scg imports itself")
+}
+
+func TestImportIncludeDirs(t *testing.T) {
+
+	root := t.TempDir()
+
+	write := func(path string, content string) {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write("input/service.scg", `
+		package service;
+
+		import "common/types.scg";
+
+		service Users {
+			rpc Get (common.Request) returns (common.Response);
+		}
+	`)
+	write("shared/common/types.scg", `
+		package common;
+
+		import "common/base.scg";
+
+		message Request {
+			Base base = 0;
+		}
+
+		message Response {
+			string name = 0;
+		}
+	`)
+	write("shared/common/base.scg", `
+		package common;
+
+		message Base {
+			string id = 0;
+		}
+	`)
+	write("shared/common/unreachable.scg", `
+		package common;
+
+		message Unreachable {
+			Missing m = 0;
+		}
+	`)
+	// the first include dir containing the file is used
+	write("vendor/common/types.scg", `
+		package ignored;
+	`)
+
+	p, err := NewParse(filepath.Join(root, "input"), filepath.Join(root, "shared"), filepath.Join(root, "vendor"))
+	require.NoError(t, err)
+
+	require.Equal(t, 3, len(p.Files))
+	assert.False(t, p.Files["service.scg"].External)
+	assert.True(t, p.Files["common/types.scg"].External)
+	assert.True(t, p.Files["common/base.scg"].External)
+	assert.Equal(t, "common", p.Files["common/types.scg"].RelativePath)
+	assert.Equal(t, 3, len(p.Packages["common"].MessageDefinitions))
+
+	_, err = NewParse(filepath.Join(root, "input"))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "imported file common/types.scg not found")
+
+	_, err = NewParse(filepath.Join(root, "input"), filepath.Join(root, "missing"))
+	require.NotNil(t, err)
+}
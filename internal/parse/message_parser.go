@@ -2,21 +2,30 @@ package parse
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
 var (
-	messageRegex                 = regexp.MustCompile(`(?s)message\s+([a-zA-Z][a-zA-Z_0-9]*)\s*{(.*?)}`)
-	fieldRegex                   = regexp.MustCompile(`^((?:list\s*\<\s*(?:.*)\s*\>)|(?:map\s*\<\s*(?:.*)\s*\>)|(?:.+?))\s+(.+?)\s*=\s*(.+?)\s*;*$`)
+	messageRegex                 = regexp.MustCompile(`(?s)message\s+([a-zA-Z][a-zA-Z_0-9]*)(?:\s+tagged)?\s*(?:\[([^\]]*)\])?\s*{(.*)}`)
+	messageTaggedRegex           = regexp.MustCompile(`^message\s+[a-zA-Z][a-zA-Z_0-9]*\s+tagged\s*[\[{]`)
+	oneofRegex                   = regexp.MustCompile(`(?s)^oneof\s+([a-zA-Z][a-zA-Z_0-9]*)\s*{(.*)}$`)
+	nestedBlockRegex             = regexp.MustCompile(`^(oneof|message|enum)\s`)
+	fieldRegex                   = regexp.MustCompile(`^(?:(optional)\s+)?((?:list\s*\<\s*(?:.*)\s*\>)|(?:map\s*\<\s*(?:.*)\s*\>)|(?:array\s*\<\s*(?:.*)\s*\>)|(?:set\s*\<\s*(?:.*)\s*\>)|(?:u?int\s*\<\s*\d+\s*\>)|(?:quant\s*\<[^\>]*\>)|(?:.+?))\s+(.+?)\s*=\s*(.+?)\s*(?:\[(.*)\])?\s*;*$`)
 	fieldNameRegex               = regexp.MustCompile(`^[a-zA-Z][a-zA-Z_0-9]*$`)
-	plainDataTypeRegex           = regexp.MustCompile(`^(byte|bool|uint8|uint16|uint32|uint64|int8|int16|int32|int64|float32|float64|string|timestamp|uuid)$`)
+	plainDataTypeRegex           = regexp.MustCompile(`^(byte|bool|uint8|uint16|uint32|uint64|int8|int16|int32|int64|float32|float64|string|bytes|timestamp|duration|uuid)$`)
 	plainDataTypeComparableRegex = regexp.MustCompile(`^(uint8|uint16|uint32|uint64|int8|int16|int32|int64|float32|float64|string|uuid)$`)
 	customDataTypeRegex          = regexp.MustCompile(`^((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)$`)
 	mapDataTypeRegex             = regexp.MustCompile(`^map\s*\<\s*((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*,\s*(.+?)\s*\>$`)
 	listDataTypeRegex            = regexp.MustCompile(`^list\s*\<\s*(.+)\s*\>$`)
+	arrayDataTypeRegex           = regexp.MustCompile(`^array\s*\<\s*(.+?)\s*,\s*(\d+)\s*\>$`)
+	bitIntDataTypeRegex          = regexp.MustCompile(`^(uint|int)\s*\<\s*(\d+)\s*\>$`)
+	quantDataTypeRegex           = regexp.MustCompile(`^quant\s*\<\s*(float32|float64)\s*,\s*([^,\s]+)\s*,\s*([^,\s]+)\s*,\s*(\d+)\s*\>$`)
+	setDataTypeRegex             = regexp.MustCompile(`^set\s*\<\s*((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\>$`)
 	validIndexRegex              = regexp.MustCompile(`^\d+$`)
 )
 
@@ -34,13 +43,20 @@ const (
 	DataTypeInt16
 	DataTypeInt32
 	DataTypeInt64
+	DataTypeUIntN
+	DataTypeIntN
 	DataTypeFloat32
 	DataTypeFloat64
+	DataTypeQuant
 	DataTypeString
+	DataTypeBytes
 	DataTypeTimestamp
+	DataTypeDuration
 	DataTypeUUID
 	DataTypeMap
 	DataTypeList
+	DataTypeArray
+	DataTypeSet
 	DataTypeCustom
 )
 
@@ -65,6 +81,7 @@ type DataTypeComparableDefinition struct {
 	CustomType               string
 	CustomTypePackage        string
 	ImportedFromOtherPackage bool
+	CustomTypeIsUUID         bool // set by the resolver if the custom type is a typedef of uuid
 	Token                    *Token
 }
 
@@ -78,24 +95,52 @@ func (d *DataTypeComparableDefinition) ToString() string {
 
 type DataTypeDefinition struct {
 	Type                     DataType
-	Key                      *DataTypeComparableDefinition
+	Key                      *DataTypeComparableDefinition // key of a map, or element of a set
 	CustomType               string
 	CustomTypePackage        string
 	SubType                  *DataTypeDefinition
+	Length                   uint32  // number of elements of an array
+	Bits                     uint32  // width of a uint<N>, int<N> or quant
+	QuantMin                 float64 // lower bound of a quant
+	QuantMax                 float64 // upper bound of a quant
 	ImportedFromOtherPackage bool
+	CustomTypeIsMessage      bool // set by the resolver if the custom type is a message
 	Token                    *Token
 }
 
 func (dt *DataTypeDefinition) GetElementType() *DataTypeDefinition {
-	if dt.Type == DataTypeList || dt.Type == DataTypeMap {
+	if dt.Type == DataTypeList || dt.Type == DataTypeMap || dt.Type == DataTypeArray {
 		if dt.SubType == nil {
-			panic("list / map subtype not found")
+			panic("list / map / array subtype not found")
 		}
 		return dt.SubType.GetElementType()
 	}
 	return dt
 }
 
+// QuantPrecision returns the distance between two adjacent values of a quant.
+func (dt *DataTypeDefinition) QuantPrecision() float64 {
+	return (dt.QuantMax - dt.QuantMin) / float64((uint64(1)<<dt.Bits)-1)
+}
+
+// IsContainer returns true for lists, maps, arrays and sets.
+func (dt *DataTypeDefinition) IsContainer() bool {
+	return dt.Type == DataTypeList || dt.Type == DataTypeMap || dt.Type == DataTypeArray || dt.Type == DataTypeSet
+}
+
+// holdsElementIndirectly returns true if the element type is reached through a
+// list or map. An array holds its elements inline, so it is only indirect if
+// its own elements are.
+func (dt *DataTypeDefinition) holdsElementIndirectly() bool {
+	switch dt.Type {
+	case DataTypeList, DataTypeMap:
+		return true
+	case DataTypeArray:
+		return dt.SubType.holdsElementIndirectly()
+	}
+	return false
+}
+
 func mapTypeEnumToString(typ DataType) string {
 	switch typ {
 	case DataTypeByte:
@@ -124,8 +169,12 @@ func mapTypeEnumToString(typ DataType) string {
 		return "float64"
 	case DataTypeString:
 		return "string"
+	case DataTypeBytes:
+		return "bytes"
 	case DataTypeTimestamp:
 		return "timestamp"
+	case DataTypeDuration:
+		return "duration"
 	case DataTypeUUID:
 		return "uuid"
 	}
@@ -175,6 +224,29 @@ func (d *DataTypeDefinition) ToString() string {
 		return fmt.Sprintf("list<%s>", d.SubType.ToString())
 	}
 
+	if d.Type == DataTypeArray {
+		return fmt.Sprintf("array<%s, %d>", d.SubType.ToString(), d.Length)
+	}
+
+	if d.Type == DataTypeSet {
+		return fmt.Sprintf("set<%s>", d.Key.ToString())
+	}
+
+	if d.Type == DataTypeUIntN {
+		return fmt.Sprintf("uint<%d>", d.Bits)
+	}
+
+	if d.Type == DataTypeIntN {
+		return fmt.Sprintf("int<%d>", d.Bits)
+	}
+
+	if d.Type == DataTypeQuant {
+		return fmt.Sprintf("quant<%s, %s, %s, %d>", d.SubType.ToString(),
+			strconv.FormatFloat(d.QuantMin, 'g', -1, 64),
+			strconv.FormatFloat(d.QuantMax, 'g', -1, 64),
+			d.Bits)
+	}
+
 	return mapTypeEnumToString(d.Type)
 }
 
@@ -182,24 +254,115 @@ type MessageFieldDefinition struct {
 	Name               string
 	Index              uint32
 	DataTypeDefinition *DataTypeDefinition
+	Optional           bool
+	Oneof              string
+	Recursive          bool // set by the resolver if the type refers back to the containing message
+	Options            Options
 	Token              *Token
 }
 
+// IsIndirect returns true if the field refers to its type through a list, map
+// or optional, which is what allows a message to contain itself.
+func (f *MessageFieldDefinition) IsIndirect() bool {
+	return f.Optional || f.DataTypeDefinition.holdsElementIndirectly()
+}
+
+// OneofDefinition groups fields of which at most one may be set. The member
+// fields are stored alongside the other fields of the message, sharing their
+// index space, and reference the oneof by name.
+type OneofDefinition struct {
+	Name  string
+	Token *Token
+}
+
+// MessageDefinition describes a message. Messages and enums declared within a
+// message are stored alongside the top level declarations under their
+// qualified name, such as `Order.Line`, and are listed by the enclosing message
+// in Nested.
 type MessageDefinition struct {
-	Name   string
-	Fields map[string]*MessageFieldDefinition
-	File   *File
-	Token  *Token
+	Name     string
+	Fields   map[string]*MessageFieldDefinition
+	Oneofs   map[string]*OneofDefinition
+	Nested   []string
+	Tagged   bool
+	Options  Options
+	Reserved *ReservedDeclarations
+	File     *File
+	Token    *Token
+}
+
+// FullName returns the name of the message qualified by the package of its
+// file, which identifies the message across languages.
+func (m *MessageDefinition) FullName() string {
+	if m.File == nil || m.File.Package == nil {
+		return m.Name
+	}
+	return m.File.Package.Name + "." + m.Name
 }
 
+// IsNested returns true if the message is declared within another message.
+func (m *MessageDefinition) IsNested() bool {
+	return strings.Contains(m.Name, ".")
+}
+
+// FieldsByIndex returns the fields in ascending index order. Indices are dense
+// for untagged messages, tagged messages may contain gaps left by removed
+// fields.
 func (m *MessageDefinition) FieldsByIndex() []*MessageFieldDefinition {
-	res := make([]*MessageFieldDefinition, len(m.Fields))
+	res := make([]*MessageFieldDefinition, 0, len(m.Fields))
+	for _, field := range m.Fields {
+		res = append(res, field)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index < res[j].Index
+	})
+	return res
+}
+
+// IsRecursive returns true if any field of the message refers back to it, in
+// which case a payload may nest arbitrarily deep.
+func (m *MessageDefinition) IsRecursive() bool {
 	for _, field := range m.Fields {
-		res[field.Index] = field
+		if field.Recursive {
+			return true
+		}
+	}
+	return false
+}
+
+// OneofFieldsByIndex returns the member fields of the named oneof in ascending
+// index order.
+func (m *MessageDefinition) OneofFieldsByIndex(name string) []*MessageFieldDefinition {
+	res := []*MessageFieldDefinition{}
+	for _, field := range m.FieldsByIndex() {
+		if field.Oneof == name {
+			res = append(res, field)
+		}
 	}
 	return res
 }
 
+// OneofsByIndex returns the oneofs of the message ordered by the lowest index
+// of their member fields.
+func (m *MessageDefinition) OneofsByIndex() []*OneofDefinition {
+	res := []*OneofDefinition{}
+	for _, field := range m.FieldsByIndex() {
+		if m.IsFirstOneofField(field) {
+			res = append(res, m.Oneofs[field.Oneof])
+		}
+	}
+	return res
+}
+
+// IsFirstOneofField returns true if the field is the lowest indexed member of
+// its oneof, which is the position the oneof as a whole is encoded at.
+func (m *MessageDefinition) IsFirstOneofField(field *MessageFieldDefinition) bool {
+	if field.Oneof == "" {
+		return false
+	}
+	return m.OneofFieldsByIndex(field.Oneof)[0] == field
+}
+
 func mapPlainDataTypeStringToEnum(typ string) (DataType, error) {
 	switch typ {
 	case "byte":
@@ -228,8 +391,12 @@ func mapPlainDataTypeStringToEnum(typ string) (DataType, error) {
 		return DataTypeFloat64, nil
 	case "string":
 		return DataTypeString, nil
+	case "bytes":
+		return DataTypeBytes, nil
 	case "timestamp":
 		return DataTypeTimestamp, nil
+	case "duration":
+		return DataTypeDuration, nil
 	case "uuid":
 		return DataTypeUUID, nil
 	}
@@ -374,6 +541,101 @@ func parseDataTypeDefinition(input *Token) (*DataTypeDefinition, *ParsingError)
 		return dt, nil
 	}
 
+	// check for fixed width integer type
+	match, perr = FindOneOrNoMatch(bitIntDataTypeRegex, input)
+	if perr != nil {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("invalid field definition: `%s", input.Content),
+			Token:   input,
+		}
+	}
+
+	if match != nil {
+		if len(match.Captures) != 2 {
+			return nil, &ParsingError{
+				Message: "invalid field definition, invalid number of matches found",
+				Token:   match.Match,
+			}
+		}
+
+		bits, err := strconv.ParseUint(match.Captures[1].Content, 10, 32)
+		if err != nil || bits == 0 || bits > 64 {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid integer width `%s`, expected a number of bits between 1 and 64", match.Captures[1].Content),
+				Token:   match.Captures[1],
+			}
+		}
+
+		dt.Type = DataTypeUIntN
+		if match.Captures[0].Content == "int" {
+			dt.Type = DataTypeIntN
+		}
+		dt.Bits = uint32(bits)
+		return dt, nil
+	}
+
+	// check for quantized float type
+	match, perr = FindOneOrNoMatch(quantDataTypeRegex, input)
+	if perr != nil {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("invalid field definition: `%s", input.Content),
+			Token:   input,
+		}
+	}
+
+	if match != nil {
+		if len(match.Captures) != 4 {
+			return nil, &ParsingError{
+				Message: "invalid field definition, invalid number of matches found",
+				Token:   match.Match,
+			}
+		}
+
+		bounds := [2]float64{}
+		for i, capture := range match.Captures[1:3] {
+			bound, err := strconv.ParseFloat(capture.Content, 64)
+			if err != nil || math.IsInf(bound, 0) || math.IsNaN(bound) {
+				return nil, &ParsingError{
+					Message: fmt.Sprintf("invalid quant bound `%s`, expected a finite number", capture.Content),
+					Token:   capture,
+				}
+			}
+			bounds[i] = bound
+		}
+		if bounds[0] >= bounds[1] {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid quant range `%s`, the minimum must be less than the maximum", input.Content),
+				Token:   input,
+			}
+		}
+
+		bits, err := strconv.ParseUint(match.Captures[3].Content, 10, 32)
+		if err != nil || bits == 0 || bits > 32 {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid quant width `%s`, expected a number of bits between 1 and 32", match.Captures[3].Content),
+				Token:   match.Captures[3],
+			}
+		}
+
+		subType, err := mapPlainDataTypeStringToEnum(match.Captures[0].Content)
+		if err != nil {
+			return nil, &ParsingError{
+				Message: err.Error(),
+				Token:   match.Captures[0],
+			}
+		}
+
+		dt.Type = DataTypeQuant
+		dt.SubType = &DataTypeDefinition{
+			Type:  subType,
+			Token: match.Captures[0],
+		}
+		dt.QuantMin = bounds[0]
+		dt.QuantMax = bounds[1]
+		dt.Bits = uint32(bits)
+		return dt, nil
+	}
+
 	// check for custom data type
 	match, perr = FindOneOrNoMatch(customDataTypeRegex, input)
 	if perr != nil {
@@ -442,6 +704,69 @@ func parseDataTypeDefinition(input *Token) (*DataTypeDefinition, *ParsingError)
 		return dt, nil
 	}
 
+	match, perr = FindOneOrNoMatch(setDataTypeRegex, input)
+	if perr != nil {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("invalid field definition: `%s", input.Content),
+			Token:   input,
+		}
+	}
+
+	if match != nil {
+		if len(match.Captures) != 1 {
+			return nil, &ParsingError{
+				Message: "invalid field definition, invalid number of matches found",
+				Token:   input,
+			}
+		}
+
+		// the elements of a set are restricted to the types allowed as map keys
+		key, perr := parseDataTypeComparableDefinition(match.Captures[0])
+		if perr != nil {
+			return nil, perr
+		}
+
+		dt.Type = DataTypeSet
+		dt.Key = key
+		return dt, nil
+	}
+
+	match, perr = FindOneOrNoMatch(arrayDataTypeRegex, input)
+	if perr != nil {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("invalid field definition: `%s", input.Content),
+			Token:   input,
+		}
+	}
+
+	if match != nil {
+		if len(match.Captures) != 2 {
+			return nil, &ParsingError{
+				Message: "invalid field definition, invalid number of matches found",
+				Token:   input,
+			}
+		}
+
+		length, err := strconv.ParseUint(match.Captures[1].Content, 10, 32)
+		if err != nil || length == 0 {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid array length `%s`, expected a positive integer", match.Captures[1].Content),
+				Token:   match.Captures[1],
+			}
+		}
+
+		// recurse to parse nested type
+		nestedDataType, perr := parseDataTypeDefinition(match.Captures[0])
+		if perr != nil {
+			return nil, perr
+		}
+
+		dt.Type = DataTypeArray
+		dt.SubType = nestedDataType
+		dt.Length = uint32(length)
+		return dt, nil
+	}
+
 	match, perr = FindOneMatch(listDataTypeRegex, input)
 	if perr != nil {
 		return nil, &ParsingError{
@@ -478,7 +803,9 @@ func parseFieldDefinition(input *Token) (*MessageFieldDefinition, *ParsingError)
 		}
 	}
 
-	typeMatch := match.Captures[0]
+	optional := match.Captures[0].Content != ""
+
+	typeMatch := match.Captures[1]
 
 	dataType, perr := parseDataTypeDefinition(typeMatch)
 	if perr != nil {
@@ -488,7 +815,16 @@ func parseFieldDefinition(input *Token) (*MessageFieldDefinition, *ParsingError)
 		}
 	}
 
-	nameMatch := match.Captures[1]
+	// an empty list or map already serves as the absent value, and an array
+	// always holds its elements, so presence is not tracked for containers
+	if optional && dataType.IsContainer() {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("invalid field type `%s`, list, map, array and set fields cannot be optional", typeMatch.Content),
+			Token:   typeMatch,
+		}
+	}
+
+	nameMatch := match.Captures[2]
 	name := nameMatch.Content
 	if !fieldNameRegex.MatchString(name) {
 		return nil, &ParsingError{
@@ -497,7 +833,7 @@ func parseFieldDefinition(input *Token) (*MessageFieldDefinition, *ParsingError)
 		}
 	}
 
-	indexMatch := match.Captures[2]
+	indexMatch := match.Captures[3]
 	index, err := strconv.Atoi(indexMatch.Content)
 	if err != nil {
 		return nil, &ParsingError{
@@ -506,14 +842,80 @@ func parseFieldDefinition(input *Token) (*MessageFieldDefinition, *ParsingError)
 		}
 	}
 
+	options, perr := parseOptions(match.Captures[4])
+	if perr != nil {
+		return nil, perr
+	}
+
 	return &MessageFieldDefinition{
 		Name:               name,
 		DataTypeDefinition: dataType,
 		Index:              uint32(index),
+		Optional:           optional,
+		Options:            options,
 		Token:              input,
 	}, nil
 }
 
+func parseOneofDefinition(input *Token) (*OneofDefinition, []*MessageFieldDefinition, *ParsingError) {
+
+	match, perr := FindOneMatch(oneofRegex, input)
+	if perr != nil {
+		return nil, nil, &ParsingError{
+			Message: fmt.Sprintf("invalid oneof definition: `%s`", input.Content),
+			Token:   input,
+		}
+	}
+
+	name := match.Captures[0].Content
+
+	tokens, perr := tokenizeMessageFields(match.Captures[1])
+	if perr != nil {
+		return nil, nil, perr
+	}
+
+	fields := []*MessageFieldDefinition{}
+	for _, token := range tokens {
+		if token.Type != MessageFieldTokenType {
+			return nil, nil, &ParsingError{
+				Message: fmt.Sprintf("oneof %s can only contain fields", name),
+				Token:   token,
+			}
+		}
+
+		field, perr := parseFieldDefinition(token)
+		if perr != nil {
+			return nil, nil, perr
+		}
+		if field.Optional {
+			return nil, nil, &ParsingError{
+				Message: fmt.Sprintf("oneof field %s cannot be optional", field.Name),
+				Token:   token,
+			}
+		}
+		field.Oneof = name
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil, &ParsingError{
+			Message: fmt.Sprintf("oneof %s must contain at least one field", name),
+			Token:   input,
+		}
+	}
+
+	return &OneofDefinition{
+		Name:  name,
+		Token: input,
+	}, fields, nil
+}
+
+var nestedBlockTokenTypes = map[string]TokenType{
+	"oneof":   MessageOneofTokenType,
+	"message": MessageTokenType,
+	"enum":    EnumTokenType,
+}
+
 func tokenizeMessageFields(input *Token) ([]*Token, *ParsingError) {
 
 	res := []*Token{}
@@ -526,6 +928,7 @@ func tokenizeMessageFields(input *Token) ([]*Token, *ParsingError) {
 
 	fieldContent := ""
 	hasFoundNonWhitespace := false
+	depth := 0
 
 	for _, c := range input.Content {
 		if !unicode.IsSpace(c) && !hasFoundNonWhitespace {
@@ -545,7 +948,39 @@ func tokenizeMessageFields(input *Token) ([]*Token, *ParsingError) {
 			fieldContent += string(c)
 		}
 
-		if c == ';' {
+		if c == '{' {
+			depth++
+		} else if c == '}' {
+			depth--
+			if depth < 0 {
+				return nil, &ParsingError{
+					Message: "unexpected closing bracket",
+					Token:   input,
+				}
+			}
+			if depth == 0 {
+				// a block is either a oneof or a nested declaration
+				match := nestedBlockRegex.FindStringSubmatch(fieldContent)
+				if match == nil {
+					return nil, &ParsingError{
+						Message: "unexpected block, expected a oneof, message or enum",
+						Token:   input,
+					}
+				}
+				res = append(res, &Token{
+					Type:                       nestedBlockTokenTypes[match[1]],
+					Content:                    fieldContent,
+					LineStart:                  startLine,
+					LineEnd:                    line,
+					LineStartCharacterPosition: startChar,
+					LineEndCharacterPosition:   character,
+				})
+				fieldContent = ""
+				startLine = line
+				startChar = character
+				hasFoundNonWhitespace = false
+			}
+		} else if c == ';' && depth == 0 {
 			res = append(res, &Token{
 				Type:                       MessageFieldTokenType,
 				Content:                    fieldContent,
@@ -571,83 +1006,227 @@ func tokenizeMessageFields(input *Token) ([]*Token, *ParsingError) {
 	return res, nil
 }
 
-func parseMessageDefinitions(tokens []*Token) (map[string]*MessageDefinition, *ParsingError) {
+// validateSequentialIndices ensures the field indices of an untagged message
+// are sequential. Untagged messages are encoded positionally so a gap cannot be
+// represented on the wire.
+func validateSequentialIndices(message *MessageDefinition) *ParsingError {
+	if message.Tagged {
+		return nil
+	}
+	indices := make(map[uint32]bool)
+	for _, field := range message.Fields {
+		indices[field.Index] = true
+	}
+	for i := 0; i < len(message.Fields); i++ {
+		_, ok := indices[uint32(i)]
+		if !ok {
+			return &ParsingError{
+				Message: fmt.Sprintf("missing index %d in message definition %s", i, message.Name),
+				Token:   message.Token,
+			}
+		}
+	}
+	return nil
+}
+
+func parseMessageDefinitions(tokens []*Token) (map[string]*MessageDefinition, map[string]*EnumDefinition, *ParsingError) {
 
 	messages := map[string]*MessageDefinition{}
+	enums := map[string]*EnumDefinition{}
 
 	for _, token := range tokens {
 		if token.Type != MessageTokenType {
 			continue
 		}
 
-		match, perr := FindOneMatch(messageRegex, token)
-		if perr != nil || len(match.Captures) != 2 {
-			return nil, &ParsingError{
-				Message: "invalid message definition",
-				Token:   token,
-			}
+		_, perr := parseMessageDefinition(token, "", messages, enums)
+		if perr != nil {
+			return nil, nil, perr
 		}
+	}
 
-		message := &MessageDefinition{
-			Name:   match.Captures[0].Content,
-			Fields: map[string]*MessageFieldDefinition{},
-			Token:  token,
-		}
+	return messages, enums, nil
+}
 
-		fields, perr := tokenizeMessageFields(match.Captures[1])
-		if perr != nil {
-			return nil, perr
+// parseMessageDefinition parses the message along with any messages and enums
+// declared within it. Nested declarations are added under their name
+// qualified by the enclosing scope.
+func parseMessageDefinition(token *Token, scope string, messages map[string]*MessageDefinition, enums map[string]*EnumDefinition) (*MessageDefinition, *ParsingError) {
+
+	match, perr := FindOneMatch(messageRegex, token)
+	if perr != nil || len(match.Captures) != 3 {
+		return nil, &ParsingError{
+			Message: "invalid message definition",
+			Token:   token,
 		}
+	}
 
-		for _, field := range fields {
-			fieldDefinition, perr := parseFieldDefinition(field)
+	options, perr := parseOptions(match.Captures[1])
+	if perr != nil {
+		return nil, perr
+	}
+
+	message := &MessageDefinition{
+		Name:     qualifyName(scope, match.Captures[0].Content),
+		Fields:   map[string]*MessageFieldDefinition{},
+		Oneofs:   map[string]*OneofDefinition{},
+		Tagged:   messageTaggedRegex.MatchString(token.Content),
+		Options:  options,
+		Reserved: newReservedDeclarations(),
+		Token:    token,
+	}
+
+	fields, perr := tokenizeMessageFields(match.Captures[2])
+	if perr != nil {
+		return nil, perr
+	}
+
+	for _, field := range fields {
+
+		fieldDefinitions := []*MessageFieldDefinition{}
+
+		switch field.Type {
+		case MessageTokenType:
+			nestedMessage, perr := parseMessageDefinition(field, message.Name, messages, enums)
 			if perr != nil {
 				return nil, perr
 			}
+			message.Nested = append(message.Nested, nestedMessage.Name)
+			continue
 
-			_, ok := message.Fields[fieldDefinition.Name]
+		case EnumTokenType:
+			enum, perr := parseEnumDefinition(field)
+			if perr != nil {
+				return nil, perr
+			}
+			enum.Name = qualifyName(message.Name, enum.Name)
+			_, ok := enums[enum.Name]
 			if ok {
 				return nil, &ParsingError{
-					Message: fmt.Sprintf("duplicate field definition %s", fieldDefinition.Name),
+					Message: fmt.Sprintf("duplicate enum definition %s", enum.Name),
 					Token:   field,
 				}
 			}
-			message.Fields[fieldDefinition.Name] = fieldDefinition
-		}
-
-		// ensure message indices are valid and sequential
-		indices := make(map[uint32]bool)
-		for _, field := range message.Fields {
+			enums[enum.Name] = enum
+			message.Nested = append(message.Nested, enum.Name)
+			continue
 
-			// track indices
-			_, ok := indices[field.Index]
+		case MessageOneofTokenType:
+			oneof, oneofFields, perr := parseOneofDefinition(field)
+			if perr != nil {
+				return nil, perr
+			}
+			_, ok := message.Oneofs[oneof.Name]
 			if ok {
 				return nil, &ParsingError{
-					Message: fmt.Sprintf("duplicate index %d in definition %s", field.Index, message.Name),
-					Token:   field.Token,
+					Message: fmt.Sprintf("duplicate oneof definition %s", oneof.Name),
+					Token:   field,
+				}
+			}
+			message.Oneofs[oneof.Name] = oneof
+			fieldDefinitions = append(fieldDefinitions, oneofFields...)
+
+		default:
+			if isReservedDeclaration(field) {
+				perr := parseReservedDeclaration(field, message.Reserved)
+				if perr != nil {
+					return nil, perr
 				}
+				continue
+			}
+
+			fieldDefinition, perr := parseFieldDefinition(field)
+			if perr != nil {
+				return nil, perr
 			}
-			indices[field.Index] = true
+			fieldDefinitions = append(fieldDefinitions, fieldDefinition)
 		}
-		for i := 0; i < len(message.Fields); i++ {
-			_, ok := indices[uint32(i)]
-			if !ok {
+
+		for _, fieldDefinition := range fieldDefinitions {
+			_, ok := message.Fields[fieldDefinition.Name]
+			if ok {
 				return nil, &ParsingError{
-					Message: fmt.Sprintf("missing index %d in message definition %s", i, message.Name),
-					Token:   message.Token,
+					Message: fmt.Sprintf("duplicate field definition %s", fieldDefinition.Name),
+					Token:   fieldDefinition.Token,
 				}
 			}
+			message.Fields[fieldDefinition.Name] = fieldDefinition
 		}
+	}
 
-		_, ok := messages[message.Name]
-		if ok {
+	// a nested message and enum would share the same generated name
+	nested := map[string]bool{}
+	for _, name := range message.Nested {
+		if nested[name] {
 			return nil, &ParsingError{
-				Message: fmt.Sprintf("duplicate message definition %s", message.Name),
+				Message: fmt.Sprintf("duplicate nested declaration %s", name),
 				Token:   token,
 			}
 		}
-		messages[message.Name] = message
+		nested[name] = true
+	}
+
+	// the oneof itself is generated as a field, so its name must not
+	// collide with any other field
+	for name, oneof := range message.Oneofs {
+		_, ok := message.Fields[name]
+		if ok {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("oneof %s collides with a field of the same name", name),
+				Token:   oneof.Token,
+			}
+		}
+	}
+
+	// retired indices and names must not be reused
+	for _, field := range message.FieldsByIndex() {
+		if message.Reserved.IsReservedIndex(field.Index) {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("field %s uses reserved index %d in definition %s", field.Name, field.Index, message.Name),
+				Token:   field.Token,
+			}
+		}
+		if message.Reserved.IsReservedName(field.Name) {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("field %s uses a reserved name in definition %s", field.Name, message.Name),
+				Token:   field.Token,
+			}
+		}
+	}
+
+	// ensure message indices are unique, whether they must also be
+	// sequential depends on the package declaration, so it is checked once
+	// the file is assembled
+	indices := make(map[uint32]bool)
+	for _, field := range message.Fields {
+
+		// track indices
+		_, ok := indices[field.Index]
+		if ok {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("duplicate index %d in definition %s", field.Index, message.Name),
+				Token:   field.Token,
+			}
+		}
+		indices[field.Index] = true
 	}
 
-	return messages, nil
+	_, ok := messages[message.Name]
+	if ok {
+		return nil, &ParsingError{
+			Message: fmt.Sprintf("duplicate message definition %s", message.Name),
+			Token:   token,
+		}
+	}
+	messages[message.Name] = message
+
+	return message, nil
+}
+
+// qualifyName returns the name of a declaration within the provided scope.
+func qualifyName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
 }
@@ -43,7 +43,7 @@ func TestMessageFieldTokenizer(t *testing.T) {
 		match, err := FindOneMatch(messageRegex, token)
 		require.Nil(t, err)
 
-		fields, err := tokenizeMessageFields(match.Captures[1])
+		fields, err := tokenizeMessageFields(match.Captures[2])
 		require.Nil(t, err)
 
 		for _, field := range fields {
@@ -83,7 +83,7 @@ func TestMessageParser(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	messages, err := parseMessageDefinitions(tokens)
+	messages, _, err := parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 
 	assert.Equal(t, 1, len(messages))
@@ -195,7 +195,7 @@ func TestMessageListParser(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	_, err = parseMessageDefinitions(tokens)
+	_, _, err = parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 }
 
@@ -209,7 +209,7 @@ func TestMessageMapParser(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	_, err = parseMessageDefinitions(tokens)
+	_, _, err = parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 }
 
@@ -224,10 +224,248 @@ func TestMessageNestedParser(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	_, err = parseMessageDefinitions(tokens)
+	_, _, err = parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 }
 
+func TestMessageBytesParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			bytes data = 0;
+			optional bytes thumbnail = 1;
+			list<bytes> chunks = 2;
+			map<string, bytes> blobs = 3;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeBytes, fields["data"].DataTypeDefinition.Type)
+	assert.Equal(t, DataTypeBytes, fields["thumbnail"].DataTypeDefinition.Type)
+	assert.Equal(t, "list<bytes>", fields["chunks"].DataTypeDefinition.ToString())
+	assert.Equal(t, "map<string, bytes>", fields["blobs"].DataTypeDefinition.ToString())
+}
+
+func TestMessageDurationParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			duration timeout = 0;
+			optional duration ttl = 1;
+			list<duration> backoff = 2;
+			map<string, duration> deadlines = 3;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeDuration, fields["timeout"].DataTypeDefinition.Type)
+	assert.True(t, fields["ttl"].Optional)
+	assert.Equal(t, "list<duration>", fields["backoff"].DataTypeDefinition.ToString())
+	assert.Equal(t, "map<string, duration>", fields["deadlines"].DataTypeDefinition.ToString())
+}
+
+func TestMessageSetParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			set<string> tags = 0;
+			set< uuid > ids = 1;
+			set<some.pkg.KeyType> keys = 2;
+			list<set<int32>> groups = 3;
+			map<string, set<uint64>> index = 4;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeSet, fields["tags"].DataTypeDefinition.Type)
+	assert.Equal(t, DataTypeComparableString, fields["tags"].DataTypeDefinition.Key.Type)
+	assert.Equal(t, "set<uuid>", fields["ids"].DataTypeDefinition.ToString())
+	assert.Equal(t, DataTypeComparableCustom, fields["keys"].DataTypeDefinition.Key.Type)
+	assert.Equal(t, "KeyType", fields["keys"].DataTypeDefinition.Key.CustomType)
+	assert.Equal(t, "list<set<int32>>", fields["groups"].DataTypeDefinition.ToString())
+	assert.Equal(t, "map<string, set<uint64>>", fields["index"].DataTypeDefinition.ToString())
+}
+
+func TestMessageSetErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { set<> a = 0; }`,
+		`message A { set<list<string>> a = 0; }`,
+		`message A { set<string, string> a = 0; }`,
+		`message A { optional set<string> a = 0; }`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestMessageBitIntParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			uint<5> flags = 0;
+			int< 12 > delta = 1;
+			optional uint<1> toggle = 2;
+			list<int<3>> steps = 3;
+			map<string, uint<64>> wide = 4;
+			array<uint<4>, 2> nibbles = 5;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeUIntN, fields["flags"].DataTypeDefinition.Type)
+	assert.Equal(t, uint32(5), fields["flags"].DataTypeDefinition.Bits)
+	assert.Equal(t, DataTypeIntN, fields["delta"].DataTypeDefinition.Type)
+	assert.Equal(t, uint32(12), fields["delta"].DataTypeDefinition.Bits)
+	assert.True(t, fields["toggle"].Optional)
+	assert.Equal(t, "list<int<3>>", fields["steps"].DataTypeDefinition.ToString())
+	assert.Equal(t, "map<string, uint<64>>", fields["wide"].DataTypeDefinition.ToString())
+	assert.Equal(t, "array<uint<4>, 2>", fields["nibbles"].DataTypeDefinition.ToString())
+}
+
+func TestMessageBitIntErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { uint<0> a = 0; }`,
+		`message A { int<65> a = 0; }`,
+		`message A { uint<> a = 0; }`,
+		`message A { uint<-1> a = 0; }`,
+		`message A { uint<x> a = 0; }`,
+		`message A { map<uint<5>, string> a = 0; }`,
+		`message A { set<int<5>> a = 0; }`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestMessageQuantParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			quant<float32, -100.0, 100.0, 12> x = 0;
+			quant< float64 , 0 , 1e3 , 32 > y = 1;
+			optional quant<float32, -1, 1, 8> z = 2;
+			list<quant<float32, -3.5, 3.5, 10>> samples = 3;
+			map<string, quant<float32, 0, 1, 4>> weights = 4;
+			array<quant<float32, 0, 360, 9>, 3> angles = 5;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeQuant, fields["x"].DataTypeDefinition.Type)
+	assert.Equal(t, DataTypeFloat32, fields["x"].DataTypeDefinition.SubType.Type)
+	assert.Equal(t, -100.0, fields["x"].DataTypeDefinition.QuantMin)
+	assert.Equal(t, 100.0, fields["x"].DataTypeDefinition.QuantMax)
+	assert.Equal(t, uint32(12), fields["x"].DataTypeDefinition.Bits)
+	assert.Equal(t, "quant<float64, 0, 1000, 32>", fields["y"].DataTypeDefinition.ToString())
+	assert.True(t, fields["z"].Optional)
+	assert.Equal(t, "list<quant<float32, -3.5, 3.5, 10>>", fields["samples"].DataTypeDefinition.ToString())
+	assert.Equal(t, "map<string, quant<float32, 0, 1, 4>>", fields["weights"].DataTypeDefinition.ToString())
+	assert.Equal(t, "array<quant<float32, 0, 360, 9>, 3>", fields["angles"].DataTypeDefinition.ToString())
+}
+
+func TestMessageQuantErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { quant<float32, 0, 1, 0> a = 0; }`,
+		`message A { quant<float32, 0, 1, 33> a = 0; }`,
+		`message A { quant<float32, 1, 1, 8> a = 0; }`,
+		`message A { quant<float32, 1, 0, 8> a = 0; }`,
+		`message A { quant<float32, 0, inf, 8> a = 0; }`,
+		`message A { quant<float32, x, 1, 8> a = 0; }`,
+		`message A { quant<int32, 0, 1, 8> a = 0; }`,
+		`message A { quant<float32, 0, 1> a = 0; }`,
+		`message A { set<quant<float32, 0, 1, 8>> a = 0; }`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestMessageArrayParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			array<float32, 16> matrix = 0;
+			array< byte , 32 > hash = 1;
+			array<map<string, int32>, 4> maps = 2;
+			list<array<array<uint8, 2>, 3>> grids = 3;
+			map<string, array<some.pkg.MyType, 2>> pairs = 4;
+		}
+	`)
+	require.Nil(t, err)
+
+	msgs, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := msgs["TestMessage"].Fields
+	assert.Equal(t, DataTypeArray, fields["matrix"].DataTypeDefinition.Type)
+	assert.Equal(t, uint32(16), fields["matrix"].DataTypeDefinition.Length)
+	assert.Equal(t, "array<float32, 16>", fields["matrix"].DataTypeDefinition.ToString())
+	assert.Equal(t, "array<byte, 32>", fields["hash"].DataTypeDefinition.ToString())
+	assert.Equal(t, "array<map<string, int32>, 4>", fields["maps"].DataTypeDefinition.ToString())
+	assert.Equal(t, "list<array<array<uint8, 2>, 3>>", fields["grids"].DataTypeDefinition.ToString())
+	assert.Equal(t, "MyType", fields["pairs"].DataTypeDefinition.GetElementType().CustomType)
+	assert.False(t, fields["matrix"].IsIndirect())
+	assert.True(t, fields["grids"].IsIndirect())
+}
+
+func TestMessageArrayErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { array<float32> a = 0; }`,
+		`message A { array<float32, 0> a = 0; }`,
+		`message A { array<float32, -1> a = 0; }`,
+		`message A { array<float32, N> a = 0; }`,
+		`message A { array<float32, 99999999999> a = 0; }`,
+		`message A { optional array<float32, 2> a = 0; }`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
+
 func TestMessageListTypedefs(t *testing.T) {
 
 	tokens, err := tokenizeFile(`
@@ -241,7 +479,7 @@ func TestMessageListTypedefs(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	_, err = parseMessageDefinitions(tokens)
+	_, _, err = parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 }
 
@@ -261,7 +499,7 @@ func TestMessageMapTypedefs(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	_, err = parseMessageDefinitions(tokens)
+	_, _, err = parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 }
 
@@ -288,7 +526,7 @@ func TestMultipleMessages(t *testing.T) {
 	`)
 	require.Nil(t, err)
 
-	messages, err := parseMessageDefinitions(tokens)
+	messages, _, err := parseMessageDefinitions(tokens)
 	require.Nil(t, err)
 
 	assert.Equal(t, 3, len(messages))
@@ -306,3 +544,268 @@ func TestMultipleMessages(t *testing.T) {
 	assert.Equal(t, "TestMessageC", messageC.Name)
 	assert.Equal(t, 1, len(messageC.Fields))
 }
+
+func TestMessageTaggedParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessageA tagged {
+			uint32 a = 0;
+			string c = 2;
+		}
+		message TestMessageB {
+			uint32 a = 0;
+		}
+	`)
+	require.Nil(t, err)
+
+	messages, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	assert.True(t, messages["TestMessageA"].Tagged)
+	assert.False(t, messages["TestMessageB"].Tagged)
+
+	fields := messages["TestMessageA"].FieldsByIndex()
+	require.Equal(t, 2, len(fields))
+	assert.Equal(t, "a", fields[0].Name)
+	assert.Equal(t, "c", fields[1].Name)
+
+	require.Nil(t, validateSequentialIndices(messages["TestMessageA"]))
+}
+
+func TestMessageUntaggedMissingIndexErr(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			uint32 a = 0;
+			string c = 2;
+		}
+	`)
+	require.Nil(t, err)
+
+	messages, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	require.NotNil(t, validateSequentialIndices(messages["TestMessage"]))
+}
+
+func TestMessageOptionalParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			optional uint32 count = 0;
+			string name = 1;
+			optional some.other_package.CustomType custom = 2;
+		}
+	`)
+	require.Nil(t, err)
+
+	messages, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	fields := messages["TestMessage"].FieldsByIndex()
+	require.Equal(t, 3, len(fields))
+
+	assert.Equal(t, "count", fields[0].Name)
+	assert.True(t, fields[0].Optional)
+	assert.Equal(t, DataTypeUInt32, fields[0].DataTypeDefinition.Type)
+
+	assert.Equal(t, "name", fields[1].Name)
+	assert.False(t, fields[1].Optional)
+
+	assert.Equal(t, "custom", fields[2].Name)
+	assert.True(t, fields[2].Optional)
+	assert.Equal(t, DataTypeCustom, fields[2].DataTypeDefinition.Type)
+	assert.Equal(t, "CustomType", fields[2].DataTypeDefinition.CustomType)
+}
+
+func TestMessageOptionalContainerErr(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			optional list<string> names = 0;
+		}
+	`)
+	require.Nil(t, err)
+
+	_, _, err = parseMessageDefinitions(tokens)
+	require.NotNil(t, err)
+}
+
+func TestMessageOneofParser(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message TestMessage {
+			string name = 0;
+			oneof shape {
+				Circle circle = 1;
+				Square square = 2;
+			}
+			uint32 count = 3;
+		}
+	`)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(tokens))
+
+	messages, _, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	msg := messages["TestMessage"]
+	require.Equal(t, 4, len(msg.Fields))
+	require.Equal(t, 1, len(msg.Oneofs))
+	require.NotNil(t, msg.Oneofs["shape"])
+
+	members := msg.OneofFieldsByIndex("shape")
+	require.Equal(t, 2, len(members))
+	assert.Equal(t, "circle", members[0].Name)
+	assert.Equal(t, "Circle", members[0].DataTypeDefinition.CustomType)
+	assert.Equal(t, "square", members[1].Name)
+
+	assert.True(t, msg.IsFirstOneofField(msg.Fields["circle"]))
+	assert.False(t, msg.IsFirstOneofField(msg.Fields["square"]))
+	assert.False(t, msg.IsFirstOneofField(msg.Fields["name"]))
+	assert.Equal(t, "", msg.Fields["count"].Oneof)
+}
+
+func TestMessageOneofErrs(t *testing.T) {
+
+	inputs := []string{
+		// optional member
+		`message TestMessage {
+			oneof shape {
+				optional Circle circle = 0;
+			}
+		}`,
+		// empty
+		`message TestMessage {
+			oneof shape {
+			}
+		}`,
+		// nested
+		`message TestMessage {
+			oneof shape {
+				oneof inner {
+					Circle circle = 0;
+				}
+			}
+		}`,
+		// name collision
+		`message TestMessage {
+			string shape = 0;
+			oneof shape {
+				Circle circle = 1;
+			}
+		}`,
+		// duplicate member name
+		`message TestMessage {
+			string circle = 0;
+			oneof shape {
+				Circle circle = 1;
+			}
+		}`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestMessageNestedDeclarations(t *testing.T) {
+
+	tokens, err := tokenizeFile(`
+		message Order {
+			enum Status {
+				PENDING = 0;
+				SHIPPED = 1;
+			}
+			message Line {
+				message Note {
+					string text = 0;
+				}
+				string sku = 0;
+				list<Note> notes = 1;
+			}
+			Status status = 0;
+			list<Line> lines = 1;
+		}
+	`)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(tokens))
+
+	messages, enums, err := parseMessageDefinitions(tokens)
+	require.Nil(t, err)
+
+	require.Equal(t, 3, len(messages))
+	require.Equal(t, 1, len(enums))
+
+	order := messages["Order"]
+	require.NotNil(t, order)
+	assert.Equal(t, 2, len(order.Fields))
+	assert.Equal(t, []string{"Order.Status", "Order.Line"}, order.Nested)
+	assert.False(t, order.IsNested())
+
+	line := messages["Order.Line"]
+	require.NotNil(t, line)
+	assert.Equal(t, 2, len(line.Fields))
+	assert.Equal(t, []string{"Order.Line.Note"}, line.Nested)
+	assert.True(t, line.IsNested())
+
+	require.NotNil(t, messages["Order.Line.Note"])
+	require.NotNil(t, enums["Order.Status"])
+	assert.Equal(t, 2, len(enums["Order.Status"].Values))
+}
+
+func TestMessageNestedDeclarationErrs(t *testing.T) {
+
+	inputs := []string{
+		// duplicate nested message
+		`message Order {
+			message Line {
+				string sku = 0;
+			}
+			message Line {
+				string sku = 0;
+			}
+		}`,
+		// nested message and enum of the same name
+		`message Order {
+			enum Line {
+				A = 0;
+			}
+			message Line {
+				string sku = 0;
+			}
+		}`,
+		// unrecognized block
+		`message Order {
+			service Line {
+				rpc Do (A) returns (B);
+			}
+		}`,
+		// declaration within a oneof
+		`message Order {
+			oneof value {
+				message Line {
+					string sku = 0;
+				}
+			}
+		}`,
+		// invalid nested message
+		`message Order {
+			message Line {
+				string sku = 0
+			}
+		}`,
+	}
+
+	for _, input := range inputs {
+		tokens, err := tokenizeFile(input)
+		require.Nil(t, err)
+
+		_, _, err = parseMessageDefinitions(tokens)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -0,0 +1,306 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	optionRegex         = regexp.MustCompile(`^\s*([a-zA-Z][a-zA-Z_0-9]*)\s*(?:=\s*(.+?))?\s*$`)
+	optionStringRegex   = regexp.MustCompile(`^"([^"]*)"$`)
+	optionBoolRegex     = regexp.MustCompile(`^(true|false)$`)
+	optionJSONNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9\-]*$`)
+)
+
+// OptionTarget is the kind of declaration an option list is attached to.
+type OptionTarget string
+
+const (
+	OptionTargetMessage   OptionTarget = "message"
+	OptionTargetField     OptionTarget = "field"
+	OptionTargetEnum      OptionTarget = "enum"
+	OptionTargetEnumValue OptionTarget = "enum value"
+	OptionTargetService   OptionTarget = "service"
+	OptionTargetMethod    OptionTarget = "method"
+)
+
+const (
+	OptionJSONName   = "json_name"
+	OptionDeprecated = "deprecated"
+	OptionIdempotent = "idempotent"
+)
+
+type optionValueType int
+
+const (
+	optionValueBool optionValueType = iota
+	optionValueString
+)
+
+type knownOption struct {
+	valueType optionValueType
+	targets   []OptionTarget
+}
+
+var knownOptions = map[string]knownOption{
+	OptionJSONName: {
+		valueType: optionValueString,
+		targets:   []OptionTarget{OptionTargetField},
+	},
+	OptionDeprecated: {
+		valueType: optionValueBool,
+		targets: []OptionTarget{
+			OptionTargetMessage,
+			OptionTargetField,
+			OptionTargetEnum,
+			OptionTargetEnumValue,
+			OptionTargetService,
+			OptionTargetMethod,
+		},
+	},
+	OptionIdempotent: {
+		valueType: optionValueBool,
+		targets:   []OptionTarget{OptionTargetMethod},
+	},
+}
+
+// OptionDefinition is a single entry of a bracketed option list, such as
+// `[json_name="mail", deprecated]`. An option declared without a value is set
+// to true. String values are stored without their quotes.
+type OptionDefinition struct {
+	Name   string
+	Value  string
+	Quoted bool
+	Token  *Token
+}
+
+// Options holds the options attached to a declaration by name.
+type Options map[string]*OptionDefinition
+
+// Bool returns true if the named option is set to true.
+func (o Options) Bool(name string) bool {
+	opt, ok := o[name]
+	return ok && !opt.Quoted && opt.Value == "true"
+}
+
+// String returns the value of the named option, or an empty string if it is
+// not set.
+func (o Options) String(name string) string {
+	opt, ok := o[name]
+	if !ok {
+		return ""
+	}
+	return opt.Value
+}
+
+// OptionsSortedByKey returns the options in ascending name order.
+func (o Options) OptionsSortedByKey() []*OptionDefinition {
+	res := make([]*OptionDefinition, 0, len(o))
+	for _, opt := range o {
+		res = append(res, opt)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// parseOptions parses the content between the brackets of an option list. An
+// absent option list yields no options.
+func parseOptions(input *Token) (Options, *ParsingError) {
+
+	options := Options{}
+
+	if strings.TrimSpace(input.Content) == "" {
+		return options, nil
+	}
+
+	// split on commas that are not within a string value
+	bounds := [][2]int{}
+	start := 0
+	inString := false
+	for i, c := range input.Content {
+		if c == '"' {
+			inString = !inString
+		} else if c == ',' && !inString {
+			bounds = append(bounds, [2]int{start, i})
+			start = i + 1
+		}
+	}
+	bounds = append(bounds, [2]int{start, len(input.Content)})
+
+	for _, bound := range bounds {
+		if strings.TrimSpace(input.Content[bound[0]:bound[1]]) == "" {
+			return nil, &ParsingError{
+				Message: "empty option in option list",
+				Token:   input,
+			}
+		}
+
+		token, perr := createSubMatchToken(input, bound[0], bound[1])
+		if perr != nil {
+			return nil, perr
+		}
+
+		match, perr := FindOneMatch(optionRegex, token)
+		if perr != nil || len(match.Captures) != 2 {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid option `%s`", strings.TrimSpace(token.Content)),
+				Token:   token,
+			}
+		}
+
+		option := &OptionDefinition{
+			Name:  match.Captures[0].Content,
+			Value: "true",
+			Token: match.Captures[0],
+		}
+
+		value := match.Captures[1].Content
+		if value != "" {
+			stringMatch := optionStringRegex.FindStringSubmatch(value)
+			if stringMatch != nil {
+				option.Value = stringMatch[1]
+				option.Quoted = true
+			} else if strings.Contains(value, "\"") {
+				return nil, &ParsingError{
+					Message: fmt.Sprintf("invalid value `%s` for option %s", value, option.Name),
+					Token:   match.Captures[1],
+				}
+			} else {
+				option.Value = value
+			}
+		}
+
+		_, ok := options[option.Name]
+		if ok {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("duplicate option %s", option.Name),
+				Token:   option.Token,
+			}
+		}
+		options[option.Name] = option
+	}
+
+	return options, nil
+}
+
+// validateOptions checks the options against those that are known. A known
+// option with a value of the wrong type is an error, whereas an unknown option,
+// or one that does not apply to the declaration, is reported as a warning so
+// that typos surface without breaking generation.
+func validateOptions(target OptionTarget, options Options) ([]*ParsingError, *ParsingError) {
+
+	warnings := []*ParsingError{}
+
+	for _, option := range options.OptionsSortedByKey() {
+		known, ok := knownOptions[option.Name]
+		if !ok {
+			warnings = append(warnings, &ParsingError{
+				Message: fmt.Sprintf("unknown %s option %s", target, option.Name),
+				Token:   option.Token,
+			})
+			continue
+		}
+
+		applies := false
+		for _, t := range known.targets {
+			if t == target {
+				applies = true
+				break
+			}
+		}
+		if !applies {
+			warnings = append(warnings, &ParsingError{
+				Message: fmt.Sprintf("option %s does not apply to a %s", option.Name, target),
+				Token:   option.Token,
+			})
+			continue
+		}
+
+		switch known.valueType {
+		case optionValueBool:
+			if option.Quoted || !optionBoolRegex.MatchString(option.Value) {
+				return nil, &ParsingError{
+					Message: fmt.Sprintf("option %s must be true or false", option.Name),
+					Token:   option.Token,
+				}
+			}
+		case optionValueString:
+			if !option.Quoted {
+				return nil, &ParsingError{
+					Message: fmt.Sprintf("option %s must be a quoted string", option.Name),
+					Token:   option.Token,
+				}
+			}
+		}
+
+		if option.Name == OptionJSONName && !optionJSONNameRegex.MatchString(option.Value) {
+			return nil, &ParsingError{
+				Message: fmt.Sprintf("invalid json name `%s`", option.Value),
+				Token:   option.Token,
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// validateFileOptions validates the options of every declaration within the
+// file, returning any warnings ordered by the name of the declaration.
+func validateFileOptions(f *File) ([]*ParsingError, *ParsingError) {
+
+	warnings := []*ParsingError{}
+
+	validate := func(target OptionTarget, options Options) *ParsingError {
+		res, perr := validateOptions(target, options)
+		if perr != nil {
+			return perr
+		}
+		warnings = append(warnings, res...)
+		return nil
+	}
+
+	for _, enum := range f.EnumsSortedByKey() {
+		perr := validate(OptionTargetEnum, enum.Options)
+		if perr != nil {
+			return nil, perr
+		}
+		for _, value := range enum.ValuesByIndex() {
+			perr := validate(OptionTargetEnumValue, value.Options)
+			if perr != nil {
+				return nil, perr
+			}
+		}
+	}
+
+	for _, msg := range f.MessagesSortedByKey() {
+		perr := validate(OptionTargetMessage, msg.Options)
+		if perr != nil {
+			return nil, perr
+		}
+		for _, field := range msg.FieldsByIndex() {
+			perr := validate(OptionTargetField, field.Options)
+			if perr != nil {
+				return nil, perr
+			}
+		}
+	}
+
+	for _, svc := range f.ServicesSortedByKey() {
+		perr := validate(OptionTargetService, svc.Options)
+		if perr != nil {
+			return nil, perr
+		}
+		for _, method := range svc.MethodsSortedByKey() {
+			perr := validate(OptionTargetMethod, method.Options)
+			if perr != nil {
+				return nil, perr
+			}
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionParser(t *testing.T) {
+
+	content := `
+		package test;
+
+		enum Status [deprecated] {
+			ACTIVE = 0;
+			INACTIVE = 1 [ deprecated = true ];
+		}
+
+		message User tagged [deprecated=true] {
+			string email = 1 [json_name="mail", deprecated];
+			Status status = 2;
+		}
+
+		service Users [deprecated=false] {
+			rpc Get (User) returns (User) [idempotent];
+			rpc Set (User) returns (User);
+		}
+	`
+
+	f, err := parseFileContent("test.scg", ".", content)
+	require.Nil(t, err)
+	assert.Empty(t, f.Warnings)
+
+	status := f.Enums["Status"]
+	assert.True(t, status.Options.Bool(OptionDeprecated))
+	assert.False(t, status.Values["ACTIVE"].Options.Bool(OptionDeprecated))
+	assert.True(t, status.Values["INACTIVE"].Options.Bool(OptionDeprecated))
+
+	user := f.MessageDefinitions["User"]
+	assert.True(t, user.Tagged)
+	assert.True(t, user.Options.Bool(OptionDeprecated))
+	assert.Equal(t, "mail", user.Fields["email"].Options.String(OptionJSONName))
+	assert.True(t, user.Fields["email"].Options[OptionJSONName].Quoted)
+	assert.True(t, user.Fields["email"].Options.Bool(OptionDeprecated))
+	assert.Empty(t, user.Fields["status"].Options)
+
+	users := f.ServiceDefinitions["Users"]
+	assert.False(t, users.Options.Bool(OptionDeprecated))
+	assert.True(t, users.Methods["Get"].Options.Bool(OptionIdempotent))
+	assert.False(t, users.Methods["Set"].Options.Bool(OptionIdempotent))
+
+	// options retain their position within the file
+	option := user.Fields["email"].Options[OptionJSONName]
+	slice := getContentByTokenRange(content, option.Token.LineStart, option.Token.LineEnd, option.Token.LineStartCharacterPosition, option.Token.LineEndCharacterPosition)
+	assert.Equal(t, "json_name", slice)
+}
+
+func TestOptionWarnings(t *testing.T) {
+
+	f, err := parseFileContent("test.scg", ".", `
+		package test;
+
+		message User [idempotent] {
+			string email = 0 [jsonname="mail"];
+		}
+	`)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(f.Warnings))
+
+	assert.Contains(t, f.Warnings[0].Message, "option idempotent does not apply to a message")
+	assert.Equal(t, 3, f.Warnings[0].Token.LineStart)
+	assert.Contains(t, f.Warnings[1].Message, "unknown field option jsonname")
+	assert.Equal(t, 4, f.Warnings[1].Token.LineStart)
+
+	// unknown options are retained for the generators
+	assert.Equal(t, "mail", f.MessageDefinitions["User"].Fields["email"].Options.String("jsonname"))
+}
+
+func TestOptionWarningsReported(t *testing.T) {
+
+	p, err := NewParseFromFiles(".", map[string]string{
+		"b.scg": `
+			package test;
+			message B {
+				string b = 0 [deprected];
+			}
+		`,
+		"a.scg": `
+			package test;
+			message A {
+				string a = 0 [deprected];
+			}
+		`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(p.Warnings))
+	assert.Equal(t, "a.scg", p.Warnings[0].Filename)
+	assert.Equal(t, "b.scg", p.Warnings[1].Filename)
+}
+
+func TestOptionErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { string a = 0 [deprecated="yes"]; }`,
+		`message A { string a = 0 [deprecated=yes]; }`,
+		`message A { string a = 0 [json_name=mail]; }`,
+		`message A { string a = 0 [json_name="has space"]; }`,
+		`message A { string a = 0 [json_name=""]; }`,
+		`message A { string a = 0 [deprecated, deprecated]; }`,
+		`message A { string a = 0 [deprecated,]; }`,
+		`message A { string a = 0 [deprecated=]; }`,
+		`message A { string a = 0 [json_name="a"b"]; }`,
+		`message A [deprecated] tagged { string a = 0; }`,
+		`message A [deprecated] other { string a = 0; }`,
+		`enum A [deprecated="true"] { B = 0; }`,
+		`service A { rpc B (C) returns (D) [idempotent=1]; }`,
+	}
+
+	for _, input := range inputs {
+		_, err := parseFileContent("test.scg", ".", "package test;\n"+input)
+		assert.NotNil(t, err, input)
+	}
+}
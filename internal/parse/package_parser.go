@@ -5,12 +5,13 @@ import (
 )
 
 var (
-	packageRegex = regexp.MustCompile(`package\s+((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\;`)
+	packageRegex = regexp.MustCompile(`package\s+((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)(?:\s+(tagged))?\s*\;`)
 )
 
 type PackageDeclaration struct {
-	Name  string
-	Token *Token
+	Name   string
+	Tagged bool
+	Token  *Token
 }
 
 func parsePackageDeclaration(tokens []*Token) (*PackageDeclaration, *ParsingError) {
@@ -23,7 +24,7 @@ func parsePackageDeclaration(tokens []*Token) (*PackageDeclaration, *ParsingErro
 		}
 
 		match, perr := FindOneMatch(packageRegex, token)
-		if perr != nil || len(match.Captures) != 1 {
+		if perr != nil || len(match.Captures) != 2 {
 			return nil, &ParsingError{
 				Message: "invalid package definition",
 				Token:   token,
@@ -38,8 +39,9 @@ func parsePackageDeclaration(tokens []*Token) (*PackageDeclaration, *ParsingErro
 		}
 
 		pkg = &PackageDeclaration{
-			Name:  match.Captures[0].Content,
-			Token: token,
+			Name:   match.Captures[0].Content,
+			Tagged: match.Captures[1].Content != "",
+			Token:  token,
 		}
 	}
 
@@ -21,6 +21,12 @@ var (
 	constDeclaration = map[string]TokenType{
 		"const": ConstTokenType,
 	}
+	importDeclaration = map[string]TokenType{
+		"import": ImportTokenType,
+	}
+	declarationModifiers = map[string]bool{
+		"tagged": true,
+	}
 )
 
 type ParseState struct {
@@ -44,6 +50,8 @@ type BlockScopedNamedDeclarationParser struct {
 	expectingName              bool
 	expectingOpeningBracket    bool
 	consumeUntilClosingBracket bool
+	inOptions                  bool
+	depth                      int
 }
 
 func (p *BlockScopedNamedDeclarationParser) Consume(c rune, state *ParseState) (TokenParser, error) {
@@ -73,7 +81,37 @@ func (p *BlockScopedNamedDeclarationParser) Consume(c rune, state *ParseState) (
 
 		state.currentToken.Content += string(c)
 
-		if c == '{' {
+		if p.inOptions {
+			// the option list is validated once the declaration is parsed
+			if c == ']' {
+				p.inOptions = false
+			}
+
+		} else if c == '[' && !p.consumeUntilClosingBracket {
+			// an option list may follow the name and any modifiers
+			if p.expectingName && state.word == "" {
+				return nil, fmt.Errorf("missing name")
+			}
+
+			if p.expectingOpeningBracket && state.inWord && !declarationModifiers[state.word] {
+				return nil, fmt.Errorf("unexpected keyword `%s`", state.word)
+			}
+
+			p.expectingName = false
+			p.expectingOpeningBracket = true
+			p.inOptions = true
+
+			state.word = ""
+			state.inWord = false
+
+		} else if c == '{' && p.consumeUntilClosingBracket {
+			// nested block within the declaration body
+			p.depth++
+
+		} else if c == '}' && p.depth > 0 {
+			p.depth--
+
+		} else if c == '{' {
 			if !p.expectingOpeningBracket && !p.expectingName {
 				return nil, fmt.Errorf("unexpected opening bracket")
 			}
@@ -82,6 +120,10 @@ func (p *BlockScopedNamedDeclarationParser) Consume(c rune, state *ParseState) (
 				return nil, fmt.Errorf("missing name")
 			}
 
+			if p.expectingOpeningBracket && state.inWord && !declarationModifiers[state.word] {
+				return nil, fmt.Errorf("unexpected keyword `%s`", state.word)
+			}
+
 			p.expectingName = false
 			p.expectingOpeningBracket = false
 			p.consumeUntilClosingBracket = true
@@ -106,9 +148,8 @@ func (p *BlockScopedNamedDeclarationParser) Consume(c rune, state *ParseState) (
 			return &KeywordTokenParser{}, nil
 
 		} else {
-			if p.expectingOpeningBracket {
-				return nil, fmt.Errorf("unexpected character `%s`", string(c))
-			}
+			// between the name and the opening bracket only modifiers are
+			// permitted, they are validated once the word is complete
 			state.word += string(c)
 			state.inWord = true
 		}
@@ -122,6 +163,12 @@ func (p *BlockScopedNamedDeclarationParser) Consume(c rune, state *ParseState) (
 			p.expectingName = false
 			p.expectingOpeningBracket = true
 
+			state.word = ""
+			state.inWord = false
+		} else if p.expectingOpeningBracket {
+			if !declarationModifiers[state.word] {
+				return nil, fmt.Errorf("unexpected keyword `%s`", state.word)
+			}
 			state.word = ""
 			state.inWord = false
 		}
@@ -155,6 +202,80 @@ func (p *PackageDeclarationParser) Consume(c rune, state *ParseState) (TokenPars
 		breakWord = true
 		state.currentToken.Content += " " // replace comment with whitespace
 
+	} else {
+		// character
+		state.currentToken.Content += string(c)
+
+		if c == ';' {
+			if !p.expectingSemiColon && !state.inWord {
+				return nil, fmt.Errorf("unexpected semi-colon")
+			}
+
+			if p.expectingSemiColon && state.inWord && !declarationModifiers[state.word] {
+				return nil, fmt.Errorf("unexpected keyword `%s`", state.word)
+			}
+
+			state.currentToken.LineEnd = state.line
+			state.currentToken.LineEndCharacterPosition = state.character
+
+			state.tokens = append(state.tokens, state.currentToken)
+			state.currentToken = nil
+
+			state.word = ""
+			state.inWord = false
+
+			// done
+			return &KeywordTokenParser{}, nil
+		} else {
+			// between the name and the semi-colon only modifiers are
+			// permitted, they are validated once the word is complete
+			state.word += string(c)
+			state.inWord = true
+		}
+	}
+
+	if breakWord && state.inWord {
+		if p.expectingPackageName {
+			p.expectingPackageName = false
+			p.expectingSemiColon = true
+			state.word = ""
+			state.inWord = false
+		} else if p.expectingSemiColon && declarationModifiers[state.word] {
+			state.word = ""
+			state.inWord = false
+		} else {
+			return nil, fmt.Errorf("unexpected keyword `%s`", state.word)
+		}
+	}
+
+	return p, nil
+}
+
+type ImportDeclarationParser struct {
+	expectingImportPath bool
+	expectingSemiColon  bool
+}
+
+func (p *ImportDeclarationParser) Consume(c rune, state *ParseState) (TokenParser, error) {
+	if c == '\n' {
+		state.line++
+		state.character = 0
+		state.inComment = false
+	} else {
+		state.character++
+	}
+	breakWord := false
+	if unicode.IsSpace(c) {
+		// whitespace
+		state.currentToken.Content += string(c)
+		breakWord = true
+
+	} else if c == commentDelimiter || state.inComment {
+		// comment
+		state.inComment = true
+		breakWord = true
+		state.currentToken.Content += " " // replace comment with whitespace
+
 	} else {
 		// character
 		state.currentToken.Content += string(c)
@@ -185,8 +306,8 @@ func (p *PackageDeclarationParser) Consume(c rune, state *ParseState) (TokenPars
 	}
 
 	if breakWord && state.inWord {
-		if p.expectingPackageName {
-			p.expectingPackageName = false
+		if p.expectingImportPath {
+			p.expectingImportPath = false
 			p.expectingSemiColon = true
 			state.word = ""
 			state.inWord = false
@@ -470,6 +591,15 @@ func (p *KeywordTokenParser) Consume(c rune, state *ParseState) (TokenParser, er
 			}, nil
 		}
 
+		if tokenType, ok = importDeclaration[state.word]; ok {
+			state.word = ""
+			state.inWord = false
+			state.currentToken.Type = tokenType
+			return &ImportDeclarationParser{
+				expectingImportPath: true,
+			}, nil
+		}
+
 		if tokenType, ok = typedefDeclaration[state.word]; ok {
 			state.word = ""
 			state.inWord = false
@@ -264,3 +264,37 @@ func TestFileTokenizerMessageMissingClosingBracketErr(t *testing.T) {
 	assert.Nil(t, tokens)
 	assert.NotNil(t, err)
 }
+
+func TestFileTokenizerTaggedModifier(t *testing.T) {
+
+	content := `
+		package test.custom tagged;
+
+		message CustomType tagged {
+			byte a = 1;
+		}
+
+		message OtherType tagged{
+			byte a = 1;
+		}
+	`
+	tokens, err := tokenizeFile(content)
+	require.Nil(t, err)
+
+	assert.Equal(t, 3, len(tokens))
+}
+
+func TestFileTokenizerUnknownModifierErr(t *testing.T) {
+
+	content := `
+		package test.custom;
+
+		message CustomType something {
+			byte a = 1;
+		}
+	`
+	tokens, err := tokenizeFile(content)
+
+	assert.Nil(t, tokens)
+	assert.NotNil(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -14,6 +15,7 @@ type Parse struct {
 	Version  string
 	Files    map[string]*File
 	Packages map[string]*Package
+	Warnings []*ParsingError
 }
 
 func (p *Parse) ToStringPretty() string {
@@ -120,15 +122,28 @@ func (p *Package) HashStringToMethodID(serviceName string, methodName string) (u
 	return methodID, nil
 }
 
-func NewParse(inputDir string) (*Parse, error) {
+// NewParse parses every file within the input directory. Imports are resolved
+// against the input directory followed by the include directories in order,
+// files outside of the input directory are only loaded if they are imported.
+func NewParse(inputDir string, includeDirs ...string) (*Parse, error) {
 	fileContents, err := searchInputPatternAndReadFiles(inputDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse input pattern: %s", err.Error())
 	}
+	for _, includeDir := range includeDirs {
+		err := ensureIsDir(includeDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include directory: %s", err.Error())
+		}
+	}
 	files, perr := parseFileContents(inputDir, fileContents)
 	if perr != nil {
 		return nil, perr.Error()
 	}
+	perr = resolveImports(files, includeDirs)
+	if perr != nil {
+		return nil, perr.Error()
+	}
 	p, perr := resolveFilesIntoParse(files)
 	if perr != nil {
 		return nil, perr.Error()
@@ -136,11 +151,15 @@ func NewParse(inputDir string) (*Parse, error) {
 	return p, nil
 }
 
-func NewParseFromFiles(inputDir string, fileContents map[string]string) (*Parse, error) {
+func NewParseFromFiles(inputDir string, fileContents map[string]string, includeDirs ...string) (*Parse, error) {
 	files, perr := parseFileContents(inputDir, fileContents)
 	if perr != nil {
 		return nil, fmt.Errorf("failed to parse input pattern: %s", perr.Error())
 	}
+	perr = resolveImports(files, includeDirs)
+	if perr != nil {
+		return nil, perr.Error()
+	}
 	p, perr := resolveFilesIntoParse(files)
 	if perr != nil {
 		return nil, perr.Error()
@@ -212,27 +231,10 @@ func parseFileContents(inputDir string, fileContents map[string]string) (map[str
 
 	for path, fileContent := range fileContents {
 
-		fileContent = strings.Replace(fileContent, "\t", "    ", -1)
-
-		relativePathAndFile, err := filepath.Rel(inputDir, path)
-		if err != nil {
-			return nil, &ParsingError{
-				Message:  "internal parsing error: failed to get relative path of file",
-				Token:    nil,
-				Filename: path,
-				Content:  fileContent,
-			}
-		}
-
-		relativeDir := filepath.Dir(relativePathAndFile)
-
-		f, perr := parseFileContent(path, relativeDir, fileContent)
+		relativePathAndFile, f, perr := parseFileContentRelativeTo(inputDir, path, fileContent)
 		if perr != nil {
-			perr.Filename = path
-			perr.Content = fileContent
 			return nil, perr
 		}
-		f.RelativePath = relativeDir
 
 		files[relativePathAndFile] = f
 	}
@@ -240,8 +242,110 @@ func parseFileContents(inputDir string, fileContents map[string]string) (map[str
 	return files, nil
 }
 
+// parseFileContentRelativeTo parses a file found within the provided root,
+// returning the file along with its path relative to the root.
+func parseFileContentRelativeTo(root string, path string, fileContent string) (string, *File, *ParsingError) {
+
+	fileContent = strings.Replace(fileContent, "\t", "    ", -1)
+
+	relativePathAndFile, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", nil, &ParsingError{
+			Message:  "internal parsing error: failed to get relative path of file",
+			Token:    nil,
+			Filename: path,
+			Content:  fileContent,
+		}
+	}
+
+	relativeDir := filepath.Dir(relativePathAndFile)
+
+	f, perr := parseFileContent(path, relativeDir, fileContent)
+	if perr != nil {
+		perr.Filename = path
+		perr.Content = fileContent
+		return "", nil, perr
+	}
+	f.RelativePath = relativeDir
+
+	for _, warning := range f.Warnings {
+		warning.Filename = path
+		warning.Content = fileContent
+	}
+
+	return relativePathAndFile, f, nil
+}
+
+// resolveImports links every import to the file it refers to. Imports are
+// first looked up among the provided files, then within the include
+// directories in order. Files loaded from an include directory are added to
+// the provided files, along with anything they import in turn.
+func resolveImports(files map[string]*File, includeDirs []string) *ParsingError {
+
+	queue := make([]string, 0, len(files))
+	for k := range files {
+		queue = append(queue, k)
+	}
+	sort.Strings(queue)
+
+	for len(queue) > 0 {
+		f := files[queue[0]]
+		queue = queue[1:]
+
+		for _, imp := range f.Imports {
+			key := filepath.FromSlash(imp.Path)
+
+			imported, ok := files[key]
+			if !ok {
+				for _, includeDir := range includeDirs {
+					path := filepath.Join(includeDir, key)
+					bs, err := os.ReadFile(path)
+					if err != nil {
+						continue
+					}
+					_, loaded, perr := parseFileContentRelativeTo(includeDir, path, string(bs))
+					if perr != nil {
+						return perr
+					}
+					loaded.External = true
+					files[key] = loaded
+					queue = append(queue, key)
+					break
+				}
+				imported, ok = files[key]
+				if !ok {
+					return &ParsingError{
+						Message:  fmt.Sprintf("imported file %s not found in the input directory or any include directory", imp.Path),
+						Token:    imp.Token,
+						Filename: f.FullPath,
+						Content:  f.Content,
+					}
+				}
+			}
+
+			if imported == f {
+				return &ParsingError{
+					Message:  fmt.Sprintf("file %s imports itself", imp.Path),
+					Token:    imp.Token,
+					Filename: f.FullPath,
+					Content:  f.Content,
+				}
+			}
+
+			imp.File = imported
+		}
+	}
+
+	return nil
+}
+
 func resolveFilesIntoParse(files map[string]*File) (*Parse, *ParsingError) {
 
+	perr := resolveNestedTypeReferences(files)
+	if perr != nil {
+		return nil, perr
+	}
+
 	parse := &Parse{
 		Files:    make(map[string]*File),
 		Packages: make(map[string]*Package),
@@ -264,6 +368,14 @@ func resolveFilesIntoParse(files map[string]*File) (*Parse, *ParsingError) {
 				ServiceDefinitions:  map[string]*ServiceDefinition{},
 			}
 		}
+		if parse.Packages[f.Package.Name].Declaration.Tagged != f.Package.Tagged {
+			return nil, &ParsingError{
+				Message:  fmt.Sprintf("package %s must be declared tagged in either all or none of its files", f.Package.Name),
+				Token:    f.Package.Token,
+				Filename: f.Name,
+				Content:  f.Content,
+			}
+		}
 		// append definitions from the file to the package
 		for _, v := range f.CustomTypeDependencies {
 			if f.Package.Name == v.CustomTypePackage {
@@ -337,10 +449,35 @@ func resolveFilesIntoParse(files map[string]*File) (*Parse, *ParsingError) {
 		}
 	}
 
-	perr := resolveDefinitions(parse)
+	perr = resolveDefinitions(parse)
 	if perr != nil {
 		return nil, perr
 	}
 
+	// report warnings in a stable order, files loaded from an include
+	// directory are not generated so their warnings are omitted
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := files[k]
+		if f.External {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if !imp.Used {
+				f.Warnings = append(f.Warnings, &ParsingError{
+					Message:  fmt.Sprintf("unused import %s", imp.Path),
+					Token:    imp.Token,
+					Filename: f.FullPath,
+					Content:  f.Content,
+				})
+			}
+		}
+		parse.Warnings = append(parse.Warnings, f.Warnings...)
+	}
+
 	return parse, nil
 }
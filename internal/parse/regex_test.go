@@ -15,7 +15,7 @@ func TestRegexMatcher(t *testing.T) {
 
 		}
 
-		message TestMessageB {
+		message TestMessageB [deprecated] {
 			uint16 c = 1;
 
 			uint32 d = 2;
@@ -34,27 +34,41 @@ func TestRegexMatcher(t *testing.T) {
 
 	match, err := FindOneMatch(messageRegex, tokens[0])
 	require.Nil(t, err)
-	assert.Equal(t, 2, len(match.Captures))
+	assert.Equal(t, 3, len(match.Captures))
 
 	for _, token := range match.Captures {
+		if token.LineStart == -1 {
+			// no option list
+			continue
+		}
 		slice := getContentByTokenRange(content, token.LineStart, token.LineEnd, token.LineStartCharacterPosition, token.LineEndCharacterPosition)
 		assert.Equal(t, token.Content, slice)
 	}
 
 	match, err = FindOneMatch(messageRegex, tokens[1])
 	require.Nil(t, err)
-	assert.Equal(t, 2, len(match.Captures))
+	assert.Equal(t, 3, len(match.Captures))
 
 	for _, token := range match.Captures {
+		if token.LineStart == -1 {
+			// no option list
+			continue
+		}
 		slice := getContentByTokenRange(content, token.LineStart, token.LineEnd, token.LineStartCharacterPosition, token.LineEndCharacterPosition)
 		assert.Equal(t, token.Content, slice)
 	}
 
+	assert.Equal(t, "deprecated", match.Captures[1].Content)
+
 	match, err = FindOneMatch(messageRegex, tokens[2])
 	require.Nil(t, err)
-	assert.Equal(t, 2, len(match.Captures))
+	assert.Equal(t, 3, len(match.Captures))
 
 	for _, token := range match.Captures {
+		if token.LineStart == -1 {
+			// no option list
+			continue
+		}
 		slice := getContentByTokenRange(content, token.LineStart, token.LineEnd, token.LineStartCharacterPosition, token.LineEndCharacterPosition)
 		assert.Equal(t, token.Content, slice)
 	}
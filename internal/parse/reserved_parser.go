@@ -0,0 +1,164 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reservedRegex      = regexp.MustCompile(`(?s)^reserved\s+([^=]+?)\s*;*$`)
+	reservedRangeRegex = regexp.MustCompile(`^(\d+)(?:\s+to\s+(\d+))?$`)
+	reservedNameRegex  = regexp.MustCompile(`^"([a-zA-Z][a-zA-Z_0-9]*)"$`)
+)
+
+// ReservedRange is an inclusive range of reserved indices.
+type ReservedRange struct {
+	Start uint32
+	End   uint32
+	Token *Token
+}
+
+// ReservedDeclarations holds the indices and names a message or enum has
+// retired. They may not be reused, as data encoded with the retired
+// declaration could otherwise be decoded as something else.
+type ReservedDeclarations struct {
+	Ranges []*ReservedRange
+	Names  map[string]*Token
+}
+
+func newReservedDeclarations() *ReservedDeclarations {
+	return &ReservedDeclarations{
+		Names: map[string]*Token{},
+	}
+}
+
+// IsReservedIndex returns true if the index falls within a reserved range.
+func (r *ReservedDeclarations) IsReservedIndex(index uint32) bool {
+	for _, rng := range r.Ranges {
+		if index >= rng.Start && index <= rng.End {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReservedName returns true if the name is reserved.
+func (r *ReservedDeclarations) IsReservedName(name string) bool {
+	_, ok := r.Names[name]
+	return ok
+}
+
+// isReservedDeclaration returns true if the token within a message or enum
+// body is a reserved declaration rather than a field or value.
+func isReservedDeclaration(input *Token) bool {
+	return reservedRegex.MatchString(input.Content)
+}
+
+// parseReservedDeclaration parses a declaration such as `reserved 3, 5 to 8;` or
+// `reserved "old_name";` and adds it to the provided reserved declarations.
+func parseReservedDeclaration(input *Token, reserved *ReservedDeclarations) *ParsingError {
+
+	match, perr := FindOneMatch(reservedRegex, input)
+	if perr != nil || len(match.Captures) != 1 {
+		return &ParsingError{
+			Message: "invalid reserved declaration",
+			Token:   input,
+		}
+	}
+
+	list := match.Captures[0]
+
+	start := 0
+	for i := 0; i <= len(list.Content); i++ {
+		if i < len(list.Content) && list.Content[i] != ',' {
+			continue
+		}
+		if strings.TrimSpace(list.Content[start:i]) == "" {
+			return &ParsingError{
+				Message: "empty entry in reserved declaration",
+				Token:   list,
+			}
+		}
+
+		token, perr := createSubMatchToken(list, start, i)
+		if perr != nil {
+			return perr
+		}
+		start = i + 1
+
+		perr = parseReservedEntry(token, reserved)
+		if perr != nil {
+			return perr
+		}
+	}
+
+	return nil
+}
+
+func parseReservedEntry(input *Token, reserved *ReservedDeclarations) *ParsingError {
+
+	content := strings.TrimSpace(input.Content)
+
+	nameMatch := reservedNameRegex.FindStringSubmatch(content)
+	if nameMatch != nil {
+		name := nameMatch[1]
+		if reserved.IsReservedName(name) {
+			return &ParsingError{
+				Message: fmt.Sprintf("name %s is reserved multiple times", name),
+				Token:   input,
+			}
+		}
+		reserved.Names[name] = input
+		return nil
+	}
+
+	rangeMatch := reservedRangeRegex.FindStringSubmatch(content)
+	if rangeMatch == nil {
+		return &ParsingError{
+			Message: fmt.Sprintf("invalid reserved entry `%s`, expected an index, a range or a quoted name", content),
+			Token:   input,
+		}
+	}
+
+	startIndex, err := strconv.ParseUint(rangeMatch[1], 10, 32)
+	if err != nil {
+		return &ParsingError{
+			Message: fmt.Sprintf("invalid reserved index `%s`", rangeMatch[1]),
+			Token:   input,
+		}
+	}
+	endIndex := startIndex
+	if rangeMatch[2] != "" {
+		endIndex, err = strconv.ParseUint(rangeMatch[2], 10, 32)
+		if err != nil {
+			return &ParsingError{
+				Message: fmt.Sprintf("invalid reserved index `%s`", rangeMatch[2]),
+				Token:   input,
+			}
+		}
+	}
+	if endIndex < startIndex {
+		return &ParsingError{
+			Message: fmt.Sprintf("invalid reserved range `%s`, the end precedes the start", content),
+			Token:   input,
+		}
+	}
+
+	rng := &ReservedRange{
+		Start: uint32(startIndex),
+		End:   uint32(endIndex),
+		Token: input,
+	}
+	for _, existing := range reserved.Ranges {
+		if rng.Start <= existing.End && existing.Start <= rng.End {
+			return &ParsingError{
+				Message: fmt.Sprintf("reserved range `%s` overlaps a previously reserved range", content),
+				Token:   input,
+			}
+		}
+	}
+	reserved.Ranges = append(reserved.Ranges, rng)
+	return nil
+}
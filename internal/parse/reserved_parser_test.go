@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservedParser(t *testing.T) {
+
+	f, err := parseFileContent("test.scg", ".", `
+		package test;
+
+		message User tagged {
+			reserved 3, 5 to 8;
+			reserved "old_name", "other_name";
+			string name = 0;
+			uint32 age = 4;
+			string email = 9;
+		}
+
+		enum Status {
+			ACTIVE = 0;
+			INACTIVE = 1;
+			reserved 2 to 4, "DELETED";
+		}
+	`)
+	require.Nil(t, err)
+
+	user := f.MessageDefinitions["User"]
+	assert.Equal(t, 3, len(user.Fields))
+	assert.Equal(t, 2, len(user.Reserved.Ranges))
+	assert.True(t, user.Reserved.IsReservedIndex(3))
+	assert.False(t, user.Reserved.IsReservedIndex(4))
+	assert.True(t, user.Reserved.IsReservedIndex(5))
+	assert.True(t, user.Reserved.IsReservedIndex(8))
+	assert.False(t, user.Reserved.IsReservedIndex(9))
+	assert.True(t, user.Reserved.IsReservedName("old_name"))
+	assert.True(t, user.Reserved.IsReservedName("other_name"))
+	assert.False(t, user.Reserved.IsReservedName("name"))
+
+	status := f.Enums["Status"]
+	assert.Equal(t, 2, len(status.Values))
+	assert.True(t, status.Reserved.IsReservedIndex(3))
+	assert.True(t, status.Reserved.IsReservedName("DELETED"))
+}
+
+func TestReservedReuseErrs(t *testing.T) {
+
+	_, err := parseFileContent("test.scg", ".", `
+		package test;
+
+		message User tagged {
+			reserved 3, 5 to 8;
+			string name = 0;
+			uint32 age = 6;
+		}
+	`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "field age uses reserved index 6")
+	// the error points at the offending field
+	assert.Equal(t, 6, err.Token.LineStart)
+
+	_, err = parseFileContent("test.scg", ".", `
+		package test;
+
+		message User {
+			string old_name = 0;
+			reserved "old_name";
+		}
+	`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "field old_name uses a reserved name")
+	assert.Equal(t, 4, err.Token.LineStart)
+
+	_, err = parseFileContent("test.scg", ".", `
+		package test;
+
+		message User tagged {
+			reserved 1;
+			oneof contact {
+				string email = 0;
+				string phone = 1;
+			}
+		}
+	`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "field phone uses reserved index 1")
+
+	_, err = parseFileContent("test.scg", ".", `
+		package test;
+
+		enum Status {
+			reserved 1, "DELETED";
+			ACTIVE = 0;
+			DELETED = 1;
+		}
+	`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "value DELETED uses reserved index 1")
+	assert.Equal(t, 6, err.Token.LineStart)
+}
+
+func TestReservedErrs(t *testing.T) {
+
+	inputs := []string{
+		`message A { reserved; string a = 0; }`,
+		`message A { reserved 1,; string a = 0; }`,
+		`message A { reserved 3 to 1; string a = 0; }`,
+		`message A { reserved 1 to 4, 3; string a = 0; }`,
+		`message A { reserved "a", "a"; string b = 0; }`,
+		`message A { reserved old_name; string a = 0; }`,
+		`message A { reserved "1abc"; string a = 0; }`,
+		`message A { reserved -1; string a = 0; }`,
+		`message A { reserved 99999999999; string a = 0; }`,
+		`enum A { reserved 1; }`,
+	}
+
+	for _, input := range inputs {
+		_, err := parseFileContent("test.scg", ".", "package test;\n"+input)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -1,6 +1,9 @@
 package parse
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func resolveServiceArgumentType(parse *Parse, method *ServiceMethodDefinition, dataType *DataTypeDefinition) *ParsingError {
 
@@ -91,7 +94,7 @@ func resolvePackageDependencies(traversed map[string]bool, parse *Parse, pkg *Pa
 	return nil
 }
 
-func resolveCustomDataType(traversed map[string]bool, parse *Parse, dataType *DataTypeDefinition) *ParsingError {
+func resolveCustomDataType(traversed map[string]bool, parse *Parse, dataType *DataTypeDefinition, indirect bool) *ParsingError {
 	pkg, ok := parse.Packages[dataType.CustomTypePackage]
 	if !ok {
 		return &ParsingError{
@@ -103,6 +106,11 @@ func resolveCustomDataType(traversed map[string]bool, parse *Parse, dataType *Da
 	// if referencing a message, continue resolving for circular dependencies
 	msg, ok := pkg.MessageDefinitions[dataType.CustomType]
 	if ok {
+		if indirect {
+			// a reference through a list, map or optional may be recursive, the
+			// message itself is resolved on its own
+			return nil
+		}
 		return resolveMessageTypes(cloneTraversed(traversed), parse, msg)
 	}
 
@@ -161,6 +169,8 @@ func resolveMessageTypes(traversed map[string]bool, parse *Parse, msg *MessageDe
 
 	traversed[msg.Name] = true
 
+	// oneof members are stored alongside the other fields of the message, so
+	// they are resolved and checked for circular references the same way
 	for _, field := range msg.Fields {
 
 		switch field.DataTypeDefinition.Type {
@@ -170,7 +180,32 @@ func resolveMessageTypes(traversed map[string]bool, parse *Parse, msg *MessageDe
 
 			if elem.Type == DataTypeCustom {
 				// custom
-				perr := resolveCustomDataType(traversed, parse, elem)
+				perr := resolveCustomDataType(traversed, parse, elem, true)
+				if perr != nil {
+					return perr
+				}
+			}
+
+		case DataTypeArray:
+			// array, the elements are held inline unless nested within a
+			// list or map
+			elem := field.DataTypeDefinition.GetElementType()
+
+			if elem.Type == DataTypeCustom {
+				// custom
+				perr := resolveCustomDataType(traversed, parse, elem, field.IsIndirect())
+				if perr != nil {
+					return perr
+				}
+			}
+
+		case DataTypeSet:
+
+			// set
+			key := field.DataTypeDefinition.Key
+			if key.Type == DataTypeComparableCustom {
+				// custom
+				perr := resolveCustomDataTypeComparable(parse, key)
 				if perr != nil {
 					return perr
 				}
@@ -192,7 +227,7 @@ func resolveMessageTypes(traversed map[string]bool, parse *Parse, msg *MessageDe
 
 			if value.Type == DataTypeCustom {
 				// custom
-				perr := resolveCustomDataType(traversed, parse, value)
+				perr := resolveCustomDataType(traversed, parse, value, true)
 				if perr != nil {
 					return perr
 				}
@@ -201,7 +236,7 @@ func resolveMessageTypes(traversed map[string]bool, parse *Parse, msg *MessageDe
 		case DataTypeCustom:
 
 			// custom
-			perr := resolveCustomDataType(traversed, parse, field.DataTypeDefinition)
+			perr := resolveCustomDataType(traversed, parse, field.DataTypeDefinition, field.Optional)
 			if perr != nil {
 				return perr
 			}
@@ -214,6 +249,157 @@ func resolveMessageTypes(traversed map[string]bool, parse *Parse, msg *MessageDe
 	return nil
 }
 
+func findMessageDefinition(parse *Parse, dataType *DataTypeDefinition) *MessageDefinition {
+	elem := dataType.GetElementType()
+	if elem.Type != DataTypeCustom {
+		return nil
+	}
+	pkg, ok := parse.Packages[elem.CustomTypePackage]
+	if !ok {
+		return nil
+	}
+	return pkg.MessageDefinitions[elem.CustomType]
+}
+
+// dataTypeReferencesMessage returns true if the data type refers to the target
+// message, either directly or through the fields of the messages it refers to.
+func dataTypeReferencesMessage(parse *Parse, dataType *DataTypeDefinition, target *MessageDefinition, visited map[*MessageDefinition]bool) bool {
+	msg := findMessageDefinition(parse, dataType)
+	if msg == nil {
+		return false
+	}
+	if msg == target {
+		return true
+	}
+	if visited[msg] {
+		return false
+	}
+	visited[msg] = true
+	for _, field := range msg.Fields {
+		if dataTypeReferencesMessage(parse, field.DataTypeDefinition, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMessageTypeReferences flags the custom types of message fields, and of
+// their container elements, that refer to messages rather than to enums or
+// typedefs, and the custom map keys and set elements that are typedefs of uuid.
+func resolveMessageTypeReferences(parse *Parse) {
+	for _, pkg := range parse.Packages {
+		for _, msg := range pkg.MessageDefinitions {
+			for _, field := range msg.Fields {
+				for dataType := field.DataTypeDefinition; dataType != nil; dataType = dataType.SubType {
+					if key := dataType.Key; key != nil && key.Type == DataTypeComparableCustom {
+						if dep, ok := parse.Packages[key.CustomTypePackage]; ok {
+							typedef, ok := dep.Typedefs[key.CustomType]
+							key.CustomTypeIsUUID = ok && typedef.DataTypeDefinition.Type == DataTypeComparableUUID
+						}
+					}
+					if dataType.Type != DataTypeCustom {
+						continue
+					}
+					dep, ok := parse.Packages[dataType.CustomTypePackage]
+					if !ok {
+						continue
+					}
+					_, dataType.CustomTypeIsMessage = dep.MessageDefinitions[dataType.CustomType]
+				}
+			}
+		}
+	}
+}
+
+func resolveRecursiveFields(parse *Parse) {
+	for _, pkg := range parse.Packages {
+		for _, msg := range pkg.MessageDefinitions {
+			for _, field := range msg.Fields {
+				field.Recursive = dataTypeReferencesMessage(parse, field.DataTypeDefinition, msg, map[*MessageDefinition]bool{})
+			}
+		}
+	}
+}
+
+// resolveNestedTypeReferences resolves references to types nested within the
+// messages of other files. A reference such as `Order.Line` is initially parsed
+// as the type `Line` of a package `Order`, which can only be told apart once
+// the declarations of every file are known. The longest matching package name
+// is preferred, falling back to the package of the referencing file.
+func resolveNestedTypeReferences(files map[string]*File) *ParsingError {
+
+	declared := map[string]map[string]bool{}
+	for _, f := range files {
+		names, ok := declared[f.Package.Name]
+		if !ok {
+			names = map[string]bool{}
+			declared[f.Package.Name] = names
+		}
+		for k := range f.Enums {
+			names[k] = true
+		}
+		for k := range f.Typedefs {
+			names[k] = true
+		}
+		for k := range f.MessageDefinitions {
+			names[k] = true
+		}
+	}
+
+	for _, f := range files {
+
+		qualify := func(dataType *DataTypeDefinition) *ParsingError {
+			visitCustomTypes(dataType, func(pkg *string, name *string) {
+				if declared[*pkg][*name] {
+					return
+				}
+				parts := strings.Split(qualifyName(*pkg, *name), ".")
+				for i := len(parts) - 1; i >= 0; i-- {
+					pkgName := f.Package.Name
+					if i > 0 {
+						pkgName = strings.Join(parts[:i], ".")
+					}
+					typeName := strings.Join(parts[i:], ".")
+					if declared[pkgName][typeName] {
+						*pkg = pkgName
+						*name = typeName
+						return
+					}
+				}
+			})
+			return populateDataTypePackageIfMissing(f.Package.Name, dataType)
+		}
+
+		for _, msg := range f.MessageDefinitions {
+			for _, field := range msg.Fields {
+				perr := qualify(field.DataTypeDefinition)
+				if perr != nil {
+					return perr
+				}
+			}
+		}
+		for _, svc := range f.ServiceDefinitions {
+			for _, method := range svc.Methods {
+				perr := qualify(method.Argument)
+				if perr != nil {
+					return perr
+				}
+				perr = qualify(method.Return)
+				if perr != nil {
+					return perr
+				}
+			}
+		}
+
+		perr := collectCustomTypeDependencies(f)
+		if perr != nil {
+			return perr
+		}
+	}
+
+	return nil
+}
+
 func resolveDefinitions(parse *Parse) *ParsingError {
 
 	// ensure all dependencies have the file set
@@ -229,29 +415,37 @@ func resolveDefinitions(parse *Parse) *ParsingError {
 				}
 			}
 
-			msg, ok := pkg.MessageDefinitions[dep.CustomTypeName]
-			if ok {
+			if msg, ok := pkg.MessageDefinitions[dep.CustomTypeName]; ok {
 				dep.File = msg.File
-				continue
-			}
-
-			typdef, ok := pkg.Typedefs[dep.CustomTypeName]
-			if ok {
+			} else if typdef, ok := pkg.Typedefs[dep.CustomTypeName]; ok {
 				dep.File = typdef.File
-				continue
-			}
-
-			enum, ok := pkg.Enums[dep.CustomTypeName]
-			if ok {
+			} else if enum, ok := pkg.Enums[dep.CustomTypeName]; ok {
 				dep.File = enum.File
-				continue
+			} else {
+				return &ParsingError{
+					Message:  fmt.Sprintf("type %s referenced in %s not found in package %s", dep.CustomTypeName, file.Name, pkg.Name),
+					Token:    dep.Token,
+					Filename: file.Name,
+					Content:  file.Content,
+				}
 			}
 
-			return &ParsingError{
-				Message:  fmt.Sprintf("type %s referenced in %s not found in package %s", dep.CustomTypeName, file.Name, pkg.Name),
-				Token:    dep.Token,
-				Filename: file.Name,
-				Content:  file.Content,
+			// types of the same package are always visible, types of another
+			// package must be declared in a file that is imported directly
+			imported := false
+			for _, imp := range file.Imports {
+				if imp.File == dep.File {
+					imp.Used = true
+					imported = true
+				}
+			}
+			if !imported && dep.CustomTypePackage != file.Package.Name {
+				return &ParsingError{
+					Message:  fmt.Sprintf("type %s.%s referenced in %s is declared in %s, which is not imported", dep.CustomTypePackage, dep.CustomTypeName, file.Name, getImportPath(dep.File)),
+					Token:    dep.Token,
+					Filename: file.Name,
+					Content:  file.Content,
+				}
 			}
 		}
 	}
@@ -303,6 +497,12 @@ func resolveDefinitions(parse *Parse) *ParsingError {
 		}
 	}
 
+	// flag fields through which messages refer back to themselves
+	resolveRecursiveFields(parse)
+
+	// flag custom types referring to messages
+	resolveMessageTypeReferences(parse)
+
 	// for const declarations, resolve and inject the underlying types
 	for _, pkg := range parse.Packages {
 		for _, constDecl := range pkg.Consts {
@@ -3,6 +3,7 @@ package parse
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -250,6 +251,52 @@ func TestResolverMapKeyMessageDependency(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestResolverSetMessageDependency(t *testing.T) {
+
+	contentA := `
+		package test;
+
+		message A {
+			set<SomeID> ids = 0;
+			set<Status> statuses = 1;
+		}
+	`
+
+	contentB := `
+		package test;
+
+		typedef SomeID = uint64;
+
+		enum Status {
+			ACTIVE = 0;
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/testA.scg": contentA,
+		"./test/testB.scg": contentB,
+	})
+	require.Nil(t, err)
+
+	// only comparable types may be held by a set
+	for _, elem := range []string{"bool", "bytes", "B"} {
+		_, err = NewParseFromFiles("./test", map[string]string{
+			"./test/testA.scg": `
+				package test;
+
+				message A {
+					set<` + elem + `> values = 0;
+				}
+
+				message B {
+					string b = 0;
+				}
+			`,
+		})
+		require.NotNil(t, err, elem)
+	}
+}
+
 func TestResolverTypedefDependencyBetweenFiles(t *testing.T) {
 
 	contentA := `
@@ -333,6 +380,7 @@ func TestResolverDependencyBetweenPackages(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			b.B b = 0;
@@ -358,6 +406,7 @@ func TestResolverListDependencyBetweenPackages(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			list<b.B> b = 0;
@@ -383,6 +432,7 @@ func TestResolverMapValueDependencyBetweenPackages(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			map<string, b.B> b = 0;
@@ -408,6 +458,7 @@ func TestResolverMapKeyDependencyBetweenPackages(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			map<b.MyID, float64> b = 0;
@@ -431,6 +482,7 @@ func TestResolverTypedefDependencyBetweenPackages(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			list<b.SomeID> b = 0;
@@ -454,6 +506,7 @@ func TestResolverDependencyBetweenPackagesCircular(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			b.B b = 0;
@@ -470,6 +523,7 @@ func TestResolverDependencyBetweenPackagesCircular(t *testing.T) {
 
 	contentC := `
 		package b;
+		import "testA.scg";
 
 		message C {
 			a.A a = 0;
@@ -488,6 +542,7 @@ func TestResolverTypedefDependencyBetweenPackagesCircular(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A {
 			b.B b = 0;
@@ -502,6 +557,7 @@ func TestResolverTypedefDependencyBetweenPackagesCircular(t *testing.T) {
 
 	contentC := `
 		package b;
+		import "testA.scg";
 
 		message C {
 			a.A a = 0;
@@ -520,6 +576,7 @@ func TestResolverTransitivePackageMissing(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A1 {
 			int32 v = 0;
@@ -553,6 +610,7 @@ func TestResolverTransitivePackageCircular(t *testing.T) {
 
 	contentA := `
 		package a;
+		import "testB.scg";
 
 		message A1 {
 			int32 v = 0;
@@ -565,6 +623,7 @@ func TestResolverTransitivePackageCircular(t *testing.T) {
 
 	contentB := `
 		package b;
+		import "testC.scg";
 
 		message B1 {
 			int32 v = 0;
@@ -577,6 +636,7 @@ func TestResolverTransitivePackageCircular(t *testing.T) {
 
 	contentC := `
 		package c;
+		import "testA.scg";
 
 		message C1 {
 			int32 v = 0;
@@ -673,3 +733,394 @@ func TestResolverTransitiveFileCircular(t *testing.T) {
 	})
 	require.NotNil(t, err)
 }
+
+func TestResolverTaggedPackage(t *testing.T) {
+
+	contentA := `
+		package test tagged;
+
+		message A {
+			int32 a = 0;
+			B b = 3;
+		}
+	`
+
+	contentB := `
+		package test tagged;
+
+		message B {
+			int32 v = 1;
+		}
+	`
+
+	p, err := NewParseFromFiles("./test", map[string]string{
+		"./test/testA.scg": contentA,
+		"./test/testB.scg": contentB,
+	})
+	require.Nil(t, err)
+	require.True(t, p.Packages["test"].MessageDefinitions["A"].Tagged)
+	require.True(t, p.Packages["test"].MessageDefinitions["B"].Tagged)
+}
+
+func TestResolverTaggedPackageMismatch(t *testing.T) {
+
+	contentA := `
+		package test tagged;
+
+		message A {
+			B b = 0;
+		}
+	`
+
+	contentB := `
+		package test;
+
+		message B {
+			int32 v = 0;
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/testA.scg": contentA,
+		"./test/testB.scg": contentB,
+	})
+	require.NotNil(t, err)
+}
+
+func TestResolverOneof(t *testing.T) {
+
+	contentA := `
+		package test;
+
+		message A {
+			string name = 0;
+			oneof value {
+				B b = 1;
+				list<B> bs = 2;
+				TestID id = 3;
+			}
+		}
+	`
+
+	contentB := `
+		package test;
+
+		typedef TestID = uint32;
+
+		message B {
+			int32 v = 0;
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/testA.scg": contentA,
+		"./test/testB.scg": contentB,
+	})
+	require.Nil(t, err)
+}
+
+func TestResolverOneofUndefinedType(t *testing.T) {
+
+	content := `
+		package test;
+
+		message A {
+			oneof value {
+				B b = 0;
+			}
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/test.scg": content,
+	})
+	require.NotNil(t, err)
+}
+
+func TestResolverOneofCircularDep(t *testing.T) {
+
+	content := `
+		package test;
+
+		message A {
+			oneof value {
+				B b = 0;
+			}
+		}
+
+		message B {
+			A a = 0;
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/test.scg": content,
+	})
+	require.NotNil(t, err)
+}
+
+func TestResolverRecursiveMessages(t *testing.T) {
+
+	content := `
+		package test;
+
+		message Tree {
+			string name = 0;
+			list<Tree> children = 1;
+			map<string, Tree> named = 2;
+			optional Tree parent = 3;
+			array<list<Tree>, 2> halves = 4;
+		}
+
+		message Thread {
+			list<Comment> comments = 0;
+		}
+
+		message Comment {
+			string text = 0;
+			Thread replies = 1;
+		}
+
+		message Leaf {
+			Tree tree = 0;
+		}
+	`
+
+	p, err := NewParseFromFiles("./test", map[string]string{
+		"./test/test.scg": content,
+	})
+	require.Nil(t, err)
+
+	pkg := p.Packages["test"]
+
+	tree := pkg.MessageDefinitions["Tree"]
+	assert.False(t, tree.Fields["name"].Recursive)
+	assert.True(t, tree.Fields["children"].Recursive)
+	assert.True(t, tree.Fields["named"].Recursive)
+	assert.True(t, tree.Fields["parent"].Recursive)
+	assert.True(t, tree.Fields["halves"].Recursive)
+	assert.True(t, tree.IsRecursive())
+
+	// mutual recursion through a list
+	assert.True(t, pkg.MessageDefinitions["Thread"].Fields["comments"].Recursive)
+	assert.True(t, pkg.MessageDefinitions["Comment"].Fields["replies"].Recursive)
+
+	// referring to a recursive message does not make a message recursive
+	assert.False(t, pkg.MessageDefinitions["Leaf"].IsRecursive())
+
+	// the direct reference from Comment to Thread is still ordered
+	sorted := p.Files["test.scg"].MessagesSortedByDependenciesAndKeys()
+	names := []string{}
+	for _, msg := range sorted {
+		names = append(names, msg.Name)
+	}
+	assert.Less(t, indexOf(names, "Thread"), indexOf(names, "Comment"))
+	assert.Less(t, indexOf(names, "Tree"), indexOf(names, "Leaf"))
+}
+
+func TestResolverRecursiveDirectErr(t *testing.T) {
+
+	inputs := []string{
+		`package test;
+
+		message A {
+			A a = 0;
+		}`,
+		`package test;
+
+		message A {
+			oneof value {
+				A a = 0;
+			}
+		}`,
+		`package test;
+
+		message A {
+			list<B> b = 0;
+			C c = 1;
+		}
+
+		message C {
+			A a = 0;
+		}
+
+		message B {
+			int32 v = 0;
+		}`,
+		`package test;
+
+		message A {
+			array<A, 2> a = 0;
+		}`,
+		`package test;
+
+		message A {
+			map<string, int32> m = 0;
+			array<array<A, 2>, 2> a = 1;
+		}`,
+	}
+
+	for _, input := range inputs {
+		_, err := NewParseFromFiles("./test", map[string]string{
+			"./test/test.scg": input,
+		})
+		assert.NotNil(t, err, input)
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestResolverNestedTypes(t *testing.T) {
+
+	contentA := `
+		package test;
+
+		message Order {
+			enum Status {
+				PENDING = 0;
+			}
+			message Line {
+				message Note {
+					Status status = 0;
+				}
+				list<Note> notes = 0;
+			}
+			Status status = 0;
+			list<Line> lines = 1;
+			Order.Line.Note note = 2;
+		}
+
+		message Local {
+			Order.Line line = 0;
+		}
+	`
+
+	contentB := `
+		package test;
+
+		message Remote {
+			Order.Line line = 0;
+			map<string, test.Order.Status> statuses = 1;
+		}
+	`
+
+	contentC := `
+		package other;
+		import "a.scg";
+
+		message Other {
+			test.Order.Line line = 0;
+		}
+
+		service Orders {
+			rpc Get (test.Order.Line) returns (Other);
+		}
+	`
+
+	p, err := NewParseFromFiles("./test", map[string]string{
+		"./test/a.scg": contentA,
+		"./test/b.scg": contentB,
+		"./test/c.scg": contentC,
+	})
+	require.Nil(t, err)
+
+	test := p.Packages["test"]
+	other := p.Packages["other"]
+
+	order := test.MessageDefinitions["Order"]
+	assert.Equal(t, "Order.Status", order.Fields["status"].DataTypeDefinition.CustomType)
+	assert.Equal(t, "Order.Line", order.Fields["lines"].DataTypeDefinition.SubType.CustomType)
+	assert.Equal(t, "Order.Line.Note", order.Fields["note"].DataTypeDefinition.CustomType)
+
+	note := test.MessageDefinitions["Order.Line.Note"]
+	assert.Equal(t, "Order.Status", note.Fields["status"].DataTypeDefinition.CustomType)
+
+	local := test.MessageDefinitions["Local"]
+	assert.Equal(t, "Order.Line", local.Fields["line"].DataTypeDefinition.CustomType)
+
+	remote := test.MessageDefinitions["Remote"]
+	assert.Equal(t, "test", remote.Fields["line"].DataTypeDefinition.CustomTypePackage)
+	assert.Equal(t, "Order.Line", remote.Fields["line"].DataTypeDefinition.CustomType)
+	assert.Equal(t, "Order.Status", remote.Fields["statuses"].DataTypeDefinition.SubType.CustomType)
+	assert.Equal(t, 1, len(remote.File.GetFileDependencies()))
+
+	line := other.MessageDefinitions["Other"].Fields["line"].DataTypeDefinition
+	assert.Equal(t, "test", line.CustomTypePackage)
+	assert.Equal(t, "Order.Line", line.CustomType)
+	assert.True(t, line.ImportedFromOtherPackage)
+	assert.NotNil(t, other.PackageDependencies["test"])
+}
+
+func TestResolverNestedTypeNotInScope(t *testing.T) {
+
+	content := `
+		package test;
+
+		message Order {
+			message Line {
+				string sku = 0;
+			}
+		}
+
+		message Invoice {
+			Line line = 0;
+		}
+	`
+
+	_, err := NewParseFromFiles("./test", map[string]string{
+		"./test/test.scg": content,
+	})
+	require.NotNil(t, err)
+}
+
+func TestResolverMessageTypeReferences(t *testing.T) {
+
+	content := `
+		package test;
+
+		enum Color {
+			RED = 0;
+		}
+
+		typedef ID = uint64;
+		typedef Key = uuid;
+
+		message Point {
+			float32 x = 0;
+		}
+
+		message Shape {
+			Point origin = 0;
+			Color color = 1;
+			ID id = 2;
+			list<Point> points = 3;
+			map<string, list<Color>> palette = 4;
+			string name = 5;
+			set<Key> keys = 6;
+			map<ID, list<map<Key, string>>> index = 7;
+		}
+	`
+
+	p, err := NewParseFromFiles("./test", map[string]string{
+		"./test/test.scg": content,
+	})
+	require.Nil(t, err)
+
+	shape := p.Packages["test"].MessageDefinitions["Shape"]
+	assert.True(t, shape.Fields["origin"].DataTypeDefinition.CustomTypeIsMessage)
+	assert.False(t, shape.Fields["color"].DataTypeDefinition.CustomTypeIsMessage)
+	assert.False(t, shape.Fields["id"].DataTypeDefinition.CustomTypeIsMessage)
+	assert.True(t, shape.Fields["points"].DataTypeDefinition.SubType.CustomTypeIsMessage)
+	assert.False(t, shape.Fields["palette"].DataTypeDefinition.SubType.SubType.CustomTypeIsMessage)
+	assert.False(t, shape.Fields["name"].DataTypeDefinition.CustomTypeIsMessage)
+	assert.True(t, shape.Fields["keys"].DataTypeDefinition.Key.CustomTypeIsUUID)
+	assert.False(t, shape.Fields["index"].DataTypeDefinition.Key.CustomTypeIsUUID)
+	assert.True(t, shape.Fields["index"].DataTypeDefinition.SubType.SubType.Key.CustomTypeIsUUID)
+}
@@ -3,13 +3,14 @@ package parse
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 )
 
 var (
-	serviceRegex       = regexp.MustCompile(`(?s)service\s+([a-zA-Z][a-zA-Z_0-9]*)\s*{(.*?)}`)
-	serviceMethodRegex = regexp.MustCompile(`^rpc\s+([a-zA-Z][a-zA-Z_0-9]*)\s*\(\s*(stream\s+)?((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\)\s*returns\s*\(\s*(stream\s+)?((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\)\s*;\s*$`)
+	serviceRegex       = regexp.MustCompile(`(?s)service\s+([a-zA-Z][a-zA-Z_0-9]*)\s*(?:\[([^\]]*)\])?\s*{(.*?)}`)
+	serviceMethodRegex = regexp.MustCompile(`^rpc\s+([a-zA-Z][a-zA-Z_0-9]*)\s*\(\s*(stream\s+)?((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\)\s*returns\s*\(\s*(stream\s+)?((?:[a-zA-Z][a-zA-Z_0-9]*)(?:\.[a-zA-Z][a-zA-Z_0-9]*)*)\s*\)\s*(?:\[(.*)\])?\s*;\s*$`)
 )
 
 type ServiceMethodDefinition struct {
@@ -18,6 +19,7 @@ type ServiceMethodDefinition struct {
 	ArgumentStream bool
 	Return         *DataTypeDefinition
 	ReturnStream   bool
+	Options        Options
 	Token          *Token
 }
 
@@ -29,10 +31,24 @@ func (m *ServiceMethodDefinition) IsStreaming() bool {
 type ServiceDefinition struct {
 	Name    string
 	Methods map[string]*ServiceMethodDefinition
+	Options Options
 	File    *File
 	Token   *Token
 }
 
+// MethodsSortedByKey returns the methods of the service in ascending name
+// order.
+func (s *ServiceDefinition) MethodsSortedByKey() []*ServiceMethodDefinition {
+	res := make([]*ServiceMethodDefinition, 0, len(s.Methods))
+	for _, method := range s.Methods {
+		res = append(res, method)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
 func tokenizeServiceMethods(input *Token) ([]*Token, *ParsingError) {
 	res := []*Token{}
 
@@ -93,7 +109,7 @@ func tokenizeServiceMethods(input *Token) ([]*Token, *ParsingError) {
 func parseMethodDefinition(input *Token) (*ServiceMethodDefinition, *ParsingError) {
 
 	match, perr := FindOneMatch(serviceMethodRegex, input)
-	if perr != nil || len(match.Captures) != 5 {
+	if perr != nil || len(match.Captures) != 6 {
 		return nil, &ParsingError{
 			Message: "invalid method declaration",
 			Token:   input,
@@ -106,6 +122,11 @@ func parseMethodDefinition(input *Token) (*ServiceMethodDefinition, *ParsingErro
 	returnStream := strings.TrimSpace(match.Captures[3].Content) == "stream"
 	returnType := match.Captures[4]
 
+	options, perr := parseOptions(match.Captures[5])
+	if perr != nil {
+		return nil, perr
+	}
+
 	argumentDefinition, perr := parseDataTypeDefinition(argumentType)
 	if perr != nil {
 		return nil, perr
@@ -134,6 +155,7 @@ func parseMethodDefinition(input *Token) (*ServiceMethodDefinition, *ParsingErro
 		ArgumentStream: argumentStream,
 		Return:         returnDefinition,
 		ReturnStream:   returnStream,
+		Options:        options,
 		Token:          input,
 	}, nil
 }
@@ -148,20 +170,26 @@ func parseServiceDefinitions(tokens []*Token) (map[string]*ServiceDefinition, *P
 		}
 
 		match, perr := FindOneMatch(serviceRegex, token)
-		if perr != nil || len(match.Captures) != 2 {
+		if perr != nil || len(match.Captures) != 3 {
 			return nil, &ParsingError{
 				Message: "invalid service definition",
 				Token:   token,
 			}
 		}
 
+		options, perr := parseOptions(match.Captures[1])
+		if perr != nil {
+			return nil, perr
+		}
+
 		service := &ServiceDefinition{
 			Name:    match.Captures[0].Content,
 			Methods: map[string]*ServiceMethodDefinition{},
+			Options: options,
 			Token:   token,
 		}
 
-		methods, perr := tokenizeServiceMethods(match.Captures[1])
+		methods, perr := tokenizeServiceMethods(match.Captures[2])
 		if perr != nil {
 			return nil, perr
 		}
@@ -37,7 +37,7 @@ func TestServiceMethodTokenizer(t *testing.T) {
 		match, err := FindOneMatch(serviceRegex, token)
 		require.Nil(t, err)
 
-		methods, err := tokenizeServiceMethods(match.Captures[1])
+		methods, err := tokenizeServiceMethods(match.Captures[2])
 		require.Nil(t, err)
 
 		for _, method := range methods {
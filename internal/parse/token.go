@@ -6,6 +6,7 @@ const (
 	UnknownTokenType TokenType = iota
 	MessageTokenType
 	MessageFieldTokenType
+	MessageOneofTokenType
 	ServiceTokenType
 	ServiceMethodTokenType
 	ServiceMethodParamTokenType
@@ -14,6 +15,7 @@ const (
 	EnumTokenType
 	EnumValueTokenType
 	ConstTokenType
+	ImportTokenType
 )
 
 type Token struct {
@@ -39,7 +39,18 @@ func replaceWordCasing(s string, fn func(string) string) string {
 	return fn(s)
 }
 
+// EnsurePascalCase converts the name to pascal case. The segments of a nested
+// name such as `Order.Line` are converted individually and joined with an
+// underscore.
 func EnsurePascalCase(s string) string {
+	segments := strings.Split(s, ".")
+	for i, segment := range segments {
+		segments[i] = ensurePascalCaseSegment(segment)
+	}
+	return strings.Join(segments, "_")
+}
+
+func ensurePascalCaseSegment(s string) string {
 	words := splitNameIntoParts(s)
 	for i, word := range words {
 		if len(word) > 0 {
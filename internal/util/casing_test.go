@@ -13,6 +13,7 @@ func TestEnsurePascalCase(t *testing.T) {
 	assert.Equal(t, "CamelCaseInput", EnsurePascalCase("camelCaseInput"))
 	assert.Equal(t, "InputWithSpaces", EnsurePascalCase("input with spaces"))
 	assert.Equal(t, "SomethingWithID", EnsurePascalCase("something_with_id"))
+	assert.Equal(t, "Order_LineItem", EnsurePascalCase("Order.line_item"))
 }
 
 func TestEnsureCamelCase(t *testing.T) {
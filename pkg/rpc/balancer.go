@@ -0,0 +1,450 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbirk/scg/pkg/log"
+	"github.com/kbirk/scg/pkg/serialize"
+)
+
+// Caller is the call surface used by the generated service clients. Client
+// calls a single backend, while BalancedClient spreads the calls across many.
+type Caller interface {
+	Call(ctx context.Context, serviceID uint64, methodID uint64, msg Message) (*serialize.Reader, error)
+	OpenStream(ctx context.Context, serviceID uint64, methodID uint64) (*ClientStream, error)
+	GetMiddleware() []Middleware
+}
+
+var (
+	_ Caller = (*Client)(nil)
+	_ Caller = (*BalancedClient)(nil)
+)
+
+var errNoHealthyEndpoints = errors.New("no healthy endpoints")
+
+// Endpoint is a backend of a BalancedClient and the client connected to it.
+type Endpoint struct {
+	addr        Address
+	client      *Client
+	outstanding atomic.Int64 // calls and open streams
+	failures    atomic.Int32 // consecutive connection failures
+	ejected     atomic.Bool  // changed with the balancer's mu held
+}
+
+// Address returns the address of the endpoint.
+func (e *Endpoint) Address() Address {
+	return e.addr
+}
+
+// Ejected reports whether the endpoint is ejected, receiving no calls until it
+// passes a health probe.
+func (e *Endpoint) Ejected() bool {
+	return e.ejected.Load()
+}
+
+// Outstanding returns the number of calls in flight and streams open on the
+// endpoint.
+func (e *Endpoint) Outstanding() int {
+	return int(e.outstanding.Load())
+}
+
+// Picker chooses the endpoint of each call among the healthy endpoints, of
+// which there is at least one. It is called concurrently and must not retain
+// the slice.
+type Picker interface {
+	Pick(endpoints []*Endpoint) *Endpoint
+}
+
+type roundRobinPicker struct {
+	next atomic.Uint64
+}
+
+// NewRoundRobinPicker returns a picker that cycles through the endpoints.
+func NewRoundRobinPicker() Picker {
+	return &roundRobinPicker{}
+}
+
+func (p *roundRobinPicker) Pick(endpoints []*Endpoint) *Endpoint {
+	n := p.next.Add(1) - 1
+	return endpoints[n%uint64(len(endpoints))]
+}
+
+type leastOutstandingPicker struct {
+	next atomic.Uint64
+}
+
+// NewLeastOutstandingPicker returns a picker that chooses the endpoint with the
+// fewest calls in flight, so that a slow backend receives fewer calls. Ties are
+// broken round robin.
+func NewLeastOutstandingPicker() Picker {
+	return &leastOutstandingPicker{}
+}
+
+func (p *leastOutstandingPicker) Pick(endpoints []*Endpoint) *Endpoint {
+	start := int(p.next.Add(1) % uint64(len(endpoints)))
+	var best *Endpoint
+	for i := range endpoints {
+		e := endpoints[(start+i)%len(endpoints)]
+		if best == nil || e.Outstanding() < best.Outstanding() {
+			best = e
+		}
+	}
+	return best
+}
+
+type BalancerConfig struct {
+	// Resolver supplies the addresses of the backends.
+	Resolver Resolver
+	// NewTransport creates the transport of the backend at an address.
+	NewTransport func(addr Address) ClientTransport
+	// Client configures the client of each backend. Its Transport is ignored.
+	Client ClientConfig
+	// Picker chooses the endpoint of each call (default round robin).
+	Picker Picker
+	// ResolveInterval is how often the resolver is queried for changes
+	// (default 30s).
+	ResolveInterval time.Duration
+	// MaxFailures is the number of consecutive calls failing on the connection
+	// of an endpoint after which it is ejected (default 3). An endpoint that
+	// cannot be connected to is ejected at once.
+	MaxFailures int
+	// HealthCheckInterval is how often the ejected endpoints are probed
+	// (default 5s).
+	HealthCheckInterval time.Duration
+	// HealthCheck probes an ejected endpoint, which is added back once it
+	// succeeds. The default probe passes once the client of the endpoint is
+	// connected, connecting it if idle; a client whose connect failed is
+	// reconnected on the backoff of ClientConfig.
+	HealthCheck func(ctx context.Context, client *Client) error
+	// Retry retries the unary calls of idempotent methods, picking the
+	// endpoint of each attempt anew (nil = no retries).
+	Retry      *RetryPolicy
+	ErrHandler func(error)
+	Logger     log.Logger
+}
+
+// BalancedClient spreads unary calls and new streams across the backends
+// supplied by a resolver, holding a Client for each. Backends whose calls fail
+// on their connection are ejected until a health probe succeeds.
+type BalancedClient struct {
+	conf       BalancerConfig
+	middleware []Middleware
+
+	mu        sync.Mutex
+	endpoints []*Endpoint
+	healthy   []*Endpoint
+	closed    bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBalancedClient resolves the initial addresses and returns a client for
+// them, which re-resolves and probes its ejected endpoints in the background
+// until closed.
+func NewBalancedClient(conf BalancerConfig) (*BalancedClient, error) {
+	if conf.Resolver == nil || conf.NewTransport == nil {
+		return nil, errors.New("balancer requires a Resolver and NewTransport")
+	}
+	if conf.Picker == nil {
+		conf.Picker = NewRoundRobinPicker()
+	}
+	if conf.ResolveInterval <= 0 {
+		conf.ResolveInterval = 30 * time.Second
+	}
+	if conf.MaxFailures <= 0 {
+		conf.MaxFailures = 3
+	}
+	if conf.HealthCheckInterval <= 0 {
+		conf.HealthCheckInterval = 5 * time.Second
+	}
+	if conf.HealthCheck == nil {
+		conf.HealthCheck = func(ctx context.Context, client *Client) error {
+			return client.probe()
+		}
+	}
+
+	b := &BalancedClient{
+		conf: conf,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := b.resolve(); err != nil {
+		return nil, err
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *BalancedClient) Middleware(middleware Middleware) {
+	b.middleware = append(b.middleware, middleware)
+}
+
+func (b *BalancedClient) GetMiddleware() []Middleware {
+	return b.middleware
+}
+
+// Endpoints returns the current endpoints, healthy or not.
+func (b *BalancedClient) Endpoints() []*Endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Endpoint(nil), b.endpoints...)
+}
+
+// Close stops the background work and closes the client of each endpoint.
+func (b *BalancedClient) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	<-b.done
+
+	b.mu.Lock()
+	endpoints := b.endpoints
+	b.endpoints = nil
+	b.healthy = nil
+	b.mu.Unlock()
+
+	var err error
+	for _, e := range endpoints {
+		if cerr := e.client.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (b *BalancedClient) Call(ctx context.Context, serviceID uint64, methodID uint64, msg Message) (*serialize.Reader, error) {
+	return retryCall(ctx, b.conf.Retry, serviceID, methodID, func() (*serialize.Reader, error) {
+		e, err := b.pick()
+		if err != nil {
+			return nil, err
+		}
+
+		e.outstanding.Add(1)
+		reader, err := e.client.Call(ctx, serviceID, methodID, msg)
+		e.outstanding.Add(-1)
+
+		b.report(e, err)
+		return reader, err
+	})
+}
+
+// OpenStream opens a stream on the picked endpoint. The stream counts as
+// outstanding on the endpoint until its receive direction ends.
+func (b *BalancedClient) OpenStream(ctx context.Context, serviceID uint64, methodID uint64) (*ClientStream, error) {
+	e, err := b.pick()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := e.client.OpenStream(ctx, serviceID, methodID)
+	b.report(e, err)
+	if err != nil {
+		return nil, err
+	}
+
+	e.outstanding.Add(1)
+	go func() {
+		<-stream.recvDone
+		e.outstanding.Add(-1)
+	}()
+	return stream, nil
+}
+
+func (b *BalancedClient) pick() (*Endpoint, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, unavailable(errClientClosed)
+	}
+	if len(b.healthy) == 0 {
+		return nil, unavailable(errNoHealthyEndpoints)
+	}
+	return b.conf.Picker.Pick(b.healthy), nil
+}
+
+// report records the outcome of a call on an endpoint. Only failures on the
+// connection count against it: an error returned by the backend shows that it
+// is up.
+func (b *BalancedClient) report(e *Endpoint, err error) {
+	if CodeOf(err) != CodeUnavailable {
+		e.failures.Store(0)
+		return
+	}
+	state := e.client.State()
+	if state == StateReady {
+		e.failures.Store(0)
+		return
+	}
+	if state == StateTransientFailure || int(e.failures.Add(1)) >= b.conf.MaxFailures {
+		b.eject(e, err)
+	}
+}
+
+func (b *BalancedClient) eject(e *Endpoint, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e.ejected.Load() || b.closed {
+		return
+	}
+	b.logWarn(fmt.Sprintf("Ejecting endpoint %s: %v", e.addr, err))
+	e.ejected.Store(true)
+	b.refreshHealthyUnsafe()
+}
+
+func (b *BalancedClient) readd(e *Endpoint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !e.ejected.Load() || b.closed {
+		return
+	}
+	b.logInfo(fmt.Sprintf("Endpoint %s is healthy again", e.addr))
+	e.ejected.Store(false)
+	e.failures.Store(0)
+	b.refreshHealthyUnsafe()
+}
+
+// refreshHealthyUnsafe rebuilds the list of endpoints offered to the picker
+// (caller holds mu).
+func (b *BalancedClient) refreshHealthyUnsafe() {
+	healthy := make([]*Endpoint, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		if !e.ejected.Load() {
+			healthy = append(healthy, e)
+		}
+	}
+	b.healthy = healthy
+}
+
+func (b *BalancedClient) run() {
+	defer close(b.done)
+
+	resolveTicker := time.NewTicker(b.conf.ResolveInterval)
+	defer resolveTicker.Stop()
+	healthTicker := time.NewTicker(b.conf.HealthCheckInterval)
+	defer healthTicker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-resolveTicker.C:
+			if err := b.resolve(); err != nil {
+				b.logError("Failed to resolve endpoints: " + err.Error())
+				if b.conf.ErrHandler != nil {
+					b.conf.ErrHandler(err)
+				}
+			}
+		case <-healthTicker.C:
+			b.probeEjected()
+		}
+	}
+}
+
+// resolve updates the endpoints to the addresses returned by the resolver,
+// keeping those whose address is unchanged. On failure, or if no addresses are
+// returned, the current endpoints are kept.
+func (b *BalancedClient) resolve() error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.conf.ResolveInterval)
+	defer cancel()
+
+	addrs, err := b.conf.Resolver.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("resolver returned no addresses")
+	}
+
+	b.mu.Lock()
+	current := make(map[Address]*Endpoint, len(b.endpoints))
+	for _, e := range b.endpoints {
+		current[e.addr] = e
+	}
+	endpoints := make([]*Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		e, ok := current[addr]
+		if !ok {
+			conf := b.conf.Client
+			conf.Transport = b.conf.NewTransport(addr)
+			e = &Endpoint{addr: addr, client: NewClient(conf)}
+			b.logDebug("Adding endpoint " + addr.String())
+		}
+		delete(current, addr)
+		endpoints = append(endpoints, e)
+	}
+	b.endpoints = endpoints
+	b.refreshHealthyUnsafe()
+	b.mu.Unlock()
+
+	// The addresses left over were removed; their calls in flight fail.
+	for _, e := range current {
+		b.logDebug("Removing endpoint " + e.addr.String())
+		e.client.Close()
+	}
+	return nil
+}
+
+// probeEjected runs the health probe on each ejected endpoint and adds back
+// those that pass.
+func (b *BalancedClient) probeEjected() {
+	b.mu.Lock()
+	var ejected []*Endpoint
+	for _, e := range b.endpoints {
+		if e.ejected.Load() {
+			ejected = append(ejected, e)
+		}
+	}
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range ejected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), b.conf.HealthCheckInterval)
+			defer cancel()
+			if err := b.conf.HealthCheck(ctx, e.client); err != nil {
+				b.logDebug(fmt.Sprintf("Endpoint %s failed its health probe: %v", e.addr, err))
+				return
+			}
+			b.readd(e)
+		}()
+	}
+	wg.Wait()
+}
+
+func (b *BalancedClient) logDebug(msg string) {
+	if b.conf.Logger != nil {
+		b.conf.Logger.Debug(msg)
+	}
+}
+
+func (b *BalancedClient) logInfo(msg string) {
+	if b.conf.Logger != nil {
+		b.conf.Logger.Info(msg)
+	}
+}
+
+func (b *BalancedClient) logWarn(msg string) {
+	if b.conf.Logger != nil {
+		b.conf.Logger.Warn(msg)
+	}
+}
+
+func (b *BalancedClient) logError(msg string) {
+	if b.conf.Logger != nil {
+		b.conf.Logger.Error(msg)
+	}
+}
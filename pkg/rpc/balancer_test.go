@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// switchTransport is a fake ClientTransport whose connects fail while down.
+type switchTransport struct {
+	mu       sync.Mutex
+	down     bool
+	attempts int
+}
+
+func (t *switchTransport) Connect() (Connection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts++
+	if t.down {
+		return nil, errors.New("connection refused")
+	}
+	return newIdleConn(), nil
+}
+
+func (t *switchTransport) setDown(down bool) {
+	t.mu.Lock()
+	t.down = down
+	t.mu.Unlock()
+}
+
+func (t *switchTransport) connects() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempts
+}
+
+// mutableResolver is a fake Resolver whose addresses can be replaced.
+type mutableResolver struct {
+	mu    sync.Mutex
+	addrs []Address
+}
+
+func (r *mutableResolver) Resolve(ctx context.Context) ([]Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Address(nil), r.addrs...), nil
+}
+
+func (r *mutableResolver) set(addrs ...Address) {
+	r.mu.Lock()
+	r.addrs = addrs
+	r.mu.Unlock()
+}
+
+func testEndpoints(outstanding ...int) []*Endpoint {
+	endpoints := make([]*Endpoint, len(outstanding))
+	for i, n := range outstanding {
+		endpoints[i] = &Endpoint{addr: Address{Host: "backend", Port: i}}
+		endpoints[i].outstanding.Store(int64(n))
+	}
+	return endpoints
+}
+
+func TestRoundRobinPicker(t *testing.T) {
+	endpoints := testEndpoints(0, 0, 0)
+	picker := NewRoundRobinPicker()
+	for i := 0; i < 6; i++ {
+		assert.Same(t, endpoints[i%3], picker.Pick(endpoints))
+	}
+}
+
+func TestLeastOutstandingPicker(t *testing.T) {
+	endpoints := testEndpoints(4, 1, 3)
+	picker := NewLeastOutstandingPicker()
+	for i := 0; i < 3; i++ {
+		assert.Same(t, endpoints[1], picker.Pick(endpoints))
+	}
+
+	// Ties are spread rather than always landing on the first endpoint.
+	endpoints = testEndpoints(2, 2, 2)
+	picked := map[*Endpoint]bool{}
+	for i := 0; i < 3; i++ {
+		picked[picker.Pick(endpoints)] = true
+	}
+	assert.Len(t, picked, 3)
+}
+
+func TestBalancedClientEjectsAndReadds(t *testing.T) {
+	transports := map[Address]*switchTransport{
+		{Host: "a", Port: 1}: {},
+		{Host: "b", Port: 1}: {down: true},
+	}
+	client, err := NewBalancedClient(BalancerConfig{
+		Resolver: NewStaticResolver(Address{Host: "a", Port: 1}, Address{Host: "b", Port: 1}),
+		NewTransport: func(addr Address) ClientTransport {
+			return transports[addr]
+		},
+		Client:              ClientConfig{Backoff: Backoff{BaseDelay: 100 * time.Millisecond, Jitter: -1}},
+		HealthCheckInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	endpoints := client.Endpoints()
+	require.Len(t, endpoints, 2)
+	a, b := endpoints[0], endpoints[1]
+	bTransport := transports[Address{Host: "b", Port: 1}]
+
+	// The call picked for b fails to connect and ejects it at once; the rest
+	// go to a.
+	failed := 0
+	for i := 0; i < 4; i++ {
+		if _, err := client.OpenStream(context.Background(), 1, 1); err != nil {
+			assert.Equal(t, CodeUnavailable, CodeOf(err))
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	assert.True(t, b.Ejected())
+	assert.False(t, a.Ejected())
+	assert.Equal(t, 3, a.Outstanding())
+
+	// The probes leave the reconnecting of b to its backoff rather than
+	// dialing it themselves.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, bTransport.connects())
+	assert.Equal(t, StateTransientFailure, b.client.State())
+
+	// b is added back once its client reconnects.
+	bTransport.setDown(false)
+	require.Eventually(t, func() bool { return !b.Ejected() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, bTransport.connects())
+
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	require.NoError(t, err)
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, b.Outstanding())
+}
+
+func TestBalancedClientNoHealthyEndpoints(t *testing.T) {
+	client, err := NewBalancedClient(BalancerConfig{
+		Resolver: NewStaticResolver(Address{Host: "a", Port: 1}),
+		NewTransport: func(addr Address) ClientTransport {
+			return &switchTransport{down: true}
+		},
+		HealthCheckInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.ErrorIs(t, err, errNoHealthyEndpoints)
+
+	require.NoError(t, client.Close())
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, errClientClosed)
+}
+
+func TestBalancedClientFollowsResolver(t *testing.T) {
+	resolver := &mutableResolver{}
+	resolver.set(Address{Host: "a", Port: 1}, Address{Host: "b", Port: 1})
+	client, err := NewBalancedClient(BalancerConfig{
+		Resolver: resolver,
+		NewTransport: func(addr Address) ClientTransport {
+			return &switchTransport{}
+		},
+		ResolveInterval: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	endpoints := client.Endpoints()
+	require.Len(t, endpoints, 2)
+	b := endpoints[1]
+	stream, err := client.OpenStream(context.Background(), 1, 1)
+	require.NoError(t, err)
+
+	// a is removed, closing its client and the stream open on it, while b is
+	// kept as is.
+	resolver.set(Address{Host: "b", Port: 1}, Address{Host: "c", Port: 1})
+	require.Eventually(t, func() bool {
+		endpoints := client.Endpoints()
+		return len(endpoints) == 2 && endpoints[1].Address() == Address{Host: "c", Port: 1}
+	}, time.Second, 10*time.Millisecond)
+	assert.Same(t, b, client.Endpoints()[0])
+
+	_, err = stream.Recv()
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+
+	// An empty resolution keeps the current endpoints.
+	resolver.set()
+	time.Sleep(60 * time.Millisecond)
+	assert.Len(t, client.Endpoints(), 2)
+}
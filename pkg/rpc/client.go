@@ -18,13 +18,37 @@ type Client struct {
 	mu            *sync.Mutex
 	conn          Connection
 	transport     ClientTransport
-	requests      map[uint64]chan *serialize.Reader
+	requests      map[uint64]pendingRequest
 	streams       map[uint64]*ClientStream
 	requestID     uint64
 	running       bool
-	connGen       uint64       // bumped on each (re)connect; guards stale-connection teardown
-	lastActivity  atomic.Int64 // UnixNano of the last frame received (keepalive)
+	connGen       uint64                // bumped on each (re)connect; guards stale-connection teardown
+	draining      map[uint64]Connection // connections told to GOAWAY, by generation
+	lastActivity  atomic.Int64          // UnixNano of the last frame received (keepalive)
 	keepaliveStop chan struct{}
+	connErr       error       // last connect error, returned while in TransientFailure
+	failures      int         // consecutive failed connects, for the backoff
+	retryTimer    *time.Timer // pending background connect while in TransientFailure
+
+	// state is written with both mu and stateMu held, so it may be read with
+	// either. stateChanged is closed and replaced on each change.
+	stateMu      sync.Mutex
+	state        State
+	stateChanged chan struct{}
+}
+
+// errClientClosed is returned by calls on a client after Close.
+var errClientClosed = errors.New("client is closed")
+
+// errConnecting is returned while another caller is connecting the client.
+var errConnecting = errors.New("client is connecting")
+
+// pendingRequest is a unary request awaiting its response on the connection of
+// generation gen.
+type pendingRequest struct {
+	ch   chan *serialize.Reader
+	conn Connection
+	gen  uint64
 }
 
 type ClientConfig struct {
@@ -39,6 +63,14 @@ type ClientConfig struct {
 	// the connection is declared dead (defaults to 2*KeepaliveInterval).
 	KeepaliveInterval time.Duration
 	KeepaliveTimeout  time.Duration
+	// Backoff spaces out the background attempts to reconnect after a failed
+	// connect (zero fields = DefaultBackoff).
+	Backoff Backoff
+	// WaitForReady makes calls on a client in TransientFailure wait until it
+	// is connected or their context is done, instead of failing immediately.
+	WaitForReady bool
+	// Retry retries the unary calls of idempotent methods (nil = no retries).
+	Retry *RetryPolicy
 }
 
 func NewClient(conf ClientConfig) *Client {
@@ -46,11 +78,52 @@ func NewClient(conf ClientConfig) *Client {
 		conf:      conf,
 		transport: conf.Transport,
 		mu:        &sync.Mutex{},
-		requests:  make(map[uint64]chan *serialize.Reader),
+		requests:  make(map[uint64]pendingRequest),
 		streams:   make(map[uint64]*ClientStream),
+		draining:  make(map[uint64]Connection),
+
+		stateChanged: make(chan struct{}),
+	}
+}
+
+// State returns the connectivity state of the client.
+func (c *Client) State() State {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+// WaitForStateChange blocks until the state of the client differs from the
+// given state. It returns false if ctx is done first.
+func (c *Client) WaitForStateChange(ctx context.Context, from State) bool {
+	c.stateMu.Lock()
+	state, changed := c.state, c.stateChanged
+	c.stateMu.Unlock()
+	if state != from {
+		return true
+	}
+
+	select {
+	case <-changed:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
+// setStateUnsafe moves the client to the given state and wakes the waiters. A
+// closed client stays in StateShutdown (caller holds mu).
+func (c *Client) setStateUnsafe(state State) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.state == state || c.state == StateShutdown {
+		return
+	}
+	c.state = state
+	close(c.stateChanged)
+	c.stateChanged = make(chan struct{})
+}
+
 func (c *Client) Middleware(middleware Middleware) {
 	c.conf.middleware = append(c.conf.middleware, middleware)
 }
@@ -61,6 +134,11 @@ func (c *Client) GetMiddleware() []Middleware {
 
 func (c *Client) Close() error {
 	c.mu.Lock()
+	c.setStateUnsafe(StateShutdown)
+	if c.retryTimer != nil {
+		c.retryTimer.Stop()
+		c.retryTimer = nil
+	}
 	c.stopKeepaliveUnsafe()
 	streams := c.streams
 	c.streams = make(map[uint64]*ClientStream)
@@ -68,46 +146,74 @@ func (c *Client) Close() error {
 	if c.conn != nil {
 		err = c.conn.Close()
 		c.conn = nil
+	}
+	for gen, conn := range c.draining {
+		conn.Close()
+		delete(c.draining, gen)
+	}
 
-		// Notify all pending requests so they don't block forever.
-		// The receive goroutine treats a closed connection as a normal exit
-		// and won't call handleError, so we must clean up here.
-		requests := c.requests
-		c.requests = make(map[uint64]chan *serialize.Reader)
-		for _, ch := range requests {
-			ch <- nil
-		}
+	// Notify all pending requests so they don't block forever. The receive
+	// goroutines find their connections already torn down, so we must clean up
+	// here.
+	requests := c.requests
+	c.requests = make(map[uint64]pendingRequest)
+	for _, req := range requests {
+		req.ch <- nil
 	}
 	c.mu.Unlock()
 
 	for _, s := range streams {
-		s.die(errors.New("connection closed"))
+		s.die(unavailable(errors.New("connection closed")))
 	}
 	return err
 }
 
+// teardownUnsafe closes the connection identified by gen, whether it is the
+// current connection or one draining after a GOAWAY, and removes its in-flight
+// requests and streams for the caller to fail. It returns false if the
+// connection was already torn down (caller holds mu).
+func (c *Client) teardownUnsafe(gen uint64) ([]chan *serialize.Reader, []*ClientStream, bool) {
+	if gen == c.connGen && c.conn != nil {
+		c.stopKeepaliveUnsafe()
+		c.conn.Close()
+		c.conn = nil
+		c.setStateUnsafe(StateIdle)
+	} else if conn, ok := c.draining[gen]; ok {
+		conn.Close()
+		delete(c.draining, gen)
+	} else {
+		return nil, nil, false
+	}
+
+	var requests []chan *serialize.Reader
+	for id, req := range c.requests {
+		if req.gen == gen {
+			requests = append(requests, req.ch)
+			delete(c.requests, id)
+		}
+	}
+	var streams []*ClientStream
+	for id, s := range c.streams {
+		if s.gen == gen {
+			streams = append(streams, s)
+			delete(c.streams, id)
+		}
+	}
+	return requests, streams, true
+}
+
 // handleError tears down the connection identified by gen and fails its
 // in-flight requests/streams. gen guards against a stale goroutine (from a
-// connection that has since been replaced by a reconnect) tearing down the new
-// connection's state.
+// connection that has since been torn down) touching the state of another
+// connection.
 func (c *Client) handleError(gen uint64, err error) error {
 	c.mu.Lock()
-	if gen != c.connGen {
-		// Stale connection; its teardown already happened. Don't touch the
-		// current connection's requests/streams.
-		c.mu.Unlock()
+	requests, streams, ok := c.teardownUnsafe(gen)
+	c.mu.Unlock()
+	if !ok {
+		// Stale connection; its teardown already happened.
 		return err
 	}
-	c.stopKeepaliveUnsafe()
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
-	requests := c.requests
-	c.requests = make(map[uint64]chan *serialize.Reader)
-	streams := c.streams
-	c.streams = make(map[uint64]*ClientStream)
-	c.mu.Unlock()
 
 	c.logError("Encountered error: " + err.Error())
 	if c.conf.ErrHandler != nil {
@@ -116,7 +222,7 @@ func (c *Client) handleError(gen uint64, err error) error {
 
 	// Fail all in-flight streams.
 	for _, s := range streams {
-		s.die(fmt.Errorf("connection error: %w", err))
+		s.die(unavailable(fmt.Errorf("connection error: %w", err)))
 	}
 
 	// Notify all pending requests of the error
@@ -132,28 +238,19 @@ func (c *Client) handleError(gen uint64, err error) error {
 // handleConnectionClosed tears down the connection identified by gen after a
 // clean server-initiated close, failing its in-flight requests and streams so
 // they don't hang. Unlike handleError it does not log or invoke ErrHandler — a
-// close is normal. gen guards against a stale goroutine from a replaced
-// connection tearing down the current one's state.
+// close is normal. gen guards against a stale goroutine touching the state of
+// another connection.
 func (c *Client) handleConnectionClosed(gen uint64) {
 	c.mu.Lock()
-	if gen != c.connGen {
-		c.mu.Unlock()
+	requests, streams, ok := c.teardownUnsafe(gen)
+	c.mu.Unlock()
+	if !ok {
 		return
 	}
-	c.stopKeepaliveUnsafe()
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
-	requests := c.requests
-	c.requests = make(map[uint64]chan *serialize.Reader)
-	streams := c.streams
-	c.streams = make(map[uint64]*ClientStream)
-	c.mu.Unlock()
 
 	// Fail all in-flight streams.
 	for _, s := range streams {
-		s.die(errors.New("connection closed"))
+		s.die(unavailable(errors.New("connection closed")))
 	}
 
 	// Notify all pending requests so they don't block forever.
@@ -188,17 +285,103 @@ func (c *Client) logError(msg string) {
 	}
 }
 
+// connectUnsafe ensures the client has a connection. An idle client connects
+// right away, while one whose last connect failed returns that error until the
+// background attempt scheduled by the backoff succeeds, and one already
+// connecting returns errConnecting (caller holds mu).
 func (c *Client) connectUnsafe() error {
 	if c.conn != nil {
 		return nil
 	}
+	switch c.state {
+	case StateShutdown:
+		return errClientClosed
+	case StateTransientFailure:
+		return c.connErr
+	case StateConnecting:
+		return errConnecting
+	}
+	return c.dialUnsafe()
+}
 
-	// connect using transport
+// retryConnect is the background attempt to connect of a client in
+// TransientFailure, run once its backoff delay has passed.
+func (c *Client) retryConnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil || c.state != StateTransientFailure {
+		return
+	}
+	c.retryTimer = nil
+	c.dialUnsafe()
+}
+
+// probe reports whether the client is connected, connecting it if idle. A
+// client in TransientFailure returns its last connect error and is left to
+// reconnect on its own backoff, so that the probes do not dial alongside it. It
+// is the default health probe of a BalancedClient.
+func (c *Client) probe() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connectUnsafe()
+}
+
+// lockConnected locks mu and ensures the client has a connection, returning
+// with mu held on success. With WaitForReady, a client in TransientFailure is
+// waited on until it connects or ctx is done.
+func (c *Client) lockConnected(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		err := c.connectUnsafe()
+		if err == nil {
+			return nil
+		}
+		state := c.state
+		c.mu.Unlock()
+
+		// a call made while another is connecting waits for its outcome
+		if (!c.conf.WaitForReady && state != StateConnecting) || state == StateShutdown {
+			return unavailable(err)
+		}
+		if !c.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
+
+// dialUnsafe connects using the transport. On failure it moves the client to
+// TransientFailure and schedules the next attempt after the backoff delay. The
+// client is Connecting for the duration of the dial, which is made without mu
+// held so that a slow dial does not block the other users of the client
+// (caller holds mu, which is held again on return).
+func (c *Client) dialUnsafe() error {
+	c.setStateUnsafe(StateConnecting)
+	c.mu.Unlock()
 	c.logDebug("Connecting to server")
 	conn, err := c.transport.Connect()
+	c.mu.Lock()
+
+	// the client may have been closed while dialing
+	if c.state == StateShutdown {
+		if err == nil {
+			conn.Close()
+		}
+		return errClientClosed
+	}
 	if err != nil {
+		delay := c.conf.Backoff.Delay(c.failures)
+		c.failures++
+		c.connErr = err
+		c.logWarn(fmt.Sprintf("Failed to connect, retrying in %s: %v", delay, err))
+		if c.retryTimer != nil {
+			c.retryTimer.Stop()
+		}
+		c.retryTimer = time.AfterFunc(delay, c.retryConnect)
+		c.setStateUnsafe(StateTransientFailure)
 		return err
 	}
+	c.failures = 0
+	c.connErr = nil
 	c.conn = conn
 	c.connGen++
 	gen := c.connGen
@@ -208,10 +391,11 @@ func (c *Client) connectUnsafe() error {
 			c.logDebug("Waiting for message")
 			bs, err := conn.Receive()
 			if err != nil {
-				// A clean server-initiated close is not an error, but the
+				// A clean server-initiated close is not an error, nor is the
+				// close of a connection the server told to GOAWAY, but the
 				// in-flight requests/streams must still be failed so they don't
 				// hang (mirrors the C++ client surfacing a clean close).
-				if err.Error() == "connection closed" {
+				if err.Error() == "connection closed" || c.isDraining(gen) {
 					c.logDebug("Connection closed normally")
 					c.handleConnectionClosed(gen)
 					return
@@ -241,7 +425,7 @@ func (c *Client) connectUnsafe() error {
 				}
 
 				c.mu.Lock()
-				ch, ok := c.requests[requestID]
+				req, ok := c.requests[requestID]
 				delete(c.requests, requestID)
 				c.mu.Unlock()
 
@@ -251,10 +435,10 @@ func (c *Client) connectUnsafe() error {
 					continue
 				}
 
-				ch <- reader
+				req.ch <- reader
 
 			case StreamPrefix:
-				if err := c.handleStreamFrame(reader); err != nil {
+				if err := c.handleStreamFrame(conn, gen, reader); err != nil {
 					c.handleError(gen, err)
 					return
 				}
@@ -276,16 +460,17 @@ func (c *Client) connectUnsafe() error {
 		if timeout <= 0 {
 			timeout = 2 * interval
 		}
-		go c.keepaliveLoop(gen, interval, timeout, stop)
+		go c.keepaliveLoop(conn, gen, interval, timeout, stop)
 	}
 
+	c.setStateUnsafe(StateReady)
 	return nil
 }
 
 // keepaliveLoop periodically probes the connection with a PING when idle and
 // fails the connection if no frame arrives within the timeout window. It exits
 // when stop is closed (on disconnect) or the connection send fails.
-func (c *Client) keepaliveLoop(gen uint64, interval, timeout time.Duration, stop chan struct{}) {
+func (c *Client) keepaliveLoop(conn Connection, gen uint64, interval, timeout time.Duration, stop chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -300,7 +485,7 @@ func (c *Client) keepaliveLoop(gen uint64, interval, timeout time.Duration, stop
 				return
 			}
 			if idle >= interval {
-				if err := c.sendStreamFrame(serializeStreamControl(StreamFramePing), 0); err != nil {
+				if err := conn.Send(serializeStreamControl(StreamFramePing), 0); err != nil {
 					return
 				}
 			}
@@ -317,16 +502,22 @@ func (c *Client) stopKeepaliveUnsafe() {
 }
 
 func (c *Client) sendMessage(ctx context.Context, serviceID uint64, methodID uint64, msg Message) (uint64, chan *serialize.Reader, error) {
+	// Ensure connection, which may wait with WaitForReady
+	err := c.lockConnected(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	// Get next request ID
-	c.mu.Lock()
 	requestID := c.requestID
 	c.requestID++
-	c.mu.Unlock()
 
-	// Serialize message
+	// Serialize message, with the time left once connected as its timeout
+	timeout := TimeoutFromContext(ctx)
 	size := int(serialize.BitsToBytes(
 		BitSizePrefix() +
 			BitSizeContext(ctx) +
+			BitSizeTimeout(timeout) +
 			serialize.BitSizeUInt64(requestID) +
 			serialize.BitSizeUInt64(serviceID) +
 			serialize.BitSizeUInt64(methodID) +
@@ -337,23 +528,17 @@ func (c *Client) sendMessage(ctx context.Context, serviceID uint64, methodID uin
 
 	SerializePrefix(writer, RequestPrefix)
 	SerializeContext(writer, ctx)
+	SerializeTimeout(writer, timeout)
 	serialize.SerializeUInt64(writer, requestID)
 	serialize.SerializeUInt64(writer, serviceID)
 	serialize.SerializeUInt64(writer, methodID)
 	msg.Serialize(writer)
 	bs := writer.Bytes()
 
-	// Ensure connection and register request
-	c.mu.Lock()
-	err := c.connectUnsafe()
-	if err != nil {
-		c.mu.Unlock()
-		return 0, nil, err
-	}
-
-	// With a buffered channel of size 1, a late send succeeds without blocking
+	// Register request. With a buffered channel of size 1, a late send succeeds
+	// without blocking
 	ch := make(chan *serialize.Reader, 1)
-	c.requests[requestID] = ch
+	c.requests[requestID] = pendingRequest{ch: ch, conn: c.conn, gen: c.connGen}
 
 	// Send message
 	err = c.conn.Send(bs, serviceID)
@@ -361,64 +546,86 @@ func (c *Client) sendMessage(ctx context.Context, serviceID uint64, methodID uin
 		delete(c.requests, requestID)
 		gen := c.connGen
 		c.mu.Unlock()
-		return 0, nil, c.handleError(gen, err)
+		return 0, nil, unavailable(c.handleError(gen, err))
 	}
 
 	c.mu.Unlock()
 	return requestID, ch, nil
 }
 
-func (c *Client) receiveMessage(ctx context.Context, requestID uint64, ch chan *serialize.Reader) (*serialize.Reader, error) {
+func (c *Client) receiveMessage(ctx context.Context, serviceID uint64, requestID uint64, ch chan *serialize.Reader) (*serialize.Reader, error) {
 	select {
 	case reader := <-ch:
 		if reader == nil {
-			return nil, errors.New("channel closed")
+			return nil, unavailable(errors.New("channel closed"))
 		}
 
 		var responseType uint8
-		serialize.DeserializeUInt8(&responseType, reader)
+		if err := serialize.DeserializeUInt8(&responseType, reader); err != nil {
+			return nil, err
+		}
 
 		if responseType == MessageResponse {
 			return reader, nil
 		}
 
-		var errMsg string
-		serialize.DeserializeString(&errMsg, reader)
-		return nil, errors.New(errMsg)
+		st := &Status{}
+		if err := DeserializeStatus(st, reader); err != nil {
+			return nil, err
+		}
+		return nil, st
 	case <-ctx.Done():
 		// Context cancelled or timed out — clean up the request entry so the
-		// receive goroutine doesn't block trying to send on the orphaned channel.
+		// receive goroutine doesn't block trying to send on the orphaned channel,
+		// and tell the server to cancel the handler if the response is still
+		// outstanding. The CANCEL is best-effort: a failed send means the
+		// connection is gone, which cancels the handler anyway.
 		c.mu.Lock()
+		req, pending := c.requests[requestID]
 		delete(c.requests, requestID)
+		if pending {
+			_ = req.conn.Send(serializeCancel(requestID), serviceID)
+		}
 		c.mu.Unlock()
 		return nil, ctx.Err()
 	}
 }
 
 func (c *Client) Call(ctx context.Context, serviceID uint64, methodID uint64, msg Message) (*serialize.Reader, error) {
-	requestID, ch, err := c.sendMessage(ctx, serviceID, methodID, msg)
-	if err != nil {
-		return nil, err
-	}
+	return retryCall(ctx, c.conf.Retry, serviceID, methodID, func() (*serialize.Reader, error) {
+		requestID, ch, err := c.sendMessage(ctx, serviceID, methodID, msg)
+		if err != nil {
+			return nil, err
+		}
 
-	return c.receiveMessage(ctx, requestID, ch)
+		return c.receiveMessage(ctx, serviceID, requestID, ch)
+	})
+}
+
+// serializeCancel serializes a CANCEL frame for an in-flight unary request.
+func serializeCancel(requestID uint64) []byte {
+	size := serialize.BitsToBytes(
+		BitSizePrefix() +
+			serialize.BitSizeUInt64(requestID))
+
+	writer := serialize.NewWriter(size)
+	SerializePrefix(writer, CancelPrefix)
+	serialize.SerializeUInt64(writer, requestID)
+	return writer.Bytes()
 }
 
 // OpenStream opens a bidirectional stream against the given service/method. The
 // returned ClientStream is registered with the client demux before the OPEN
 // frame is sent, so no inbound frame can be missed.
 func (c *Client) OpenStream(ctx context.Context, serviceID uint64, methodID uint64) (*ClientStream, error) {
-	c.mu.Lock()
-
-	if err := c.connectUnsafe(); err != nil {
-		c.mu.Unlock()
+	if err := c.lockConnected(ctx); err != nil {
 		return nil, err
 	}
 
 	streamID := c.requestID
 	c.requestID++
 
-	stream := newClientStream(c, ctx, streamID, serviceID, c.conf.StreamRecvBufferSize)
+	stream := newClientStream(c, c.conn, c.connGen, ctx, streamID, serviceID, c.conf.StreamRecvBufferSize)
 	c.streams[streamID] = stream
 
 	err := c.conn.Send(serializeStreamOpen(ctx, streamID, serviceID, methodID), serviceID)
@@ -426,28 +633,28 @@ func (c *Client) OpenStream(ctx context.Context, serviceID uint64, methodID uint
 		delete(c.streams, streamID)
 		gen := c.connGen
 		c.mu.Unlock()
-		return nil, c.handleError(gen, err)
+		return nil, unavailable(c.handleError(gen, err))
 	}
 
 	c.mu.Unlock()
 	return stream, nil
 }
 
-// connection returns the current connection (nil if not connected).
-func (c *Client) connection() Connection {
+// handleGoAway stops issuing new calls on the connection identified by gen,
+// after the server announced that it is shutting down. The calls in flight on
+// it run to completion while the next call connects anew; the server closes
+// the connection once they are done.
+func (c *Client) handleGoAway(gen uint64) {
 	c.mu.Lock()
-	conn := c.conn
-	c.mu.Unlock()
-	return conn
-}
-
-// sendStreamFrame writes a pre-serialized stream frame to the connection.
-func (c *Client) sendStreamFrame(bs []byte, serviceID uint64) error {
-	conn := c.connection()
-	if conn == nil {
-		return ErrStreamClosed
+	defer c.mu.Unlock()
+	if gen != c.connGen || c.conn == nil {
+		return
 	}
-	return conn.Send(bs, serviceID)
+	c.logInfo("Server is shutting down, draining connection")
+	c.stopKeepaliveUnsafe()
+	c.draining[gen] = c.conn
+	c.conn = nil
+	c.setStateUnsafe(StateIdle)
 }
 
 func (c *Client) removeStream(streamID uint64) {
@@ -456,9 +663,19 @@ func (c *Client) removeStream(streamID uint64) {
 	c.mu.Unlock()
 }
 
-// handleStreamFrame routes an inbound stream frame to the correct ClientStream.
-// Runs on the client's single receive goroutine, preserving per-stream order.
-func (c *Client) handleStreamFrame(reader *serialize.Reader) error {
+// isDraining reports whether the connection identified by gen was told to
+// GOAWAY.
+func (c *Client) isDraining(gen uint64) bool {
+	c.mu.Lock()
+	_, ok := c.draining[gen]
+	c.mu.Unlock()
+	return ok
+}
+
+// handleStreamFrame routes an inbound stream frame on the connection identified
+// by gen to the correct ClientStream. Runs on the connection's receive
+// goroutine, preserving per-stream order.
+func (c *Client) handleStreamFrame(conn Connection, gen uint64, reader *serialize.Reader) error {
 	var streamID uint64
 	if err := serialize.DeserializeUInt64(&streamID, reader); err != nil {
 		return err
@@ -471,11 +688,15 @@ func (c *Client) handleStreamFrame(reader *serialize.Reader) error {
 
 	// Connection-level keepalive frames are not associated with a stream.
 	if frameKind == StreamFramePing {
-		return c.sendStreamFrame(serializeStreamControl(StreamFramePong), 0)
+		return conn.Send(serializeStreamControl(StreamFramePong), 0)
 	}
 	if frameKind == StreamFramePong {
 		return nil // liveness already recorded via lastActivity
 	}
+	if frameKind == StreamFrameGoAway {
+		c.handleGoAway(gen)
+		return nil
+	}
 
 	c.mu.Lock()
 	stream, ok := c.streams[streamID]
@@ -490,7 +711,7 @@ func (c *Client) handleStreamFrame(reader *serialize.Reader) error {
 	case StreamFrameMessage:
 		if stream.deliver(reader) {
 			// Bounded buffer overflowed: notify the server and drop the stream.
-			_ = c.sendStreamFrame(serializeStreamClose(streamID, StreamStatusError, errStreamOverflow.Error()), stream.serviceID)
+			_ = conn.Send(serializeStreamClose(streamID, errStreamOverflow), stream.serviceID)
 			c.removeStream(streamID)
 		}
 
@@ -500,21 +721,17 @@ func (c *Client) handleStreamFrame(reader *serialize.Reader) error {
 		stream.closeRecv(io.EOF)
 
 	case StreamFrameClose:
-		var status uint8
-		if err := serialize.DeserializeUInt8(&status, reader); err != nil {
-			return err
-		}
-		var message string
-		if err := serialize.DeserializeString(&message, reader); err != nil {
+		st := &Status{}
+		if err := DeserializeStatus(st, reader); err != nil {
 			return err
 		}
-		if status == StreamStatusOK {
+		if st.Code == CodeOK {
 			stream.die(io.EOF)
 		} else {
-			if message == "" {
-				message = "stream closed with error"
+			if st.Message == "" {
+				st.Message = "stream closed with error"
 			}
-			stream.die(errors.New(message))
+			stream.die(st)
 		}
 		c.removeStream(streamID)
 
@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// State is the connectivity state of a client.
+type State int
+
+const (
+	// StateIdle is the state of a client without a connection, before its
+	// first call and after its connection is lost. The next call connects.
+	StateIdle State = iota
+	// StateConnecting is the state of a client while it connects.
+	StateConnecting
+	// StateReady is the state of a client with a connection.
+	StateReady
+	// StateTransientFailure is the state of a client whose last attempt to
+	// connect failed. It tries again in the background after a backoff, and
+	// calls fail until it is ready, unless WaitForReady is set.
+	StateTransientFailure
+	// StateShutdown is the state of a closed client. It is final.
+	StateShutdown
+)
+
+var stateNames = [...]string{
+	StateIdle:             "Idle",
+	StateConnecting:       "Connecting",
+	StateReady:            "Ready",
+	StateTransientFailure: "TransientFailure",
+	StateShutdown:         "Shutdown",
+}
+
+func (s State) String() string {
+	if s >= 0 && int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Backoff configures the delay between attempts. The delay after n
+// consecutive failures is BaseDelay*Multiplier^n, capped at MaxDelay and
+// randomized by up to ±Jitter of itself, so that the clients of a failed
+// backend spread out their attempts instead of retrying in lockstep. Zero
+// fields use the values of DefaultBackoff.
+type Backoff struct {
+	BaseDelay  time.Duration
+	Multiplier float64
+	// Jitter is the fraction of the delay it is randomized by. A negative
+	// Jitter disables the randomization.
+	Jitter   float64
+	MaxDelay time.Duration
+}
+
+// DefaultBackoff is the backoff used for the fields of a Backoff left zero.
+var DefaultBackoff = Backoff{
+	BaseDelay:  time.Second,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   2 * time.Minute,
+}
+
+// Delay returns the delay before the attempt that follows the given number of
+// consecutive failures.
+func (b Backoff) Delay(failures int) time.Duration {
+	if b.BaseDelay <= 0 {
+		b.BaseDelay = DefaultBackoff.BaseDelay
+	}
+	if b.Multiplier <= 0 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+	if b.Jitter == 0 {
+		b.Jitter = DefaultBackoff.Jitter
+	}
+	if b.MaxDelay <= 0 {
+		b.MaxDelay = DefaultBackoff.MaxDelay
+	}
+
+	delay := float64(b.BaseDelay)
+	max := float64(b.MaxDelay)
+	for i := 0; i < failures && delay < max; i++ {
+		delay *= b.Multiplier
+	}
+	if delay > max {
+		delay = max
+	}
+	if b.Jitter > 0 {
+		delay *= 1 + b.Jitter*(rand.Float64()*2-1)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,270 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbirk/scg/pkg/serialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idleConn is a fake Connection that accepts every frame, passing it to sent if
+// set, and receives nothing until it is closed.
+type idleConn struct {
+	once   sync.Once
+	closed chan struct{}
+	sent   chan []byte
+}
+
+func newIdleConn() *idleConn {
+	return &idleConn{closed: make(chan struct{})}
+}
+
+func (c *idleConn) Send(data []byte, serviceID uint64) error {
+	if c.sent != nil {
+		c.sent <- append([]byte(nil), data...)
+	}
+	return nil
+}
+
+func (c *idleConn) Receive() ([]byte, error) {
+	<-c.closed
+	return nil, errors.New("connection closed")
+}
+
+func (c *idleConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// flakyTransport is a fake ClientTransport that fails its first connects.
+type flakyTransport struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+func (t *flakyTransport) Connect() (Connection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts++
+	if t.attempts <= t.failures {
+		return nil, errors.New("connection refused")
+	}
+	return newIdleConn(), nil
+}
+
+func (t *flakyTransport) attemptCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempts
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{BaseDelay: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.1, MaxDelay: time.Second}
+	for i := 0; i < 100; i++ {
+		assert.InDelta(t, float64(100*time.Millisecond), float64(b.Delay(0)), float64(10*time.Millisecond))
+		assert.InDelta(t, float64(400*time.Millisecond), float64(b.Delay(2)), float64(40*time.Millisecond))
+		assert.InDelta(t, float64(time.Second), float64(b.Delay(1000)), float64(100*time.Millisecond))
+	}
+
+	assert.InDelta(t, float64(DefaultBackoff.BaseDelay), float64(Backoff{}.Delay(0)), float64(DefaultBackoff.BaseDelay)*DefaultBackoff.Jitter)
+
+	exact := Backoff{BaseDelay: 100 * time.Millisecond, Multiplier: 2, Jitter: -1, MaxDelay: time.Second}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 100*time.Millisecond, exact.Delay(0))
+		assert.Equal(t, 400*time.Millisecond, exact.Delay(2))
+		assert.Equal(t, time.Second, exact.Delay(1000))
+	}
+}
+
+func TestClientConnectivityState(t *testing.T) {
+	transport := &flakyTransport{failures: 2}
+	client := NewClient(ClientConfig{
+		Transport: transport,
+		Backoff:   Backoff{BaseDelay: 20 * time.Millisecond, MaxDelay: 50 * time.Millisecond},
+	})
+	assert.Equal(t, StateIdle, client.State())
+
+	// The first call fails fast and leaves the client retrying in the background.
+	_, err := client.OpenStream(context.Background(), 1, 1)
+	require.Error(t, err)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.Equal(t, StateTransientFailure, client.State())
+
+	// Calls made before the next attempt fail with the last error without
+	// connecting again.
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.Equal(t, 1, transport.attemptCount())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for client.State() != StateReady {
+		require.True(t, client.WaitForStateChange(ctx, client.State()))
+	}
+	assert.Equal(t, 3, transport.attemptCount())
+
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close())
+	assert.Equal(t, StateShutdown, client.State())
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, client.WaitForStateChange(ctx, StateShutdown))
+
+	_, err = client.OpenStream(context.Background(), 1, 1)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.Equal(t, 3, transport.attemptCount())
+}
+
+func TestClientWaitForReady(t *testing.T) {
+	client := NewClient(ClientConfig{
+		Transport:    &flakyTransport{failures: 2},
+		Backoff:      Backoff{BaseDelay: 20 * time.Millisecond, MaxDelay: 50 * time.Millisecond},
+		WaitForReady: true,
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := client.OpenStream(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, client.State())
+}
+
+func TestClientWaitForReadyRespectsContext(t *testing.T) {
+	client := NewClient(ClientConfig{
+		Transport:    &flakyTransport{failures: 1 << 30},
+		Backoff:      Backoff{BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond},
+		WaitForReady: true,
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.OpenStream(ctx, 1, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// blockingTransport is a fake ClientTransport whose connects block until they
+// are released, signalling each connect on dialing. Its connections pass the
+// frames they send to sent if set.
+type blockingTransport struct {
+	release chan struct{}
+	dialing chan struct{}
+	sent    chan []byte
+	mu      sync.Mutex
+	conns   []*idleConn
+}
+
+func (t *blockingTransport) Connect() (Connection, error) {
+	t.dialing <- struct{}{}
+	<-t.release
+	conn := newIdleConn()
+	conn.sent = t.sent
+	t.mu.Lock()
+	t.conns = append(t.conns, conn)
+	t.mu.Unlock()
+	return conn, nil
+}
+
+func TestClientDialDoesNotHoldLock(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{}), dialing: make(chan struct{}, 2)}
+	client := NewClient(ClientConfig{Transport: transport})
+
+	opened := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.OpenStream(context.Background(), 1, 1)
+			opened <- err
+		}()
+	}
+	<-transport.dialing
+
+	// The client remains usable while the dial is in progress, and a second
+	// call waits for it rather than dialing again.
+	assert.Equal(t, StateConnecting, client.State())
+	select {
+	case <-transport.dialing:
+		t.Fatal("a second dial was started")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(transport.release)
+	require.NoError(t, <-opened)
+	require.NoError(t, <-opened)
+	assert.Equal(t, StateReady, client.State())
+	require.NoError(t, client.Close())
+}
+
+func TestClientCloseDuringDial(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{}), dialing: make(chan struct{}, 1)}
+	client := NewClient(ClientConfig{Transport: transport})
+
+	opened := make(chan error, 1)
+	go func() {
+		_, err := client.OpenStream(context.Background(), 1, 1)
+		opened <- err
+	}()
+	<-transport.dialing
+
+	// Close does not wait for the dial, whose connection is closed once it is
+	// made.
+	require.NoError(t, client.Close())
+	assert.Equal(t, StateShutdown, client.State())
+
+	close(transport.release)
+	err := <-opened
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.ErrorIs(t, err, errClientClosed)
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	require.Len(t, transport.conns, 1)
+	select {
+	case <-transport.conns[0].closed:
+	default:
+		t.Fatal("the connection made after Close was not closed")
+	}
+}
+
+func TestClientTimeoutExcludesConnect(t *testing.T) {
+	transport := &blockingTransport{
+		release: make(chan struct{}),
+		dialing: make(chan struct{}, 1),
+		sent:    make(chan []byte, 1),
+	}
+	client := NewClient(ClientConfig{Transport: transport})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	go client.Call(ctx, 1, 1, &emptyStreamMessage{})
+	<-transport.dialing
+
+	// The request is sent once connected, carrying the time left after the
+	// connect rather than before it.
+	time.Sleep(200 * time.Millisecond)
+	connected := time.Now()
+	close(transport.release)
+	data := <-transport.sent
+
+	reader := serialize.NewReader(data)
+	var prefix [16]byte
+	require.NoError(t, DeserializePrefix(&prefix, reader))
+	reqCtx := context.Background()
+	require.NoError(t, DeserializeContext(&reqCtx, reader))
+	var timeout time.Duration
+	require.NoError(t, DeserializeTimeout(&timeout, reader))
+
+	assert.False(t, connected.Add(timeout).After(deadline))
+}
@@ -21,6 +21,14 @@ var (
 		0x00, 0x00, 0x73, 0x63,
 		0x67, 0x2D, 0x73, 0x74,
 		0x72, 0x65, 0x61, 0x6D}
+	// CancelPrefix tags a frame cancelling an in-flight unary request, sent by
+	// the client when the context of a call is done before its response
+	// arrives. It carries only the request id. "scg-cancel"
+	CancelPrefix = [16]byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x73, 0x63,
+		0x67, 0x2D, 0x63, 0x61,
+		0x6E, 0x63, 0x65, 0x6C}
 )
 
 const (
@@ -38,18 +46,13 @@ const DefaultMaxRecvMessageSize uint32 = 32 << 20 // 32 MiB
 
 // Streaming frame kinds. Carried as a uint8 immediately after the stream id.
 const (
-	StreamFrameOpen      = uint8(0x01) // client -> server: open a stream (ctx, serviceID, methodID)
+	StreamFrameOpen      = uint8(0x01) // client -> server: open a stream (ctx, timeout, serviceID, methodID)
 	StreamFrameMessage   = uint8(0x02) // bidirectional: a single serialized message
 	StreamFrameHalfClose = uint8(0x03) // sender is done sending, still receiving
-	StreamFrameClose     = uint8(0x04) // terminal: status + message
+	StreamFrameClose     = uint8(0x04) // terminal: status
 	StreamFramePing      = uint8(0x05) // connection-level keepalive probe (stream id ignored)
 	StreamFramePong      = uint8(0x06) // connection-level keepalive reply (stream id ignored)
-)
-
-// Stream close statuses, carried in a CLOSE frame.
-const (
-	StreamStatusOK    = uint8(0x00)
-	StreamStatusError = uint8(0x01)
+	StreamFrameGoAway    = uint8(0x07) // server -> client: shutting down, issue new calls elsewhere (stream id ignored)
 )
 
 type Message interface {
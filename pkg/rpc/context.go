@@ -2,6 +2,9 @@ package rpc
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/kbirk/scg/pkg/serialize"
 )
@@ -144,6 +147,9 @@ func DeserializeContext(ctx *context.Context, reader *serialize.Reader) error {
 		if err := serialize.CheckLength(reader, size); err != nil {
 			return err
 		}
+		if err := serialize.CheckEntries[string, []byte](reader, size); err != nil {
+			return err
+		}
 		md := NewMetadata()
 		for i := 0; i < int(size); i++ {
 			var k string
@@ -163,6 +169,9 @@ func DeserializeContext(ctx *context.Context, reader *serialize.Reader) error {
 			if err := serialize.CheckLength(reader, size); err != nil {
 				return err
 			}
+			if err := serialize.CheckStringLength(reader, size); err != nil {
+				return err
+			}
 			val := make([]byte, size)
 			for i := 0; i < int(size); i++ {
 				err := reader.ReadBits(&val[i], 8)
@@ -177,3 +186,49 @@ func DeserializeContext(ctx *context.Context, reader *serialize.Reader) error {
 	}
 	return nil
 }
+
+// TimeoutFromContext returns the time remaining until the deadline of ctx, or
+// zero if it has none. Requests and stream OPEN frames carry the deadline as
+// this duration rather than as a point in time, so that the client and server
+// need not agree on the clock. A deadline that has already passed is reported
+// as one nanosecond, so that the server still observes it.
+func TimeoutFromContext(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return time.Nanosecond
+	}
+	return timeout
+}
+
+func BitSizeTimeout(timeout time.Duration) int {
+	return serialize.BitSizeUInt64(uint64(timeout))
+}
+
+func SerializeTimeout(writer *serialize.Writer, timeout time.Duration) {
+	serialize.SerializeUInt64(writer, uint64(timeout))
+}
+
+func DeserializeTimeout(timeout *time.Duration, reader *serialize.Reader) error {
+	var nanos uint64
+	if err := serialize.DeserializeUInt64(&nanos, reader); err != nil {
+		return err
+	}
+	if nanos > math.MaxInt64 {
+		return fmt.Errorf("timeout of %d nanoseconds is out of range", nanos)
+	}
+	*timeout = time.Duration(nanos)
+	return nil
+}
+
+// withTimeout bounds ctx by a timeout received from the client. A zero timeout
+// leaves ctx without a deadline.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Address is the host and port of a backend.
+type Address struct {
+	Host string
+	Port int
+}
+
+func (a Address) String() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// ParseAddress parses a "host:port" address.
+func ParseAddress(s string) (Address, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return Address{}, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return Address{}, fmt.Errorf("invalid port in address %q", s)
+	}
+	return Address{Host: host, Port: port}, nil
+}
+
+// Resolver supplies the addresses of the backends of a BalancedClient, which
+// calls Resolve again at each ResolveInterval to pick up changes.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]Address, error)
+}
+
+// StaticResolver resolves to a fixed list of addresses.
+type StaticResolver struct {
+	addrs []Address
+}
+
+func NewStaticResolver(addrs ...Address) *StaticResolver {
+	return &StaticResolver{addrs: append([]Address(nil), addrs...)}
+}
+
+func (r *StaticResolver) Resolve(ctx context.Context) ([]Address, error) {
+	return append([]Address(nil), r.addrs...), nil
+}
+
+// FileResolver resolves to the addresses listed in a file, one "host:port" per
+// line. Blank lines and lines starting with # are ignored. The file is read
+// again only when its size or modification time changes, so it can be watched
+// at a short ResolveInterval and rewritten to add or remove backends.
+type FileResolver struct {
+	path string
+
+	mu      sync.Mutex
+	size    int64
+	modTime time.Time
+	addrs   []Address
+}
+
+func NewFileResolver(path string) *FileResolver {
+	return &FileResolver{path: path}
+}
+
+func (r *FileResolver) Resolve(ctx context.Context) ([]Address, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.addrs != nil && info.Size() == r.size && info.ModTime().Equal(r.modTime) {
+		return append([]Address(nil), r.addrs...), nil
+	}
+
+	bs, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+	addrs := []Address{}
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		addr, err := ParseAddress(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", r.path, line, err)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	r.size = info.Size()
+	r.modTime = info.ModTime()
+	r.addrs = addrs
+	return append([]Address(nil), addrs...), nil
+}
+
+// DNSResolver resolves a host name to the addresses of its A records on Port,
+// or, if Service is set, to the targets and ports of the SRV records of
+// _Service._Proto.Host. SRV priorities and weights are not used; the picker of
+// the BalancedClient spreads the calls.
+type DNSResolver struct {
+	Host    string
+	Port    int
+	Service string
+	Proto   string // "tcp" if empty
+	// Resolver performs the lookups (nil = net.DefaultResolver). Its Dial can
+	// direct the queries to a local resolver.
+	Resolver *net.Resolver
+}
+
+func (r *DNSResolver) Resolve(ctx context.Context) ([]Address, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	if r.Service != "" {
+		proto := r.Proto
+		if proto == "" {
+			proto = "tcp"
+		}
+		_, srvs, err := resolver.LookupSRV(ctx, r.Service, proto, r.Host)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]Address, 0, len(srvs))
+		for _, srv := range srvs {
+			addrs = append(addrs, Address{Host: strings.TrimSuffix(srv.Target, "."), Port: int(srv.Port)})
+		}
+		return addrs, nil
+	}
+
+	ips, err := resolver.LookupIP(ctx, "ip4", r.Host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]Address, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, Address{Host: ip.String(), Port: r.Port})
+	}
+	return addrs, nil
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	addr, err := ParseAddress("backend:9000")
+	require.NoError(t, err)
+	assert.Equal(t, Address{Host: "backend", Port: 9000}, addr)
+	assert.Equal(t, "backend:9000", addr.String())
+
+	addr, err = ParseAddress("[::1]:80")
+	require.NoError(t, err)
+	assert.Equal(t, "[::1]:80", addr.String())
+
+	_, err = ParseAddress("backend")
+	assert.Error(t, err)
+	_, err = ParseAddress("backend:http")
+	assert.Error(t, err)
+}
+
+func TestFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backends")
+	require.NoError(t, os.WriteFile(path, []byte("# backends\na:1\n\n  b:2  \n"), 0o644))
+
+	resolver := NewFileResolver(path)
+	addrs, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Address{{Host: "a", Port: 1}, {Host: "b", Port: 2}}, addrs)
+
+	require.NoError(t, os.WriteFile(path, []byte("c:3\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	addrs, err = resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Address{{Host: "c", Port: 3}}, addrs)
+
+	require.NoError(t, os.WriteFile(path, []byte("c:3\nd\n"), 0o644))
+	_, err = resolver.Resolve(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backends:2")
+}
+
+// serveDNS answers the A and SRV queries sent to a local UDP socket with the
+// given records, and returns a resolver that queries it.
+func serveDNS(t *testing.T, a []net.IP, srv []net.SRV) *net.Resolver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+
+			// The question follows the 12 byte header: the name's labels, then
+			// its type and class.
+			end := 12
+			for end < n && query[end] != 0 {
+				end += int(query[end]) + 1
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			qtype := binary.BigEndian.Uint16(query[end-4:])
+
+			var answers [][]byte
+			switch qtype {
+			case 1: // A
+				for _, ip := range a {
+					answers = append(answers, ip.To4())
+				}
+			case 33: // SRV
+				for _, s := range srv {
+					rdata := binary.BigEndian.AppendUint16(nil, s.Priority)
+					rdata = binary.BigEndian.AppendUint16(rdata, s.Weight)
+					rdata = binary.BigEndian.AppendUint16(rdata, s.Port)
+					for _, label := range strings.Split(strings.TrimSuffix(s.Target, "."), ".") {
+						rdata = append(rdata, byte(len(label)))
+						rdata = append(rdata, label...)
+					}
+					answers = append(answers, append(rdata, 0))
+				}
+			}
+
+			resp := append([]byte(nil), query[:2]...)     // id
+			resp = append(resp, 0x81, 0x80)               // response, recursion available
+			resp = binary.BigEndian.AppendUint16(resp, 1) // questions
+			resp = binary.BigEndian.AppendUint16(resp, uint16(len(answers)))
+			resp = append(resp, 0, 0, 0, 0) // authority, additional
+			resp = append(resp, query[12:end]...)
+			for _, rdata := range answers {
+				resp = append(resp, 0xc0, 12) // name: pointer to the question
+				resp = binary.BigEndian.AppendUint16(resp, qtype)
+				resp = binary.BigEndian.AppendUint16(resp, 1) // class IN
+				resp = binary.BigEndian.AppendUint32(resp, 60)
+				resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+				resp = append(resp, rdata...)
+			}
+			conn.WriteTo(resp, from)
+		}
+	}()
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+}
+
+func TestDNSResolver(t *testing.T) {
+	dns := serveDNS(t,
+		[]net.IP{net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2)},
+		[]net.SRV{{Target: "a.backend.test.", Port: 9001, Priority: 1, Weight: 1}})
+
+	resolver := &DNSResolver{Host: "backend.test.", Port: 9000, Resolver: dns}
+	addrs, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Address{{Host: "10.0.0.1", Port: 9000}, {Host: "10.0.0.2", Port: 9000}}, addrs)
+
+	resolver = &DNSResolver{Host: "backend.test.", Service: "rpc", Resolver: dns}
+	addrs, err = resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Address{{Host: "a.backend.test", Port: 9001}}, addrs)
+}
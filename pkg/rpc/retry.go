@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kbirk/scg/pkg/serialize"
+)
+
+// MethodInfo describes a unary method as declared in the schema. The generated
+// clients register the info of each of their methods.
+type MethodInfo struct {
+	// Idempotent is set for methods marked [idempotent], which are safe to
+	// call more than once and so may be retried.
+	Idempotent bool
+}
+
+type methodKey struct {
+	serviceID uint64
+	methodID  uint64
+}
+
+var (
+	methodInfosMu sync.RWMutex
+	methodInfos   = make(map[methodKey]MethodInfo)
+)
+
+// RegisterMethodInfo records the info of a method, by service and method ID.
+func RegisterMethodInfo(serviceID uint64, methodID uint64, info MethodInfo) {
+	methodInfosMu.Lock()
+	methodInfos[methodKey{serviceID, methodID}] = info
+	methodInfosMu.Unlock()
+}
+
+// LookupMethodInfo returns the info registered for a method.
+func LookupMethodInfo(serviceID uint64, methodID uint64) (MethodInfo, bool) {
+	methodInfosMu.RLock()
+	defer methodInfosMu.RUnlock()
+	info, ok := methodInfos[methodKey{serviceID, methodID}]
+	return info, ok
+}
+
+// RetryPolicy retries the unary calls of idempotent methods that fail with a
+// retryable code. The attempts share the context of the call, and no attempt
+// is made that could not start before its deadline. Calls of methods that are
+// not idempotent, or unknown, are made once.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first (<= 1
+	// disables retries).
+	MaxAttempts int
+	// Backoff spaces out the attempts (zero fields = DefaultBackoff).
+	Backoff Backoff
+	// RetryableCodes are the codes whose failures are retried (default
+	// CodeUnavailable).
+	RetryableCodes []Code
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	code := CodeOf(err)
+	if len(p.RetryableCodes) == 0 {
+		return code == CodeUnavailable
+	}
+	return slices.Contains(p.RetryableCodes, code)
+}
+
+// retryCall makes a unary call of the given method through call, retrying it
+// according to policy (nil = no retries).
+func retryCall(ctx context.Context, policy *RetryPolicy, serviceID uint64, methodID uint64, call func() (*serialize.Reader, error)) (*serialize.Reader, error) {
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return call()
+	}
+	if info, ok := LookupMethodInfo(serviceID, methodID); !ok || !info.Idempotent {
+		return call()
+	}
+
+	for attempt := 1; ; attempt++ {
+		reader, err := call()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) || ctx.Err() != nil {
+			return reader, err
+		}
+
+		delay := policy.Backoff.Delay(attempt - 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return reader, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return reader, err
+		}
+	}
+}
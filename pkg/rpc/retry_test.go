@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbirk/scg/pkg/serialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	retryTestServiceID = 0xfff0
+	idempotentMethodID = 1
+	mutatingMethodID   = 2
+)
+
+func init() {
+	RegisterMethodInfo(retryTestServiceID, idempotentMethodID, MethodInfo{Idempotent: true})
+	RegisterMethodInfo(retryTestServiceID, mutatingMethodID, MethodInfo{Idempotent: false})
+}
+
+// failingCall returns a call that fails with err until it has been made the
+// given number of times, counting its attempts.
+func failingCall(failures int, err error, attempts *int) func() (*serialize.Reader, error) {
+	return func() (*serialize.Reader, error) {
+		*attempts++
+		if *attempts <= failures {
+			return nil, err
+		}
+		return serialize.NewReader(nil), nil
+	}
+}
+
+func TestMethodInfoRegistry(t *testing.T) {
+	info, ok := LookupMethodInfo(retryTestServiceID, idempotentMethodID)
+	require.True(t, ok)
+	assert.True(t, info.Idempotent)
+
+	_, ok = LookupMethodInfo(retryTestServiceID, 99)
+	assert.False(t, ok)
+}
+
+func TestRetryCall(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, Backoff: Backoff{BaseDelay: time.Millisecond}}
+	errUnavailable := unavailable(errors.New("connection closed"))
+
+	attempts := 0
+	_, err := retryCall(context.Background(), policy, retryTestServiceID, idempotentMethodID, failingCall(2, errUnavailable, &attempts))
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// The last failure is returned once the attempts run out.
+	attempts = 0
+	_, err = retryCall(context.Background(), policy, retryTestServiceID, idempotentMethodID, failingCall(5, errUnavailable, &attempts))
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 3, attempts)
+
+	// Methods that are not idempotent, or unknown, are called once.
+	for _, methodID := range []uint64{mutatingMethodID, 99} {
+		attempts = 0
+		_, err = retryCall(context.Background(), policy, retryTestServiceID, methodID, failingCall(2, errUnavailable, &attempts))
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	}
+
+	// So are failures with a code that is not retryable.
+	attempts = 0
+	_, err = retryCall(context.Background(), policy, retryTestServiceID, idempotentMethodID, failingCall(2, NewStatus(CodeNotFound, "missing"), &attempts))
+	assert.Equal(t, CodeNotFound, CodeOf(err))
+	assert.Equal(t, 1, attempts)
+
+	policy.RetryableCodes = []Code{CodeNotFound}
+	attempts = 0
+	_, err = retryCall(context.Background(), policy, retryTestServiceID, idempotentMethodID, failingCall(2, NewStatus(CodeNotFound, "missing"), &attempts))
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryCallRespectsDeadline(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 5, Backoff: Backoff{BaseDelay: time.Second}}
+	errUnavailable := unavailable(errors.New("connection closed"))
+
+	// No attempt is made that could not start before the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	attempts := 0
+	start := time.Now()
+	_, err := retryCall(ctx, policy, retryTestServiceID, idempotentMethodID, failingCall(5, errUnavailable, &attempts))
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// A cancellation during the backoff stops the retries.
+	policy.Backoff = Backoff{BaseDelay: 200 * time.Millisecond}
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	attempts = 0
+	_, err = retryCall(ctx, policy, retryTestServiceID, idempotentMethodID, failingCall(5, errUnavailable, &attempts))
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 1, attempts)
+}
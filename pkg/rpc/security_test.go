@@ -54,6 +54,69 @@ func TestDeserializeContextRejectsOversizedCount(t *testing.T) {
 	assert.Contains(t, err.Error(), "remaining")
 }
 
+// TestDeserializeContextLimits verifies the metadata of a context is bounded by
+// the limits of the reader, which the server sets from its configuration.
+func TestDeserializeContextLimits(t *testing.T) {
+	md := NewMetadata()
+	md.PutString("a", "value")
+	md.PutString("b", "value")
+	ctx := NewContextWithMetadata(context.Background(), md)
+
+	w := serialize.NewWriter(serialize.BitsToBytes(BitSizeContext(ctx)))
+	SerializeContext(w, ctx)
+
+	r := serialize.NewReader(w.Bytes())
+	r.SetLimits(serialize.Limits{MaxElements: 1})
+	out := context.Background()
+	err := DeserializeContext(&out, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 1 elements")
+
+	r = serialize.NewReader(w.Bytes())
+	r.SetLimits(serialize.Limits{MaxStringLength: 4})
+	err = DeserializeContext(&out, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 4 bytes")
+
+	r = serialize.NewReader(w.Bytes())
+	r.SetLimits(serialize.Limits{MaxElements: 2, MaxStringLength: 8})
+	require.NoError(t, DeserializeContext(&out, r))
+}
+
+// TestTimeoutFromContext verifies the deadline of a context is sent as the time
+// remaining until it, and an expired deadline is still sent.
+func TestTimeoutFromContext(t *testing.T) {
+	assert.Zero(t, TimeoutFromContext(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	timeout := TimeoutFromContext(ctx)
+	assert.Greater(t, timeout, 59*time.Second)
+	assert.LessOrEqual(t, timeout, time.Minute)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, time.Nanosecond, TimeoutFromContext(expired))
+
+	w := serialize.NewWriter(serialize.BitsToBytes(BitSizeTimeout(timeout)))
+	SerializeTimeout(w, timeout)
+	var actual time.Duration
+	require.NoError(t, DeserializeTimeout(&actual, serialize.NewReader(w.Bytes())))
+	assert.Equal(t, timeout, actual)
+}
+
+// TestDeserializeTimeoutRejectsOverflow verifies a timeout that does not fit a
+// time.Duration is rejected rather than wrapping to a negative duration.
+func TestDeserializeTimeoutRejectsOverflow(t *testing.T) {
+	w := serialize.NewWriter(16)
+	serialize.SerializeUInt64(w, 1<<63)
+
+	var timeout time.Duration
+	err := DeserializeTimeout(&timeout, serialize.NewReader(w.Bytes()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+}
+
 // TestServerStreamContextCancelledOnDie verifies the server handler's context is
 // cancelled when the stream dies, so a push-only handler can observe client
 // cancellation / connection loss via Context().Done() instead of only on its
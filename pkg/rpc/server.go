@@ -20,6 +20,31 @@ type Server struct {
 	running          bool
 	mu               *sync.Mutex
 	middlewareCache  map[uint64][]Middleware
+
+	// The open connections and the number of in-flight unary requests and
+	// streams, tracked so that Shutdown can drain them.
+	connMu           sync.Mutex
+	conns            map[Connection]struct{}
+	inflightRequests int
+	inflightStreams  int
+	draining         bool
+	drained          chan struct{}
+}
+
+// ShutdownError is returned by Shutdown when its context is done before the
+// in-flight calls complete. It reports the calls that were abandoned.
+type ShutdownError struct {
+	Requests int // unary requests still in flight
+	Streams  int // streams still open
+	Err      error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown abandoned %d requests and %d streams: %v", e.Requests, e.Streams, e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
 }
 
 type ServerGroup struct {
@@ -45,18 +70,26 @@ type ServerConfig struct {
 	// goroutines and stream buffers.
 	KeepaliveInterval time.Duration
 	KeepaliveTimeout  time.Duration
+	// Limits bounds the decoding of every frame received by the server,
+	// including the request context and messages decoded by stream handlers,
+	// before any middleware runs. The zero value only bounds the nesting depth.
+	Limits serialize.Limits
 }
 
 type serverStub interface {
 	HandleWrapper(context.Context, []Middleware, uint64, *serialize.Reader) []byte
 }
 
+// RespondWithError serializes an error response carrying the status of err, as
+// returned by StatusFromError.
 func RespondWithError(requestID uint64, err error) []byte {
+	st := StatusFromError(err)
+
 	size := serialize.BitsToBytes(
 		BitSizePrefix() +
 			serialize.BitSizeUInt64(requestID) +
 			serialize.BitSizeUInt8(ErrorResponse) +
-			serialize.BitSizeString(err.Error()))
+			BitSizeStatus(st))
 
 	writer := getWriter(size)
 	defer putWriter(writer)
@@ -64,7 +97,7 @@ func RespondWithError(requestID uint64, err error) []byte {
 	SerializePrefix(writer, ResponsePrefix)
 	serialize.SerializeUInt64(writer, requestID)
 	serialize.SerializeUInt8(writer, ErrorResponse)
-	serialize.SerializeString(writer, err.Error())
+	SerializeStatus(writer, st)
 
 	// Copy bytes since we're returning the writer to the pool
 	bs := writer.Bytes()
@@ -113,6 +146,7 @@ func NewServer(conf ServerConfig) *Server {
 		groupByServiceID: make(map[uint64]*ServerGroup),
 		middlewareCache:  make(map[uint64][]Middleware),
 		mu:               &sync.Mutex{},
+		conns:            make(map[Connection]struct{}),
 	}
 
 	return s
@@ -204,7 +238,7 @@ func (s *Server) getMiddlewareStackForServiceID(serviceID uint64) ([]Middleware,
 
 	group, ok := s.groupByServiceID[serviceID]
 	if !ok {
-		return nil, fmt.Errorf("service with id %d not found", serviceID)
+		return nil, Errorf(CodeUnimplemented, "service with id %d not found", serviceID)
 	}
 
 	// get the lineage from this group to the root
@@ -230,7 +264,7 @@ func (s *Server) getServiceByID(id uint64) (serverStub, error) {
 
 	group, ok := s.groupByServiceID[id]
 	if !ok {
-		return nil, fmt.Errorf("service with id %d not found", id)
+		return nil, Errorf(CodeUnimplemented, "service with id %d not found", id)
 	}
 	return group.getServiceByID(id)
 }
@@ -238,19 +272,74 @@ func (s *Server) getServiceByID(id uint64) (serverStub, error) {
 func (g *ServerGroup) getServiceByID(id uint64) (serverStub, error) {
 	service, ok := g.services[id]
 	if !ok {
-		return nil, fmt.Errorf("service with id %d not found", id)
+		return nil, Errorf(CodeUnimplemented, "service with id %d not found", id)
 	}
 	return service, nil
 }
 
+// addConn registers an open connection. It returns false if the server is
+// shutting down, in which case the connection must be closed.
+func (s *Server) addConn(conn Connection) bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) removeConn(conn Connection) {
+	s.connMu.Lock()
+	delete(s.conns, conn)
+	s.connMu.Unlock()
+}
+
+func (s *Server) isDraining() bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return s.draining
+}
+
+// addInflight adjusts the number of in-flight unary requests and streams, and
+// signals a draining Shutdown once none remain.
+func (s *Server) addInflight(requests int, streams int) {
+	s.connMu.Lock()
+	s.inflightRequests += requests
+	s.inflightStreams += streams
+	if s.draining {
+		s.signalDrainedUnsafe()
+	}
+	s.connMu.Unlock()
+}
+
+// signalDrainedUnsafe closes the drained channel once no calls are in flight
+// (caller holds connMu).
+func (s *Server) signalDrainedUnsafe() {
+	if s.drained != nil && s.inflightRequests == 0 && s.inflightStreams == 0 {
+		close(s.drained)
+		s.drained = nil
+	}
+}
+
 func (s *Server) handleConnection(conn Connection) {
 	defer conn.Close()
 
+	if !s.addConn(conn) {
+		return
+	}
+	defer s.removeConn(conn)
+
 	// Per-connection registry of live streams. Failed on disconnect so handler
 	// goroutines blocked in Recv observe the terminal error and return.
 	cs := newConnStreams()
 	defer cs.terminateAll(fmt.Errorf("connection closed"))
 
+	// Per-connection registry of in-flight unary requests. Cancelled on
+	// disconnect, as no response can be delivered.
+	reqs := newConnRequests()
+	defer reqs.cancelAll(fmt.Errorf("connection closed"))
+
 	// Server-initiated keepalive detects a client that vanished without a clean
 	// close: without it, Receive() below would block forever, leaking this
 	// goroutine, its per-stream handlers, and their buffers. When enabled, the
@@ -268,8 +357,9 @@ func (s *Server) handleConnection(conn Connection) {
 		// read message
 		bs, err := conn.Receive()
 		if err != nil {
-			// Don't treat normal connection closures as errors
-			if err.Error() == "connection closed" {
+			// Don't treat normal connection closures as errors, nor the
+			// connections closed by Shutdown
+			if err.Error() == "connection closed" || s.isDraining() {
 				break
 			}
 			s.handleError(err)
@@ -278,6 +368,7 @@ func (s *Server) handleConnection(conn Connection) {
 		lastActivity.Store(time.Now().UnixNano())
 
 		reader := serialize.NewReader(bs)
+		reader.SetLimits(s.conf.Limits)
 
 		var prefix [16]byte
 		if err := DeserializePrefix(&prefix, reader); err != nil {
@@ -288,8 +379,10 @@ func (s *Server) handleConnection(conn Connection) {
 		switch prefix {
 		case RequestPrefix:
 			// Unary calls run concurrently, one goroutine per request.
-			r := reader
-			go s.handleUnaryRequest(conn, r)
+			s.handleUnaryRequest(conn, reqs, reader)
+
+		case CancelPrefix:
+			s.handleCancel(reqs, reader)
 
 		case StreamPrefix:
 			// Stream frames are routed inline on the read loop to preserve
@@ -339,9 +432,74 @@ func (s *Server) serverKeepaliveLoop(conn Connection, lastActivity *atomic.Int64
 	}
 }
 
+// errRequestCancelled is the cause of the context of a unary handler whose
+// request the client cancelled.
+var errRequestCancelled = NewStatus(CodeCanceled, "request cancelled by client")
+
+// connRequests is the per-connection registry of in-flight unary requests, so
+// that a CANCEL from the client reaches the context of the matching handler.
+// The connection read loop and each handler goroutine touch it, so it is
+// guarded.
+type connRequests struct {
+	mu       sync.Mutex
+	requests map[uint64]context.CancelCauseFunc
+}
+
+func newConnRequests() *connRequests {
+	return &connRequests{requests: make(map[uint64]context.CancelCauseFunc)}
+}
+
+// add registers a request, unless one with the same id is already in flight.
+func (c *connRequests) add(id uint64, cancel context.CancelCauseFunc) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.requests[id]; ok {
+		return false
+	}
+	c.requests[id] = cancel
+	return true
+}
+
+func (c *connRequests) remove(id uint64) {
+	c.mu.Lock()
+	delete(c.requests, id)
+	c.mu.Unlock()
+}
+
+func (c *connRequests) count() int {
+	c.mu.Lock()
+	n := len(c.requests)
+	c.mu.Unlock()
+	return n
+}
+
+// cancel cancels the context of the handler of a request, if it is still in
+// flight.
+func (c *connRequests) cancel(id uint64, cause error) {
+	c.mu.Lock()
+	cancel, ok := c.requests[id]
+	c.mu.Unlock()
+	if ok {
+		cancel(cause)
+	}
+}
+
+func (c *connRequests) cancelAll(cause error) {
+	c.mu.Lock()
+	requests := c.requests
+	c.requests = make(map[uint64]context.CancelCauseFunc)
+	c.mu.Unlock()
+
+	for _, cancel := range requests {
+		cancel(cause)
+	}
+}
+
 // handleUnaryRequest processes a single unary request frame (prefix already
-// consumed) and writes the response.
-func (s *Server) handleUnaryRequest(conn Connection, reader *serialize.Reader) {
+// consumed). The header is read on the connection read loop, so that the
+// request is registered before a CANCEL for it can be read; the handler then
+// runs in its own goroutine and writes the response.
+func (s *Server) handleUnaryRequest(conn Connection, reqs *connRequests, reader *serialize.Reader) {
 	// get the context
 	ctx := context.Background()
 	err := DeserializeContext(&ctx, reader)
@@ -350,6 +508,14 @@ func (s *Server) handleUnaryRequest(conn Connection, reader *serialize.Reader) {
 		return
 	}
 
+	// get the deadline of the caller
+	var timeout time.Duration
+	err = DeserializeTimeout(&timeout, reader)
+	if err != nil {
+		s.handleError(err)
+		return
+	}
+
 	// get the request id
 	var requestID uint64
 	err = serialize.DeserializeUInt64(&requestID, reader)
@@ -366,23 +532,48 @@ func (s *Server) handleUnaryRequest(conn Connection, reader *serialize.Reader) {
 		return
 	}
 
+	// bound the handler by the deadline of the caller, and let a CANCEL from
+	// the client cancel it
+	ctx, cancelTimeout := withTimeout(ctx, timeout)
+	ctx, cancel := context.WithCancelCause(ctx)
+	tracked := reqs.add(requestID, cancel)
+
+	s.addInflight(1, 0)
+	go func() {
+		defer s.addInflight(-1, 0)
+		defer cancelTimeout()
+		defer cancel(context.Canceled)
+		if tracked {
+			defer reqs.remove(requestID)
+		}
+		s.runUnaryHandler(ctx, conn, requestID, serviceID, reader)
+	}()
+}
+
+// runUnaryHandler runs the handler of a unary request and writes the response.
+func (s *Server) runUnaryHandler(ctx context.Context, conn Connection, requestID uint64, serviceID uint64, reader *serialize.Reader) {
 	// acquire the service
 	service, err := s.getServiceByID(serviceID)
 	if err != nil {
-		s.handleError(err)
+		s.respondWithError(conn, requestID, serviceID, err)
 		return
 	}
 
 	// gather middleware for the call
 	middleware, err := s.getMiddlewareStackForServiceID(serviceID)
 	if err != nil {
-		s.handleError(err)
+		s.respondWithError(conn, requestID, serviceID, err)
 		return
 	}
 
 	// handle the request
 	bs := service.HandleWrapper(ctx, middleware, requestID, reader)
 
+	// the client no longer waits for the response of a cancelled request
+	if context.Cause(ctx) == errRequestCancelled {
+		return
+	}
+
 	// send response
 	err = conn.Send(bs, serviceID)
 	if err != nil {
@@ -391,6 +582,26 @@ func (s *Server) handleUnaryRequest(conn Connection, reader *serialize.Reader) {
 	}
 }
 
+// respondWithError reports err and answers the request with it, so that the
+// caller does not wait on a response that will never come.
+func (s *Server) respondWithError(conn Connection, requestID uint64, serviceID uint64, err error) {
+	s.handleError(err)
+	if err := conn.Send(RespondWithError(requestID, err), serviceID); err != nil {
+		s.handleError(err)
+	}
+}
+
+// handleCancel cancels the handler of the unary request named by a CANCEL frame
+// (prefix already consumed). A request that has already completed is ignored.
+func (s *Server) handleCancel(reqs *connRequests, reader *serialize.Reader) {
+	var requestID uint64
+	if err := serialize.DeserializeUInt64(&requestID, reader); err != nil {
+		s.handleError(err)
+		return
+	}
+	reqs.cancel(requestID, errRequestCancelled)
+}
+
 // handleStreamFrame routes one inbound stream frame. OPEN spawns a handler
 // goroutine; MSG/HALF_CLOSE/CLOSE are delivered to the existing stream.
 func (s *Server) handleStreamFrame(conn Connection, cs *connStreams, reader *serialize.Reader) {
@@ -422,6 +633,11 @@ func (s *Server) handleStreamFrame(conn Connection, cs *connStreams, reader *ser
 			s.handleError(err)
 			return
 		}
+		var timeout time.Duration
+		if err := DeserializeTimeout(&timeout, reader); err != nil {
+			s.handleError(err)
+			return
+		}
 		var serviceID uint64
 		if err := serialize.DeserializeUInt64(&serviceID, reader); err != nil {
 			s.handleError(err)
@@ -435,24 +651,32 @@ func (s *Server) handleStreamFrame(conn Connection, cs *connStreams, reader *ser
 
 		// Reject a duplicate stream id rather than orphaning the existing stream.
 		if cs.get(streamID) != nil {
-			_ = conn.Send(serializeStreamClose(streamID, StreamStatusError, "duplicate stream id"), serviceID)
+			_ = conn.Send(serializeStreamClose(streamID, NewStatus(CodeAlreadyExists, "duplicate stream id")), serviceID)
 			return
 		}
 		// Enforce the per-connection concurrent-stream cap.
 		if max := s.conf.MaxConcurrentStreams; max > 0 && cs.count() >= max {
-			_ = conn.Send(serializeStreamClose(streamID, StreamStatusError, "max concurrent streams exceeded"), serviceID)
+			_ = conn.Send(serializeStreamClose(streamID, NewStatus(CodeResourceExhausted, "max concurrent streams exceeded")), serviceID)
 			return
 		}
 
+		// The stream context is bounded by the deadline of the caller, and
+		// released when the handler returns.
+		ctx, cancel := withTimeout(ctx, timeout)
 		stream := newServerStream(conn, ctx, streamID, serviceID, s.conf.StreamRecvBufferSize)
 		cs.add(streamID, stream)
-		go s.runStreamHandler(conn, cs, stream, methodID)
+		s.addInflight(0, 1)
+		go func() {
+			defer s.addInflight(0, -1)
+			defer cancel()
+			s.runStreamHandler(conn, cs, stream, methodID)
+		}()
 
 	case StreamFrameMessage:
 		if st := cs.get(streamID); st != nil {
 			if st.deliver(reader) {
 				// Bounded buffer overflowed: notify the client and drop the stream.
-				_ = conn.Send(serializeStreamClose(streamID, StreamStatusError, errStreamOverflow.Error()), st.serviceID)
+				_ = conn.Send(serializeStreamClose(streamID, errStreamOverflow), st.serviceID)
 				cs.remove(streamID)
 			}
 		}
@@ -465,7 +689,7 @@ func (s *Server) handleStreamFrame(conn Connection, cs *connStreams, reader *ser
 	case StreamFrameClose:
 		// Client cancelled the stream; surface an error to the handler.
 		if st := cs.get(streamID); st != nil {
-			st.die(fmt.Errorf("stream cancelled by client"))
+			st.die(NewStatus(CodeCanceled, "stream cancelled by client"))
 			cs.remove(streamID)
 		}
 
@@ -485,7 +709,7 @@ func (s *Server) runStreamHandler(conn Connection, cs *connStreams, stream *Serv
 	defer stream.cancel(context.Canceled)
 
 	closeWithError := func(err error) {
-		_ = conn.Send(serializeStreamClose(stream.streamID, StreamStatusError, err.Error()), serviceID)
+		_ = conn.Send(serializeStreamClose(stream.streamID, StatusFromError(err)), serviceID)
 	}
 
 	service, err := s.getServiceByID(serviceID)
@@ -496,7 +720,7 @@ func (s *Server) runStreamHandler(conn Connection, cs *connStreams, stream *Serv
 
 	streamStub, ok := service.(streamServerStub)
 	if !ok {
-		closeWithError(fmt.Errorf("service with id %d does not support streaming", serviceID))
+		closeWithError(Errorf(CodeUnimplemented, "service with id %d does not support streaming", serviceID))
 		return
 	}
 
@@ -519,7 +743,7 @@ func (s *Server) runStreamHandler(conn Connection, cs *connStreams, stream *Serv
 		return
 	}
 
-	_ = conn.Send(serializeStreamClose(stream.streamID, StreamStatusOK, ""), serviceID)
+	_ = conn.Send(serializeStreamClose(stream.streamID, nil), serviceID)
 }
 
 func (s *Server) ListenAndServe() error {
@@ -566,10 +790,63 @@ func (s *Server) ListenAndServe() error {
 	return nil
 }
 
+// Shutdown gracefully stops the server. It stops accepting connections and
+// sends a GOAWAY on each open connection, so that clients issue their next
+// calls on a new connection, then waits for the in-flight unary requests and
+// streams to complete. Calls that arrive on a connection before its client has
+// seen the GOAWAY are still served. Once ctx is done, the remaining connections
+// are closed, cancelling their handlers, and a *ShutdownError reports what was
+// abandoned.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.mu.Lock()
 	s.running = false
 	s.mu.Unlock()
 
-	return s.transport.Close()
+	s.connMu.Lock()
+	s.draining = true
+	drained := make(chan struct{})
+	s.drained = drained
+	s.signalDrainedUnsafe()
+	conns := s.connsUnsafe()
+	s.connMu.Unlock()
+
+	err := s.transport.Close()
+
+	goAway := serializeStreamControl(StreamFrameGoAway)
+	for _, conn := range conns {
+		_ = conn.Send(goAway, 0)
+	}
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.connMu.Lock()
+		err = &ShutdownError{
+			Requests: s.inflightRequests,
+			Streams:  s.inflightStreams,
+			Err:      ctx.Err(),
+		}
+		s.connMu.Unlock()
+		s.logWarn(err.Error())
+	}
+
+	// Closing the connections unblocks their read loops, which cancel the
+	// handlers still running.
+	s.connMu.Lock()
+	conns = s.connsUnsafe()
+	s.connMu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	return err
+}
+
+// connsUnsafe returns the open connections (caller holds connMu).
+func (s *Server) connsUnsafe() []Connection {
+	conns := make([]Connection, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	return conns
 }
@@ -0,0 +1,213 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbirk/scg/pkg/serialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingUnaryService is a minimal unary stub whose handler blocks until its
+// context is done and reports the cause.
+type blockingUnaryService struct {
+	causes chan error
+}
+
+func (s *blockingUnaryService) HandleWrapper(ctx context.Context, middleware []Middleware, requestID uint64, reader *serialize.Reader) []byte {
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+	}
+	s.causes <- context.Cause(ctx)
+	return RespondWithError(requestID, ctx.Err())
+}
+
+// requestFrameReader builds a request frame and returns a reader positioned just
+// past the prefix, matching how handleConnection hands frames to
+// handleUnaryRequest.
+func requestFrameReader(t *testing.T, requestID, serviceID, methodID uint64) *serialize.Reader {
+	t.Helper()
+	ctx := context.Background()
+	writer := serialize.NewWriter(64)
+	SerializePrefix(writer, RequestPrefix)
+	SerializeContext(writer, ctx)
+	SerializeTimeout(writer, 0)
+	serialize.SerializeUInt64(writer, requestID)
+	serialize.SerializeUInt64(writer, serviceID)
+	serialize.SerializeUInt64(writer, methodID)
+	r := serialize.NewReader(writer.Bytes())
+	var prefix [16]byte
+	require.NoError(t, DeserializePrefix(&prefix, r))
+	return r
+}
+
+func cancelFrameReader(t *testing.T, requestID uint64) *serialize.Reader {
+	t.Helper()
+	r := serialize.NewReader(serializeCancel(requestID))
+	var prefix [16]byte
+	require.NoError(t, DeserializePrefix(&prefix, r))
+	require.Equal(t, CancelPrefix, prefix)
+	return r
+}
+
+// TestServerCancelsUnaryHandler verifies a CANCEL frame cancels the context of
+// the matching in-flight handler, and that no response is sent for it.
+func TestServerCancelsUnaryHandler(t *testing.T) {
+	const serviceID, methodID = uint64(42), uint64(7)
+
+	svc := &blockingUnaryService{causes: make(chan error, 2)}
+	server := NewServer(ServerConfig{})
+	server.RegisterServer(serviceID, "fake", svc)
+
+	conn := &recordingConn{}
+	reqs := newConnRequests()
+	defer reqs.cancelAll(context.Canceled)
+
+	server.handleUnaryRequest(conn, reqs, requestFrameReader(t, 1, serviceID, methodID))
+	server.handleUnaryRequest(conn, reqs, requestFrameReader(t, 2, serviceID, methodID))
+	require.Equal(t, 2, reqs.count(), "requests should be registered before their handlers run")
+
+	// A CANCEL for an unknown request is ignored.
+	server.handleCancel(reqs, cancelFrameReader(t, 99))
+	require.Equal(t, 2, reqs.count())
+
+	server.handleCancel(reqs, cancelFrameReader(t, 1))
+	select {
+	case cause := <-svc.causes:
+		assert.Equal(t, errRequestCancelled, cause)
+	case <-time.After(time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+
+	require.Eventually(t, func() bool { return reqs.count() == 1 }, time.Second, 10*time.Millisecond,
+		"a completed request should be removed from the registry")
+
+	conn.mu.Lock()
+	sent := len(conn.sent)
+	conn.mu.Unlock()
+	assert.Zero(t, sent, "no response should be sent for a cancelled request")
+}
+
+// TestServerRespondsToUnknownService verifies a request for a service that is
+// not registered is answered with CodeUnimplemented rather than left pending.
+func TestServerRespondsToUnknownService(t *testing.T) {
+	server := NewServer(ServerConfig{})
+
+	conn := &recordingConn{}
+	reqs := newConnRequests()
+	defer reqs.cancelAll(context.Canceled)
+
+	server.handleUnaryRequest(conn, reqs, requestFrameReader(t, 3, 1234, 1))
+
+	var frame []byte
+	require.Eventually(t, func() bool {
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+		if len(conn.sent) == 0 {
+			return false
+		}
+		frame = conn.sent[0]
+		return true
+	}, time.Second, 10*time.Millisecond, "the request should be answered")
+
+	reader := serialize.NewReader(frame)
+	var prefix [16]byte
+	require.NoError(t, DeserializePrefix(&prefix, reader))
+	require.Equal(t, ResponsePrefix, prefix)
+	var requestID uint64
+	require.NoError(t, serialize.DeserializeUInt64(&requestID, reader))
+	assert.Equal(t, uint64(3), requestID)
+	var responseType uint8
+	require.NoError(t, serialize.DeserializeUInt8(&responseType, reader))
+	require.Equal(t, ErrorResponse, responseType)
+	st := &Status{}
+	require.NoError(t, DeserializeStatus(st, reader))
+	var err error = st
+	assert.Equal(t, CodeUnimplemented, CodeOf(err))
+}
+
+// idleTransport is a ServerTransport that never accepts a connection, for
+// driving Shutdown without a listener.
+type idleTransport struct{}
+
+func (idleTransport) Listen() error               { return nil }
+func (idleTransport) Accept() (Connection, error) { return nil, errors.New("transport is closed") }
+func (idleTransport) Close() error                { return nil }
+
+func (c *recordingConn) sentGoAway() bool {
+	goAway := serializeStreamControl(StreamFrameGoAway)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range c.sent {
+		if bytes.Equal(f, goAway) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestServerShutdownDrainsStreams verifies Shutdown sends a GOAWAY and waits for
+// an in-flight stream to complete before closing the connection.
+func TestServerShutdownDrainsStreams(t *testing.T) {
+	const serviceID, methodID = uint64(42), uint64(7)
+
+	svc := &blockingStreamService{block: make(chan struct{})}
+	server := NewServer(ServerConfig{Transport: idleTransport{}})
+	server.RegisterServer(serviceID, "fake", svc)
+
+	conn := &recordingConn{}
+	require.True(t, server.addConn(conn))
+	cs := newConnStreams()
+	defer cs.terminateAll(errors.New("test done"))
+
+	server.handleStreamFrame(conn, cs, openFrameReader(t, 1, serviceID, methodID))
+	time.AfterFunc(100*time.Millisecond, func() { close(svc.block) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.Shutdown(ctx))
+
+	assert.True(t, conn.sentGoAway(), "server should send a GOAWAY")
+	assert.True(t, conn.sentCloseWith(CodeOK, ""), "the stream should complete before shutdown returns")
+	conn.mu.Lock()
+	assert.True(t, conn.closed, "the connection should be closed once drained")
+	conn.mu.Unlock()
+	assert.False(t, server.addConn(&recordingConn{}), "no connection should be accepted after shutdown")
+}
+
+// TestServerShutdownReportsAbandoned verifies Shutdown gives up on the calls
+// still in flight once its context is done, and reports them.
+func TestServerShutdownReportsAbandoned(t *testing.T) {
+	const serviceID, methodID = uint64(42), uint64(7)
+
+	svc := &blockingUnaryService{causes: make(chan error, 1)}
+	server := NewServer(ServerConfig{Transport: idleTransport{}})
+	server.RegisterServer(serviceID, "fake", svc)
+
+	conn := &recordingConn{}
+	require.True(t, server.addConn(conn))
+	reqs := newConnRequests()
+	defer reqs.cancelAll(context.Canceled)
+
+	server.handleUnaryRequest(conn, reqs, requestFrameReader(t, 1, serviceID, methodID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.Equal(t, 1, shutdownErr.Requests)
+	assert.Equal(t, 0, shutdownErr.Streams)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.True(t, conn.sentGoAway(), "server should send a GOAWAY")
+	conn.mu.Lock()
+	assert.True(t, conn.closed, "the connection should be force-closed")
+	conn.mu.Unlock()
+}
@@ -0,0 +1,233 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kbirk/scg/pkg/serialize"
+)
+
+// Code classifies the outcome of a call, so that callers can branch on the kind
+// of failure rather than on its message. The values match the C++ scg::rpc::Code
+// and must not be renumbered.
+type Code uint32
+
+const (
+	CodeOK                 Code = 0
+	CodeCanceled           Code = 1
+	CodeUnknown            Code = 2
+	CodeInvalidArgument    Code = 3
+	CodeDeadlineExceeded   Code = 4
+	CodeNotFound           Code = 5
+	CodeAlreadyExists      Code = 6
+	CodePermissionDenied   Code = 7
+	CodeResourceExhausted  Code = 8
+	CodeFailedPrecondition Code = 9
+	CodeAborted            Code = 10
+	CodeOutOfRange         Code = 11
+	CodeUnimplemented      Code = 12
+	CodeInternal           Code = 13
+	CodeUnavailable        Code = 14
+	CodeDataLoss           Code = 15
+	CodeUnauthenticated    Code = 16
+)
+
+var codeNames = [...]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "Canceled",
+	CodeUnknown:            "Unknown",
+	CodeInvalidArgument:    "InvalidArgument",
+	CodeDeadlineExceeded:   "DeadlineExceeded",
+	CodeNotFound:           "NotFound",
+	CodeAlreadyExists:      "AlreadyExists",
+	CodePermissionDenied:   "PermissionDenied",
+	CodeResourceExhausted:  "ResourceExhausted",
+	CodeFailedPrecondition: "FailedPrecondition",
+	CodeAborted:            "Aborted",
+	CodeOutOfRange:         "OutOfRange",
+	CodeUnimplemented:      "Unimplemented",
+	CodeInternal:           "Internal",
+	CodeUnavailable:        "Unavailable",
+	CodeDataLoss:           "DataLoss",
+	CodeUnauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+// DetailMessage is a message that can be attached to a Status as a detail. The
+// generated messages implement it; MessageName identifies the type of a detail
+// on the wire, so that Go and C++ peers agree on it.
+type DetailMessage interface {
+	Message
+	MessageName() string
+}
+
+// StatusDetail is an encoded detail message and the name of its type.
+type StatusDetail struct {
+	Type string
+	Data []byte
+}
+
+// Status is the outcome of a call as sent by the server in an error response or
+// a stream CLOSE frame. It implements error, so a handler may return one
+// directly, and a client recovers it with errors.As or StatusFromError.
+type Status struct {
+	Code    Code
+	Message string
+	Details []StatusDetail
+	cause   error
+}
+
+// NewStatus returns a status with the code and message.
+func NewStatus(code Code, message string) *Status {
+	return &Status{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Errorf returns a status with the code and a formatted message as an error.
+func Errorf(code Code, format string, args ...any) error {
+	return NewStatus(code, fmt.Sprintf(format, args...))
+}
+
+// Error returns the message of the status, or the name of its code if the
+// message is empty.
+func (s *Status) Error() string {
+	if s.Message == "" {
+		return s.Code.String()
+	}
+	return s.Message
+}
+
+// Unwrap returns the local error a client status was derived from, if any.
+func (s *Status) Unwrap() error {
+	return s.cause
+}
+
+// WithDetails appends the encoded details to the status and returns it.
+func (s *Status) WithDetails(details ...DetailMessage) *Status {
+	for _, detail := range details {
+		s.Details = append(s.Details, StatusDetail{
+			Type: detail.MessageName(),
+			Data: detail.ToBytes(),
+		})
+	}
+	return s
+}
+
+// Detail decodes the first detail of the same type as msg into msg. It returns
+// false if the status has no such detail or it cannot be decoded.
+func (s *Status) Detail(msg DetailMessage) bool {
+	name := msg.MessageName()
+	for _, detail := range s.Details {
+		if detail.Type == name {
+			return msg.FromBytes(detail.Data) == nil
+		}
+	}
+	return false
+}
+
+// StatusFromError returns the status carried by err. Context errors map to
+// CodeCanceled and CodeDeadlineExceeded, and any other error to CodeUnknown with
+// the error as its message. It returns nil for a nil error.
+func StatusFromError(err error) *Status {
+	if err == nil {
+		return nil
+	}
+	var st *Status
+	if errors.As(err, &st) {
+		return st
+	}
+	code := CodeUnknown
+	if errors.Is(err, context.Canceled) {
+		code = CodeCanceled
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		code = CodeDeadlineExceeded
+	}
+	return &Status{
+		Code:    code,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+// CodeOf returns the code of the status carried by err, or CodeOK for a nil
+// error.
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+	return StatusFromError(err).Code
+}
+
+// unavailable wraps a local connection failure as a CodeUnavailable status, so
+// that callers can distinguish it from a failure reported by the server.
+func unavailable(err error) error {
+	return &Status{
+		Code:    CodeUnavailable,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+func BitSizeStatus(st *Status) int {
+	size := serialize.BitSizeUInt32(uint32(st.Code)) +
+		serialize.BitSizeString(st.Message) +
+		serialize.BitSizeUInt32(uint32(len(st.Details)))
+	for _, detail := range st.Details {
+		size += serialize.BitSizeString(detail.Type)
+		size += serialize.BitSizeBytes(detail.Data)
+	}
+	return size
+}
+
+func SerializeStatus(writer *serialize.Writer, st *Status) {
+	serialize.SerializeUInt32(writer, uint32(st.Code))
+	serialize.SerializeString(writer, st.Message)
+	serialize.SerializeUInt32(writer, uint32(len(st.Details)))
+	for _, detail := range st.Details {
+		serialize.SerializeString(writer, detail.Type)
+		serialize.SerializeBytes(writer, detail.Data)
+	}
+}
+
+func DeserializeStatus(st *Status, reader *serialize.Reader) error {
+	var code uint32
+	if err := serialize.DeserializeUInt32(&code, reader); err != nil {
+		return err
+	}
+	st.Code = Code(code)
+	if err := serialize.DeserializeString(&st.Message, reader); err != nil {
+		return err
+	}
+	var count uint32
+	if err := serialize.DeserializeUInt32(&count, reader); err != nil {
+		return err
+	}
+	st.Details = nil
+	if count > 0 {
+		if err := serialize.CheckLength(reader, count); err != nil {
+			return err
+		}
+		if err := serialize.CheckElements[StatusDetail](reader, count); err != nil {
+			return err
+		}
+		st.Details = make([]StatusDetail, count)
+		for i := range st.Details {
+			if err := serialize.DeserializeString(&st.Details[i].Type, reader); err != nil {
+				return err
+			}
+			if err := serialize.DeserializeBytes(&st.Details[i].Data, reader); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kbirk/scg/pkg/serialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stringDetail is a minimal DetailMessage wrapping a string.
+type stringDetail struct {
+	emptyStreamMessage
+	value string
+}
+
+func (d *stringDetail) MessageName() string { return "test.StringDetail" }
+
+func (d *stringDetail) ToBytes() []byte {
+	writer := serialize.NewWriter(serialize.BitsToBytes(serialize.BitSizeString(d.value)))
+	serialize.SerializeString(writer, d.value)
+	return writer.Bytes()
+}
+
+func (d *stringDetail) FromBytes(bs []byte) error {
+	return serialize.DeserializeString(&d.value, serialize.NewReader(bs))
+}
+
+func TestStatusSerialization(t *testing.T) {
+	st := NewStatus(CodePermissionDenied, "not yours").WithDetails(&stringDetail{value: "owner"})
+
+	writer := serialize.NewWriter(serialize.BitsToBytes(BitSizeStatus(st)))
+	SerializeStatus(writer, st)
+
+	actual := &Status{}
+	require.NoError(t, DeserializeStatus(actual, serialize.NewReader(writer.Bytes())))
+	assert.Equal(t, st, actual)
+
+	detail := &stringDetail{}
+	assert.True(t, actual.Detail(detail))
+	assert.Equal(t, "owner", detail.value)
+}
+
+// TestStatusEncodingMatchesCpp pins the encoding of a status to the one
+// produced by scg::rpc::Status in C++ (test_status_matches_go).
+func TestStatusEncodingMatchesCpp(t *testing.T) {
+	st := &Status{
+		Code:    CodeNotFound,
+		Message: "missing",
+		Details: []StatusDetail{{Type: "test.Detail", Data: []byte{1, 2, 3}}},
+	}
+
+	writer := serialize.NewWriter(serialize.BitsToBytes(BitSizeStatus(st)))
+	SerializeStatus(writer, st)
+	assert.Equal(t, "0b3cd096363797e67636c005746573742e44657461696c0704080c00", hex.EncodeToString(writer.Bytes()))
+}
+
+func TestDeserializeStatusRejectsOversizedDetails(t *testing.T) {
+	writer := serialize.NewWriter(32)
+	serialize.SerializeUInt32(writer, uint32(CodeInternal))
+	serialize.SerializeString(writer, "")
+	serialize.SerializeUInt32(writer, 1<<30) // detail count (hostile)
+
+	err := DeserializeStatus(&Status{}, serialize.NewReader(writer.Bytes()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remaining")
+}
+
+func TestStatusFromError(t *testing.T) {
+	assert.Nil(t, StatusFromError(nil))
+	assert.Equal(t, CodeOK, CodeOf(nil))
+
+	wrapped := fmt.Errorf("lookup failed: %w", Errorf(CodeNotFound, "user %d not found", 7))
+	var st *Status
+	require.True(t, errors.As(wrapped, &st))
+	assert.Equal(t, CodeNotFound, st.Code)
+	assert.Equal(t, "user 7 not found", st.Error())
+	assert.Equal(t, CodeNotFound, CodeOf(wrapped))
+
+	assert.Equal(t, CodeUnknown, CodeOf(errors.New("boom")))
+	assert.Equal(t, "boom", StatusFromError(errors.New("boom")).Message)
+	assert.Equal(t, CodeCanceled, CodeOf(context.Canceled))
+	assert.Equal(t, CodeDeadlineExceeded, CodeOf(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+
+	cause := errors.New("connection refused")
+	err := unavailable(cause)
+	assert.Equal(t, CodeUnavailable, CodeOf(err))
+	assert.ErrorIs(t, err, cause)
+
+	assert.Equal(t, "Unavailable", NewStatus(CodeUnavailable, "").Error())
+	assert.Equal(t, "Code(99)", Code(99).String())
+}
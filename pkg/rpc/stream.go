@@ -21,7 +21,7 @@ const defaultStreamRecvBufferSize = 1024
 var ErrStreamClosed = errors.New("stream closed")
 
 // errStreamOverflow terminates a stream whose bounded receive buffer overflowed.
-var errStreamOverflow = errors.New("stream receive buffer overflow")
+var errStreamOverflow = NewStatus(CodeResourceExhausted, "stream receive buffer overflow")
 
 // streamRecvBufferSizeOrDefault normalizes a configured buffer size.
 func streamRecvBufferSizeOrDefault(size int) int {
@@ -105,11 +105,13 @@ func (c *connStreams) terminateAll(err error) {
 // ----------------------------------------------------------------------------
 
 func serializeStreamOpen(ctx context.Context, streamID uint64, serviceID uint64, methodID uint64) []byte {
+	timeout := TimeoutFromContext(ctx)
 	size := serialize.BitsToBytes(
 		BitSizePrefix() +
 			serialize.BitSizeUInt64(streamID) +
 			serialize.BitSizeUInt8(StreamFrameOpen) +
 			BitSizeContext(ctx) +
+			BitSizeTimeout(timeout) +
 			serialize.BitSizeUInt64(serviceID) +
 			serialize.BitSizeUInt64(methodID))
 
@@ -118,6 +120,7 @@ func serializeStreamOpen(ctx context.Context, streamID uint64, serviceID uint64,
 	serialize.SerializeUInt64(writer, streamID)
 	serialize.SerializeUInt8(writer, StreamFrameOpen)
 	SerializeContext(writer, ctx)
+	SerializeTimeout(writer, timeout)
 	serialize.SerializeUInt64(writer, serviceID)
 	serialize.SerializeUInt64(writer, methodID)
 	return writer.Bytes()
@@ -157,8 +160,8 @@ func serializeStreamHalfClose(streamID uint64) []byte {
 	return writer.Bytes()
 }
 
-// serializeStreamControl builds a connection-level keepalive frame (PING/PONG).
-// The stream id is unused (0).
+// serializeStreamControl builds a connection-level control frame (PING, PONG or
+// GOAWAY). The stream id is unused (0).
 func serializeStreamControl(frameKind uint8) []byte {
 	size := serialize.BitsToBytes(
 		BitSizePrefix() +
@@ -172,20 +175,24 @@ func serializeStreamControl(frameKind uint8) []byte {
 	return writer.Bytes()
 }
 
-func serializeStreamClose(streamID uint64, status uint8, message string) []byte {
+// serializeStreamClose builds the terminal CLOSE frame of a stream. A nil status
+// closes the stream cleanly (CodeOK).
+func serializeStreamClose(streamID uint64, st *Status) []byte {
+	if st == nil {
+		st = &Status{Code: CodeOK}
+	}
+
 	size := serialize.BitsToBytes(
 		BitSizePrefix() +
 			serialize.BitSizeUInt64(streamID) +
 			serialize.BitSizeUInt8(StreamFrameClose) +
-			serialize.BitSizeUInt8(status) +
-			serialize.BitSizeString(message))
+			BitSizeStatus(st))
 
 	writer := serialize.NewWriter(size)
 	SerializePrefix(writer, StreamPrefix)
 	serialize.SerializeUInt64(writer, streamID)
 	serialize.SerializeUInt8(writer, StreamFrameClose)
-	serialize.SerializeUInt8(writer, status)
-	serialize.SerializeString(writer, message)
+	SerializeStatus(writer, st)
 	return writer.Bytes()
 }
 
@@ -195,6 +202,8 @@ func serializeStreamClose(streamID uint64, status uint8, message string) []byte
 
 type ClientStream struct {
 	client    *Client
+	conn      Connection // the connection the stream was opened on
+	gen       uint64     // generation of conn
 	streamID  uint64
 	serviceID uint64
 	ctx       context.Context
@@ -209,9 +218,11 @@ type ClientStream struct {
 	sendClosed bool // local CloseSend has been issued
 }
 
-func newClientStream(client *Client, ctx context.Context, streamID uint64, serviceID uint64, bufferSize int) *ClientStream {
+func newClientStream(client *Client, conn Connection, gen uint64, ctx context.Context, streamID uint64, serviceID uint64, bufferSize int) *ClientStream {
 	return &ClientStream{
 		client:    client,
+		conn:      conn,
+		gen:       gen,
 		streamID:  streamID,
 		serviceID: serviceID,
 		ctx:       ctx,
@@ -240,11 +251,7 @@ func (s *ClientStream) Send(msg Message) error {
 	}
 	s.mu.Unlock()
 
-	conn := s.client.connection()
-	if conn == nil {
-		return ErrStreamClosed
-	}
-	return sendStreamMessage(conn, s.serviceID, s.streamID, msg)
+	return sendStreamMessage(s.conn, s.serviceID, s.streamID, msg)
 }
 
 // Recv blocks until the next message arrives, the stream is cleanly closed
@@ -281,7 +288,7 @@ func (s *ClientStream) CloseSend() error {
 	s.sendClosed = true
 	s.mu.Unlock()
 
-	return s.client.sendStreamFrame(serializeStreamHalfClose(s.streamID), s.serviceID)
+	return s.conn.Send(serializeStreamHalfClose(s.streamID), s.serviceID)
 }
 
 // deliver enqueues an inbound message; called by the client demux on the I/O
@@ -340,7 +347,7 @@ func (s *ClientStream) cancel(err error) {
 	s.die(err)
 	s.client.removeStream(s.streamID)
 	if !already {
-		_ = s.client.sendStreamFrame(serializeStreamClose(s.streamID, StreamStatusError, "stream cancelled by client"), s.serviceID)
+		_ = s.conn.Send(serializeStreamClose(s.streamID, NewStatus(CodeCanceled, "stream cancelled by client")), s.serviceID)
 	}
 }
 
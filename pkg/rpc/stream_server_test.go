@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/kbirk/scg/pkg/serialize"
 	"github.com/stretchr/testify/require"
@@ -37,13 +38,13 @@ func (c *recordingConn) Close() error {
 	return nil
 }
 
-// closeMessages deserializes every recorded CLOSE frame and returns their status
-// messages. Frames are bit-packed, so the payload must be parsed rather than
+// closeStatuses deserializes every recorded CLOSE frame and returns their
+// statuses. Frames are bit-packed, so the payload must be parsed rather than
 // byte-searched.
-func (c *recordingConn) closeMessages() []string {
+func (c *recordingConn) closeStatuses() []*Status {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	var msgs []string
+	var statuses []*Status
 	for _, f := range c.sent {
 		r := serialize.NewReader(f)
 		var prefix [16]byte
@@ -58,22 +59,18 @@ func (c *recordingConn) closeMessages() []string {
 		if serialize.DeserializeUInt8(&kind, r) != nil || kind != StreamFrameClose {
 			continue
 		}
-		var status uint8
-		if serialize.DeserializeUInt8(&status, r) != nil {
+		st := &Status{}
+		if DeserializeStatus(st, r) != nil {
 			continue
 		}
-		var msg string
-		if serialize.DeserializeString(&msg, r) != nil {
-			continue
-		}
-		msgs = append(msgs, msg)
+		statuses = append(statuses, st)
 	}
-	return msgs
+	return statuses
 }
 
-func (c *recordingConn) sentCloseWith(needle string) bool {
-	for _, m := range c.closeMessages() {
-		if strings.Contains(m, needle) {
+func (c *recordingConn) sentCloseWith(code Code, needle string) bool {
+	for _, st := range c.closeStatuses() {
+		if st.Code == code && strings.Contains(st.Message, needle) {
 			return true
 		}
 	}
@@ -99,7 +96,12 @@ func (s *blockingStreamService) HandleStreamWrapper(ctx context.Context, stream
 // the prefix, matching how handleConnection hands frames to handleStreamFrame.
 func openFrameReader(t *testing.T, streamID, serviceID, methodID uint64) *serialize.Reader {
 	t.Helper()
-	bs := serializeStreamOpen(context.Background(), streamID, serviceID, methodID)
+	return openFrameReaderWithContext(t, context.Background(), streamID, serviceID, methodID)
+}
+
+func openFrameReaderWithContext(t *testing.T, ctx context.Context, streamID, serviceID, methodID uint64) *serialize.Reader {
+	t.Helper()
+	bs := serializeStreamOpen(ctx, streamID, serviceID, methodID)
 	r := serialize.NewReader(bs)
 	var prefix [16]byte
 	require.NoError(t, DeserializePrefix(&prefix, r))
@@ -129,10 +131,43 @@ func TestServerRejectsDuplicateStreamID(t *testing.T) {
 	// Second OPEN reusing id 1 must be rejected and must not displace the first.
 	server.handleStreamFrame(conn, cs, openFrameReader(t, 1, serviceID, methodID))
 	require.NotNil(t, cs.get(1), "duplicate OPEN must not orphan the existing stream")
-	require.True(t, conn.sentCloseWith("duplicate stream id"),
+	require.True(t, conn.sentCloseWith(CodeAlreadyExists, "duplicate stream id"),
 		"server should reject a duplicate stream id with a CLOSE(error)")
 }
 
+// TestServerStreamContextCarriesDeadline verifies the deadline of the OPEN
+// context bounds the context of the stream on the server.
+func TestServerStreamContextCarriesDeadline(t *testing.T) {
+	const serviceID, methodID = uint64(42), uint64(7)
+
+	svc := &blockingStreamService{block: make(chan struct{})}
+	defer close(svc.block)
+
+	server := NewServer(ServerConfig{})
+	server.RegisterServer(serviceID, "fake", svc)
+
+	conn := &recordingConn{}
+	cs := newConnStreams()
+	defer cs.terminateAll(errors.New("test done"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server.handleStreamFrame(conn, cs, openFrameReaderWithContext(t, ctx, 1, serviceID, methodID))
+	stream := cs.get(1)
+	require.NotNil(t, stream)
+
+	deadline, ok := stream.Context().Deadline()
+	require.True(t, ok, "stream context should carry the deadline of the caller")
+	require.WithinDuration(t, time.Now().Add(5*time.Second), deadline, time.Second)
+
+	// Without a deadline on the OPEN context, the stream has none.
+	server.handleStreamFrame(conn, cs, openFrameReader(t, 2, serviceID, methodID))
+	require.NotNil(t, cs.get(2))
+	_, ok = cs.get(2).Context().Deadline()
+	require.False(t, ok)
+}
+
 // TestServerEnforcesMaxConcurrentStreams verifies the per-connection cap rejects
 // an OPEN beyond the limit with a CLOSE(error).
 func TestServerEnforcesMaxConcurrentStreams(t *testing.T) {
@@ -154,6 +189,6 @@ func TestServerEnforcesMaxConcurrentStreams(t *testing.T) {
 	// Second distinct stream exceeds the cap of 1.
 	server.handleStreamFrame(conn, cs, openFrameReader(t, 2, serviceID, methodID))
 	require.Nil(t, cs.get(2), "stream beyond the cap must not be registered")
-	require.True(t, conn.sentCloseWith("max concurrent streams exceeded"),
+	require.True(t, conn.sentCloseWith(CodeResourceExhausted, "max concurrent streams exceeded"),
 		"server should reject an over-cap stream with a CLOSE(error)")
 }
@@ -46,3 +46,27 @@ func TestDeserializeStringRejectsOversizedLength(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "remaining")
 }
+
+func TestDeserializeBytesRejectsOversizedLength(t *testing.T) {
+	w := NewWriter(8)
+	SerializeUInt32(w, 1<<30)
+	w.WriteBytes([]byte("abc"))
+	r := NewReader(w.Bytes())
+
+	var b []byte
+	err := DeserializeBytes(&b, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remaining")
+}
+
+func TestReaderDepthLimit(t *testing.T) {
+	reader := NewReader(nil)
+
+	for i := 0; i < MaxDepth; i++ {
+		require.NoError(t, reader.Descend())
+	}
+	require.Error(t, reader.Descend())
+
+	reader.Ascend()
+	require.NoError(t, reader.Descend())
+}
@@ -0,0 +1,45 @@
+package serialize
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JSONDuration is the JSON representation of a duration field. It marshals as
+// a Go duration string such as "1.5s" rather than a number of nanoseconds.
+// Generated code converts duration fields to and from this type inside the
+// message JSON methods, the fields themselves remain time.Duration.
+type JSONDuration time.Duration
+
+func (d JSONDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON accepts a duration string, or a number of nanoseconds so that
+// data written while the field was an integer still decodes.
+func (d *JSONDuration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		err := json.Unmarshal(data, &str)
+		if err != nil {
+			return err
+		}
+		parsed, err := time.ParseDuration(str)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", str, err)
+		}
+		*d = JSONDuration(parsed)
+		return nil
+	}
+	var nanoseconds int64
+	err := json.Unmarshal(data, &nanoseconds)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: %w", string(data), err)
+	}
+	*d = JSONDuration(nanoseconds)
+	return nil
+}
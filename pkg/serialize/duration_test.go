@@ -0,0 +1,42 @@
+package serialize
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONDuration(t *testing.T) {
+
+	inputs := map[time.Duration]string{
+		0:                              `"0s"`,
+		1500 * time.Millisecond:        `"1.5s"`,
+		-250 * time.Microsecond:        `"-250µs"`,
+		90*time.Minute + 3*time.Second: `"1h30m3s"`,
+	}
+
+	for input, expected := range inputs {
+		bs, err := json.Marshal(JSONDuration(input))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(bs))
+
+		var output JSONDuration
+		err = json.Unmarshal(bs, &output)
+		require.NoError(t, err)
+		assert.Equal(t, input, time.Duration(output))
+	}
+
+	// integer nanoseconds are accepted
+	var output JSONDuration
+	err := json.Unmarshal([]byte(`1500000000`), &output)
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, time.Duration(output))
+
+	err = json.Unmarshal([]byte(`"1.5 seconds"`), &output)
+	assert.Error(t, err)
+	err = json.Unmarshal([]byte(`true`), &output)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package serialize
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Limits bounds the work and memory that decoding a single frame can cause.
+// CheckLength only bounds each declared length against the bytes remaining, so
+// without limits a frame of nested lists of maps of lists may still declare
+// far more than it is worth decoding. The limits are carried by the Reader and
+// enforced by the generated Deserialize and DeserializeDelta methods.
+type Limits struct {
+	// MaxDepth bounds how deeply recursive messages may nest. Zero uses the
+	// default of MaxDepth, as unbounded nesting could exhaust the stack.
+	MaxDepth uint32
+	// MaxElements bounds the number of elements of each list, map and set
+	// (0 = unlimited).
+	MaxElements uint32
+	// MaxStringLength bounds the number of bytes of each string and bytes value
+	// (0 = unlimited).
+	MaxStringLength uint32
+	// MaxAllocation bounds the total number of bytes the collections, strings
+	// and bytes of a frame may declare, sized as their decoded Go values
+	// (0 = unlimited). Declared sizes are charged before anything is allocated.
+	MaxAllocation int
+}
+
+// SetLimits sets the limits enforced while deserializing from the reader, and
+// resets the allocation budget.
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits
+	r.allocated = 0
+}
+
+// Limits returns the limits enforced while deserializing from the reader.
+func (r *Reader) Limits() Limits {
+	return r.limits
+}
+
+func (r *Reader) maxDepth() uint32 {
+	if r.limits.MaxDepth == 0 {
+		return MaxDepth
+	}
+	return r.limits.MaxDepth
+}
+
+// allocate charges a declared allocation to the budget of the reader.
+func (r *Reader) allocate(numBytes uint64) error {
+	if r.limits.MaxAllocation <= 0 {
+		return nil
+	}
+	r.allocated += numBytes
+	if r.allocated > uint64(r.limits.MaxAllocation) {
+		return fmt.Errorf("Reader exceeded the allocation budget of %d bytes", r.limits.MaxAllocation)
+	}
+	return nil
+}
+
+// CheckStringLength validates the declared length of a string or bytes value
+// against the limits of the reader and charges it to the allocation budget.
+func CheckStringLength(reader *Reader, length uint32) error {
+	if reader.limits.MaxStringLength > 0 && length > reader.limits.MaxStringLength {
+		return fmt.Errorf("declared length %d exceeds the limit of %d bytes", length, reader.limits.MaxStringLength)
+	}
+	return reader.allocate(uint64(length))
+}
+
+// CheckElements validates the declared length of a list or set of T against
+// the limits of the reader and charges its elements to the allocation budget.
+// It is called by the generated code before the collection is allocated.
+func CheckElements[T any](reader *Reader, count uint32) error {
+	if reader.limits.MaxElements > 0 && count > reader.limits.MaxElements {
+		return fmt.Errorf("declared length %d exceeds the limit of %d elements", count, reader.limits.MaxElements)
+	}
+	var v T
+	return reader.allocate(uint64(count) * uint64(unsafe.Sizeof(v)))
+}
+
+// CheckEntries validates the declared length of a map of K to V as
+// CheckElements does, charging both the key and the value of each entry.
+func CheckEntries[K comparable, V any](reader *Reader, count uint32) error {
+	if reader.limits.MaxElements > 0 && count > reader.limits.MaxElements {
+		return fmt.Errorf("declared length %d exceeds the limit of %d elements", count, reader.limits.MaxElements)
+	}
+	var k K
+	var v V
+	return reader.allocate(uint64(count) * uint64(unsafe.Sizeof(k)+unsafe.Sizeof(v)))
+}
@@ -0,0 +1,88 @@
+package serialize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderLimitsDepth(t *testing.T) {
+	reader := NewReader(nil)
+	reader.SetLimits(Limits{MaxDepth: 2})
+
+	require.NoError(t, reader.Descend())
+	require.NoError(t, reader.Descend())
+	err := reader.Descend()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depth of 2")
+
+	// a zero depth uses the default
+	reader = NewReader(nil)
+	reader.SetLimits(Limits{MaxElements: 1})
+	for i := 0; i < MaxDepth; i++ {
+		require.NoError(t, reader.Descend())
+	}
+	require.Error(t, reader.Descend())
+}
+
+func TestReaderLimitsElements(t *testing.T) {
+	reader := NewReader(nil)
+	require.NoError(t, CheckElements[uint64](reader, 1<<30))
+
+	reader.SetLimits(Limits{MaxElements: 4})
+	require.NoError(t, CheckElements[uint64](reader, 4))
+	err := CheckElements[uint64](reader, 5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 4 elements")
+
+	err = CheckEntries[string, uint64](reader, 5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 4 elements")
+}
+
+func TestReaderLimitsString(t *testing.T) {
+	w := NewWriter(32)
+	SerializeString(w, "abcdefgh")
+	SerializeBytes(w, []byte("abcdefgh"))
+
+	reader := NewReader(w.Bytes())
+	reader.SetLimits(Limits{MaxStringLength: 7})
+	var s string
+	err := DeserializeString(&s, reader)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 7 bytes")
+
+	reader = NewReader(w.Bytes())
+	reader.SetLimits(Limits{MaxStringLength: 8})
+	require.NoError(t, DeserializeString(&s, reader))
+	var b []byte
+	require.NoError(t, DeserializeBytes(&b, reader))
+}
+
+func TestReaderLimitsAllocation(t *testing.T) {
+	w := NewWriter(32)
+	SerializeString(w, "abcdefgh")
+	SerializeBytes(w, []byte("abcdefgh"))
+
+	// the budget is shared by every value of the frame
+	reader := NewReader(w.Bytes())
+	reader.SetLimits(Limits{MaxAllocation: 12})
+	var s string
+	require.NoError(t, DeserializeString(&s, reader))
+	var b []byte
+	err := DeserializeBytes(&b, reader)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allocation budget of 12 bytes")
+
+	// collections are charged the size of their decoded elements
+	reader = NewReader(nil)
+	reader.SetLimits(Limits{MaxAllocation: 64})
+	require.NoError(t, CheckElements[uint64](reader, 4))
+	require.NoError(t, CheckEntries[uint32, uint32](reader, 4))
+	require.Error(t, CheckElements[uint8](reader, 1))
+
+	// and setting the limits resets the budget
+	reader.SetLimits(Limits{MaxAllocation: 64})
+	require.NoError(t, CheckElements[uint8](reader, 64))
+}
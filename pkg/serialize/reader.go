@@ -5,9 +5,16 @@ import (
 	"fmt"
 )
 
+// MaxDepth bounds how deeply recursive messages may nest when deserializing,
+// unless the reader is given other Limits.
+const MaxDepth = 64
+
 type Reader struct {
 	bytes       []byte
 	numBitsRead uint32
+	depth       uint32
+	limits      Limits
+	allocated   uint64
 }
 
 func NewReader(data []byte) *Reader {
@@ -28,6 +35,33 @@ func (r *Reader) RemainingBytes() int {
 	return len(r.bytes) - consumed
 }
 
+// Descend records that deserialization entered a recursive message. It fails
+// once the nesting exceeds the maximum depth of the reader's limits so a hostile
+// payload cannot exhaust the stack. Every successful call must be paired with a
+// call to Ascend.
+func (r *Reader) Descend() error {
+	if maxDepth := r.maxDepth(); r.depth >= maxDepth {
+		return fmt.Errorf("Reader exceeded the maximum nesting depth of %d", maxDepth)
+	}
+	r.depth++
+	return nil
+}
+
+// Ascend records that deserialization left a recursive message.
+func (r *Reader) Ascend() {
+	r.depth--
+}
+
+// skipBits advances the reader without copying out the skipped bits.
+func (r *Reader) skipBits(numBits uint32) error {
+	neededBytes := (uint64(r.numBitsRead) + uint64(numBits) + 7) / 8
+	if neededBytes > uint64(len(r.bytes)) {
+		return errInsufficientData(len(r.bytes), int(neededBytes))
+	}
+	r.numBitsRead += numBits
+	return nil
+}
+
 func errInsufficientData(available, needed int) error {
 	return fmt.Errorf("Reader does not contain enough data to fill the argument, num bytes available: %d, num bytes needed: %d", available, needed)
 }
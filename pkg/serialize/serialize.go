@@ -2,6 +2,7 @@ package serialize
 
 import (
 	"fmt"
+	"math"
 	"time"
 	"unsafe"
 
@@ -53,6 +54,25 @@ func DeserializeTime(data *time.Time, reader *Reader) error {
 	return nil
 }
 
+// durations are written as a zigzag encoded number of nanoseconds
+func BitSizeDuration(data time.Duration) int {
+	return BitSizeInt64(int64(data))
+}
+
+func SerializeDuration(writer *Writer, data time.Duration) {
+	SerializeInt64(writer, int64(data))
+}
+
+func DeserializeDuration(data *time.Duration, reader *Reader) error {
+	var nanoseconds int64
+	err := DeserializeInt64(&nanoseconds, reader)
+	if err != nil {
+		return err
+	}
+	*data = time.Duration(nanoseconds)
+	return nil
+}
+
 // CheckLength validates a wire-declared element/byte count against the bytes
 // remaining in the reader. It is the guard prescribed for length-prefixed
 // allocations: every element of the collections that use it occupies at least
@@ -95,6 +115,9 @@ func DeserializeString(data *string, reader *Reader) error {
 	if err := CheckLength(reader, length); err != nil {
 		return err
 	}
+	if err := CheckStringLength(reader, length); err != nil {
+		return err
+	}
 
 	// Fast path for byte-aligned reads
 	if reader.numBitsRead&7 == 0 {
@@ -118,6 +141,59 @@ func DeserializeString(data *string, reader *Reader) error {
 	return nil
 }
 
+func BitSizeBytes(data []byte) int {
+	ln := len(data)
+	return BitSizeUInt32(uint32(ln)) + BytesToBits(ln)
+}
+
+func SerializeBytes(writer *Writer, data []byte) {
+	SerializeUInt32(writer, uint32(len(data)))
+	writer.WriteBytes(data)
+}
+
+// DeserializeBytes reads a length prefixed byte slice. When the reader is byte
+// aligned the result is a subslice of the input buffer rather than a copy, so
+// the input buffer must not be modified while the result is in use.
+func DeserializeBytes(data *[]byte, reader *Reader) error {
+	var length uint32
+	if err := DeserializeUInt32(&length, reader); err != nil {
+		return err
+	}
+
+	if length == 0 {
+		*data = []byte{}
+		return nil
+	}
+
+	if err := CheckLength(reader, length); err != nil {
+		return err
+	}
+	if err := CheckStringLength(reader, length); err != nil {
+		return err
+	}
+
+	// Fast path for byte-aligned reads
+	if reader.numBitsRead&7 == 0 {
+		byteIndex := reader.numBitsRead >> 3
+		end := byteIndex + length
+		if int(end) > len(reader.bytes) {
+			return fmt.Errorf("Reader does not contain enough data to fill the argument")
+		}
+		// cap the capacity so appending to the result cannot overwrite the
+		// bytes that follow it in the input buffer
+		*data = reader.bytes[byteIndex:end:end]
+		reader.numBitsRead += length * 8
+		return nil
+	}
+
+	buf := make([]byte, length)
+	if err := reader.ReadBytes(buf); err != nil {
+		return err
+	}
+	*data = buf
+	return nil
+}
+
 func BitSizeBool(bool) int {
 	return 1
 }
@@ -270,6 +346,79 @@ func DeserializeInt64(data *int64, reader *Reader) error {
 	return varDecodeInt(reader, data, 8)
 }
 
+// uint<N> and int<N> are written with exactly N bits, least significant bits
+// first, signed values in two's complement. Encoding a value that does not fit
+// in N bits is a programming error and panics, as serialization cannot fail.
+
+func BitSizeUIntN[T ~uint8 | ~uint16 | ~uint32 | ~uint64](data T, numBits uint32) int {
+	return int(numBits)
+}
+
+func SerializeUIntN[T ~uint8 | ~uint16 | ~uint32 | ~uint64](writer *Writer, data T, numBits uint32) {
+	if numBits < 64 && uint64(data)>>numBits != 0 {
+		panic(fmt.Sprintf("value %d is out of range for uint<%d>", uint64(data), numBits))
+	}
+	writeBitsN(writer, uint64(data), numBits)
+}
+
+func DeserializeUIntN[T ~uint8 | ~uint16 | ~uint32 | ~uint64](data *T, reader *Reader, numBits uint32) error {
+	var val uint64
+	err := readBitsN(reader, &val, numBits)
+	if err != nil {
+		return err
+	}
+	*data = T(val)
+	return nil
+}
+
+func BitSizeIntN[T ~int8 | ~int16 | ~int32 | ~int64](data T, numBits uint32) int {
+	return int(numBits)
+}
+
+func SerializeIntN[T ~int8 | ~int16 | ~int32 | ~int64](writer *Writer, data T, numBits uint32) {
+	if numBits < 64 {
+		limit := int64(1) << (numBits - 1)
+		if int64(data) < -limit || int64(data) >= limit {
+			panic(fmt.Sprintf("value %d is out of range for int<%d>", int64(data), numBits))
+		}
+	}
+	writeBitsN(writer, uint64(data), numBits)
+}
+
+func DeserializeIntN[T ~int8 | ~int16 | ~int32 | ~int64](data *T, reader *Reader, numBits uint32) error {
+	var val uint64
+	err := readBitsN(reader, &val, numBits)
+	if err != nil {
+		return err
+	}
+	// sign extend from bit N-1
+	shift := 64 - numBits
+	*data = T(int64(val<<shift) >> shift)
+	return nil
+}
+
+func writeBitsN(writer *Writer, val uint64, numBits uint32) {
+	for numBits > 0 {
+		n := min(numBits, 8)
+		writer.WriteBits(uint8(val), n)
+		val >>= 8
+		numBits -= n
+	}
+}
+
+func readBitsN(reader *Reader, val *uint64, numBits uint32) error {
+	*val = 0
+	for shift := uint32(0); shift < numBits; shift += 8 {
+		var b byte
+		err := reader.ReadBits(&b, min(numBits-shift, 8))
+		if err != nil {
+			return err
+		}
+		*val |= uint64(b) << shift
+	}
+	return nil
+}
+
 func BitSizeFloat32(data float32) int {
 	return BytesToBits(4)
 }
@@ -305,3 +454,46 @@ func DeserializeFloat64(data *float64, reader *Reader) error {
 	*data = UnpackFloat64(b)
 	return nil
 }
+
+// quant<T, min, max, N> fields are written as an N bit unsigned integer, the
+// value scaled over [min, max] and rounded to the nearest of the 2^N - 1 steps.
+// Values outside of the range are clamped, and NaN is written as min. The
+// arithmetic is done in float64 with each step rounded separately, so that
+// every implementation decodes the same bits to the same value.
+
+// QuantPrecision returns the distance between two adjacent values of a quant.
+func QuantPrecision(minVal float64, maxVal float64, numBits uint32) float64 {
+	return (maxVal - minVal) / float64(quantSteps(numBits))
+}
+
+func BitSizeQuant[T ~float32 | ~float64](data T, minVal float64, maxVal float64, numBits uint32) int {
+	return int(numBits)
+}
+
+func SerializeQuant[T ~float32 | ~float64](writer *Writer, data T, minVal float64, maxVal float64, numBits uint32) {
+	val := float64(data)
+	if !(val > minVal) {
+		val = minVal
+	} else if val > maxVal {
+		val = maxVal
+	}
+	steps := quantSteps(numBits)
+	normalized := (val - minVal) / (maxVal - minVal)
+	writeBitsN(writer, uint64(math.Round(normalized*float64(steps))), numBits)
+}
+
+func DeserializeQuant[T ~float32 | ~float64](data *T, reader *Reader, minVal float64, maxVal float64, numBits uint32) error {
+	var val uint64
+	err := readBitsN(reader, &val, numBits)
+	if err != nil {
+		return err
+	}
+	steps := quantSteps(numBits)
+	scaled := float64(val) * (maxVal - minVal)
+	*data = T(minVal + scaled/float64(steps))
+	return nil
+}
+
+func quantSteps(numBits uint32) uint64 {
+	return (uint64(1) << numBits) - 1
+}
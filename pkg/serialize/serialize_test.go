@@ -70,6 +70,38 @@ func TestSerializeTime(t *testing.T) {
 	assert.True(t, input.Equal(output))
 }
 
+func TestSerializeDuration(t *testing.T) {
+
+	inputs := []time.Duration{
+		0,
+		time.Nanosecond,
+		-time.Nanosecond,
+		1500 * time.Millisecond,
+		-90 * time.Minute,
+		math.MaxInt64,
+		math.MinInt64,
+	}
+
+	for _, input := range inputs {
+		size := BitSizeDuration(input)
+
+		writer := NewWriter(BitsToBytes(size))
+		SerializeDuration(writer, input)
+
+		bs := writer.Bytes()
+		reader := NewReader(bs)
+
+		var output time.Duration
+		err := DeserializeDuration(&output, reader)
+		require.NoError(t, err)
+
+		assert.Equal(t, input, output)
+	}
+
+	// small durations of either sign stay small on the wire
+	assert.Equal(t, BitSizeInt64(1), BitSizeDuration(-time.Nanosecond))
+}
+
 func TestSerializeString(t *testing.T) {
 
 	input := "Hello, World! This is my test string 12312341234! \\@#$%@&^&%^\n newline \t _yay 世界"
@@ -89,6 +121,46 @@ func TestSerializeString(t *testing.T) {
 	assert.Equal(t, input, output)
 }
 
+func TestSerializeBytes(t *testing.T) {
+
+	input := []byte{0x00, 0x01, 0xfe, 0xff, 0x7f}
+
+	size := BitSizeBytes(input)
+
+	writer := NewWriter(BitsToBytes(size))
+	SerializeBytes(writer, input)
+
+	bs := writer.Bytes()
+	reader := NewReader(bs)
+
+	var output []byte
+	err := DeserializeBytes(&output, reader)
+	require.NoError(t, err)
+
+	assert.Equal(t, input, output)
+
+	// 6 bits of padding before the 10 bit length prefix leaves the bytes
+	// aligned, in which case they alias the input buffer
+	writer = NewWriter(BitsToBytes(size + 6))
+	writer.WriteBits(0, 6)
+	SerializeBytes(writer, input)
+
+	bs = writer.Bytes()
+	reader = NewReader(bs)
+
+	var padding byte
+	err = reader.ReadBits(&padding, 6)
+	require.NoError(t, err)
+	err = DeserializeBytes(&output, reader)
+	require.NoError(t, err)
+
+	assert.Equal(t, input, output)
+	assert.Equal(t, len(output), cap(output))
+
+	bs[len(bs)-len(input)] = 0xaa
+	assert.Equal(t, byte(0xaa), output[0])
+}
+
 func TestSerializeBool(t *testing.T) {
 
 	input := true
@@ -298,6 +370,122 @@ func TestSerializeInt64(t *testing.T) {
 	}
 }
 
+func TestSerializeUIntN(t *testing.T) {
+
+	writer := NewWriter(0)
+	SerializeUIntN(writer, uint8(5), 3)
+	SerializeUIntN(writer, uint8(1), 1)
+	SerializeUIntN(writer, uint16(4095), 12)
+	SerializeUIntN(writer, uint64(math.MaxUint64), 64)
+	SerializeUIntN(writer, uint32(0), 17)
+
+	size := BitSizeUIntN(uint8(5), 3) + BitSizeUIntN(uint8(1), 1) + BitSizeUIntN(uint16(4095), 12) +
+		BitSizeUIntN(uint64(math.MaxUint64), 64) + BitSizeUIntN(uint32(0), 17)
+	assert.Equal(t, 97, size)
+	assert.Equal(t, BitsToBytes(size), len(writer.Bytes()))
+
+	reader := NewReader(writer.Bytes())
+	var a, b uint8
+	var c uint16
+	var d uint64
+	var e uint32
+	require.NoError(t, DeserializeUIntN(&a, reader, 3))
+	require.NoError(t, DeserializeUIntN(&b, reader, 1))
+	require.NoError(t, DeserializeUIntN(&c, reader, 12))
+	require.NoError(t, DeserializeUIntN(&d, reader, 64))
+	require.NoError(t, DeserializeUIntN(&e, reader, 17))
+	assert.Equal(t, uint8(5), a)
+	assert.Equal(t, uint8(1), b)
+	assert.Equal(t, uint16(4095), c)
+	assert.Equal(t, uint64(math.MaxUint64), d)
+	assert.Equal(t, uint32(0), e)
+
+	assert.Error(t, DeserializeUIntN(&e, reader, 17))
+
+	assert.Panics(t, func() {
+		SerializeUIntN(NewWriter(0), uint8(8), 3)
+	})
+}
+
+func TestSerializeIntN(t *testing.T) {
+
+	inputs := []struct {
+		value   int64
+		numBits uint32
+	}{
+		{0, 1},
+		{-1, 1},
+		{-2048, 12},
+		{2047, 12},
+		{-5, 7},
+		{math.MinInt64, 64},
+		{math.MaxInt64, 64},
+	}
+
+	writer := NewWriter(0)
+	for _, input := range inputs {
+		SerializeIntN(writer, input.value, input.numBits)
+	}
+
+	reader := NewReader(writer.Bytes())
+	for _, input := range inputs {
+		var output int64
+		require.NoError(t, DeserializeIntN(&output, reader, input.numBits))
+		assert.Equal(t, input.value, output)
+	}
+
+	assert.Panics(t, func() {
+		SerializeIntN(NewWriter(0), int16(2048), 12)
+	})
+	assert.Panics(t, func() {
+		SerializeIntN(NewWriter(0), int8(-2), 1)
+	})
+}
+
+func TestSerializeQuant(t *testing.T) {
+
+	inputs := []struct {
+		value    float64
+		expected float64
+	}{
+		{-100, -100},
+		{100, 100},
+		{0.5, 0.5},
+		{-33.3, -33.3},
+		{250, 100},
+		{-250, -100},
+		{math.Inf(1), 100},
+		{math.NaN(), -100},
+	}
+
+	precision := QuantPrecision(-100, 100, 12)
+	assert.InDelta(t, 200.0/4095.0, precision, 1e-12)
+
+	writer := NewWriter(0)
+	for _, input := range inputs {
+		SerializeQuant(writer, input.value, -100, 100, 12)
+		assert.Equal(t, 12, BitSizeQuant(input.value, -100, 100, 12))
+	}
+
+	reader := NewReader(writer.Bytes())
+	for _, input := range inputs {
+		var output float64
+		require.NoError(t, DeserializeQuant(&output, reader, -100, 100, 12))
+		assert.InDelta(t, input.expected, output, precision/2)
+	}
+
+	// the bounds are exact
+	writer = NewWriter(0)
+	SerializeQuant(writer, float32(-1), -1, 1, 1)
+	SerializeQuant(writer, float32(1), -1, 1, 1)
+	reader = NewReader(writer.Bytes())
+	var lo, hi float32
+	require.NoError(t, DeserializeQuant(&lo, reader, -1, 1, 1))
+	require.NoError(t, DeserializeQuant(&hi, reader, -1, 1, 1))
+	assert.Equal(t, float32(-1), lo)
+	assert.Equal(t, float32(1), hi)
+}
+
 func TestSerializeMultipleTypesInSequence(t *testing.T) {
 	// Create test data of different types
 	strValue := "Hello, World! 世界"
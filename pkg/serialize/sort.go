@@ -0,0 +1,45 @@
+package serialize
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// Keys are written in ascending order by a deterministic writer. Numbers and
+// enums are ordered by value, and strings and uuids by their bytes, matching the
+// ordering of std::map in the C++ encoding.
+
+// SortKeys sorts the keys in ascending order.
+func SortKeys[K cmp.Ordered](keys []K) {
+	slices.Sort(keys)
+}
+
+// SortUUIDKeys sorts the uuid keys in ascending order of their bytes.
+func SortUUIDKeys[K ~[16]byte](keys []K) {
+	slices.SortFunc(keys, func(a, b K) int {
+		return bytes.Compare(a[:], b[:])
+	})
+}
+
+// SortedKeys returns the keys of the map, or the elements of the set, in
+// ascending order.
+func SortedKeys[M ~map[K]V, K cmp.Ordered, V any](m M) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	SortKeys(keys)
+	return keys
+}
+
+// SortedUUIDKeys returns the uuid keys of the map, or the elements of the set,
+// in ascending order of their bytes.
+func SortedUUIDKeys[M ~map[K]V, K ~[16]byte, V any](m M) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	SortUUIDKeys(keys)
+	return keys
+}
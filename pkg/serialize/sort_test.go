@@ -0,0 +1,75 @@
+package serialize
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortKeys(t *testing.T) {
+	strs := []string{"b", "", "ab", "a", "\xff"}
+	SortKeys(strs)
+	assert.Equal(t, []string{"", "a", "ab", "b", "\xff"}, strs)
+
+	ints := []int32{3, -1, 0, -20}
+	SortKeys(ints)
+	assert.Equal(t, []int32{-20, -1, 0, 3}, ints)
+
+	type ID uuid.UUID
+	ids := []ID{{0x80}, {0x01, 0xff}, {0x01}}
+	SortUUIDKeys(ids)
+	assert.Equal(t, []ID{{0x01}, {0x01, 0xff}, {0x80}}, ids)
+}
+
+func TestSortedKeys(t *testing.T) {
+	type Color uint16
+	assert.Equal(t, []Color{0, 1, 2}, SortedKeys(map[Color]string{2: "", 0: "", 1: ""}))
+	assert.Equal(t, []float64{-1.5, 0, 2}, SortedKeys(map[float64]struct{}{2: {}, -1.5: {}, 0: {}}))
+	assert.Equal(t, []uuid.UUID{{0x01}, {0x02}}, SortedUUIDKeys(map[uuid.UUID]int{{0x02}: 0, {0x01}: 0}))
+	assert.Empty(t, SortedKeys(map[string]int(nil)))
+}
+
+func TestWriterDeterministic(t *testing.T) {
+	input := map[string]uint32{
+		"charlie": 3,
+		"alpha":   1,
+		"bravo":   2,
+		"delta":   300,
+	}
+
+	serializeMap := func(writer *Writer) {
+		SerializeUInt32(writer, uint32(len(input)))
+		for _, k := range SortedKeys(input) {
+			SerializeString(writer, k)
+			SerializeUInt32(writer, input[k])
+		}
+	}
+
+	writer := NewWriter(1)
+	assert.False(t, writer.Deterministic())
+	writer.SetDeterministic(true)
+	serializeMap(writer)
+
+	// the mode survives a reset
+	writer.Reset()
+	assert.True(t, writer.Deterministic())
+	serializeMap(writer)
+
+	// matches the bytes written by the C++ test_serialize_deterministic for the
+	// same std::map
+	assert.Equal(t, "092c10c606871636c002627261766f053c30861626c7965676c00264656c7461590600", hex.EncodeToString(writer.Bytes()))
+
+	set := map[int32]struct{}{5: {}, -3: {}, 100: {}, 0: {}, 7: {}, 12: {}}
+
+	writer = NewWriter(1)
+	SerializeUInt32(writer, uint32(len(set)))
+	for _, k := range SortedKeys(set) {
+		SerializeInt32(writer, k)
+	}
+
+	// matches the bytes written for the same std::unordered_set by a
+	// deterministic C++ writer
+	assert.Equal(t, "0d5c000b7840069201", hex.EncodeToString(writer.Bytes()))
+}
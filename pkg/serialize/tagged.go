@@ -0,0 +1,136 @@
+package serialize
+
+import (
+	"fmt"
+)
+
+// WireKind describes how the payload of a tagged field is laid out on the wire.
+// It carries just enough information for a decoder that does not recognize the
+// field index to skip over the payload, which is what makes tagged messages
+// forward and backward compatible.
+type WireKind uint8
+
+const (
+	WireKindBit       WireKind = iota // a single bit (bool)
+	WireKindByte                      // 8 bits (byte, uint8, int8)
+	WireKindVarUInt16                 // varint capped at 2 bytes
+	WireKindVarUInt32                 // varint capped at 4 bytes
+	WireKindVarUInt64                 // varint capped at 8 bytes
+	WireKindVarInt16                  // sign bit followed by a varint capped at 2 bytes
+	WireKindVarInt32                  // sign bit followed by a varint capped at 4 bytes
+	WireKindVarInt64                  // sign bit followed by a varint capped at 8 bytes
+	WireKindFixed32                   // 32 bits (float32)
+	WireKindFixed64                   // 64 bits (float64)
+	WireKindFixed128                  // 128 bits (uuid)
+	WireKindDelimited                 // bit length prefix followed by the payload
+)
+
+const wireKindNumBits = 4
+
+func BitSizeFieldTag(index uint32) int {
+	return BitSizeUInt32(index) + wireKindNumBits
+}
+
+func SerializeFieldTag(writer *Writer, index uint32, kind WireKind) {
+	SerializeUInt32(writer, index)
+	writer.WriteBits(uint8(kind), wireKindNumBits)
+}
+
+func DeserializeFieldTag(index *uint32, kind *WireKind, reader *Reader) error {
+	if err := DeserializeUInt32(index, reader); err != nil {
+		return err
+	}
+	var k byte
+	if err := reader.ReadBits(&k, wireKindNumBits); err != nil {
+		return err
+	}
+	if WireKind(k) > WireKindDelimited {
+		return fmt.Errorf("field %d has unrecognized wire kind %d", *index, k)
+	}
+	*kind = WireKind(k)
+	return nil
+}
+
+// CheckWireKind validates that a recognized field index arrived with the wire
+// kind the decoder expects. A mismatch means the field changed type between
+// schema versions, which is not a compatible change.
+func CheckWireKind(index uint32, got WireKind, expected WireKind) error {
+	if got != expected {
+		return fmt.Errorf("field %d has wire kind %d, expected %d", index, got, expected)
+	}
+	return nil
+}
+
+// BitSizeDelimited returns the size of the length prefix for a delimited
+// payload of the provided number of bits. It does not include the payload.
+func BitSizeDelimited(numBits int) int {
+	return BitSizeUInt64(uint64(numBits))
+}
+
+func SerializeDelimited(writer *Writer, numBits int) {
+	SerializeUInt64(writer, uint64(numBits))
+}
+
+// DeserializeDelimited reads the length prefix of a delimited payload and
+// returns the reader bit position at which the payload ends. The declared length
+// is bounded against the data actually present.
+func DeserializeDelimited(end *uint32, reader *Reader) error {
+	var numBits uint64
+	if err := DeserializeUInt64(&numBits, reader); err != nil {
+		return err
+	}
+	available := uint64(len(reader.bytes))*8 - uint64(reader.numBitsRead)
+	if numBits > available {
+		return fmt.Errorf("declared length of %d bits exceeds %d remaining bits", numBits, available)
+	}
+	*end = reader.numBitsRead + uint32(numBits)
+	return nil
+}
+
+// FinishDelimited advances the reader to the end of a delimited payload. A
+// decoder for an older schema may legitimately consume less than the payload
+// holds (a nested message gained fields), but never more.
+func FinishDelimited(reader *Reader, end uint32) error {
+	if reader.numBitsRead > end {
+		return fmt.Errorf("delimited payload overrun by %d bits", reader.numBitsRead-end)
+	}
+	reader.numBitsRead = end
+	return nil
+}
+
+// SkipField consumes the payload of a field the decoder does not recognize.
+func SkipField(reader *Reader, kind WireKind) error {
+	var u uint64
+	var i int64
+	switch kind {
+	case WireKindBit:
+		return reader.skipBits(1)
+	case WireKindByte:
+		return reader.skipBits(8)
+	case WireKindVarUInt16:
+		return varDecodeUint(reader, &u, 2)
+	case WireKindVarUInt32:
+		return varDecodeUint(reader, &u, 4)
+	case WireKindVarUInt64:
+		return varDecodeUint(reader, &u, 8)
+	case WireKindVarInt16:
+		return varDecodeInt(reader, &i, 2)
+	case WireKindVarInt32:
+		return varDecodeInt(reader, &i, 4)
+	case WireKindVarInt64:
+		return varDecodeInt(reader, &i, 8)
+	case WireKindFixed32:
+		return reader.skipBits(32)
+	case WireKindFixed64:
+		return reader.skipBits(64)
+	case WireKindFixed128:
+		return reader.skipBits(128)
+	case WireKindDelimited:
+		var end uint32
+		if err := DeserializeDelimited(&end, reader); err != nil {
+			return err
+		}
+		return FinishDelimited(reader, end)
+	}
+	return fmt.Errorf("unrecognized wire kind %d", kind)
+}
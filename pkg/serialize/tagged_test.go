@@ -0,0 +1,135 @@
+package serialize
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeFieldTag(t *testing.T) {
+
+	size := BitSizeFieldTag(1234)
+
+	writer := NewWriter(BitsToBytes(size))
+	SerializeFieldTag(writer, 1234, WireKindDelimited)
+
+	reader := NewReader(writer.Bytes())
+
+	var index uint32
+	var kind WireKind
+	err := DeserializeFieldTag(&index, &kind, reader)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(1234), index)
+	assert.Equal(t, WireKindDelimited, kind)
+}
+
+func TestSkipUnknownFields(t *testing.T) {
+
+	nested := "some nested payload"
+	nestedBits := BitSizeString(nested)
+
+	writer := NewWriter(64)
+
+	SerializeFieldTag(writer, 0, WireKindBit)
+	SerializeBool(writer, true)
+	SerializeFieldTag(writer, 1, WireKindByte)
+	SerializeUInt8(writer, 0xAB)
+	SerializeFieldTag(writer, 2, WireKindVarUInt16)
+	SerializeUInt16(writer, 0xFFFF)
+	SerializeFieldTag(writer, 3, WireKindVarUInt32)
+	SerializeUInt32(writer, 0xFFFFFFFF)
+	SerializeFieldTag(writer, 4, WireKindVarUInt64)
+	SerializeUInt64(writer, 12345678)
+	SerializeFieldTag(writer, 5, WireKindVarInt16)
+	SerializeInt16(writer, -1234)
+	SerializeFieldTag(writer, 6, WireKindVarInt32)
+	SerializeInt32(writer, -123456)
+	SerializeFieldTag(writer, 7, WireKindVarInt64)
+	SerializeInt64(writer, -12345678)
+	SerializeFieldTag(writer, 8, WireKindFixed32)
+	SerializeFloat32(writer, 3.14)
+	SerializeFieldTag(writer, 9, WireKindFixed64)
+	SerializeFloat64(writer, 3.14)
+	SerializeFieldTag(writer, 10, WireKindFixed128)
+	SerializeUUID(writer, uuid.New())
+	SerializeFieldTag(writer, 11, WireKindDelimited)
+	SerializeDelimited(writer, nestedBits)
+	SerializeString(writer, nested)
+	// the only field the decoder recognizes
+	SerializeFieldTag(writer, 12, WireKindVarUInt32)
+	SerializeUInt32(writer, 42)
+
+	reader := NewReader(writer.Bytes())
+
+	for i := 0; i < 12; i++ {
+		var index uint32
+		var kind WireKind
+		err := DeserializeFieldTag(&index, &kind, reader)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(i), index)
+
+		err = SkipField(reader, kind)
+		require.NoError(t, err)
+	}
+
+	var index uint32
+	var kind WireKind
+	err := DeserializeFieldTag(&index, &kind, reader)
+	require.NoError(t, err)
+	require.NoError(t, CheckWireKind(index, kind, WireKindVarUInt32))
+
+	var value uint32
+	err = DeserializeUInt32(&value, reader)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(42), value)
+}
+
+func TestDelimitedPartialConsume(t *testing.T) {
+
+	writer := NewWriter(16)
+	SerializeDelimited(writer, BitSizeUInt32(1)+BitSizeUInt32(2))
+	SerializeUInt32(writer, 1)
+	SerializeUInt32(writer, 2)
+	SerializeUInt32(writer, 3)
+
+	reader := NewReader(writer.Bytes())
+
+	var end uint32
+	err := DeserializeDelimited(&end, reader)
+	require.NoError(t, err)
+
+	// an older decoder only knows about the first value
+	var first uint32
+	err = DeserializeUInt32(&first, reader)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), first)
+
+	err = FinishDelimited(reader, end)
+	require.NoError(t, err)
+
+	var next uint32
+	err = DeserializeUInt32(&next, reader)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), next)
+}
+
+func TestDelimitedRejectsOversizedLength(t *testing.T) {
+
+	writer := NewWriter(16)
+	SerializeDelimited(writer, 1<<30)
+	SerializeUInt32(writer, 1)
+
+	reader := NewReader(writer.Bytes())
+
+	var end uint32
+	err := DeserializeDelimited(&end, reader)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remaining")
+}
+
+func TestCheckWireKindMismatch(t *testing.T) {
+	require.Error(t, CheckWireKind(3, WireKindDelimited, WireKindVarUInt32))
+}
@@ -2,11 +2,18 @@ package serialize
 
 import (
 	"encoding/binary"
+	"hash"
 )
 
+// hashWriterSize is the size of the buffer through which a hash writer streams
+// its bytes.
+const hashWriterSize = 256
+
 type Writer struct {
 	bytes          []byte
 	numBitsWritten uint32
+	deterministic  bool
+	sink           hash.Hash
 }
 
 func NewWriter(size int) *Writer {
@@ -15,6 +22,68 @@ func NewWriter(size int) *Writer {
 	}
 }
 
+// NewHashWriter returns a deterministic writer that streams the encoded bytes
+// into h through a small buffer, rather than retaining them, so that a message
+// can be hashed without first being encoded in full. Flush must be called once
+// the value has been written. The bytes hashed are exactly those of the
+// deterministic encoding, matching the C++ HashWriter.
+func NewHashWriter(h hash.Hash) *Writer {
+	return &Writer{
+		bytes:         make([]byte, hashWriterSize),
+		deterministic: true,
+		sink:          h,
+	}
+}
+
+// SetDeterministic enables the deterministic mode, in which map entries and set
+// elements are written in ascending order of their keys, so that equal messages
+// always encode to the same bytes, matching the C++ encoding. It is disabled by
+// default as sorting the keys costs an allocation per map or set. The mode is
+// retained across calls to Reset.
+func (w *Writer) SetDeterministic(deterministic bool) {
+	w.deterministic = deterministic
+}
+
+// Deterministic returns true if the writer is in the deterministic mode.
+func (w *Writer) Deterministic() bool {
+	return w.deterministic
+}
+
+// Flush writes the buffered bytes of a hash writer, including the final partial
+// byte, to its hash and resets the writer. It does nothing for other writers.
+func (w *Writer) Flush() {
+	if w.sink == nil {
+		return
+	}
+	w.sink.Write(w.Bytes())
+	w.Reset()
+}
+
+// drain writes the complete bytes of a hash writer to its hash, moving the
+// final partial byte to the front of the buffer.
+func (w *Writer) drain() {
+	n := w.numBitsWritten >> 3
+	w.sink.Write(w.bytes[:n])
+	var partial byte
+	if int(n) < len(w.bytes) {
+		partial = w.bytes[n]
+	}
+	clear(w.bytes)
+	w.bytes[0] = partial
+	w.numBitsWritten &= 7
+}
+
+// reserve ensures there is room for the next numBits bits, draining the buffer
+// of a hash writer before growing it.
+func (w *Writer) reserve(numBits uint32) {
+	neededBytes := (w.numBitsWritten + numBits + 7) / 8
+	if neededBytes > uint32(len(w.bytes)) && w.sink != nil {
+		w.drain()
+		neededBytes = (w.numBitsWritten + numBits + 7) / 8
+	}
+	w.ensureCapacity(neededBytes)
+}
+
 func (w *Writer) ensureCapacity(neededBytes uint32) {
 	// Check capacity before writing
 	if neededBytes > uint32(len(w.bytes)) {
@@ -35,8 +104,7 @@ func (w *Writer) WriteBits(val uint8, numBitsToWrite uint32) {
 	}
 
 	// Check capacity before writing
-	neededBytes := (w.numBitsWritten + numBitsToWrite + 7) / 8
-	w.ensureCapacity(neededBytes)
+	w.reserve(numBitsToWrite)
 
 	// Mask val to ensure we only write numBitsToWrite bits
 	val &= (1 << numBitsToWrite) - 1
@@ -60,10 +128,8 @@ func (w *Writer) WriteBits(val uint8, numBitsToWrite uint32) {
 
 func (w *Writer) WriteByte(val byte) {
 	if w.numBitsWritten&7 == 0 {
+		w.reserve(8)
 		byteIndex := w.numBitsWritten >> 3
-		if int(byteIndex)+1 > len(w.bytes) {
-			w.ensureCapacity(uint32(byteIndex) + 1)
-		}
 		w.bytes[byteIndex] = val
 		w.numBitsWritten += 8
 	} else {
@@ -76,12 +142,21 @@ func (w *Writer) WriteBytes(data []byte) {
 		return
 	}
 
+	if w.sink != nil {
+		// stream large writes through the buffer of a hash writer in chunks
+		for len(data) > hashWriterSize-1 {
+			w.writeBytes(data[:hashWriterSize-1])
+			data = data[hashWriterSize-1:]
+		}
+	}
+	w.writeBytes(data)
+}
+
+func (w *Writer) writeBytes(data []byte) {
+	w.reserve(uint32(len(data) * 8))
+
 	if w.numBitsWritten&7 == 0 {
 		byteIndex := w.numBitsWritten >> 3
-		neededBytes := int(byteIndex) + len(data)
-		if neededBytes > len(w.bytes) {
-			w.ensureCapacity(uint32(neededBytes))
-		}
 		copy(w.bytes[byteIndex:], data)
 		w.numBitsWritten += uint32(len(data) * 8)
 	} else {
@@ -89,12 +164,6 @@ func (w *Writer) WriteBytes(data []byte) {
 		bitOffset := w.numBitsWritten & 7
 		byteIndex := w.numBitsWritten >> 3
 
-		totalBits := w.numBitsWritten + uint32(len(data)*8)
-		neededBytes := (totalBits + 7) / 8
-		if neededBytes > uint32(len(w.bytes)) {
-			w.ensureCapacity(neededBytes)
-		}
-
 		// Try to write 8 bytes at a time using encoding/binary for safety and portability
 		// We need at least 8 bytes in input and enough space in output
 
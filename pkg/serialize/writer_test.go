@@ -0,0 +1,47 @@
+package serialize
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashWriter(t *testing.T) {
+
+	large := make([]byte, 1000)
+	for i := range large {
+		large[i] = byte(i * 7)
+	}
+
+	write := func(writer *Writer) {
+		SerializeString(writer, "hello")
+		SerializeBool(writer, true)
+		// unaligned writes spanning the buffer
+		SerializeBytes(writer, large)
+		for i := 0; i < 300; i++ {
+			SerializeUInt32(writer, uint32(i*i))
+			writer.WriteBits(uint8(i), 3)
+		}
+		// aligned writes spanning the buffer
+		writer.WriteBits(0, 2)
+		writer.WriteBytes(large)
+		writer.WriteByte(0xff)
+		SerializeBool(writer, true)
+	}
+
+	writer := NewWriter(16)
+	write(writer)
+	expected := sha256.Sum256(writer.Bytes())
+
+	hasher := sha256.New()
+	hashWriter := NewHashWriter(hasher)
+	assert.True(t, hashWriter.Deterministic())
+	write(hashWriter)
+	hashWriter.Flush()
+
+	var actual [32]byte
+	hasher.Sum(actual[:0])
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, hashWriterSize, hashWriter.Capacity())
+}
@@ -8,8 +8,11 @@
 #include "scg/writer.h"
 #include "scg/reader.h"
 #include "scg/macro.h"
+#include "scg/status.h"
 
 using scg::error::Error;
+using scg::rpc::Code;
+using scg::rpc::Status;
 using scg::serialize::bit_size;
 using scg::serialize::serialize;
 using scg::serialize::deserialize;
@@ -537,6 +540,120 @@ void test_error_in_struct_serialization()
 	}
 }
 
+// StringDetail is a minimal detail message wrapping a string.
+struct StringDetail {
+	std::string value;
+
+	static const char* messageName()
+	{
+		return "test.StringDetail";
+	}
+
+	std::vector<uint8_t> toBytes() const
+	{
+		scg::serialize::Writer writer;
+		serialize(writer, value);
+		return writer.bytes();
+	}
+
+	Error fromBytes(const std::vector<uint8_t>& data)
+	{
+		scg::serialize::Reader reader(data);
+		return deserialize(value, reader);
+	}
+};
+
+void test_status_to_error()
+{
+	Status status(Code::NOT_FOUND, "user not found");
+	status.addDetail(StringDetail{"owner"});
+
+	Error err = status.toError();
+	TEST_CHECK(err);
+	TEST_CHECK(err.message() == "user not found");
+	TEST_CHECK(err.status() != nullptr);
+	TEST_CHECK(err.status()->code() == Code::NOT_FOUND);
+
+	// the status survives copies and moves of the error
+	Error copied = err;
+	Error moved = std::move(copied);
+	Status actual = Status::fromError(moved);
+	TEST_CHECK(actual.code() == Code::NOT_FOUND);
+	TEST_CHECK(actual.message() == "user not found");
+
+	StringDetail detail;
+	TEST_CHECK(actual.detail(detail));
+	TEST_CHECK(detail.value == "owner");
+
+	// an empty message falls back to the name of the code
+	TEST_CHECK(Status(Code::UNAVAILABLE, "").toError().message() == "Unavailable");
+	TEST_CHECK(!Status().toError());
+}
+
+void test_status_from_error()
+{
+	TEST_CHECK(Status::fromError(nullptr).ok());
+
+	Error plain("boom");
+	TEST_CHECK(plain.status() == nullptr);
+	Status status = Status::fromError(plain);
+	TEST_CHECK(status.code() == Code::UNKNOWN);
+	TEST_CHECK(status.message() == "boom");
+	TEST_CHECK(status.details().empty());
+}
+
+void test_status_serialize_deserialize()
+{
+	Status input(Code::NOT_FOUND, "missing");
+	input.addDetail(StringDetail{"owner"});
+
+	scg::serialize::Writer writer(scg::serialize::bits_to_bytes(bit_size(input)));
+	serialize(writer, input);
+
+	scg::serialize::Reader reader(writer.bytes());
+	Status output;
+	auto err = deserialize(output, reader);
+	TEST_CHECK(!err);
+	TEST_CHECK(output.code() == Code::NOT_FOUND);
+	TEST_CHECK(output.message() == "missing");
+	TEST_CHECK(output.details().size() == 1);
+
+	StringDetail detail;
+	TEST_CHECK(output.detail(detail));
+	TEST_CHECK(detail.value == "owner");
+}
+
+// RawDetail is a detail with fixed bytes, matching the detail of the Go test.
+struct RawDetail {
+	static const char* messageName()
+	{
+		return "test.Detail";
+	}
+
+	std::vector<uint8_t> toBytes() const
+	{
+		return {1, 2, 3};
+	}
+};
+
+// The encoding of a status must match rpc.SerializeStatus in Go.
+void test_status_matches_go()
+{
+	Status status(Code::NOT_FOUND, "missing");
+	status.addDetail(RawDetail{});
+
+	scg::serialize::Writer writer;
+	serialize(writer, status);
+
+	std::string hex;
+	for (auto b : writer.bytes()) {
+		char buf[3];
+		std::snprintf(buf, sizeof(buf), "%02x", b);
+		hex += buf;
+	}
+	TEST_CHECK(hex == "0b3cd096363797e67636c005746573742e44657461696c0704080c00");
+}
+
 // helper method to reduce redundant test typing
 #define TEST(x) {#x, x}
 
@@ -569,6 +686,10 @@ TEST_LIST = {
 	TEST(test_error_multiple_reassignment),
 	TEST(test_error_serialize_deserialize),
 	TEST(test_error_in_struct_serialization),
+	TEST(test_status_to_error),
+	TEST(test_status_from_error),
+	TEST(test_status_serialize_deserialize),
+	TEST(test_status_matches_go),
 
 	{ NULL, NULL }
 };
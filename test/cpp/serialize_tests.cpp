@@ -41,6 +41,70 @@ void test_serialize_context()
 	TEST_CHECK(str1 == str2);
 }
 
+void test_context_timeout()
+{
+	scg::context::Context none;
+	TEST_CHECK(none.timeout() == 0);
+	TEST_CHECK(!none.expired());
+
+	scg::context::Context input;
+	input.setDeadline(std::chrono::system_clock::now() + std::chrono::minutes(1));
+	uint64_t timeout = input.timeout();
+	TEST_CHECK(timeout > uint64_t(std::chrono::nanoseconds(std::chrono::seconds(59)).count()));
+	TEST_CHECK(timeout <= uint64_t(std::chrono::nanoseconds(std::chrono::minutes(1)).count()));
+
+	scg::context::Context output;
+	output.setTimeout(timeout);
+	TEST_CHECK(output.hasDeadline());
+	TEST_CHECK(!output.expired());
+
+	scg::context::Context expired;
+	expired.setDeadline(std::chrono::system_clock::now() - std::chrono::seconds(1));
+	TEST_CHECK(expired.expired());
+	TEST_CHECK(expired.timeout() == 1);
+
+	// a timeout past the range of the clock clamps to the latest deadline
+	scg::context::Context far;
+	far.setTimeout(UINT64_MAX - 1);
+	TEST_CHECK(far.hasDeadline());
+	TEST_CHECK(!far.expired());
+	TEST_CHECK(far.getDeadline() == std::chrono::system_clock::time_point::max());
+	TEST_CHECK(far.timeout() > uint64_t(std::numeric_limits<int64_t>::max() / 2));
+}
+
+void test_context_cancel()
+{
+	scg::context::Context ctx;
+	scg::context::Context copy = ctx;
+	TEST_CHECK(!ctx.cancelled());
+
+	copy.cancel();
+	TEST_CHECK(copy.cancelled());
+	TEST_CHECK(ctx.cancelled());
+
+	scg::context::Context other;
+	TEST_CHECK(!other.cancelled());
+
+	// callbacks run once on cancellation, unless removed beforehand
+	int calls = 0;
+	int removedCalls = 0;
+	scg::context::Context notified;
+	scg::context::Context notifiedCopy = notified;
+	notified.onCancel([&calls]() { calls++; });
+	auto id = notified.onCancel([&removedCalls]() { removedCalls++; });
+	notified.removeOnCancel(id);
+	TEST_CHECK(calls == 0);
+
+	notifiedCopy.cancel();
+	notifiedCopy.cancel();
+	TEST_CHECK(calls == 1);
+	TEST_CHECK(removedCalls == 0);
+
+	// and immediately once the context is already cancelled
+	notified.onCancel([&calls]() { calls++; });
+	TEST_CHECK(calls == 2);
+}
+
 void test_serialize_uint8()
 {
 	uint8_t NUM_STEPS = UINT8_MAX;
@@ -377,6 +441,759 @@ void test_serialize_pingpong()
 	TEST_CHECK(output.valByteArray.size() == input.valByteArray.size());
 }
 
+void test_serialize_tagged_compatibility()
+{
+	pingpong::NestedPayload nested;
+	nested.valString = "nested";
+	nested.valDouble = 1.5;
+
+	// an older reader skips the fields it does not know about
+	{
+		pingpong::TaggedPayloadV2 input;
+		input.id = 42;
+		input.name = "newer";
+		input.score = 3.14;
+		input.nested = {nested};
+		input.counts = {{"x", -1}, {"y", 2}};
+		input.active = true;
+		input.key = scg::type::uuid::random();
+
+		pingpong::TaggedPayloadV1 output;
+		auto err = output.fromBytes(input.toBytes());
+		TEST_CHECK(!err);
+
+		TEST_CHECK(output.id == input.id);
+		TEST_CHECK(output.name == input.name);
+		TEST_CHECK(output.nested.size() == 1);
+		TEST_CHECK(output.nested[0].valString == nested.valString);
+		TEST_CHECK(output.nested[0].valDouble == nested.valDouble);
+	}
+
+	// a newer reader leaves the fields missing from the payload defaulted
+	{
+		pingpong::TaggedPayloadV1 input;
+		input.id = 7;
+		input.name = "older";
+		input.nested = {nested};
+
+		pingpong::TaggedPayloadV2 output;
+		output.score = 1.0;
+		output.active = true;
+		auto err = output.fromBytes(input.toBytes());
+		TEST_CHECK(!err);
+
+		TEST_CHECK(output.id == input.id);
+		TEST_CHECK(output.name == input.name);
+		TEST_CHECK(output.nested.size() == 1);
+		TEST_CHECK(output.score == 0.0);
+		TEST_CHECK(!output.active);
+		TEST_CHECK(output.counts.empty());
+	}
+}
+
+void test_serialize_optional()
+{
+	pingpong::NestedPayload nested;
+	nested.valString = "nested";
+	nested.valDouble = 1.5;
+
+	pingpong::OptionalPayload input;
+	input.count = 0;
+	input.name = "name";
+	input.note = "a note";
+	input.nested = nested;
+
+	pingpong::OptionalPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	// a present zero value is distinct from an absent one
+	TEST_CHECK(output.count.has_value());
+	TEST_CHECK(*output.count == 0);
+	TEST_CHECK(output.name == input.name);
+	TEST_CHECK(*output.note == *input.note);
+	TEST_CHECK(output.nested->valString == nested.valString);
+	TEST_CHECK(!output.updated.has_value());
+
+	// absent fields decode as empty, clearing any previous value
+	pingpong::OptionalPayload empty;
+	empty.name = "other";
+	err = output.fromBytes(empty.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(!output.count.has_value());
+	TEST_CHECK(output.name == empty.name);
+	TEST_CHECK(!output.note.has_value());
+	TEST_CHECK(!output.nested.has_value());
+}
+
+void test_serialize_oneof()
+{
+	pingpong::NestedPayload nested;
+	nested.valString = "nested";
+	nested.valDouble = 1.5;
+
+	pingpong::OneofPayload input;
+	input.name = "name";
+	input.value = nested;
+	input.when.emplace<2>("label");
+	input.trailer = 7;
+
+	pingpong::OneofPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.name == input.name);
+	TEST_CHECK(output.value.index() == 1);
+	TEST_CHECK(std::get<1>(output.value).valString == nested.valString);
+	TEST_CHECK(std::get<2>(output.when) == "label");
+	TEST_CHECK(output.trailer == input.trailer);
+
+	// a set zero value is distinct from an unset oneof
+	input.value.emplace<2>(0);
+	input.when = std::monostate{};
+	err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.value.index() == 2);
+	TEST_CHECK(std::get<2>(output.value) == 0);
+	TEST_CHECK(output.when.index() == 0);
+	TEST_CHECK(output.trailer == input.trailer);
+
+	pingpong::TaggedOneofPayload tagged;
+	tagged.id = 3;
+	tagged.value.emplace<2>("text");
+
+	pingpong::TaggedOneofPayload taggedOutput;
+	err = taggedOutput.fromBytes(tagged.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(taggedOutput.id == tagged.id);
+	TEST_CHECK(std::get<2>(taggedOutput.value) == "text");
+
+	tagged.value = std::monostate{};
+	err = taggedOutput.fromBytes(tagged.toBytes());
+	TEST_CHECK(!err);
+	TEST_CHECK(taggedOutput.value.index() == 0);
+}
+
+void test_serialize_recursive()
+{
+	pingpong::TreeNode input;
+	input.label = "root";
+	input.children.emplace_back();
+	input.children[0].label = "child";
+	input.named["named"].label = "named";
+	input.next = std::make_unique<pingpong::TreeNode>();
+	input.next->label = "next";
+
+	pingpong::TreeNode output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.label == input.label);
+	TEST_CHECK(output.children.size() == 1);
+	TEST_CHECK(output.children[0].label == "child");
+	TEST_CHECK(output.named.at("named").label == "named");
+	TEST_CHECK(output.next != nullptr);
+	TEST_CHECK(output.next->label == "next");
+	TEST_CHECK(output.next->next == nullptr);
+
+	pingpong::Thread thread;
+	thread.topic = "topic";
+	thread.comments.emplace_back();
+	thread.comments[0].text = "comment";
+	thread.comments[0].replies.comments.emplace_back();
+	thread.comments[0].replies.comments[0].text = "reply";
+
+	pingpong::Thread threadOutput;
+	err = threadOutput.fromBytes(thread.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(threadOutput.comments.size() == 1);
+	TEST_CHECK(threadOutput.comments[0].replies.comments.size() == 1);
+	TEST_CHECK(threadOutput.comments[0].replies.comments[0].text == "reply");
+
+	pingpong::TaggedTreeNode tagged;
+	tagged.value = 1;
+	tagged.parent = std::make_unique<pingpong::TaggedTreeNode>();
+	tagged.parent->value = 2;
+
+	pingpong::TaggedTreeNode taggedOutput;
+	err = taggedOutput.fromBytes(tagged.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(taggedOutput.value == 1);
+	TEST_CHECK(taggedOutput.parent != nullptr);
+	TEST_CHECK(taggedOutput.parent->value == 2);
+}
+
+void test_serialize_recursive_depth_limit()
+{
+	pingpong::TreeNode root;
+	pingpong::TreeNode* node = &root;
+	for (uint32_t i = 1; i < scg::serialize::MAX_DEPTH; i++) {
+		node->next = std::make_unique<pingpong::TreeNode>();
+		node = node->next.get();
+	}
+
+	pingpong::TreeNode output;
+	auto err = output.fromBytes(root.toBytes());
+	TEST_CHECK(!err);
+
+	node->next = std::make_unique<pingpong::TreeNode>();
+	err = output.fromBytes(root.toBytes());
+	TEST_CHECK(err);
+}
+
+void test_copy_recursive()
+{
+	pingpong::TreeNode input;
+	input.label = "root";
+	input.children.emplace_back();
+	input.children[0].next = std::make_unique<pingpong::TreeNode>();
+	input.children[0].next->label = "child next";
+	input.next = std::make_unique<pingpong::TreeNode>();
+	input.next->label = "next";
+
+	pingpong::TreeNode copy = input;
+	TEST_CHECK(copy == input);
+	TEST_CHECK(copy.next != nullptr);
+	TEST_CHECK(copy.next.get() != input.next.get());
+	TEST_CHECK(copy.children[0].next.get() != input.children[0].next.get());
+
+	copy.next->label = "changed";
+	copy.children[0].next->label = "changed";
+	TEST_CHECK(input.next->label == "next");
+	TEST_CHECK(input.children[0].next->label == "child next");
+
+	std::vector<pingpong::TreeNode> nodes{input};
+	TEST_CHECK(nodes[0] == input);
+
+	pingpong::TreeNode assigned;
+	assigned = input;
+	TEST_CHECK(assigned == input);
+	TEST_CHECK(assigned.next.get() != input.next.get());
+
+	assigned = pingpong::TreeNode();
+	TEST_CHECK(assigned.next == nullptr);
+
+	pingpong::TaggedTreeNode tagged;
+	tagged.value = 1;
+	tagged.parent = std::make_unique<pingpong::TaggedTreeNode>();
+	tagged.parent->value = 2;
+
+	pingpong::TaggedTreeNode taggedCopy(tagged);
+	TEST_CHECK(taggedCopy.parent.get() != tagged.parent.get());
+	TEST_CHECK(taggedCopy.parent->value == 2);
+}
+
+void test_serialize_nested()
+{
+	pingpong::Order::Line line;
+	line.sku = "sku";
+	line.quantity = 2;
+	line.status = pingpong::Order::Status::ORDER_PENDING;
+
+	pingpong::Order input;
+	input.number = 7;
+	input.status = pingpong::Order::Status::ORDER_SHIPPED;
+	input.lines.push_back(line);
+
+	pingpong::Order output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.number == input.number);
+	TEST_CHECK(output.status == input.status);
+	TEST_CHECK(output.lines.size() == 1);
+	TEST_CHECK(output.lines[0].sku == line.sku);
+	TEST_CHECK(output.lines[0].quantity == line.quantity);
+
+	pingpong::Invoice invoice;
+	invoice.line = line;
+	invoice.statuses["a"] = pingpong::Order_Status::ORDER_SHIPPED;
+
+	pingpong::Invoice invoiceOutput;
+	err = invoiceOutput.fromBytes(invoice.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(invoiceOutput.line.sku == line.sku);
+	TEST_CHECK(invoiceOutput.statuses.at("a") == pingpong::Order::Status::ORDER_SHIPPED);
+}
+
+void test_serialize_array()
+{
+	pingpong::ArrayPayload input;
+	for (uint32_t i = 0; i < input.matrix.size(); i++) {
+		input.matrix[i] = float32_t(i) * 0.5f;
+	}
+	for (uint32_t i = 0; i < input.hash.size(); i++) {
+		input.hash[i] = uint8_t(i);
+	}
+	input.pair[0].valString = "a";
+	input.pair[1].valString = "b";
+	input.points.push_back({1, 2, 3});
+	input.grid = {{{1, 2}, {3, 4}}};
+
+	pingpong::ArrayPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.matrix == input.matrix);
+	TEST_CHECK(output.hash == input.hash);
+	TEST_CHECK(output.pair[1].valString == "b");
+	TEST_CHECK(output.points == input.points);
+	TEST_CHECK(output.grid == input.grid);
+
+	// the length of an array is known from the schema, so only the elements
+	// are written
+	pingpong::HashPayload hash;
+	TEST_CHECK(hash.toBytes().size() == 32);
+}
+
+void test_serialize_bytes()
+{
+	pingpong::BytesPayload input;
+	input.data = {'h', 'e', 'l', 'l', 'o'};
+	input.thumbnail = std::vector<uint8_t>{0xff, 0xd8};
+	input.chunks = {{1, 2, 3}, {}};
+	input.blobs["a"] = {0x00, 0x7f, 0x80};
+
+	pingpong::BytesPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.data == input.data);
+	TEST_CHECK(output.thumbnail == input.thumbnail);
+	TEST_CHECK(output.chunks == input.chunks);
+	TEST_CHECK(output.blobs == input.blobs);
+
+	// bytes are base64 encoded in JSON
+	auto json = input.toJSON();
+	TEST_CHECK(std::string(json.begin(), json.end()).find("\"data\":\"aGVsbG8=\"") != std::string::npos);
+
+	pingpong::BytesPayload jsonOutput;
+	jsonOutput.fromJSON(json);
+	TEST_CHECK(jsonOutput.data == input.data);
+	TEST_CHECK(jsonOutput.thumbnail == input.thumbnail);
+	TEST_CHECK(jsonOutput.chunks == input.chunks);
+	TEST_CHECK(jsonOutput.blobs == input.blobs);
+
+	// bytes share the encoding of list<byte>
+	pingpong::ByteListPayload list;
+	list.data = input.data;
+	pingpong::BlobPayload blob;
+	blob.data = input.data;
+	TEST_CHECK(list.toBytes() == blob.toBytes());
+
+	// but byte lists, like any other byte vector, remain arrays of numbers in JSON
+	auto listJSON = list.toJSON();
+	TEST_CHECK(std::string(listJSON.begin(), listJSON.end()) == "{\"data\":[104,101,108,108,111]}");
+	TEST_CHECK(nlohmann::json(std::vector<uint8_t>{1, 2}).dump() == "[1,2]");
+
+	pingpong::ByteListPayload listOutput;
+	listOutput.fromJSON(listJSON);
+	TEST_CHECK(listOutput.data == list.data);
+}
+
+void test_serialize_set()
+{
+	pingpong::SetPayload input;
+	input.tags = {"a", "b"};
+	input.ids.insert(scg::type::uuid::random());
+	input.keys.insert(pingpong::KeyType("key"));
+	input.enums.insert(pingpong::EnumType::ENUM_TYPE_2);
+	input.groups = {{1, -2}, {}};
+	input.index["x"] = {7};
+
+	pingpong::SetPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.tags == input.tags);
+	TEST_CHECK(output.ids == input.ids);
+	TEST_CHECK(output.keys == input.keys);
+	TEST_CHECK(output.enums == input.enums);
+	TEST_CHECK(output.groups == input.groups);
+	TEST_CHECK(output.index == input.index);
+
+	// a set is encoded as a list of its elements, and rejects duplicates
+	pingpong::StringListPayload list;
+	list.values = {"a", "b"};
+
+	pingpong::StringSetPayload set;
+	err = set.fromBytes(list.toBytes());
+	TEST_CHECK(!err);
+	TEST_CHECK(set.values.size() == 2);
+
+	list.values.push_back("a");
+	err = set.fromBytes(list.toBytes());
+	TEST_CHECK(err);
+
+	// sets are encoded in JSON as arrays of their elements, as in Go
+	pingpong::StringSetPayload single;
+	single.values = {"a"};
+	auto json = single.toJSON();
+	TEST_CHECK(std::string(json.begin(), json.end()) == "{\"values\":[\"a\"]}");
+
+	std::string doc = "{\"tags\":[\"a\",\"b\"],\"ids\":[],\"keys\":[\"key\"],\"enums\":[1],\"groups\":[[1,-2],[]],\"index\":{\"x\":[7]}}";
+	pingpong::SetPayload jsonOutput;
+	jsonOutput.fromJSON(std::vector<uint8_t>(doc.begin(), doc.end()));
+	TEST_CHECK(jsonOutput.tags == input.tags);
+	TEST_CHECK(jsonOutput.ids.empty());
+	TEST_CHECK(jsonOutput.keys == input.keys);
+	TEST_CHECK(jsonOutput.enums == input.enums);
+	TEST_CHECK(jsonOutput.groups == input.groups);
+	TEST_CHECK(jsonOutput.index == input.index);
+}
+
+void test_serialize_duration()
+{
+	using namespace std::chrono_literals;
+
+	pingpong::DurationPayload input;
+	input.timeout = 1500ms;
+	input.ttl = 90min;
+	input.backoff = {100ms, -1s, 0ns};
+	input.deadlines["read"] = 250us;
+	input.windows[0] = {1h};
+	input.limit = std::chrono::nanoseconds(1);
+
+	pingpong::DurationPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.timeout == input.timeout);
+	TEST_CHECK(output.ttl == input.ttl);
+	TEST_CHECK(output.backoff == input.backoff);
+	TEST_CHECK(output.deadlines == input.deadlines);
+	TEST_CHECK(output.windows == input.windows);
+	TEST_CHECK(output.limit == input.limit);
+
+	// json uses the string form of Go's time.Duration
+	auto json = nlohmann::json::parse(input.toJSON());
+	TEST_CHECK(json["timeout"] == "1.5s");
+	TEST_CHECK(json["ttl"] == "1h30m0s");
+	TEST_CHECK(json["deadlines"]["read"] == "250µs");
+
+	pingpong::DurationPayload jsonOutput;
+	jsonOutput.fromJSON(input.toJSON());
+	TEST_CHECK(jsonOutput.timeout == input.timeout);
+	TEST_CHECK(jsonOutput.ttl == input.ttl);
+	TEST_CHECK(jsonOutput.backoff == input.backoff);
+
+	auto [parsed, ok] = scg::type::parse_duration("-2h45m0.5s");
+	TEST_CHECK(ok);
+	TEST_CHECK(parsed == -(2h + 45min + 500ms));
+	TEST_CHECK(!scg::type::parse_duration("1.5 seconds").second);
+
+	// a duration is encoded as an int64 of nanoseconds
+	pingpong::Int64Payload integer;
+	integer.value = -3000000000;
+	pingpong::DurationPayload untagged;
+	untagged.timeout = -3s;
+
+	auto integerBytes = integer.toBytes();
+	auto durationBytes = untagged.toBytes();
+	TEST_CHECK(std::equal(integerBytes.begin(), integerBytes.end(), durationBytes.begin()));
+}
+
+void test_serialize_bit_int()
+{
+	pingpong::BitIntPayload input;
+	input.flags = 31;
+	input.delta = -2048;
+	input.toggle = 1;
+	input.steps = {-4, 3, 0, -1};
+	input.wide["max"] = UINT64_MAX;
+	input.nibbles = {15, 7};
+	input.full = INT64_MIN;
+
+	pingpong::BitIntPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(output.flags == 31);
+	TEST_CHECK(output.delta == -2048);
+	TEST_CHECK(output.toggle && *output.toggle == 1);
+	TEST_CHECK(output.steps.size() == 4 && output.steps[0] == -4 && output.steps[3] == -1);
+	TEST_CHECK(output.wide["max"] == UINT64_MAX);
+	TEST_CHECK(output.nibbles[0] == 15 && output.nibbles[1] == 7);
+	TEST_CHECK(output.full == INT64_MIN);
+
+	pingpong::BitIntPayload jsonOutput;
+	jsonOutput.fromJSON(input.toJSON());
+	TEST_CHECK(jsonOutput.delta == -2048);
+	TEST_CHECK(jsonOutput.wide["max"] == UINT64_MAX);
+
+	// fields are written with exactly their declared number of bits
+	pingpong::BitIntPayload oneStep;
+	oneStep.steps = {1};
+	pingpong::BitIntPayload twoSteps;
+	twoSteps.steps = {1, 2};
+	TEST_CHECK(bit_size(oneStep) + 3 == bit_size(twoSteps));
+
+	// values that do not fit are rejected when encoding
+	pingpong::BitIntPayload invalid;
+	invalid.flags = 32;
+	TEST_EXCEPTION(invalid.toBytes(), std::out_of_range);
+	invalid.flags = 0;
+	invalid.delta = 2048;
+	TEST_EXCEPTION(invalid.toBytes(), std::out_of_range);
+
+	pingpong::TaggedBitIntPayload tagged;
+	tagged.flags = 17;
+	tagged.delta = -7;
+	pingpong::TaggedBitIntPayload taggedOutput;
+	err = taggedOutput.fromBytes(tagged.toBytes());
+	TEST_CHECK(!err);
+	TEST_CHECK(taggedOutput.flags == 17);
+	TEST_CHECK(taggedOutput.delta && *taggedOutput.delta == -7);
+}
+
+void test_serialize_quant()
+{
+	pingpong::QuantPayload input;
+	input.x = 12.5f;
+	input.distance = 999.999;
+	input.pitch = 0.25f;
+	input.samples = {-0.5f, 0.1f, 0.5f};
+	input.weights["a"] = 0.3f;
+	input.angles = {0.0f, 90.0f, 359.9f};
+
+	pingpong::QuantPayload output;
+	auto err = output.fromBytes(input.toBytes());
+	TEST_CHECK(!err);
+
+	TEST_CHECK(std::abs(float32_t(output.x) - float32_t(input.x)) <= pingpong::QuantPayload::xPrecision / 2);
+	TEST_CHECK(std::abs(float64_t(output.distance) - float64_t(input.distance)) <= pingpong::QuantPayload::distancePrecision / 2);
+	TEST_CHECK(output.pitch && std::abs(float32_t(*output.pitch) - 0.25f) <= pingpong::QuantPayload::pitchPrecision / 2);
+	TEST_CHECK(output.samples.size() == 3);
+	TEST_CHECK(std::abs(float32_t(output.samples[1]) - 0.1f) <= pingpong::QuantPayload::samplesPrecision / 2);
+	TEST_CHECK(std::abs(float32_t(output.weights["a"]) - 0.3f) <= pingpong::QuantPayload::weightsPrecision / 2);
+	TEST_CHECK(std::abs(float32_t(output.angles[2]) - 359.9f) <= pingpong::QuantPayload::anglesPrecision / 2);
+
+	// fields are written with exactly their declared number of bits
+	pingpong::QuantPayload oneSample;
+	oneSample.samples = {0.0f};
+	pingpong::QuantPayload twoSamples;
+	twoSamples.samples = {0.0f, 0.0f};
+	TEST_CHECK(bit_size(oneSample) + 10 == bit_size(twoSamples));
+
+	// values outside of the range are clamped
+	pingpong::QuantPayload clamped;
+	clamped.x = 1000.0f;
+	clamped.distance = -1.0;
+	err = output.fromBytes(clamped.toBytes());
+	TEST_CHECK(!err);
+	TEST_CHECK(float32_t(output.x) == 100.0f);
+	TEST_CHECK(float64_t(output.distance) == 0.0);
+
+	pingpong::TaggedQuantPayload tagged;
+	tagged.x = -42.0f;
+	tagged.pitch = 0.25f;
+	pingpong::TaggedQuantPayload taggedOutput;
+	err = taggedOutput.fromBytes(tagged.toBytes());
+	TEST_CHECK(!err);
+	TEST_CHECK(std::abs(float32_t(taggedOutput.x) + 42.0f) <= pingpong::TaggedQuantPayload::xPrecision / 2);
+	TEST_CHECK(taggedOutput.pitch.has_value());
+}
+
+std::string to_hex(const std::vector<uint8_t>& data)
+{
+	static const char* digits = "0123456789abcdef";
+	std::string str;
+	for (auto b : data) {
+		str += digits[b >> 4];
+		str += digits[b & 0xf];
+	}
+	return str;
+}
+
+pingpong::EntityID entity_id(const std::string& str)
+{
+	return pingpong::EntityID(scg::type::uuid::fromString(str).first);
+}
+
+void test_serialize_deterministic()
+{
+	// the same bytes are written by the Go TestWriterDeterministic
+	std::map<std::string, uint32_t> counts = {{"charlie", 3}, {"alpha", 1}, {"bravo", 2}, {"delta", 300}};
+	scg::serialize::Writer writer(1);
+	writer.write(counts);
+	TEST_CHECK(to_hex(writer.bytes()) == "092c10c606871636c002627261766f053c30861626c7965676c00264656c7461590600");
+
+	std::unordered_map<std::string, uint32_t> unordered(counts.begin(), counts.end());
+	scg::serialize::Writer unorderedWriter(1);
+	unorderedWriter.setDeterministic(true);
+	unorderedWriter.write(unordered);
+	TEST_CHECK(to_hex(unorderedWriter.bytes()) == to_hex(writer.bytes()));
+
+	std::unordered_set<int32_t> set = {5, -3, 100, 0, 7, 12};
+	scg::serialize::Writer setWriter(1);
+	setWriter.setDeterministic(true);
+	setWriter.write(set);
+	TEST_CHECK(to_hex(setWriter.bytes()) == "0d5c000b7840069201");
+
+	// and by the Go TestSerializeDeterministic
+	pingpong::DeterministicPayload input;
+	input.counts = {{"c", 3}, {"a", 1}, {"b", 2}, {"aa", 4}};
+	input.entities = {
+		entity_id("f0000000-0000-4000-8000-000000000001"),
+		entity_id("00000000-0000-4000-8000-000000000002"),
+		entity_id("0f000000-0000-4000-8000-000000000003"),
+	};
+	input.enums = {pingpong::EnumType::ENUM_TYPE_3, pingpong::EnumType::ENUM_TYPE_1};
+	input.groups[pingpong::KeyType("y")] = {3, -1};
+	input.groups[pingpong::KeyType("x")] = {10, 0, -7};
+	input.weights[scg::type::uuid::fromString("80000000-0000-4000-8000-000000000000").first] = 0.5;
+	input.weights[scg::type::uuid::fromString("01000000-0000-4000-8000-000000000000").first] = -2;
+
+	std::vector<uint8_t> data(scg::serialize::bits_to_bytes(bit_size(input)), 0);
+	scg::serialize::WriterView view(data);
+	view.setDeterministic(true);
+	view.write(input);
+	TEST_CHECK(to_hex(data) == "090c103640016161090c2056c00063071c00000000000000040008000000000020f0000000000000040008000000000030000f00000000000400080000000000105080020a1800ef801ba08201f20a3880030a08000000000000020004000000000000000600000000000000040000000000020004000000000000f80107000000000000");
+
+	// and by the Go TestSerializeDeltaDeterministic
+	pingpong::DeterministicPayload baseline;
+	baseline.counts = {{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}};
+	baseline.enums = {pingpong::EnumType::ENUM_TYPE_1, pingpong::EnumType::ENUM_TYPE_2};
+	pingpong::DeterministicPayload delta;
+	delta.counts = {{"a", 1}, {"b", 20}, {"e", 5}, {"f", 6}};
+	delta.enums = {pingpong::EnumType::ENUM_TYPE_3};
+
+	std::vector<uint8_t> deltaData(scg::serialize::bits_to_bytes(bit_size_delta(delta, baseline)), 0);
+	scg::serialize::WriterView deltaView(deltaData);
+	deltaView.setDeterministic(true);
+	serialize_delta(deltaView, delta, baseline);
+	TEST_CHECK(to_hex(deltaData) == "0b18606c00b20306104b6180b20506306bc0020c30400100");
+}
+
+void test_serialize_sum256()
+{
+	// known answers of the FIPS 180-4 examples
+	scg::hash::Sha256 empty;
+	auto emptySum = empty.sum();
+	TEST_CHECK(to_hex(std::vector<uint8_t>(emptySum.begin(), emptySum.end())) == "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855");
+
+	std::string abc = "abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq";
+	scg::hash::Sha256 twoBlocks;
+	twoBlocks.update(reinterpret_cast<const uint8_t*>(abc.data()), 20);
+	twoBlocks.update(reinterpret_cast<const uint8_t*>(abc.data()) + 20, abc.size() - 20);
+	auto twoBlocksSum = twoBlocks.sum();
+	TEST_CHECK(to_hex(std::vector<uint8_t>(twoBlocksSum.begin(), twoBlocksSum.end())) == "248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1");
+
+	// the same digest is computed by the Go TestSerializeSum256
+	pingpong::DeterministicPayload input;
+	input.counts = {{"c", 3}, {"a", 1}, {"b", 2}, {"aa", 4}};
+	input.entities = {
+		entity_id("f0000000-0000-4000-8000-000000000001"),
+		entity_id("00000000-0000-4000-8000-000000000002"),
+		entity_id("0f000000-0000-4000-8000-000000000003"),
+	};
+	input.enums = {pingpong::EnumType::ENUM_TYPE_3, pingpong::EnumType::ENUM_TYPE_1};
+	input.groups[pingpong::KeyType("y")] = {3, -1};
+	input.groups[pingpong::KeyType("x")] = {10, 0, -7};
+	input.weights[scg::type::uuid::fromString("80000000-0000-4000-8000-000000000000").first] = 0.5;
+	input.weights[scg::type::uuid::fromString("01000000-0000-4000-8000-000000000000").first] = -2;
+
+	auto sum = input.sum256();
+	TEST_CHECK(to_hex(std::vector<uint8_t>(sum.begin(), sum.end())) == "568c760a191422c635a7f1f6952fe31c91929159462b9b4a559adba85bf301ff");
+
+	pingpong::DeterministicPayload output = input;
+	TEST_CHECK(output.sum256() == sum);
+	output.counts["a"] = 10;
+	TEST_CHECK(output.sum256() != sum);
+
+	// large byte fields are streamed through the hasher
+	pingpong::BytesPayload payload;
+	payload.data.resize(4096);
+	for (uint32_t i = 0; i < payload.data.size(); i++) {
+		payload.data[i] = uint8_t(i);
+	}
+	auto bytes = payload.toBytes();
+	scg::hash::Sha256 hasher;
+	hasher.update(bytes.data(), bytes.size());
+	TEST_CHECK(payload.sum256() == hasher.sum());
+
+	scg::hash::Sha256 streamed;
+	payload.hashTo(streamed);
+	TEST_CHECK(streamed.sum() == hasher.sum());
+}
+
+void test_serialize_delta()
+{
+	pingpong::OptionalPayload baseline;
+	baseline.count = 1;
+	baseline.name = "name";
+	baseline.nested = pingpong::NestedPayload();
+	baseline.nested->valString = "nested";
+
+	// a delta is decoded in place, into a copy of the baseline
+	pingpong::OptionalPayload input = baseline;
+	input.count.reset();
+	input.note = "note";
+	input.nested->valDouble = 2;
+
+	pingpong::OptionalPayload output = baseline;
+	auto err = output.fromBytesDelta(input.toBytesDelta(baseline));
+	TEST_CHECK(!err);
+	TEST_CHECK(output == input);
+	TEST_CHECK(!output.count.has_value());
+	TEST_CHECK(output.note == "note");
+	TEST_CHECK(output.nested->valDouble == 2);
+
+	// an unchanged message writes a single bit per field
+	TEST_CHECK(baseline.toBytesDelta(baseline).size() == 1);
+
+	pingpong::TreeNode node;
+	node.label = "root";
+	node.children.resize(2);
+	node.children[0].label = "a";
+	node.children[1].label = "b";
+	node.named["c"].label = "c";
+
+	pingpong::TreeNode nodeInput;
+	nodeInput.label = "root";
+	nodeInput.children.resize(3);
+	nodeInput.children[0].label = "a";
+	nodeInput.children[1].label = "B";
+	nodeInput.children[2].label = "d";
+	nodeInput.named["e"].label = "e";
+	nodeInput.next = std::make_unique<pingpong::TreeNode>();
+	nodeInput.next->label = "next";
+
+	pingpong::TreeNode nodeOutput;
+	err = nodeOutput.fromBytes(node.toBytes());
+	TEST_CHECK(!err);
+	err = nodeOutput.fromBytesDelta(nodeInput.toBytesDelta(node));
+	TEST_CHECK(!err);
+	TEST_CHECK(nodeOutput == nodeInput);
+	TEST_CHECK(nodeOutput.named.count("c") == 0);
+	TEST_CHECK(nodeOutput.next != nullptr);
+
+	pingpong::OneofPayload oneof;
+	oneof.value.emplace<1>().valString = "nested";
+	pingpong::OneofPayload oneofInput;
+	oneofInput.value.emplace<3>(std::vector<std::string>{"a", "b"});
+	oneofInput.trailer = 7;
+
+	pingpong::OneofPayload oneofOutput = oneof;
+	err = oneofOutput.fromBytesDelta(oneofInput.toBytesDelta(oneof));
+	TEST_CHECK(!err);
+	TEST_CHECK(oneofOutput == oneofInput);
+
+	// a truncated delta is rejected
+	auto data = nodeInput.toBytesDelta(node);
+	data.resize(data.size() / 2);
+	err = nodeOutput.fromBytes(node.toBytes());
+	TEST_CHECK(!err);
+	err = nodeOutput.fromBytesDelta(data);
+	TEST_CHECK(err);
+}
+
 struct TestStructA {
 	uint32_t a = 0;
 	float64_t b = 1;
@@ -738,7 +1555,25 @@ TEST_LIST = {
 	TEST(test_serialize_vector),
 	TEST(test_serialize_map),
 	TEST(test_serialize_pingpong),
+	TEST(test_serialize_tagged_compatibility),
+	TEST(test_serialize_optional),
+	TEST(test_serialize_oneof),
+	TEST(test_serialize_recursive),
+	TEST(test_serialize_recursive_depth_limit),
+	TEST(test_copy_recursive),
+	TEST(test_serialize_nested),
+	TEST(test_serialize_array),
+	TEST(test_serialize_bytes),
+	TEST(test_serialize_set),
+	TEST(test_serialize_duration),
+	TEST(test_serialize_bit_int),
+	TEST(test_serialize_quant),
+	TEST(test_serialize_deterministic),
+	TEST(test_serialize_sum256),
+	TEST(test_serialize_delta),
 	TEST(test_serialize_context),
+	TEST(test_context_timeout),
+	TEST(test_context_cancel),
 	TEST(test_serialize_macros),
 	TEST(test_serialize_multiple_types_in_sequence),
 	TEST(test_serialize_multiple_strings_in_sequence),
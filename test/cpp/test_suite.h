@@ -92,6 +92,63 @@ public:
 	}
 };
 
+// PingPong server implementation that fails with a status
+class PingPongServerStatus : public pingpong::PingPongServer {
+public:
+	std::pair<pingpong::PongResponse, scg::error::Error> ping(
+		const scg::context::Context& ctx,
+		const pingpong::PingRequest& req
+	) override {
+		pingpong::Pong pong;
+		pong.count = req.ping.count;
+		scg::rpc::Status status(scg::rpc::Code::NOT_FOUND, "no pong to ping");
+		status.addDetail(pong);
+		return std::make_pair(pingpong::PongResponse{}, status.toError());
+	}
+};
+
+// PingPong server implementation that waits until the deadline of the call
+// has passed, so that tests can observe the deadline of the caller reaching the
+// handler
+class PingPongServerDeadline : public pingpong::PingPongServer {
+public:
+	std::atomic<bool> expired{false};
+
+	std::pair<pingpong::PongResponse, scg::error::Error> ping(
+		const scg::context::Context& ctx,
+		const pingpong::PingRequest& req
+	) override {
+		if (!ctx.hasDeadline()) {
+			return std::make_pair(pingpong::PongResponse{}, scg::rpc::Status(scg::rpc::Code::FAILED_PRECONDITION, "no deadline").toError());
+		}
+		auto giveUp = std::chrono::system_clock::now() + std::chrono::seconds(5);
+		while (!ctx.expired() && std::chrono::system_clock::now() < giveUp) {
+			std::this_thread::sleep_for(std::chrono::milliseconds(10));
+		}
+		expired = ctx.expired();
+		return std::make_pair(pingpong::PongResponse{}, scg::rpc::Status(scg::rpc::Code::DEADLINE_EXCEEDED, "deadline exceeded").toError());
+	}
+};
+
+// PingPong server implementation that waits until the call is cancelled, so
+// that tests can observe the cancellation of the caller reaching the handler
+class PingPongServerBlocking : public pingpong::PingPongServer {
+public:
+	std::atomic<bool> cancelled{false};
+
+	std::pair<pingpong::PongResponse, scg::error::Error> ping(
+		const scg::context::Context& ctx,
+		const pingpong::PingRequest& req
+	) override {
+		auto giveUp = std::chrono::system_clock::now() + std::chrono::seconds(5);
+		while (!ctx.cancelled() && std::chrono::system_clock::now() < giveUp) {
+			std::this_thread::sleep_for(std::chrono::milliseconds(10));
+		}
+		cancelled = ctx.cancelled();
+		return std::make_pair(pingpong::PongResponse{}, scg::rpc::Status(scg::rpc::Code::CANCELED, "cancelled").toError());
+	}
+};
+
 // TesterA server implementation
 class TesterAServerImpl : public basic::TesterAServer {
 public:
@@ -594,6 +651,43 @@ inline void runServerErrorTest(TestContext& ctx) {
 	printf("Server Error test passed\n");
 }
 
+// Test server returns a status with details
+inline void runServerStatusTest(TestContext& ctx) {
+	if (ctx.isUsingExternalServer()) {
+		printf("Skipping Server Status test (using external server)\n");
+		return;
+	}
+
+	printf("Running Server Status test...\n");
+
+	ctx.startServerWithSetup([](scg::rpc::Server* server) {
+		auto impl = std::make_shared<PingPongServerStatus>();
+		pingpong::registerPingPongServer(server, impl);
+	});
+
+	auto client = ctx.createClient();
+	pingpong::PingPongClient pingPongClient(client);
+
+	scg::context::Context context;
+	pingpong::PingRequest req;
+	req.ping.count = 7;
+
+	auto [res, err] = pingPongClient.ping(context, req);
+	TEST_CHECK(err != nullptr);
+	if (err) {
+		TEST_CHECK(err.message() == "no pong to ping");
+		auto status = scg::rpc::Status::fromError(err);
+		TEST_CHECK(status.code() == scg::rpc::Code::NOT_FOUND);
+		pingpong::Pong pong;
+		TEST_CHECK(status.detail(pong));
+		TEST_CHECK(pong.count == 7);
+	}
+
+	client->disconnect();
+	ctx.stopServer();
+	printf("Server Status test passed\n");
+}
+
 // Test server groups with isolated middleware
 inline void runServerGroupsTest(TestContext& ctx) {
 	if (ctx.isUsingExternalServer()) {
@@ -814,6 +908,89 @@ inline void runGracefulShutdownTest(TestContext& ctx) {
 	printf("Graceful Shutdown test passed\n");
 }
 
+// Test that shutdown lets an in-flight call complete, that the client moves
+// its next call to the server that replaced it, that a call still in flight
+// when the timeout expires is abandoned and reported, and that shutdown without
+// a timeout abandons the calls in flight without reporting them
+inline void runGracefulShutdownDrainTest(TestContext& ctx) {
+	if (ctx.isUsingExternalServer()) {
+		printf("Skipping Graceful Shutdown Drain test (using external server)\n");
+		return;
+	}
+
+	printf("Running Graceful Shutdown Drain test...\n");
+
+	ctx.startServer();
+	auto client = ctx.createClient();
+
+	pingpong::PingPongClient pingPongClient(client);
+
+	scg::error::Error inflightErr;
+	int32_t inflightCount = 0;
+	std::thread inflight([&]() {
+		scg::context::Context context;
+		context.put("sleep", "500");
+		pingpong::PingRequest req;
+		req.ping.count = 1;
+		auto [res, err] = pingPongClient.ping(context, req);
+		inflightErr = err;
+		inflightCount = res.pong.count;
+	});
+	std::this_thread::sleep_for(std::chrono::milliseconds(100));
+
+	auto err = ctx.server()->shutdown(std::chrono::milliseconds(3000));
+	inflight.join();
+	TEST_CHECK(!err);
+	TEST_CHECK(!inflightErr);
+	TEST_CHECK(inflightCount == 2);
+
+	// The replacement server takes the next call.
+	ctx.startServer();
+	{
+		scg::context::Context context;
+		pingpong::PingRequest req;
+		req.ping.count = 2;
+		auto [res, err] = pingPongClient.ping(context, req);
+		TEST_CHECK(!err);
+		TEST_CHECK(res.pong.count == 3);
+	}
+
+	// A call still in flight at the timeout is abandoned and reported.
+	inflight = std::thread([&]() {
+		scg::context::Context context;
+		context.put("sleep", "2000");
+		pingpong::PingRequest req;
+		auto [res, err] = pingPongClient.ping(context, req);
+		inflightErr = err;
+	});
+	std::this_thread::sleep_for(std::chrono::milliseconds(100));
+
+	err = ctx.server()->shutdown(std::chrono::milliseconds(200));
+	inflight.join();
+	TEST_CHECK(err != nullptr);
+	TEST_CHECK(err && err.message() == "shutdown abandoned 1 requests and 0 streams");
+	TEST_CHECK(scg::rpc::Status::fromError(inflightErr).code() == scg::rpc::Code::UNAVAILABLE);
+
+	// Without a timeout, shutdown closes immediately and reports no error.
+	ctx.startServer();
+	inflight = std::thread([&]() {
+		scg::context::Context context;
+		context.put("sleep", "500");
+		pingpong::PingRequest req;
+		auto [res, err] = pingPongClient.ping(context, req);
+		inflightErr = err;
+	});
+	std::this_thread::sleep_for(std::chrono::milliseconds(100));
+
+	err = ctx.server()->shutdown();
+	inflight.join();
+	TEST_CHECK(!err);
+	TEST_CHECK(scg::rpc::Status::fromError(inflightErr).code() == scg::rpc::Code::UNAVAILABLE);
+
+	client->disconnect();
+	printf("Graceful Shutdown Drain test passed\n");
+}
+
 // Test high concurrency with request/response verification
 inline void runConcurrencyTest(TestContext& ctx) {
 	printf("Running Concurrency test...\n");
@@ -1132,6 +1309,100 @@ inline void runContextTimeoutRecoveryTest(TestContext& ctx) {
 	printf("Context Timeout Recovery test passed\n");
 }
 
+// Test that the deadline of the caller is sent with the request, so that the
+// handler of a call the caller has given up on can stop early
+inline void runContextDeadlinePropagationTest(TestContext& ctx) {
+	if (ctx.isUsingExternalServer()) {
+		printf("Skipping Context Deadline Propagation test (using external server)\n");
+		return;
+	}
+
+	printf("Running Context Deadline Propagation test...\n");
+
+	auto impl = std::make_shared<PingPongServerDeadline>();
+	ctx.startServerWithSetup([impl](scg::rpc::Server* server) {
+		pingpong::registerPingPongServer(server, impl);
+	});
+
+	auto client = ctx.createClient();
+	pingpong::PingPongClient pingPongClient(client);
+
+	// A call without a deadline reaches the handler without one.
+	{
+		scg::context::Context context;
+		pingpong::PingRequest req;
+
+		auto [res, err] = pingPongClient.ping(context, req);
+		TEST_CHECK(err != nullptr);
+		TEST_CHECK(scg::rpc::Status::fromError(err).code() == scg::rpc::Code::FAILED_PRECONDITION);
+	}
+
+	{
+		scg::context::Context context;
+		context.setDeadline(std::chrono::system_clock::now() + std::chrono::milliseconds(200));
+		pingpong::PingRequest req;
+
+		auto start = std::chrono::steady_clock::now();
+		auto [res, err] = pingPongClient.ping(context, req);
+		TEST_CHECK(err != nullptr);
+
+		// The handler is released by the same deadline, not by its own timeout.
+		while (!impl->expired && std::chrono::steady_clock::now() - start < std::chrono::seconds(3)) {
+			std::this_thread::sleep_for(std::chrono::milliseconds(10));
+		}
+		TEST_CHECK(impl->expired);
+		TEST_CHECK(std::chrono::steady_clock::now() - start < std::chrono::seconds(2));
+	}
+
+	client->disconnect();
+	ctx.stopServer();
+	printf("Context Deadline Propagation test passed\n");
+}
+
+// Test that cancelling the context of a call cancels the context of its handler
+// on the server
+inline void runContextCancelPropagationTest(TestContext& ctx) {
+	if (ctx.isUsingExternalServer()) {
+		printf("Skipping Context Cancel Propagation test (using external server)\n");
+		return;
+	}
+
+	printf("Running Context Cancel Propagation test...\n");
+
+	auto impl = std::make_shared<PingPongServerBlocking>();
+	ctx.startServerWithSetup([impl](scg::rpc::Server* server) {
+		pingpong::registerPingPongServer(server, impl);
+	});
+
+	auto client = ctx.createClient();
+	pingpong::PingPongClient pingPongClient(client);
+
+	scg::context::Context context;
+	pingpong::PingRequest req;
+
+	// Copies of a context share its cancellation.
+	std::thread canceller([context]() mutable {
+		std::this_thread::sleep_for(std::chrono::milliseconds(200));
+		context.cancel();
+	});
+
+	auto start = std::chrono::steady_clock::now();
+	auto [res, err] = pingPongClient.ping(context, req);
+	canceller.join();
+	TEST_CHECK(err != nullptr);
+	TEST_CHECK(scg::rpc::Status::fromError(err).code() == scg::rpc::Code::CANCELED);
+
+	while (!impl->cancelled && std::chrono::steady_clock::now() - start < std::chrono::seconds(3)) {
+		std::this_thread::sleep_for(std::chrono::milliseconds(10));
+	}
+	TEST_CHECK(impl->cancelled);
+	TEST_CHECK(std::chrono::steady_clock::now() - start < std::chrono::seconds(2));
+
+	client->disconnect();
+	ctx.stopServer();
+	printf("Context Cancel Propagation test passed\n");
+}
+
 // Test multiple clients
 inline void runMultipleClientsTest(TestContext& ctx) {
 	printf("Running Multiple Clients test...\n");
@@ -2320,6 +2591,12 @@ inline void runTestSuite(const TestSuiteConfig& config) {
 			runServerErrorTest(ctx);
 		}
 
+		{
+			printf("\n=== Running Server Status Test ===\n");
+			TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
+			runServerStatusTest(ctx);
+		}
+
 		if (!config.skipGroupTests) {
 			{
 				printf("\n=== Running Server Groups Test ===\n");
@@ -2347,6 +2624,12 @@ inline void runTestSuite(const TestSuiteConfig& config) {
 				runGracefulShutdownTest(ctx);
 			}
 
+			{
+				printf("\n=== Running Graceful Shutdown Drain Test ===\n");
+				TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
+				runGracefulShutdownDrainTest(ctx);
+			}
+
 			{
 				printf("\n=== Running Large Payload Test ===\n");
 				TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
@@ -2371,6 +2654,18 @@ inline void runTestSuite(const TestSuiteConfig& config) {
 				runContextTimeoutRecoveryTest(ctx);
 			}
 
+			{
+				printf("\n=== Running Context Deadline Propagation Test ===\n");
+				TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
+				runContextDeadlinePropagationTest(ctx);
+			}
+
+			{
+				printf("\n=== Running Context Cancel Propagation Test ===\n");
+				TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
+				runContextCancelPropagationTest(ctx);
+			}
+
 			{
 				printf("\n=== Running Multiple Clients Test ===\n");
 				TestContext ctx(config.factory, id++, config.maxRetries, config.useExternalServer);
@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -355,6 +356,66 @@ func TestMalformedFrames(t *testing.T) {
 	}
 }
 
+// TestServerDecodeLimits: requests exceeding the decode limits of the server
+// are rejected, while the connection keeps serving requests within them, on
+// every transport.
+func TestServerDecodeLimits(t *testing.T) {
+	for i, p := range allProbes() {
+		p := p
+		port := 18840 + i
+		t.Run(p.name, func(t *testing.T) {
+			server := rpc.NewServer(rpc.ServerConfig{
+				Transport:  p.serverTransport(port),
+				ErrHandler: func(err error) {},
+				Limits: serialize.Limits{
+					MaxElements:     8,
+					MaxStringLength: 64,
+					MaxAllocation:   256,
+				},
+			})
+			pingpong.RegisterPingPongServer(server, &pingpongServer{})
+			go func() { server.ListenAndServe() }()
+			defer server.Shutdown(context.Background())
+			time.Sleep(150 * time.Millisecond)
+
+			client := rpc.NewClient(rpc.ClientConfig{Transport: p.clientTransport("127.0.0.1", port)})
+			defer client.Close()
+
+			pp := pingpong.NewPingPongClient(client)
+
+			_, err := pp.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{
+				Payload: pingpong.TestPayload{ValString: strings.Repeat("a", 65)},
+			}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "exceeds the limit of 64 bytes")
+
+			_, err = pp.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{
+				Payload: pingpong.TestPayload{ValListPayload: make([]pingpong.NestedPayload, 9)},
+			}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "exceeds the limit of 8 elements")
+
+			// within the limits of each value, but over the total budget
+			_, err = pp.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{
+				Payload: pingpong.TestPayload{
+					ValString:      strings.Repeat("a", 64),
+					ValByteArray:   make([]byte, 8),
+					ValListPayload: make([]pingpong.NestedPayload, 8),
+				},
+			}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "allocation budget")
+
+			resp, err := pp.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{
+				Count:   41,
+				Payload: pingpong.TestPayload{ValString: strings.Repeat("a", 64)},
+			}})
+			require.NoError(t, err)
+			require.Equal(t, int32(42), resp.Pong.Count)
+		})
+	}
+}
+
 // oversizedOpenFrame builds an OPEN whose context declares a ~1 GiB metadata
 // value but supplies none — exercising the pre-auth allocation guard over the
 // wire (server must reject, not allocate).
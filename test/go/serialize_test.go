@@ -2,13 +2,19 @@ package test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kbirk/scg/pkg/rpc"
 	"github.com/kbirk/scg/pkg/serialize"
 	"github.com/kbirk/scg/test/scg/generated/basic"
+	"github.com/kbirk/scg/test/scg/generated/pingpong"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSerializeContext(t *testing.T) {
@@ -210,3 +216,1208 @@ func TestSerializeComplicated(t *testing.T) {
 	assert.Equal(t, input.StructBArray, output.StructBArray)
 	assert.Equal(t, input.StructC, output.StructC)
 }
+
+func TestSerializeTaggedForwardCompatible(t *testing.T) {
+	input := pingpong.TaggedPayloadV2{
+		ID:    42,
+		Name:  "newer",
+		Score: 3.14,
+		Nested: []pingpong.NestedPayload{
+			{ValString: "a", ValDouble: 1.5},
+		},
+		Counts: map[string]int64{"x": -1, "y": 2},
+		Active: true,
+		Key:    uuid.New(),
+	}
+
+	// an older reader skips the fields it does not know about
+	var output pingpong.TaggedPayloadV1
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	assert.Equal(t, input.ID, output.ID)
+	assert.Equal(t, input.Name, output.Name)
+	assert.Equal(t, input.Nested, output.Nested)
+}
+
+func TestSerializeTaggedBackwardCompatible(t *testing.T) {
+	input := pingpong.TaggedPayloadV1{
+		ID:   7,
+		Name: "older",
+		Nested: []pingpong.NestedPayload{
+			{ValString: "b", ValDouble: 2.5},
+		},
+	}
+
+	// a newer reader leaves the fields missing from the payload zeroed
+	output := pingpong.TaggedPayloadV2{
+		Score:  1.0,
+		Active: true,
+	}
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	assert.Equal(t, input.ID, output.ID)
+	assert.Equal(t, input.Name, output.Name)
+	assert.Equal(t, input.Nested, output.Nested)
+	assert.Equal(t, float64(0), output.Score)
+	assert.False(t, output.Active)
+	assert.Nil(t, output.Counts)
+}
+
+func TestSerializeOptional(t *testing.T) {
+	count := uint32(0)
+	note := "a note"
+	updated := time.Now()
+
+	input := pingpong.OptionalPayload{
+		Count: &count,
+		Name:  "name",
+		Note:  &note,
+		Nested: &pingpong.NestedPayload{
+			ValString: "nested",
+			ValDouble: 1.5,
+		},
+		Updated: &updated,
+	}
+
+	var output pingpong.OptionalPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	// a present zero value is distinct from an absent one
+	require.NotNil(t, output.Count)
+	assert.Equal(t, uint32(0), *output.Count)
+	assert.Equal(t, input.Name, output.Name)
+	assert.Equal(t, note, *output.Note)
+	assert.Equal(t, *input.Nested, *output.Nested)
+	assert.True(t, updated.Equal(*output.Updated))
+
+	// absent fields decode as nil, clearing any previous value
+	input = pingpong.OptionalPayload{
+		Name: "other",
+	}
+	err = output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	assert.Nil(t, output.Count)
+	assert.Equal(t, input.Name, output.Name)
+	assert.Nil(t, output.Note)
+	assert.Nil(t, output.Nested)
+	assert.Nil(t, output.Updated)
+}
+
+func TestSerializeOptionalJSON(t *testing.T) {
+	count := uint32(0)
+
+	input := pingpong.OptionalPayload{
+		Count: &count,
+		Name:  "name",
+	}
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"count":0,"name":"name"}`, string(bs))
+
+	var output pingpong.OptionalPayload
+	err = output.FromJSON(bs)
+	require.NoError(t, err)
+
+	require.NotNil(t, output.Count)
+	assert.Equal(t, uint32(0), *output.Count)
+	assert.Nil(t, output.Note)
+}
+
+func TestSerializeTaggedOptional(t *testing.T) {
+	limit := uint32(0)
+
+	input := pingpong.TaggedPayloadV2{
+		ID:    1,
+		Limit: &limit,
+	}
+
+	var output pingpong.TaggedPayloadV2
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	require.NotNil(t, output.Limit)
+	assert.Equal(t, uint32(0), *output.Limit)
+	assert.Nil(t, output.Extra)
+
+	// absent optional fields are omitted from the payload entirely
+	input.Limit = nil
+	assert.Less(t, len(input.ToBytes()), len((&pingpong.TaggedPayloadV2{ID: 1, Limit: &limit}).ToBytes()))
+
+	err = output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Nil(t, output.Limit)
+}
+
+func TestSerializeOneof(t *testing.T) {
+	stamp := time.Now()
+
+	inputs := []pingpong.OneofPayload{
+		{
+			Name: "nested",
+			Value: &pingpong.OneofPayload_Value_Nested{
+				Nested: pingpong.NestedPayload{
+					ValString: "nested",
+					ValDouble: 1.5,
+				},
+			},
+			When:    &pingpong.OneofPayload_When_Stamp{Stamp: stamp},
+			Trailer: 7,
+		},
+		{
+			Name:    "count",
+			Value:   &pingpong.OneofPayload_Value_Count{Count: 0},
+			When:    &pingpong.OneofPayload_When_Label{Label: "label"},
+			Trailer: 8,
+		},
+		{
+			Name:    "tags",
+			Value:   &pingpong.OneofPayload_Value_Tags{Tags: []string{"a", "b"}},
+			Trailer: 9,
+		},
+		{
+			Name:    "unset",
+			Trailer: 10,
+		},
+	}
+
+	for _, input := range inputs {
+		var output pingpong.OneofPayload
+		err := output.FromBytes(input.ToBytes())
+		require.NoError(t, err)
+
+		assert.Equal(t, input.Name, output.Name)
+		assert.Equal(t, input.Value, output.Value)
+		assert.Equal(t, input.Trailer, output.Trailer)
+		if input.When == nil {
+			assert.Nil(t, output.When)
+		} else if when, ok := input.When.(*pingpong.OneofPayload_When_Stamp); ok {
+			require.IsType(t, &pingpong.OneofPayload_When_Stamp{}, output.When)
+			assert.True(t, when.Stamp.Equal(output.When.(*pingpong.OneofPayload_When_Stamp).Stamp))
+		} else {
+			assert.Equal(t, input.When, output.When)
+		}
+	}
+}
+
+func TestSerializeOneofRejectsUnknownDiscriminator(t *testing.T) {
+	// the discriminator of the value oneof directly follows the name
+	writer := serialize.NewWriter(16)
+	serialize.SerializeString(writer, "name")
+	serialize.SerializeUInt32(writer, 99)
+	serialize.SerializeUInt32(writer, 1)
+
+	var output pingpong.OneofPayload
+	err := output.FromBytes(writer.Bytes())
+	require.Error(t, err)
+}
+
+func TestSerializeOneofJSON(t *testing.T) {
+	input := pingpong.OneofPayload{
+		Name:  "count",
+		Value: &pingpong.OneofPayload_Value_Count{Count: 0},
+	}
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"count","value":{"count":0},"trailer":0}`, string(bs))
+
+	var output pingpong.OneofPayload
+	err = output.FromJSON(bs)
+	require.NoError(t, err)
+
+	assert.Equal(t, input.Value, output.Value)
+	assert.Nil(t, output.When)
+}
+
+func TestSerializeTaggedOneof(t *testing.T) {
+	inputs := []pingpong.TaggedOneofPayload{
+		{
+			ID: 1,
+			Value: &pingpong.TaggedOneofPayload_Value_Nested{
+				Nested: pingpong.NestedPayload{
+					ValString: "nested",
+				},
+			},
+		},
+		{
+			ID:    2,
+			Value: &pingpong.TaggedOneofPayload_Value_Text{Text: "text"},
+		},
+		{
+			ID: 3,
+			Value: &pingpong.TaggedOneofPayload_Value_Counts{
+				Counts: map[string]int64{"a": 1},
+			},
+		},
+		{
+			ID: 4,
+		},
+	}
+
+	for _, input := range inputs {
+		var output pingpong.TaggedOneofPayload
+		err := output.FromBytes(input.ToBytes())
+		require.NoError(t, err)
+		assert.Equal(t, input, output)
+	}
+}
+
+func TestSerializeRecursive(t *testing.T) {
+	// deserialized lists and maps are always non-nil
+	node := func(label string, children ...pingpong.TreeNode) pingpong.TreeNode {
+		return pingpong.TreeNode{
+			Label:    label,
+			Children: append([]pingpong.TreeNode{}, children...),
+			Named:    map[string]pingpong.TreeNode{},
+		}
+	}
+
+	c := node("c")
+	cNext := node("c.next")
+	c.Next = &cNext
+
+	input := node("root", node("a", node("a.a")), node("b"))
+	input.Named["c"] = c
+	next := node("next")
+	input.Next = &next
+
+	bs := input.ToBytes()
+
+	var output pingpong.TreeNode
+	err := output.FromBytes(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err = input.ToJSON()
+	require.NoError(t, err)
+
+	output = pingpong.TreeNode{}
+	err = output.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestSerializeMutuallyRecursive(t *testing.T) {
+	input := pingpong.Thread{
+		Topic: "topic",
+		Comments: []pingpong.Comment{
+			{
+				Text: "first",
+				Replies: pingpong.Thread{
+					Comments: []pingpong.Comment{{Text: "reply"}},
+				},
+			},
+		},
+	}
+
+	bs := input.ToBytes()
+
+	var output pingpong.Thread
+	err := output.FromBytes(bs)
+	require.NoError(t, err)
+
+	require.Len(t, output.Comments, 1)
+	assert.Equal(t, "first", output.Comments[0].Text)
+	require.Len(t, output.Comments[0].Replies.Comments, 1)
+	assert.Equal(t, "reply", output.Comments[0].Replies.Comments[0].Text)
+}
+
+func TestSerializeTaggedRecursive(t *testing.T) {
+	input := pingpong.TaggedTreeNode{
+		Value: 1,
+		Children: []pingpong.TaggedTreeNode{
+			{
+				Value:    2,
+				Children: []pingpong.TaggedTreeNode{},
+				Parent:   &pingpong.TaggedTreeNode{Value: 3, Children: []pingpong.TaggedTreeNode{}},
+			},
+		},
+	}
+
+	bs := input.ToBytes()
+
+	var output pingpong.TaggedTreeNode
+	err := output.FromBytes(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestSerializeRecursiveDepthLimit(t *testing.T) {
+	chain := func(length int) *pingpong.TreeNode {
+		root := &pingpong.TreeNode{}
+		node := root
+		for i := 1; i < length; i++ {
+			node.Next = &pingpong.TreeNode{}
+			node = node.Next
+		}
+		return root
+	}
+
+	var output pingpong.TreeNode
+	err := output.FromBytes(chain(serialize.MaxDepth).ToBytes())
+	require.NoError(t, err)
+
+	err = output.FromBytes(chain(serialize.MaxDepth + 1).ToBytes())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depth")
+
+	tagged := &pingpong.TaggedTreeNode{}
+	for i := 0; i < serialize.MaxDepth; i++ {
+		tagged = &pingpong.TaggedTreeNode{Children: []pingpong.TaggedTreeNode{*tagged}}
+	}
+
+	var taggedOutput pingpong.TaggedTreeNode
+	err = taggedOutput.FromBytes(tagged.ToBytes())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depth")
+}
+
+func TestDeserializeLimits(t *testing.T) {
+	tree := pingpong.TreeNode{
+		Label: "root",
+		Children: []pingpong.TreeNode{
+			{Label: "a", Next: &pingpong.TreeNode{Label: "b"}},
+		},
+		Named: map[string]pingpong.TreeNode{
+			"c": {Label: "c"},
+		},
+	}
+	bs := tree.ToBytes()
+
+	deserialize := func(limits serialize.Limits) error {
+		reader := serialize.NewReader(bs)
+		reader.SetLimits(limits)
+		var output pingpong.TreeNode
+		return output.Deserialize(reader)
+	}
+
+	require.NoError(t, deserialize(serialize.Limits{MaxDepth: 3, MaxElements: 1, MaxStringLength: 4}))
+
+	err := deserialize(serialize.Limits{MaxDepth: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "depth")
+
+	err = deserialize(serialize.Limits{MaxStringLength: 3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 3 bytes")
+
+	tree.Named["d"] = pingpong.TreeNode{}
+	bs = tree.ToBytes()
+	err = deserialize(serialize.Limits{MaxElements: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 1 elements")
+
+	err = deserialize(serialize.Limits{MaxAllocation: 64})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allocation budget")
+
+	// deltas are bounded by the same limits
+	baseline := pingpong.TreeNode{}
+	delta := tree.ToBytesDelta(&baseline)
+	reader := serialize.NewReader(delta)
+	reader.SetLimits(serialize.Limits{MaxElements: 1})
+	var output pingpong.TreeNode
+	err = output.DeserializeDelta(reader, &baseline)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit of 1 elements")
+}
+
+func TestSerializeNested(t *testing.T) {
+	line := pingpong.Order_Line{
+		Sku:      "sku",
+		Quantity: 2,
+		Status:   pingpong.Order_Status_OrderPending,
+	}
+	input := pingpong.Order{
+		Number: 7,
+		Status: pingpong.Order_Status_OrderShipped,
+		Lines:  []pingpong.Order_Line{line},
+	}
+
+	var output pingpong.Order
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	invoice := pingpong.Invoice{
+		Line: line,
+		Statuses: map[string]pingpong.Order_Status{
+			"a": pingpong.Order_Status_OrderShipped,
+		},
+	}
+
+	var invoiceOutput pingpong.Invoice
+	err = invoiceOutput.FromBytes(invoice.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, invoice, invoiceOutput)
+}
+
+func TestSerializeJSONName(t *testing.T) {
+	display := "Ada"
+	input := pingpong.Account{
+		EmailAddress: "ada@example.com",
+		NickName:     "ada",
+		DisplayName:  &display,
+	}
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"mail":"ada@example.com","nick_name":"ada","display":"Ada"}`, string(bs))
+
+	var output pingpong.Account
+	err = output.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestSerializeArray(t *testing.T) {
+	input := pingpong.ArrayPayload{
+		Pair: [2]pingpong.NestedPayload{
+			{ValString: "a", ValDouble: 1.5},
+			{ValString: "b", ValDouble: -2},
+		},
+		Points: [][3]int32{{1, 2, 3}, {-4, 5, -6}},
+		IDs: map[string][2]uuid.UUID{
+			"ids": {uuid.New(), uuid.New()},
+		},
+		Grid: [2][2]uint8{{1, 2}, {3, 4}},
+	}
+	for i := range input.Matrix {
+		input.Matrix[i] = float32(i) * 0.5
+	}
+	for i := range input.Hash {
+		input.Hash[i] = byte(i)
+	}
+
+	var output pingpong.ArrayPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+
+	var jsonOutput pingpong.ArrayPayload
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+}
+
+func TestSerializeArrayOmitsLength(t *testing.T) {
+	// the length of an array is known from the schema, so only the elements
+	// are written
+	input := pingpong.HashPayload{}
+	assert.Equal(t, 32*8, input.BitSize())
+	assert.Equal(t, 32, len(input.ToBytes()))
+}
+
+func TestSerializeTaggedArray(t *testing.T) {
+	input := pingpong.TaggedArrayPayload{
+		ID:  7,
+		Key: [4]byte{1, 2, 3, 4},
+	}
+
+	var output pingpong.TaggedArrayPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestSerializeBytes(t *testing.T) {
+	thumbnail := []byte{0xff, 0xd8}
+	input := pingpong.BytesPayload{
+		Data:      []byte("hello, world"),
+		Thumbnail: &thumbnail,
+		Chunks:    [][]byte{{1, 2, 3}, {}},
+		Blobs: map[string][]byte{
+			"a": {0x00, 0x7f, 0x80},
+		},
+	}
+
+	var output pingpong.BytesPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), `"data":"aGVsbG8sIHdvcmxk"`)
+
+	var jsonOutput pingpong.BytesPayload
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+}
+
+func TestSerializeBytesMatchesByteList(t *testing.T) {
+	// bytes share the encoding of list<byte>, so a field can be migrated
+	// without breaking existing data
+	data := []byte{1, 2, 3, 4, 5}
+	list := pingpong.ByteListPayload{Data: data}
+	blob := pingpong.BlobPayload{Data: data}
+	assert.Equal(t, list.ToBytes(), blob.ToBytes())
+
+	var output pingpong.BlobPayload
+	err := output.FromBytes(list.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, blob, output)
+}
+
+func TestSerializeSet(t *testing.T) {
+	input := pingpong.SetPayload{
+		Tags: map[string]struct{}{"a": {}, "b": {}},
+		IDs:  map[uuid.UUID]struct{}{uuid.New(): {}},
+		Keys: map[pingpong.KeyType]struct{}{"key": {}},
+		Enums: map[pingpong.EnumType]struct{}{
+			pingpong.EnumType_EnumType2: {},
+		},
+		Groups: []map[int32]struct{}{{1: {}, -2: {}}, {}},
+		Index: map[string]map[uint64]struct{}{
+			"x": {7: {}},
+		},
+	}
+
+	var output pingpong.SetPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+
+	var jsonOutput pingpong.SetPayload
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+}
+
+func TestSerializeSetJSON(t *testing.T) {
+	// sets are marshalled as arrays of their elements, as in C++
+	input := pingpong.StringSetPayload{
+		Values: map[string]struct{}{"a": {}},
+	}
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"values":["a"]}`, string(bs))
+
+	var output pingpong.SetPayload
+	err = output.FromJSON([]byte(`{"tags":["a","b"],"ids":[],"keys":["key"],"enums":[1],"groups":[[1,-2],[]],"index":{"x":[7]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, pingpong.SetPayload{
+		Tags:   map[string]struct{}{"a": {}, "b": {}},
+		IDs:    map[uuid.UUID]struct{}{},
+		Keys:   map[pingpong.KeyType]struct{}{"key": {}},
+		Enums:  map[pingpong.EnumType]struct{}{pingpong.EnumType_EnumType2: {}},
+		Groups: []map[int32]struct{}{{1: {}, -2: {}}, {}},
+		Index: map[string]map[uint64]struct{}{
+			"x": {7: {}},
+		},
+	}, output)
+}
+
+func TestSerializeSetRejectsDuplicates(t *testing.T) {
+	// a set is encoded as a list of its elements
+	list := pingpong.StringListPayload{
+		Values: []string{"a", "b"},
+	}
+
+	var set pingpong.StringSetPayload
+	err := set.FromBytes(list.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"a": {}, "b": {}}, set.Values)
+
+	list.Values = append(list.Values, "a")
+	err = set.FromBytes(list.ToBytes())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate")
+}
+
+func TestSerializeDuration(t *testing.T) {
+	ttl := 90 * time.Minute
+	input := pingpong.DurationPayload{
+		Timeout:   1500 * time.Millisecond,
+		Ttl:       &ttl,
+		Backoff:   []time.Duration{100 * time.Millisecond, -time.Second, 0},
+		Deadlines: map[string]time.Duration{"read": 250 * time.Microsecond},
+		Windows:   [2][]time.Duration{{time.Hour}, {}},
+		Limit:     &pingpong.DurationPayload_Limit_Interval{Interval: time.Nanosecond},
+	}
+
+	var output pingpong.DurationPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), `"timeout":"1.5s"`)
+	assert.Contains(t, string(bs), `"ttl":"1h30m0s"`)
+	assert.Contains(t, string(bs), `"backoff":["100ms","-1s","0s"]`)
+	assert.Contains(t, string(bs), `"limit":{"interval":"1ns"}`)
+
+	var jsonOutput pingpong.DurationPayload
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+
+	input.Ttl = nil
+	input.Limit = &pingpong.DurationPayload_Limit_Schedule{Schedule: []time.Duration{time.Second, 2 * time.Second}}
+	bs, err = input.ToJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(bs), `"ttl"`)
+
+	jsonOutput = pingpong.DurationPayload{}
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+
+	err = jsonOutput.FromJSON([]byte(`{"timeout":"1.5 seconds"}`))
+	assert.Error(t, err)
+}
+
+func TestSerializeDurationMatchesInt64(t *testing.T) {
+	// a duration is encoded as an int64 of nanoseconds
+	duration := pingpong.TaggedDurationPayload{
+		Timeout: -3 * time.Second,
+	}
+	integer := pingpong.Int64Payload{
+		Value: int64(-3 * time.Second),
+	}
+	untagged := pingpong.DurationPayload{
+		Timeout: -3 * time.Second,
+	}
+	assert.Equal(t, integer.ToBytes(), untagged.ToBytes()[:len(integer.ToBytes())])
+
+	var output pingpong.TaggedDurationPayload
+	err := output.FromBytes(duration.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, duration, output)
+
+	// integer nanoseconds are accepted when reading JSON
+	err = output.FromJSON([]byte(`{"timeout":2000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, output.Timeout)
+}
+
+func TestSerializeBitInt(t *testing.T) {
+	toggle := uint8(1)
+	input := pingpong.BitIntPayload{
+		Flags:   31,
+		Delta:   -2048,
+		Toggle:  &toggle,
+		Steps:   []int8{-4, 3, 0, -1},
+		Wide:    map[string]uint64{"max": math.MaxUint64},
+		Nibbles: [2]uint8{15, 7},
+		Full:    math.MinInt64,
+	}
+
+	var output pingpong.BitIntPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	bs, err := input.ToJSON()
+	require.NoError(t, err)
+
+	var jsonOutput pingpong.BitIntPayload
+	err = jsonOutput.FromJSON(bs)
+	require.NoError(t, err)
+	assert.Equal(t, input, jsonOutput)
+
+	// fields are written with exactly their declared number of bits
+	unset := pingpong.BitIntPayload{}
+	set := pingpong.BitIntPayload{Toggle: &toggle}
+	assert.Equal(t, unset.BitSize()+1, set.BitSize())
+	oneStep := pingpong.BitIntPayload{Steps: []int8{1}}
+	twoSteps := pingpong.BitIntPayload{Steps: []int8{1, 2}}
+	assert.Equal(t, oneStep.BitSize()+3, twoSteps.BitSize())
+
+	// values that do not fit are rejected when encoding
+	assert.Panics(t, func() {
+		(&pingpong.BitIntPayload{Flags: 32}).ToBytes()
+	})
+	assert.Panics(t, func() {
+		(&pingpong.BitIntPayload{Delta: 2048}).ToBytes()
+	})
+	assert.Panics(t, func() {
+		(&pingpong.BitIntPayload{Steps: []int8{-5}}).ToBytes()
+	})
+
+	var tagged pingpong.TaggedBitIntPayload
+	delta := int16(-7)
+	taggedInput := pingpong.TaggedBitIntPayload{Flags: 17, Delta: &delta}
+	err = tagged.FromBytes(taggedInput.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, taggedInput, tagged)
+}
+
+func TestSerializeQuant(t *testing.T) {
+	pitch := float32(0.25)
+	input := pingpong.QuantPayload{
+		X:        12.5,
+		Distance: 999.999,
+		Pitch:    &pitch,
+		Samples:  []float32{-0.5, 0.1, 0.5},
+		Weights:  map[string]float32{"a": 0.3},
+		Angles:   [3]float32{0, 90, 359.9},
+	}
+
+	var output pingpong.QuantPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+
+	assert.InDelta(t, input.X, output.X, pingpong.QuantPayload_XPrecision/2)
+	assert.InDelta(t, input.Distance, output.Distance, pingpong.QuantPayload_DistancePrecision/2)
+	assert.InDelta(t, *input.Pitch, *output.Pitch, pingpong.QuantPayload_PitchPrecision/2)
+	require.Len(t, output.Samples, 3)
+	for i := range input.Samples {
+		assert.InDelta(t, input.Samples[i], output.Samples[i], pingpong.QuantPayload_SamplesPrecision/2)
+	}
+	assert.InDelta(t, input.Weights["a"], output.Weights["a"], pingpong.QuantPayload_WeightsPrecision/2)
+	for i := range input.Angles {
+		assert.InDelta(t, input.Angles[i], output.Angles[i], pingpong.QuantPayload_AnglesPrecision/2)
+	}
+
+	// decoded values are stable
+	var again pingpong.QuantPayload
+	err = again.FromBytes(output.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, output, again)
+
+	// fields are written with exactly their declared number of bits
+	oneSample := pingpong.QuantPayload{Samples: []float32{0}}
+	twoSamples := pingpong.QuantPayload{Samples: []float32{0, 0}}
+	assert.Equal(t, oneSample.BitSize()+10, twoSamples.BitSize())
+
+	// values outside of the range are clamped
+	clamped := pingpong.QuantPayload{X: 1000, Distance: -1}
+	output = pingpong.QuantPayload{}
+	err = output.FromBytes(clamped.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, float32(100), output.X)
+	assert.Equal(t, float64(0), output.Distance)
+
+	tagged := pingpong.TaggedQuantPayload{X: -42, Pitch: &pitch}
+	var taggedOutput pingpong.TaggedQuantPayload
+	err = taggedOutput.FromBytes(tagged.ToBytes())
+	require.NoError(t, err)
+	assert.InDelta(t, tagged.X, taggedOutput.X, pingpong.TaggedQuantPayload_XPrecision/2)
+	require.NotNil(t, taggedOutput.Pitch)
+	assert.InDelta(t, *tagged.Pitch, *taggedOutput.Pitch, pingpong.TaggedQuantPayload_PitchPrecision/2)
+}
+
+func TestSerializeDeltaUnchanged(t *testing.T) {
+	baseline := pingpong.TestPayload{
+		ValUint32:    42,
+		ValString:    "unchanged",
+		ValTimestamp: time.Now(),
+		ValUUID:      uuid.New(),
+		ValListPayload: []pingpong.NestedPayload{
+			{ValString: "a", ValDouble: 1},
+		},
+		ValMapKeyEnum: map[pingpong.KeyType]pingpong.EnumType{
+			"key": pingpong.EnumType_EnumType2,
+		},
+		ValByteArray: []byte{1, 2, 3},
+	}
+	input := baseline
+
+	// each unchanged field costs a single bit
+	assert.Equal(t, 21, input.BitSizeDelta(&baseline))
+	bs := input.ToBytesDelta(&baseline)
+	assert.Equal(t, 3, len(bs))
+
+	var output pingpong.TestPayload
+	err := output.FromBytesDelta(bs, &baseline)
+	require.NoError(t, err)
+	assert.True(t, output.Equal(&input))
+}
+
+func TestSerializeDelta(t *testing.T) {
+	baseline := pingpong.TestPayload{
+		ValUint32: 42,
+		ValString: "baseline",
+		ValListPayload: []pingpong.NestedPayload{
+			{ValString: "a", ValDouble: 1},
+			{ValString: "b", ValDouble: 2},
+			{ValString: "c", ValDouble: 3},
+		},
+		ValMapKeyEnum: map[pingpong.KeyType]pingpong.EnumType{
+			"kept":    pingpong.EnumType_EnumType1,
+			"changed": pingpong.EnumType_EnumType1,
+			"removed": pingpong.EnumType_EnumType1,
+		},
+		ValByteArray: []byte{1, 2, 3},
+	}
+
+	input := pingpong.TestPayload{
+		ValUint32: 43,
+		ValString: "baseline",
+		ValListPayload: []pingpong.NestedPayload{
+			{ValString: "a", ValDouble: 1},
+			{ValString: "b", ValDouble: 2.5},
+			{ValString: "c", ValDouble: 3},
+			{ValString: "d", ValDouble: 4},
+		},
+		ValMapKeyEnum: map[pingpong.KeyType]pingpong.EnumType{
+			"kept":    pingpong.EnumType_EnumType1,
+			"changed": pingpong.EnumType_EnumType3,
+			"added":   pingpong.EnumType_EnumType2,
+		},
+		ValByteArray: []byte{1, 2, 3},
+	}
+
+	bs := input.ToBytesDelta(&baseline)
+	assert.Less(t, len(bs), len(input.ToBytes()))
+
+	var output pingpong.TestPayload
+	err := output.FromBytesDelta(bs, &baseline)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	// shrinking a list only writes the new length and the kept elements
+	input.ValListPayload = input.ValListPayload[:1]
+	output = pingpong.TestPayload{}
+	err = output.FromBytesDelta(input.ToBytesDelta(&baseline), &baseline)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	// the baseline is left untouched
+	assert.Equal(t, 3, len(baseline.ValListPayload))
+	assert.Equal(t, 2.0, baseline.ValListPayload[1].ValDouble)
+	assert.Equal(t, pingpong.EnumType_EnumType1, baseline.ValMapKeyEnum["changed"])
+}
+
+func TestSerializeDeltaNilBaseline(t *testing.T) {
+	// a missing baseline is treated as an empty message
+	input := pingpong.OptionalPayload{
+		Name: "name",
+		Nested: &pingpong.NestedPayload{
+			ValString: "nested",
+		},
+	}
+
+	var output pingpong.OptionalPayload
+	err := output.FromBytesDelta(input.ToBytesDelta(nil), nil)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	err = output.FromBytesDelta(input.ToBytesDelta(&pingpong.OptionalPayload{}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestSerializeDeltaOptional(t *testing.T) {
+	count := uint32(1)
+	note := "note"
+	baseline := pingpong.OptionalPayload{
+		Count: &count,
+		Name:  "name",
+		Nested: &pingpong.NestedPayload{
+			ValString: "nested",
+			ValDouble: 1,
+		},
+	}
+
+	updated := uint32(2)
+	inputs := []pingpong.OptionalPayload{
+		// cleared, set and changed
+		{
+			Name: "name",
+			Note: &note,
+			Nested: &pingpong.NestedPayload{
+				ValString: "nested",
+				ValDouble: 2,
+			},
+		},
+		{
+			Count: &updated,
+			Name:  "name",
+		},
+		baseline,
+	}
+
+	for _, input := range inputs {
+		var output pingpong.OptionalPayload
+		err := output.FromBytesDelta(input.ToBytesDelta(&baseline), &baseline)
+		require.NoError(t, err)
+		assert.True(t, output.Equal(&input))
+		assert.Equal(t, input, output)
+	}
+}
+
+func TestSerializeDeltaOneof(t *testing.T) {
+	baseline := pingpong.OneofPayload{
+		Name: "name",
+		Value: &pingpong.OneofPayload_Value_Nested{
+			Nested: pingpong.NestedPayload{ValString: "nested", ValDouble: 1},
+		},
+		When: &pingpong.OneofPayload_When_Label{Label: "label"},
+	}
+
+	inputs := []pingpong.OneofPayload{
+		// the same member, changed
+		{
+			Name: "name",
+			Value: &pingpong.OneofPayload_Value_Nested{
+				Nested: pingpong.NestedPayload{ValString: "nested", ValDouble: 2},
+			},
+			When: &pingpong.OneofPayload_When_Label{Label: "label"},
+		},
+		// another member
+		{
+			Name:  "name",
+			Value: &pingpong.OneofPayload_Value_Tags{Tags: []string{"a", "b"}},
+			When:  &pingpong.OneofPayload_When_Label{Label: "label"},
+		},
+		// unset
+		{
+			Name: "name",
+		},
+	}
+
+	for _, input := range inputs {
+		var output pingpong.OneofPayload
+		err := output.FromBytesDelta(input.ToBytesDelta(&baseline), &baseline)
+		require.NoError(t, err)
+		assert.True(t, output.Equal(&input))
+		assert.Equal(t, input, output)
+	}
+}
+
+func TestSerializeDeltaRecursive(t *testing.T) {
+	node := func(label string, children ...pingpong.TreeNode) pingpong.TreeNode {
+		return pingpong.TreeNode{
+			Label:    label,
+			Children: append([]pingpong.TreeNode{}, children...),
+			Named:    map[string]pingpong.TreeNode{},
+		}
+	}
+
+	baseline := node("root", node("a", node("a.a")), node("b"))
+	baseline.Named["c"] = node("c", node("c.a"))
+	next := node("next")
+	baseline.Next = &next
+
+	input := node("root", node("a", node("a.a", node("a.a.a"))), node("b"))
+	input.Named["c"] = node("c", node("c.b"))
+	input.Named["d"] = node("d")
+	changedNext := node("next", node("next.a"))
+	input.Next = &changedNext
+
+	bs := input.ToBytesDelta(&baseline)
+	assert.Less(t, len(bs), len(input.ToBytes()))
+
+	var output pingpong.TreeNode
+	err := output.FromBytesDelta(bs, &baseline)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+	assert.True(t, output.Equal(&input))
+	assert.False(t, output.Equal(&baseline))
+}
+
+func TestSerializeDeltaInPlace(t *testing.T) {
+	// a delta may be applied to the baseline itself
+	baseline := pingpong.ArrayPayload{
+		Pair: [2]pingpong.NestedPayload{
+			{ValString: "a"},
+			{ValString: "b"},
+		},
+		Points: [][3]int32{{1, 2, 3}},
+		IDs: map[string][2]uuid.UUID{
+			"ids": {uuid.New(), uuid.New()},
+		},
+	}
+
+	input := baseline
+	input.Pair[1].ValDouble = 2
+	input.Points = [][3]int32{{1, 2, 4}, {5, 6, 7}}
+	input.IDs = map[string][2]uuid.UUID{
+		"ids": {baseline.IDs["ids"][0], uuid.New()},
+	}
+	input.Grid[1][0] = 9
+
+	bs := input.ToBytesDelta(&baseline)
+	err := baseline.FromBytesDelta(bs, &baseline)
+	require.NoError(t, err)
+	assert.Equal(t, input, baseline)
+}
+
+func TestSerializeDeltaSet(t *testing.T) {
+	baseline := pingpong.SetPayload{
+		Tags:   map[string]struct{}{"a": {}, "b": {}},
+		Groups: []map[int32]struct{}{{1: {}}},
+	}
+
+	input := pingpong.SetPayload{
+		Tags:   map[string]struct{}{"b": {}, "c": {}},
+		Groups: []map[int32]struct{}{{1: {}, 2: {}}, {3: {}}},
+	}
+
+	var output pingpong.SetPayload
+	err := output.FromBytesDelta(input.ToBytesDelta(&baseline), &baseline)
+	require.NoError(t, err)
+	assert.True(t, output.Equal(&input))
+	assert.Equal(t, input.Tags, output.Tags)
+	assert.Equal(t, input.Groups, output.Groups)
+}
+
+func TestSerializeDeltaTruncated(t *testing.T) {
+	baseline := pingpong.TestPayload{
+		ValString: "baseline",
+	}
+	input := pingpong.TestPayload{
+		ValString: "input",
+		ValListPayload: []pingpong.NestedPayload{
+			{ValString: "a"},
+		},
+	}
+
+	bs := input.ToBytesDelta(&baseline)
+	for i := 0; i < len(bs); i++ {
+		var output pingpong.TestPayload
+		err := output.FromBytesDelta(bs[:i], &baseline)
+		assert.Error(t, err)
+	}
+}
+
+func TestSerializeDeterministic(t *testing.T) {
+	input := pingpong.DeterministicPayload{
+		Counts: map[string]uint32{"c": 3, "a": 1, "b": 2, "aa": 4},
+		Entities: map[pingpong.EntityID]struct{}{
+			pingpong.EntityID(uuid.MustParse("f0000000-0000-4000-8000-000000000001")): {},
+			pingpong.EntityID(uuid.MustParse("00000000-0000-4000-8000-000000000002")): {},
+			pingpong.EntityID(uuid.MustParse("0f000000-0000-4000-8000-000000000003")): {},
+		},
+		Enums: map[pingpong.EnumType]struct{}{
+			pingpong.EnumType_EnumType3: {},
+			pingpong.EnumType_EnumType1: {},
+		},
+		Groups: map[pingpong.KeyType]map[int32]struct{}{
+			"y": {3: {}, -1: {}},
+			"x": {10: {}, 0: {}, -7: {}},
+		},
+		Weights: map[uuid.UUID]float64{
+			uuid.MustParse("80000000-0000-4000-8000-000000000000"): 0.5,
+			uuid.MustParse("01000000-0000-4000-8000-000000000000"): -2,
+		},
+	}
+
+	serializeDeterministic := func(msg *pingpong.DeterministicPayload) []byte {
+		writer := serialize.NewWriter(serialize.BitsToBytes(msg.BitSize()))
+		writer.SetDeterministic(true)
+		msg.Serialize(writer)
+		return writer.Bytes()
+	}
+
+	expected := serializeDeterministic(&input)
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, expected, serializeDeterministic(&input))
+	}
+
+	var output pingpong.DeterministicPayload
+	err := output.FromBytes(expected)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+	assert.Equal(t, expected, serializeDeterministic(&output))
+
+	// matches the bytes written by the C++ test_serialize_deterministic
+	assert.Equal(t, "090c103640016161090c2056c00063071c00000000000000040008000000000020f0000000000000040008000000000030000f00000000000400080000000000105080020a1800ef801ba08201f20a3880030a08000000000000020004000000000000000600000000000000040000000000020004000000000000f80107000000000000", hex.EncodeToString(expected))
+}
+
+func TestSerializeDeltaDeterministic(t *testing.T) {
+	baseline := pingpong.DeterministicPayload{
+		Counts: map[string]uint32{"a": 1, "b": 2, "c": 3, "d": 4},
+		Enums: map[pingpong.EnumType]struct{}{
+			pingpong.EnumType_EnumType1: {},
+			pingpong.EnumType_EnumType2: {},
+		},
+	}
+	input := pingpong.DeterministicPayload{
+		Counts: map[string]uint32{"a": 1, "b": 20, "e": 5, "f": 6},
+		Enums: map[pingpong.EnumType]struct{}{
+			pingpong.EnumType_EnumType3: {},
+		},
+	}
+
+	serializeDeterministic := func() []byte {
+		writer := serialize.NewWriter(serialize.BitsToBytes(input.BitSizeDelta(&baseline)))
+		writer.SetDeterministic(true)
+		input.SerializeDelta(writer, &baseline)
+		return writer.Bytes()
+	}
+
+	expected := serializeDeterministic()
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, expected, serializeDeterministic())
+	}
+
+	var output pingpong.DeterministicPayload
+	err := output.FromBytesDelta(expected, &baseline)
+	require.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	// matches the bytes written by the C++ test_serialize_deterministic
+	assert.Equal(t, "0b18606c00b20306104b6180b20506306bc0020c30400100", hex.EncodeToString(expected))
+}
+
+func TestSerializeSum256(t *testing.T) {
+	input := pingpong.DeterministicPayload{
+		Counts: map[string]uint32{"c": 3, "a": 1, "b": 2, "aa": 4},
+		Entities: map[pingpong.EntityID]struct{}{
+			pingpong.EntityID(uuid.MustParse("f0000000-0000-4000-8000-000000000001")): {},
+			pingpong.EntityID(uuid.MustParse("00000000-0000-4000-8000-000000000002")): {},
+			pingpong.EntityID(uuid.MustParse("0f000000-0000-4000-8000-000000000003")): {},
+		},
+		Enums: map[pingpong.EnumType]struct{}{
+			pingpong.EnumType_EnumType3: {},
+			pingpong.EnumType_EnumType1: {},
+		},
+		Groups: map[pingpong.KeyType]map[int32]struct{}{
+			"y": {3: {}, -1: {}},
+			"x": {10: {}, 0: {}, -7: {}},
+		},
+		Weights: map[uuid.UUID]float64{
+			uuid.MustParse("80000000-0000-4000-8000-000000000000"): 0.5,
+			uuid.MustParse("01000000-0000-4000-8000-000000000000"): -2,
+		},
+	}
+
+	// the digest is that of the deterministic encoding
+	writer := serialize.NewWriter(serialize.BitsToBytes(input.BitSize()))
+	writer.SetDeterministic(true)
+	input.Serialize(writer)
+	expected := sha256.Sum256(writer.Bytes())
+
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, expected, input.Sum256())
+	}
+
+	var output pingpong.DeterministicPayload
+	err := output.FromBytes(input.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, expected, output.Sum256())
+
+	output.Counts["a"] = 10
+	assert.NotEqual(t, expected, output.Sum256())
+
+	// matches the digest computed by the C++ test_serialize_sum256
+	assert.Equal(t, "568c760a191422c635a7f1f6952fe31c91929159462b9b4a559adba85bf301ff", hex.EncodeToString(expected[:]))
+
+	// large byte fields are streamed through the hasher
+	payload := pingpong.BytesPayload{
+		Data: make([]byte, 4096),
+	}
+	for i := range payload.Data {
+		payload.Data[i] = byte(i)
+	}
+	hasher := sha256.New()
+	payload.HashTo(hasher)
+	assert.Equal(t, sha256.Sum256(payload.ToBytes()), payload.Sum256())
+	assert.Equal(t, payload.Sum256(), [32]byte(hasher.Sum(nil)))
+}
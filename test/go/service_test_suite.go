@@ -2,6 +2,7 @@ package test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
@@ -94,6 +95,10 @@ func RunTestSuite(t *testing.T, config TestSuiteConfig) {
 			runPingPongFailTest(t, config.Factory, port)
 		})
 
+		t.Run("PingPongStatus", func(t *testing.T) {
+			runPingPongStatusTest(t, config.Factory, port)
+		})
+
 		if !config.SkipGroupTests {
 			t.Run("ServerGroupsMiddleware", func(t *testing.T) {
 				runServerGroupsMiddlewareTest(t, config.Factory, port)
@@ -123,6 +128,18 @@ func RunTestSuite(t *testing.T, config TestSuiteConfig) {
 				runGracefulShutdownTest(t, config.Factory, port)
 			})
 
+			t.Run("GracefulShutdownDrain", func(t *testing.T) {
+				runGracefulShutdownDrainTest(t, config.Factory, port)
+			})
+
+			t.Run("LoadBalancing", func(t *testing.T) {
+				runLoadBalancingTest(t, config.Factory, port)
+			})
+
+			t.Run("Retry", func(t *testing.T) {
+				runRetryTest(t, config.Factory, port)
+			})
+
 			t.Run("LargePayload", func(t *testing.T) {
 				sizes := config.LargePayloadSizes
 				if sizes == nil {
@@ -151,6 +168,14 @@ func RunTestSuite(t *testing.T, config TestSuiteConfig) {
 				runContextTimeoutRecoveryTest(t, config.Factory, port)
 			})
 
+			t.Run("ContextDeadlinePropagation", func(t *testing.T) {
+				runContextDeadlinePropagationTest(t, config.Factory, port)
+			})
+
+			t.Run("ContextCancelPropagation", func(t *testing.T) {
+				runContextCancelPropagationTest(t, config.Factory, port)
+			})
+
 			t.Run("ContextMetadata", func(t *testing.T) {
 				runContextMetadataTest(t, config.Factory, port)
 			})
@@ -643,6 +668,55 @@ func runPingPongFailTest(t *testing.T, factory TransportFactory, id int) {
 	})
 	assert.Error(t, err)
 	assert.Equal(t, "unable to ping the pong", err.Error())
+	assert.Equal(t, rpc.CodeUnknown, rpc.CodeOf(err))
+
+	err = server.Shutdown(context.Background())
+	require.NoError(t, err)
+}
+
+// runPingPongStatusTest tests that a status returned by a handler reaches the
+// client with its code and details
+func runPingPongStatusTest(t *testing.T, factory TransportFactory, id int) {
+	server := rpc.NewServer(rpc.ServerConfig{
+		Transport: factory.CreateServerTransport(id),
+		ErrHandler: func(err error) {
+			require.NoError(t, err)
+		},
+	})
+	pingpong.RegisterPingPongServer(server, &pingpongServerStatus{})
+
+	go func() {
+		server.ListenAndServe()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := rpc.NewClient(rpc.ClientConfig{
+		Transport: factory.CreateClientTransport(id),
+		ErrHandler: func(err error) {
+			require.NoError(t, err)
+		},
+	})
+
+	c := pingpong.NewPingPongClient(client)
+
+	_, err := c.Ping(context.Background(), &pingpong.PingRequest{
+		Ping: pingpong.Ping{
+			Count: 7,
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "no pong to ping", err.Error())
+	assert.Equal(t, rpc.CodeNotFound, rpc.CodeOf(err))
+
+	var st *rpc.Status
+	require.True(t, errors.As(err, &st))
+	assert.Equal(t, rpc.CodeNotFound, st.Code)
+
+	pong := &pingpong.Pong{}
+	require.True(t, st.Detail(pong))
+	assert.Equal(t, int32(7), pong.Count)
+	assert.False(t, st.Detail(&pingpong.Ping{}))
 
 	err = server.Shutdown(context.Background())
 	require.NoError(t, err)
@@ -969,6 +1043,209 @@ func runGracefulShutdownTest(t *testing.T, factory TransportFactory, id int) {
 	assert.Equal(t, 10, success+errors, "All requests should complete")
 }
 
+// runGracefulShutdownDrainTest verifies a rolling restart: shutdown lets an
+// in-flight call complete while the GOAWAY moves the next call to the server
+// that replaced it, and calls still in flight at the deadline are abandoned.
+func runGracefulShutdownDrainTest(t *testing.T, factory TransportFactory, port int) {
+	startServer := func() *rpc.Server {
+		server := rpc.NewServer(rpc.ServerConfig{
+			Transport: factory.CreateServerTransport(port),
+		})
+		pingpong.RegisterPingPongServer(server, &pingpongServer{})
+		go server.ListenAndServe()
+		time.Sleep(100 * time.Millisecond)
+		return server
+	}
+
+	sleepCtx := func(ms string) context.Context {
+		md := rpc.NewMetadata()
+		md.PutString("sleep", ms)
+		return rpc.NewContextWithMetadata(context.Background(), md)
+	}
+
+	server1 := startServer()
+
+	client := rpc.NewClient(rpc.ClientConfig{
+		Transport: factory.CreateClientTransport(port),
+	})
+	defer client.Close()
+
+	c := pingpong.NewPingPongClient(client)
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := c.Ping(sleepCtx("500"), &pingpong.PingRequest{Ping: pingpong.Ping{Count: 1}})
+		inflight <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		shutdown <- server1.Shutdown(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// The replacement server takes the next call while the first one drains.
+	server2 := startServer()
+	defer server2.Shutdown(context.Background())
+
+	resp, err := c.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{Count: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), resp.Pong.Count)
+
+	select {
+	case err := <-inflight:
+		assert.NoError(t, err, "the in-flight call should complete during the drain")
+	case <-time.After(3 * time.Second):
+		t.Fatal("in-flight call did not complete")
+	}
+	select {
+	case err := <-shutdown:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("shutdown did not return once drained")
+	}
+
+	// A call still in flight at the deadline is abandoned and reported.
+	go func() {
+		_, err := c.Ping(sleepCtx("2000"), &pingpong.PingRequest{})
+		inflight <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = server2.Shutdown(ctx)
+
+	var shutdownErr *rpc.ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.Equal(t, 1, shutdownErr.Requests)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case err := <-inflight:
+		assert.Equal(t, rpc.CodeUnavailable, rpc.CodeOf(err))
+	case <-time.After(time.Second):
+		t.Fatal("abandoned call did not fail when the connection closed")
+	}
+}
+
+// runLoadBalancingTest verifies that a balanced client spreads calls across its
+// backends, ejects a backend that goes down and adds it back once it is up.
+func runLoadBalancingTest(t *testing.T, factory TransportFactory, port int) {
+	var counts [2]atomic.Int32
+	startServer := func(i int) *rpc.Server {
+		server := rpc.NewServer(rpc.ServerConfig{
+			Transport: factory.CreateServerTransport(port + i),
+		})
+		server.Middleware(func(ctx context.Context, req rpc.Message, next rpc.Handler) (rpc.Message, error) {
+			counts[i].Add(1)
+			return next(ctx, req)
+		})
+		pingpong.RegisterPingPongServer(server, &pingpongServer{})
+		go server.ListenAndServe()
+		time.Sleep(100 * time.Millisecond)
+		return server
+	}
+
+	serverA := startServer(0)
+	defer serverA.Shutdown(context.Background())
+	serverB := startServer(1)
+
+	// The ports of the addresses are the ids given to the transport factory.
+	client, err := rpc.NewBalancedClient(rpc.BalancerConfig{
+		Resolver: rpc.NewStaticResolver(
+			rpc.Address{Host: "localhost", Port: port},
+			rpc.Address{Host: "localhost", Port: port + 1}),
+		NewTransport: func(addr rpc.Address) rpc.ClientTransport {
+			return factory.CreateClientTransport(addr.Port)
+		},
+		Picker:              rpc.NewLeastOutstandingPicker(),
+		HealthCheckInterval: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	c := pingpong.NewPingPongClient(client)
+	ping := func() error {
+		_, err := c.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{Count: 1}})
+		return err
+	}
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, ping())
+	}
+	assert.Equal(t, int32(5), counts[0].Load())
+	assert.Equal(t, int32(5), counts[1].Load())
+
+	// With B down, the call picked for it fails to connect and ejects it. A call
+	// sent before the client saw B close its connection may fail as well.
+	require.NoError(t, serverB.Shutdown(context.Background()))
+	failed := 0
+	for i := 0; i < 10; i++ {
+		if err := ping(); err != nil {
+			assert.Equal(t, rpc.CodeUnavailable, rpc.CodeOf(err))
+			failed++
+		}
+	}
+	assert.LessOrEqual(t, failed, 2)
+	assert.Equal(t, int32(5+10-failed), counts[0].Load())
+	endpoints := client.Endpoints()
+	require.Len(t, endpoints, 2)
+	assert.True(t, endpoints[1].Ejected())
+
+	// B is added back by the health probe once it is up again.
+	serverB = startServer(1)
+	defer serverB.Shutdown(context.Background())
+	require.Eventually(t, func() bool { return !endpoints[1].Ejected() }, 2*time.Second, 10*time.Millisecond)
+
+	counts[1].Store(0)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, ping())
+	}
+	assert.Equal(t, int32(5), counts[1].Load())
+}
+
+// runRetryTest verifies that a call of an idempotent method is retried until a
+// server that is starting up accepts it, and that the retries stop at the
+// caller's deadline.
+func runRetryTest(t *testing.T, factory TransportFactory, port int) {
+	client := rpc.NewClient(rpc.ClientConfig{
+		Transport: factory.CreateClientTransport(port),
+		Backoff:   rpc.Backoff{BaseDelay: 20 * time.Millisecond, MaxDelay: 50 * time.Millisecond},
+		Retry: &rpc.RetryPolicy{
+			MaxAttempts: 10,
+			Backoff:     rpc.Backoff{BaseDelay: 50 * time.Millisecond, MaxDelay: 100 * time.Millisecond},
+		},
+	})
+	defer client.Close()
+	c := pingpong.NewPingPongClient(client)
+
+	// No server is listening yet, so the retries end at the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Ping(ctx, &pingpong.PingRequest{Ping: pingpong.Ping{Count: 1}})
+	require.Error(t, err)
+	assert.Equal(t, rpc.CodeUnavailable, rpc.CodeOf(err))
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	server := rpc.NewServer(rpc.ServerConfig{
+		Transport: factory.CreateServerTransport(port),
+	})
+	pingpong.RegisterPingPongServer(server, &pingpongServer{})
+	defer server.Shutdown(context.Background())
+	time.AfterFunc(150*time.Millisecond, func() {
+		server.ListenAndServe()
+	})
+
+	resp, err := c.Ping(context.Background(), &pingpong.PingRequest{Ping: pingpong.Ping{Count: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.Pong.Count)
+}
+
 // runLargePayloadTest tests handling of large messages
 func runLargePayloadTest(t *testing.T, factory TransportFactory, id int, testCases []LargePayloadTestCase) {
 	server := rpc.NewServer(rpc.ServerConfig{
@@ -1381,6 +1658,118 @@ func runContextTimeoutRecoveryTest(t *testing.T, factory TransportFactory, port
 	assert.Equal(t, int32(43), resp.Pong.Count)
 }
 
+// runContextDeadlinePropagationTest verifies that the deadline of the caller is
+// sent with the request, so that the handler of a call the caller has given up
+// on is cancelled rather than left running.
+func runContextDeadlinePropagationTest(t *testing.T, factory TransportFactory, port int) {
+	serverTransport := factory.CreateServerTransport(port)
+	server := rpc.NewServer(rpc.ServerConfig{
+		Transport: serverTransport,
+	})
+
+	pingPongSvc := &pingpongServerDeadline{
+		done: make(chan error, 1),
+	}
+	pingpong.RegisterPingPongServer(server, pingPongSvc)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			if err.Error() != "transport is closed" {
+				fmt.Printf("Server error: %v\n", err)
+			}
+		}
+	}()
+	defer server.Shutdown(context.Background())
+
+	time.Sleep(100 * time.Millisecond)
+
+	clientTransport := factory.CreateClientTransport(port)
+	client := rpc.NewClient(rpc.ClientConfig{
+		Transport: clientTransport,
+	})
+	defer client.Close()
+
+	svc := pingpong.NewPingPongClient(client)
+
+	// A call without a deadline reaches the handler without one.
+	_, err := svc.Ping(context.Background(), &pingpong.PingRequest{})
+	require.Error(t, err)
+	assert.Equal(t, rpc.CodeFailedPrecondition, rpc.CodeOf(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = svc.Ping(ctx, &pingpong.PingRequest{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The handler is released by the same deadline, not by its own timeout.
+	select {
+	case err := <-pingPongSvc.done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler context did not observe the deadline of the caller")
+	}
+}
+
+// runContextCancelPropagationTest verifies that cancelling the context of a call
+// cancels the context of its handler on the server.
+func runContextCancelPropagationTest(t *testing.T, factory TransportFactory, port int) {
+	serverTransport := factory.CreateServerTransport(port)
+	server := rpc.NewServer(rpc.ServerConfig{
+		Transport: serverTransport,
+	})
+
+	pingPongSvc := &pingpongServerBlocking{
+		done: make(chan error, 1),
+	}
+	pingpong.RegisterPingPongServer(server, pingPongSvc)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			if err.Error() != "transport is closed" {
+				fmt.Printf("Server error: %v\n", err)
+			}
+		}
+	}()
+	defer server.Shutdown(context.Background())
+
+	time.Sleep(100 * time.Millisecond)
+
+	clientTransport := factory.CreateClientTransport(port)
+	client := rpc.NewClient(rpc.ClientConfig{
+		Transport: clientTransport,
+	})
+	defer client.Close()
+
+	svc := pingpong.NewPingPongClient(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := svc.Ping(ctx, &pingpong.PingRequest{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case err := <-pingPongSvc.done:
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler context was not cancelled with the caller")
+	}
+
+	// The connection remains usable after the cancellation.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel2()
+	_, err = svc.Ping(ctx2, &pingpong.PingRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-pingPongSvc.done
+}
+
 func runContextMetadataTest(t *testing.T, factory TransportFactory, port int) {
 	// Start server
 	serverTransport := factory.CreateServerTransport(port)
@@ -1732,6 +2121,7 @@ func runStreamServerErrorTest(t *testing.T, factory TransportFactory, id int) {
 	_, err = stream.Recv()
 	require.Error(t, err)
 	assert.Equal(t, "requested failure", err.Error())
+	assert.Equal(t, rpc.CodeUnknown, rpc.CodeOf(err))
 }
 
 // runStreamConcurrentTest opens multiple independent streams on one connection
@@ -1894,6 +2284,7 @@ func runStreamConnectionDropTest(t *testing.T, factory TransportFactory, id int)
 
 	_, err = stream.Recv()
 	require.Error(t, err)
+	assert.Equal(t, rpc.CodeUnavailable, rpc.CodeOf(err))
 }
 
 // runStreamAuthFailTest verifies stream OPEN is gated by server middleware
@@ -2112,6 +2503,7 @@ func runStreamMaxConcurrentTest(t *testing.T, factory TransportFactory, id int)
 	_, err = s3.Recv()
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "max concurrent streams")
+	assert.Equal(t, rpc.CodeResourceExhausted, rpc.CodeOf(err))
 
 	// Freeing a slot lets a new stream open.
 	require.NoError(t, s1.CloseSend())
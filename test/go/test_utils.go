@@ -64,3 +64,47 @@ type pingpongServerFail struct {
 func (s *pingpongServerFail) Ping(ctx context.Context, req *pingpong.PingRequest) (*pingpong.PongResponse, error) {
 	return nil, fmt.Errorf("unable to ping the pong")
 }
+
+type pingpongServerStatus struct {
+}
+
+func (s *pingpongServerStatus) Ping(ctx context.Context, req *pingpong.PingRequest) (*pingpong.PongResponse, error) {
+	return nil, rpc.NewStatus(rpc.CodeNotFound, "no pong to ping").WithDetails(&pingpong.Pong{
+		Count: req.Ping.Count,
+	})
+}
+
+// pingpongServerDeadline blocks each call until its context is done and reports
+// the error of the context, so that tests can observe the deadline of the caller
+// reaching the handler.
+type pingpongServerDeadline struct {
+	done chan error
+}
+
+func (s *pingpongServerDeadline) Ping(ctx context.Context, req *pingpong.PingRequest) (*pingpong.PongResponse, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, rpc.Errorf(rpc.CodeFailedPrecondition, "no deadline")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+	}
+	s.done <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+// pingpongServerBlocking blocks each call until its context is done and reports
+// the error of the context, so that tests can observe the cancellation of the
+// caller reaching the handler.
+type pingpongServerBlocking struct {
+	done chan error
+}
+
+func (s *pingpongServerBlocking) Ping(ctx context.Context, req *pingpong.PingRequest) (*pingpong.PongResponse, error) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+	}
+	s.done <- ctx.Err()
+	return nil, ctx.Err()
+}